@@ -0,0 +1,321 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Kinds of archived resource, also the keys of archivedRecord.Memberships
+const (
+	archivedEngineer = "engineer"
+	archivedDev      = "dev"
+	archivedOps      = "ops"
+	archivedDevOps   = "devops"
+)
+
+// archivedReports keys the engineers that reported to an archived engineer in its
+// archivedRecord.Memberships
+const archivedReports = "reports"
+
+var archivedKindNames = map[string]string{
+	archivedEngineer: "engineer",
+	archivedDev:      "dev group",
+	archivedOps:      "ops group",
+	archivedDevOps:   "devops group",
+}
+
+// archivedRecord is a deleted resource, kept with the groups it was removed from so it
+// can be restored until the retention period runs out. Resource uses the export
+// document shape, and Memberships lists the IDs of those groups by kind.
+type archivedRecord struct {
+	Kind        string              `json:"kind"`
+	Id          string              `json:"id"`
+	ArchivedAt  time.Time           `json:"archived_at"`
+	ArchivedBy  string              `json:"archived_by"`
+	Resource    json.RawMessage     `json:"resource"`
+	Memberships map[string][]string `json:"memberships"`
+}
+
+// ArchiveStorage holds archived resources, oldest first. A resource archived again
+// replaces its earlier record.
+type ArchiveStorage interface {
+	Put(record *archivedRecord) error
+	Find(kind string, id string) (*archivedRecord, bool)
+	// List returns the records of kind, or every record when kind is empty
+	List(kind string) []*archivedRecord
+	Delete(kind string, id string) bool
+	Clear()
+}
+
+// archiveResource records resource as archived by actor, along with the groups it is
+// being removed from
+func archiveResource(uow *unitOfWork, kind string, id string, actor string, resource any, memberships map[string][]string) error {
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		return fmt.Errorf("failed to encode archived %s: %w", kind, err)
+	}
+	return uow.archive.Put(&archivedRecord{
+		Kind:        kind,
+		Id:          id,
+		ArchivedAt:  uow.now().UTC(),
+		ArchivedBy:  actor,
+		Resource:    raw,
+		Memberships: memberships,
+	})
+}
+
+func cloneArchivedRecord(record *archivedRecord) *archivedRecord {
+	out := *record
+	out.Memberships = make(map[string][]string, len(record.Memberships))
+	for kind, ids := range record.Memberships {
+		out.Memberships[kind] = append([]string{}, ids...)
+	}
+	return &out
+}
+
+func archiveKey(kind string, id string) string {
+	return kind + "/" + id
+}
+
+// ArchiveStore is the in-memory archive
+type ArchiveStore struct {
+	mu      timedRWMutex
+	records orderedRecords[*archivedRecord]
+}
+
+func newArchiveStore() *ArchiveStore {
+	return &ArchiveStore{mu: timedRWMutex{name: "archive"}, records: newOrderedRecords[*archivedRecord]()}
+}
+
+func (s *ArchiveStore) Put(record *archivedRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := archiveKey(record.Kind, record.Id)
+	s.records.remove(key)
+	s.records.put(key, cloneArchivedRecord(record))
+	return nil
+}
+
+func (s *ArchiveStore) Find(kind string, id string) (*archivedRecord, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if record, found := s.records.get(archiveKey(kind, id)); found {
+		return cloneArchivedRecord(record), true
+	}
+	return nil, false
+}
+
+func (s *ArchiveStore) List(kind string) []*archivedRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*archivedRecord, 0)
+	s.records.each(func(record *archivedRecord) {
+		if kind == "" || record.Kind == kind {
+			out = append(out, cloneArchivedRecord(record))
+		}
+	})
+	return out
+}
+
+func (s *ArchiveStore) Delete(kind string, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, found := s.records.remove(archiveKey(kind, id))
+	return found
+}
+
+func (s *ArchiveStore) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = newOrderedRecords[*archivedRecord]()
+}
+
+// SQLiteArchiveStore keeps the archive in the same database as the resources
+type SQLiteArchiveStore struct {
+	db     queryer
+	faults *storageFaults
+}
+
+func (s *SQLiteArchiveStore) Put(record *archivedRecord) error {
+	memberships, err := json.Marshal(record.Memberships)
+	if err != nil {
+		return err
+	}
+	return withTx(s.db, func(tx queryer) error {
+		if _, err := tx.Exec("DELETE FROM archive WHERE kind = ? AND id = ?", record.Kind, record.Id); err != nil {
+			return err
+		}
+		_, err := tx.Exec("INSERT INTO archive (kind, id, archived_at, archived_by, resource, memberships) VALUES (?, ?, ?, ?, ?, ?)",
+			record.Kind, record.Id, record.ArchivedAt.UnixNano(), record.ArchivedBy, string(record.Resource), string(memberships))
+		return err
+	})
+}
+
+func (s *SQLiteArchiveStore) Find(kind string, id string) (*archivedRecord, bool) {
+	records := s.query("SELECT kind, id, archived_at, archived_by, resource, memberships FROM archive WHERE kind = ? AND id = ?", kind, id)
+	if len(records) == 0 {
+		return nil, false
+	}
+	return records[0], true
+}
+
+func (s *SQLiteArchiveStore) List(kind string) []*archivedRecord {
+	return s.query("SELECT kind, id, archived_at, archived_by, resource, memberships FROM archive WHERE ? = '' OR kind = ? ORDER BY rowid", kind, kind)
+}
+
+func (s *SQLiteArchiveStore) Delete(kind string, id string) bool {
+	return execAffected(s.db, s.faults, "DELETE FROM archive WHERE kind = ? AND id = ?", kind, id)
+}
+
+func (s *SQLiteArchiveStore) Clear() {
+	execAffected(s.db, s.faults, "DELETE FROM archive")
+}
+
+func (s *SQLiteArchiveStore) query(query string, args ...any) []*archivedRecord {
+	out := make([]*archivedRecord, 0)
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		s.faults.record(fmt.Errorf("failed to query archive: %w", err))
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		record := &archivedRecord{}
+		var archivedAt int64
+		var resource, memberships string
+		if err := rows.Scan(&record.Kind, &record.Id, &archivedAt, &record.ArchivedBy, &resource, &memberships); err != nil {
+			s.faults.record(fmt.Errorf("failed to scan archived record: %w", err))
+			continue
+		}
+		record.ArchivedAt = time.Unix(0, archivedAt).UTC()
+		record.Resource = json.RawMessage(resource)
+		if err := json.Unmarshal([]byte(memberships), &record.Memberships); err != nil {
+			s.faults.record(fmt.Errorf("failed to decode memberships of archived %s %s: %w", record.Kind, record.Id, err))
+		}
+		out = append(out, record)
+	}
+	if err := rows.Err(); err != nil {
+		s.faults.record(fmt.Errorf("failed to read archive: %w", err))
+	}
+	return out
+}
+
+// capture returns a function putting the record of kind and id back the way it is now
+func (s *ArchiveStore) capture(kind string, id string) func() {
+	key := archiveKey(kind, id)
+	s.mu.RLock()
+	old, existed := s.records.get(key)
+	next := s.records.next(key)
+	s.mu.RUnlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.records.remove(key)
+		if existed {
+			s.records.insertBefore(next, key, old)
+		}
+	}
+}
+
+type journaledArchiveStore struct {
+	*ArchiveStore
+	journal *undoJournal
+}
+
+func (s *journaledArchiveStore) Put(record *archivedRecord) error {
+	s.journal.record(s.capture(record.Kind, record.Id))
+	return s.ArchiveStore.Put(record)
+}
+
+func (s *journaledArchiveStore) Delete(kind string, id string) bool {
+	s.journal.record(s.capture(kind, id))
+	return s.ArchiveStore.Delete(kind, id)
+}
+
+func (s *journaledArchiveStore) Clear() {
+	for _, record := range s.List("") {
+		s.journal.record(s.capture(record.Kind, record.Id))
+	}
+	s.ArchiveStore.Clear()
+}
+
+// purgeArchive permanently deletes the records archived before cutoff, returning how many
+func (s *Server) purgeArchive(cutoff time.Time) (int, error) {
+	purged := 0
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		purged = 0
+		for _, record := range uow.archive.List("") {
+			if record.ArchivedAt.Before(cutoff) && uow.archive.Delete(record.Kind, record.Id) {
+				purged++
+			}
+		}
+		return nil
+	})
+	return purged, err
+}
+
+// retentionJob purges records of server archived longer than period ago
+type retentionJob struct {
+	server *Server
+	period time.Duration
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// configureRetention stops the running retention job and starts one purging records
+// archived for longer than period, such as 720h. Archived records are kept forever when
+// period is empty.
+func (s *Server) configureRetention(period string) error {
+	if s.retention != nil {
+		s.retention.stop()
+		s.retention = nil
+	}
+	if period == "" {
+		return nil
+	}
+	duration, err := time.ParseDuration(period)
+	if err != nil || duration <= 0 {
+		return errors.New("retention must be a positive duration such as 720h, got " + period)
+	}
+	s.retention = &retentionJob{server: s, period: duration}
+	s.retention.start()
+	return nil
+}
+
+func (j *retentionJob) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.done.Add(1)
+	go func() {
+		defer j.done.Done()
+		ticker := time.NewTicker(j.server.retentionInterval)
+		defer ticker.Stop()
+		for {
+			j.purge()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (j *retentionJob) purge() {
+	purged, err := j.server.purgeArchive(j.server.now().Add(-j.period))
+	if err != nil {
+		log.Printf("retention: failed to purge archived records: %v", err)
+	} else if purged > 0 {
+		log.Printf("retention: purged %d records archived more than %s ago", purged, j.period)
+	}
+}
+
+func (j *retentionJob) stop() {
+	j.cancel()
+	j.done.Wait()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+var archiveTests = []struct {
+	description string
+	method      string
+	url         string
+	body        string
+	expected    int
+}{
+	{"delete engineer", "DELETE", "/engineers/E1", "", http.StatusOK},
+	{"deleted engineer is hidden", "GET", "/engineers/id/E1", "", http.StatusNotFound},
+	{"delete archived engineer", "DELETE", "/engineers/E1", "", http.StatusNotFound},
+	{"restore unknown engineer", "POST", "/engineers/E9/restore", "", http.StatusNotFound},
+	{"take the archived name", "PUT", "/engineers/E2", `{"name": "bob", "email": "alice@bob.com"}`, http.StatusOK},
+	{"restore with name taken", "POST", "/engineers/E1/restore", "", http.StatusConflict},
+	{"give the name back", "PUT", "/engineers/E2", `{"name": "alice", "email": "alice@bob.com"}`, http.StatusOK},
+	{"restore engineer", "POST", "/engineers/E1/restore", "", http.StatusOK},
+	{"restored engineer is found", "GET", "/engineers/id/E1", "", http.StatusOK},
+	{"delete dev group", "DELETE", "/dev/D1", "", http.StatusOK},
+	{"delete devops group", "DELETE", "/devops/DO1", "", http.StatusOK},
+	{"restore devops without its archived dev group", "POST", "/devops/DO1/restore", "", http.StatusOK},
+	{"restore dev group into devops", "POST", "/dev/D1/restore", "", http.StatusOK},
+	{"restore dev group twice", "POST", "/dev/D1/restore", "", http.StatusNotFound},
+	{"delete and restore ops group", "DELETE", "/op/O1", "", http.StatusOK},
+	{"restore ops group", "POST", "/op/O1/restore", "", http.StatusOK},
+}
+
+func testDeleteAndRestore(t *testing.T, s *Server) {
+	gin.SetMode(gin.TestMode)
+	seedUnitOfWork(t, s)
+	router := newTestRouter(t, s)
+
+	for _, test := range archiveTests {
+		w := mockConditionalRequest(router, test.method, test.url, "", test.body)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+	}
+
+	chart := s.exportOrgChart()
+	expected := []chartGroup{{Id: "D1", Name: "dev_ferrets", Engineers: []string{"E1"}}}
+	if !reflect.DeepEqual(chart.Devs, expected) {
+		t.Errorf("Expected: %+v, Received: %+v", expected, chart.Devs)
+	}
+	expected = []chartGroup{{Id: "O1", Name: "op_ferrets", Engineers: []string{"E1"}}}
+	if !reflect.DeepEqual(chart.Ops, expected) {
+		t.Errorf("Expected: %+v, Received: %+v", expected, chart.Ops)
+	}
+	expectedDevOps := []chartDevOps{{Id: "DO1", Devs: []string{"D1"}, Ops: []string{"O1"}}}
+	if !reflect.DeepEqual(chart.DevOps, expectedDevOps) {
+		t.Errorf("Expected: %+v, Received: %+v", expectedDevOps, chart.DevOps)
+	}
+	if records := s.archiveStore.List(""); len(records) != 0 {
+		t.Errorf("Expected: an empty archive, Received: %+v", records)
+	}
+}
+
+func TestDeleteAndRestore(t *testing.T) {
+	s := newTestServer(t)
+	testDeleteAndRestore(t, s)
+}
+
+func TestSQLiteDeleteAndRestore(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testDeleteAndRestore(t, s)
+}
+
+func TestListArchive(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedUnitOfWork(t, s)
+	router := newTestRouter(t, s)
+	mockConditionalRequest(router, "DELETE", "/engineers/E1", "", "")
+	mockConditionalRequest(router, "DELETE", "/dev/D1", "", "")
+
+	w := mockConditionalRequest(router, "GET", "/archive?kind=engineer", "", "")
+	var records []archivedRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &records); err != nil || len(records) != 1 {
+		t.Fatalf("Expected: one archived engineer, Received: %s", w.Body.String())
+	}
+	record := records[0]
+	expected := map[string][]string{archivedDev: {"D1"}, archivedOps: {"O1"}, archivedReports: {}}
+	if record.Id != "E1" || record.ArchivedBy != "anonymous" || !reflect.DeepEqual(record.Memberships, expected) {
+		t.Errorf("Expected: E1 archived by anonymous with memberships %v, Received: %+v", expected, record)
+	}
+	var engineer devops_resource.Engineer
+	if json.Unmarshal(record.Resource, &engineer); engineer.Name != "bob" {
+		t.Errorf("Expected: the archived engineer bob, Received: %s", record.Resource)
+	}
+
+	if w := mockConditionalRequest(router, "GET", "/archive", "", ""); w.Header().Get("X-Total-Count") != "2" {
+		t.Errorf("Expected: X-Total-Count 2, Received: %s", w.Header().Get("X-Total-Count"))
+	}
+	if w := mockConditionalRequest(router, "GET", "/archive?kind=people", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected: Status Code %d, Received: Status Code %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestListIncludeArchived(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedUnitOfWork(t, s)
+	router := newTestRouter(t, s)
+	mockConditionalRequest(router, "DELETE", "/engineers/E1", "", "")
+	mockConditionalRequest(router, "DELETE", "/dev/D1", "", "")
+
+	var listed []map[string]any
+	w := mockConditionalRequest(router, "GET", "/engineers", "", "")
+	if json.Unmarshal(w.Body.Bytes(), &listed); len(listed) != 1 || listed[0]["id"] != "E2" {
+		t.Errorf("Expected: only E2 without include_archived, Received: %s", w.Body.String())
+	}
+
+	w = mockConditionalRequest(router, "GET", "/engineers?include_archived=true&sort=id", "", "")
+	if json.Unmarshal(w.Body.Bytes(), &listed); len(listed) != 2 {
+		t.Fatalf("Expected: E1 and E2, Received: %s", w.Body.String())
+	}
+	if listed[0]["id"] != "E1" || listed[0]["name"] != "bob" || listed[0]["archived_by"] != "anonymous" || listed[0]["archived_at"] == nil {
+		t.Errorf("Expected: archived E1 with archived_at and archived_by, Received: %v", listed[0])
+	}
+	if _, found := listed[1]["archived_at"]; found || listed[1]["id"] != "E2" {
+		t.Errorf("Expected: E2 without archived_at, Received: %v", listed[1])
+	}
+
+	w = mockConditionalRequest(router, "GET", "/dev?include_archived=true&name=ferrets", "", "")
+	if json.Unmarshal(w.Body.Bytes(), &listed); len(listed) != 1 || listed[0]["id"] != "D1" || listed[0]["archived_by"] != "anonymous" {
+		t.Errorf("Expected: the archived D1, Received: %s", w.Body.String())
+	}
+
+	if w := mockConditionalRequest(router, "GET", "/op?include_archived=maybe", "", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected: Status Code %d, Received: Status Code %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestPurgeArchive(t *testing.T) {
+	s := newTestServer(t)
+	seedUnitOfWork(t, s)
+	s.Engineers.Delete("E1", anyVersion, "")
+	s.Devs.Delete("D1", anyVersion, "")
+
+	if purged, _ := s.purgeArchive(time.Now().Add(-time.Hour)); purged != 0 {
+		t.Errorf("Expected: nothing archived an hour ago to be purged, Received: %d purged", purged)
+	}
+	if purged, _ := s.purgeArchive(time.Now().Add(time.Second)); purged != 2 {
+		t.Errorf("Expected: 2 purged, Received: %d purged", purged)
+	}
+	if _, err := s.Engineers.Restore("E1"); err == nil {
+		t.Errorf("Error: Expected Errors, recieved none.")
+	}
+}
+
+func TestRetentionJob(t *testing.T) {
+	s := newTestServer(t)
+	s.retentionInterval = 10 * time.Millisecond
+	seedUnitOfWork(t, s)
+	s.Engineers.Delete("E2", anyVersion, "")
+
+	if err := s.configureRetention("1ms"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.archiveStore.List("")) > 0 && time.Now().Before(deadline) {
+		time.Sleep(s.retentionInterval)
+	}
+	if records := s.archiveStore.List(""); len(records) != 0 {
+		t.Errorf("Expected: the retention job to purge the archive, Received: %+v", records)
+	}
+
+	for _, period := range []string{"soon", "-1h", "0s"} {
+		if err := s.configureRetention(period); err == nil {
+			t.Errorf("\nTest: %s\nError: Expected Errors, recieved none.", period)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// auditEntry records one unit of work committed for a POST, PUT, PATCH or DELETE
+type auditEntry struct {
+	Seq        int64                  `json:"seq"`
+	Time       time.Time              `json:"time"`
+	Actor      string                 `json:"actor"`
+	Client     string                 `json:"client"`
+	Method     string                 `json:"method"`
+	Route      string                 `json:"route"`
+	Path       string                 `json:"path"`
+	Resource   string                 `json:"resource"`
+	ResourceID string                 `json:"resource_id,omitempty"`
+	Before     json.RawMessage        `json:"before,omitempty"`
+	After      json.RawMessage        `json:"after,omitempty"`
+	Diff       map[string]auditChange `json:"diff,omitempty"`
+}
+
+// auditChange is the before and after value of one top level field
+type auditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// auditQuery selects entries, empty fields match everything
+type auditQuery struct {
+	resource   string
+	resourceID string
+	since      time.Time
+}
+
+func (q auditQuery) matches(entry *auditEntry) bool {
+	return (q.resource == "" || entry.Resource == q.resource) &&
+		(q.resourceID == "" || entry.ResourceID == q.resourceID) &&
+		!entry.Time.Before(q.since)
+}
+
+// AuditSink is an append-only store of audit entries
+type AuditSink interface {
+	// Append assigns the next sequence number to entry and stores it
+	Append(entry *auditEntry) error
+	// Query returns the matching entries, oldest first
+	Query(query auditQuery) ([]*auditEntry, error)
+}
+
+// auditTarget maps the routes under prefix to a resource, the kind of ID created for it
+// and a snapshot of its state in a unit of work
+type auditTarget struct {
+	prefix   string
+	resource string
+	kind     string
+	snapshot func(uow *unitOfWork, id string) any
+}
+
+// more specific prefixes first, /devops must win over /dev
+var auditTargets = []auditTarget{
+	{"/engineers", "engineers", archivedEngineer, snapshotEngineer},
+	{"/devops", "devops", archivedDevOps, snapshotDevOps},
+	{"/dev", "dev", archivedDev, snapshotDev},
+	{"/op", "ops", archivedOps, snapshotOps},
+	{"/import", "import", "", func(uow *unitOfWork, _ string) any {
+		return exportStores(uow.engineers, uow.devs, uow.ops, uow.devops)
+	}},
+}
+
+func findAuditTarget(route string) (auditTarget, bool) {
+	for _, target := range auditTargets {
+		if strings.HasPrefix(route, target.prefix) {
+			return target, true
+		}
+	}
+	return auditTarget{}, false
+}
+
+// snapshots use the export document shapes so memberships are recorded as IDs
+func snapshotEngineer(uow *unitOfWork, id string) any {
+	if engineer, found := uow.engineers.FindByID(id); found {
+		return engineer
+	}
+	return nil
+}
+
+func snapshotDev(uow *unitOfWork, id string) any {
+	if dev, found := uow.devs.FindByID(id); found {
+		return devChart(dev)
+	}
+	return nil
+}
+
+func snapshotOps(uow *unitOfWork, id string) any {
+	if op, found := uow.ops.FindByID(id); found {
+		return opsChart(op)
+	}
+	return nil
+}
+
+func snapshotDevOps(uow *unitOfWork, id string) any {
+	if devops, found := uow.devops.FindByID(id); found {
+		return devOpsChart(devops)
+	}
+	return nil
+}
+
+func marshalSnapshot(snapshot any) json.RawMessage {
+	if snapshot == nil {
+		return nil
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("audit: failed to encode snapshot: %v", err)
+		return nil
+	}
+	return raw
+}
+
+// diffSnapshots lists the top level fields that differ between two JSON objects
+func diffSnapshots(before json.RawMessage, after json.RawMessage) map[string]auditChange {
+	var beforeFields, afterFields map[string]any
+	json.Unmarshal(before, &beforeFields)
+	json.Unmarshal(after, &afterFields)
+
+	diff := map[string]auditChange{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			diff[field] = auditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, seen := beforeFields[field]; !seen {
+			diff[field] = auditChange{After: value}
+		}
+	}
+	return diff
+}
+
+func actorOf(c *gin.Context) string {
+	if value, found := c.Get(principalKey); found {
+		return value.(principal).Subject
+	}
+	return "anonymous"
+}
+
+// auditHook appends an entry to the audit log of the server for every unit of work
+// committed for the request of c, with the target resource as it was before and after
+type auditHook struct {
+	server *Server
+	c      *gin.Context
+	target auditTarget
+	before json.RawMessage
+}
+
+func (h *auditHook) begin(uow *unitOfWork) {
+	h.before = marshalSnapshot(h.target.snapshot(uow, h.c.Param("id")))
+}
+
+func (h *auditHook) prepare(uow *unitOfWork) (func(), error) {
+	id := h.c.Param("id")
+	if id == "" && h.target.kind != "" {
+		id = uow.created[h.target.kind]
+	}
+	entry := &auditEntry{
+		Time:       h.server.now().UTC(),
+		Actor:      actorOf(h.c),
+		Client:     h.c.ClientIP(),
+		Method:     h.c.Request.Method,
+		Route:      h.c.FullPath(),
+		Path:       h.c.Request.URL.Path,
+		Resource:   h.target.resource,
+		ResourceID: id,
+		Before:     h.before,
+		After:      marshalSnapshot(h.target.snapshot(uow, id)),
+	}
+	entry.Diff = diffSnapshots(entry.Before, entry.After)
+	return func() {
+		if err := h.server.audit.Append(entry); err != nil {
+			log.Printf("audit: failed to record %s %s: %v", entry.Method, entry.Path, err)
+		}
+	}, nil
+}
+
+// recordAudit audits the units of work the handlers after it commit, see auditHook
+func (s *Server) recordAudit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if target, found := findAuditTarget(c.FullPath()); found {
+			addUnitOfWorkHook(c, &auditHook{server: s, c: c, target: target})
+		}
+		c.Next()
+	}
+}
+
+// parseAuditQuery reads ?resource= (a resource such as engineers, or engineers/<id>) and ?since= (RFC 3339)
+func parseAuditQuery(c *gin.Context) (auditQuery, error) {
+	var query auditQuery
+	query.resource, query.resourceID, _ = strings.Cut(c.Query("resource"), "/")
+	if value := c.Query("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return query, badRequest("invalid_since", "since must be an RFC 3339 timestamp such as 2024-01-02T15:04:05Z")
+		}
+		query.since = since
+	}
+	return query, nil
+}
+
+var auditSortFields = map[string]func(*auditEntry) string{
+	"time": func(entry *auditEntry) string { return fmt.Sprintf("%020d", entry.Time.UnixNano()) },
+}
+
+func auditEntryID(entry *auditEntry) string { return fmt.Sprintf("%020d", entry.Seq) }
+
+// server handler for GET /audit
+func (s *Server) getAudit(c *gin.Context) {
+	query, err := parseAuditQuery(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	params, err := parseListParams(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	entries, err := s.audit.Query(query)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	page, err := paginate(c, entries, params, auditSortFields, auditEntryID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
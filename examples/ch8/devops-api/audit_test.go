@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func mockAuditRequest(router *gin.Engine, method string, url string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func queryAudit(t *testing.T, router *gin.Engine, url string) []auditEntry {
+	w := mockAuditRequest(router, "GET", url, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected: Status Code 200 from %s, Received: Status Code %d\nBody: %s", url, w.Code, w.Body.String())
+	}
+	var entries []auditEntry
+	json.Unmarshal(w.Body.Bytes(), &entries)
+	return entries
+}
+
+func TestAuditRecordsMutations(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	start := time.Now().UTC().Add(-time.Second).Format(time.RFC3339)
+
+	var engineer struct{ Id string }
+	w := mockAuditRequest(router, "POST", "/engineers", `{"name": "bob", "email": "bob@bob.com"}`)
+	json.Unmarshal(w.Body.Bytes(), &engineer)
+	mockAuditRequest(router, "PUT", "/engineers/"+engineer.Id, `{"name": "rob", "email": "bob@bob.com"}`)
+	mockAuditRequest(router, "POST", "/dev", `{"name": "dev_ferrets"}`)
+	mockAuditRequest(router, "POST", "/engineers", `{"name": "rob", "email": "rob@bob.com"}`)
+	mockAuditRequest(router, "DELETE", "/engineers/"+engineer.Id, "")
+
+	entries := queryAudit(t, router, "/audit?resource=engineers/"+engineer.Id+"&since="+start)
+	if len(entries) != 3 {
+		t.Fatalf("Expected: 3 entries for the engineer, Received: %d %v", len(entries), entries)
+	}
+	if entries[0].Method != "POST" || entries[0].Before != nil || entries[0].Actor != "anonymous" {
+		t.Errorf("Expected a create entry without a before snapshot, Received: %+v", entries[0])
+	}
+	if change := entries[1].Diff["name"]; change.Before != "bob" || change.After != "rob" || len(entries[1].Diff) != 1 {
+		t.Errorf("Expected only the name in the update diff, Received: %v", entries[1].Diff)
+	}
+	if entries[2].Method != "DELETE" || entries[2].After != nil || entries[2].Route != "/engineers/:id" {
+		t.Errorf("Expected a delete entry without an after snapshot, Received: %+v", entries[2])
+	}
+
+	if entries := queryAudit(t, router, "/audit?resource=dev"); len(entries) != 1 {
+		t.Errorf("Expected: 1 dev entry, the failed duplicate engineer is not audited, Received: %d", len(entries))
+	}
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	if entries := queryAudit(t, router, "/audit?since="+future); len(entries) != 0 {
+		t.Errorf("Expected: no entries from the future, Received: %d", len(entries))
+	}
+	if w := mockAuditRequest(router, "GET", "/audit?since=yesterday", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected: Status Code 400 for a bad since, Received: Status Code %d", w.Code)
+	}
+}
+
+func TestAuditSinks(t *testing.T) {
+	dir := t.TempDir()
+	sinks := []struct {
+		description string
+		open        func() (AuditSink, error)
+	}{
+		{"memory", func() (AuditSink, error) { return newMemoryAuditSink(), nil }},
+		{"file", func() (AuditSink, error) { return openFileAuditSink(filepath.Join(dir, "audit.jsonl")) }},
+		{"sqlite", func() (AuditSink, error) { return openSQLiteAuditSink(filepath.Join(dir, "audit.db")) }},
+	}
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range sinks {
+		sink, err := test.open()
+		if err != nil {
+			t.Fatalf("\nTest: %s\nfailed to open sink: %v", test.description, err)
+		}
+		sink.Append(&auditEntry{Time: old, Resource: "engineers", ResourceID: "E1", Method: "POST"})
+		sink.Append(&auditEntry{Time: time.Now().UTC(), Resource: "dev", ResourceID: "D1", Method: "POST"})
+		sink.Append(&auditEntry{Time: time.Now().UTC(), Resource: "engineers", ResourceID: "E1", Method: "DELETE"})
+
+		engineers, _ := sink.Query(auditQuery{resource: "engineers", resourceID: "E1"})
+		recent, _ := sink.Query(auditQuery{since: old.Add(time.Hour)})
+		if len(engineers) != 2 || engineers[1].Seq != 3 || engineers[1].Method != "DELETE" {
+			t.Errorf("\nTest: %s\nExpected: both engineer entries in order, Received: %v", test.description, engineers)
+		}
+		if len(recent) != 2 || recent[0].Resource != "dev" {
+			t.Errorf("\nTest: %s\nExpected: the two recent entries, Received: %v", test.description, recent)
+		}
+	}
+
+	// the file sink keeps numbering after a restart
+	sink, _ := openFileAuditSink(filepath.Join(dir, "audit.jsonl"))
+	entry := &auditEntry{Time: time.Now().UTC(), Resource: "ops"}
+	sink.Append(entry)
+	if entry.Seq != 4 {
+		t.Errorf("Expected: seq 4 after reopening the file, Received: %d", entry.Seq)
+	}
+}
+
+func TestAuditSnapshotsInTheUnitOfWork(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	s.configureRateLimits(rateLimit{}, rateLimit{Rate: 0.001, Burst: 2})
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+
+	var engineer struct{ Id string }
+	w := mockAuditRequest(router, "POST", "/engineers", `{"name": "bob", "email": "bob@bob.com"}`)
+	json.Unmarshal(w.Body.Bytes(), &engineer)
+	mockAuditRequest(router, "PUT", "/engineers/"+engineer.Id, `{"name": "rob", "email": "bob@bob.com"}`)
+	if w := mockAuditRequest(router, "POST", "/dev", `{"name": "dev_ferrets"}`); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected: Status Code 429 once the writes are used up, Received: Status Code %d", w.Code)
+	}
+
+	// reading the log counts against the reads, not the writes
+	entries := queryAudit(t, router, "/audit")
+	if len(entries) != 2 {
+		t.Fatalf("Expected: 2 entries, the limited request is not audited, Received: %d %v", len(entries), entries)
+	}
+	if entries[0].ResourceID != engineer.Id || entries[0].Before != nil || entries[0].After == nil {
+		t.Errorf("Expected a create entry of %s with only an after snapshot, Received: %+v", engineer.Id, entries[0])
+	}
+	if change := entries[1].Diff["name"]; change.Before != "bob" || change.After != "rob" || len(entries[1].Diff) != 1 {
+		t.Errorf("Expected only the name in the update diff, Received: %v", entries[1].Diff)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit sinks selectable with -audit or DEVOPS_AUDIT
+const (
+	auditMemory = "memory"
+	auditFile   = "file"
+	auditSQLite = "sqlite"
+)
+
+// configureAudit swaps the audit log of the server for the requested sink, path defaults to
+// audit.jsonl for the file sink and audit.db for the sqlite sink
+func (s *Server) configureAudit(kind string, path string) error {
+	switch kind {
+	case auditMemory:
+		s.audit = newMemoryAuditSink()
+		return nil
+	case auditFile:
+		sink, err := openFileAuditSink(orDefault(path, "audit.jsonl"))
+		if err != nil {
+			return err
+		}
+		s.audit = sink
+		return nil
+	case auditSQLite:
+		sink, err := openSQLiteAuditSink(orDefault(path, "audit.db"))
+		if err != nil {
+			return err
+		}
+		s.audit = sink
+		return nil
+	}
+	return errors.New("unknown audit sink " + kind)
+}
+
+// closeAudit flushes and closes the audit log when its sink holds a file or database
+func (s *Server) closeAudit() error {
+	if closer, isCloser := s.audit.(io.Closer); isCloser {
+		return closer.Close()
+	}
+	return nil
+}
+
+func orDefault(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// MemoryAuditSink keeps entries until the API stops
+type MemoryAuditSink struct {
+	mu      sync.RWMutex
+	entries []*auditEntry
+}
+
+func newMemoryAuditSink() *MemoryAuditSink {
+	return &MemoryAuditSink{entries: make([]*auditEntry, 0)}
+}
+
+func (s *MemoryAuditSink) Append(entry *auditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.Seq = int64(len(s.entries) + 1)
+	copied := *entry
+	s.entries = append(s.entries, &copied)
+	return nil
+}
+
+func (s *MemoryAuditSink) Query(query auditQuery) ([]*auditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*auditEntry, 0)
+	for _, entry := range s.entries {
+		if query.matches(entry) {
+			copied := *entry
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+// FileAuditSink appends one JSON object per line to a file
+type FileAuditSink struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	seq  int64
+}
+
+func openFileAuditSink(path string) (*FileAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	sink := &FileAuditSink{path: path, file: file}
+	// continue numbering after the entries already in the file
+	err = sink.scan(func(entry *auditEntry) { sink.seq = entry.Seq })
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return sink, nil
+}
+
+// scan decodes every line of the file in order
+func (s *FileAuditSink) scan(fn func(entry *auditEntry)) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("audit log %s line %d is corrupt: %w", s.path, line, err)
+		}
+		fn(&entry)
+	}
+	return scanner.Err()
+}
+
+func (s *FileAuditSink) Append(entry *auditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry.Seq = s.seq + 1
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(raw, '\n')); err != nil {
+		return err
+	}
+	s.seq = entry.Seq
+	return nil
+}
+
+func (s *FileAuditSink) Query(query auditQuery) ([]*auditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*auditEntry, 0)
+	err := s.scan(func(entry *auditEntry) {
+		if query.matches(entry) {
+			out = append(out, entry)
+		}
+	})
+	return out, err
+}
+
+// Close syncs the entries written so far to disk before closing the file
+func (s *FileAuditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(s.file.Sync(), s.file.Close())
+}
+
+// Schema for the SQLite audit sink, the entry itself is stored as JSON
+const auditSchema = `
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	time INTEGER NOT NULL,
+	resource TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	entry TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_resource ON audit_log (resource, resource_id, time);
+`
+
+// SQLiteAuditSink stores entries in the audit_log table
+type SQLiteAuditSink struct {
+	db *sql.DB
+}
+
+func openSQLiteAuditSink(path string) (*SQLiteAuditSink, error) {
+	db, err := openSQLite(path, auditSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &SQLiteAuditSink{db: db}, nil
+}
+
+func (s *SQLiteAuditSink) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteAuditSink) Append(entry *auditEntry) error {
+	return withTx(s.db, func(tx queryer) error {
+		rows, err := tx.Query("SELECT COALESCE(MAX(seq), 0) + 1 FROM audit_log")
+		if err != nil {
+			return err
+		}
+		var seq int64
+		rows.Next()
+		err = rows.Scan(&seq)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+		entry.Seq = seq
+		raw, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO audit_log (seq, time, resource, resource_id, entry) VALUES (?, ?, ?, ?, ?)",
+			seq, entry.Time.UnixNano(), entry.Resource, entry.ResourceID, string(raw))
+		return err
+	})
+}
+
+func (s *SQLiteAuditSink) Query(query auditQuery) ([]*auditEntry, error) {
+	rows, err := s.db.Query(`SELECT entry FROM audit_log
+		WHERE (? = '' OR resource = ?) AND (? = '' OR resource_id = ?) AND time >= ?
+		ORDER BY seq`,
+		query.resource, query.resource, query.resourceID, query.resourceID, sinceNanos(query.since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make([]*auditEntry, 0)
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, err
+		}
+		var entry auditEntry
+		if err := json.Unmarshal([]byte(raw), &entry); err != nil {
+			return nil, err
+		}
+		out = append(out, &entry)
+	}
+	return out, rows.Err()
+}
+
+// sinceNanos treats the zero time as the beginning of the log
+func sinceNanos(since time.Time) int64 {
+	if since.IsZero() {
+		return 0
+	}
+	return since.UnixNano()
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// role is what a caller is allowed to do, each role includes the ones below it
+type role int
+
+const (
+	roleNone role = iota
+	roleViewer
+	roleEditor
+	roleAdmin
+)
+
+var roleNames = map[string]role{"viewer": roleViewer, "editor": roleEditor, "admin": roleAdmin}
+
+func (r role) String() string {
+	for name, value := range roleNames {
+		if value == r {
+			return name
+		}
+	}
+	return "none"
+}
+
+// principal is the authenticated caller of a request
+type principal struct {
+	Subject string
+	Role    role
+}
+
+const principalKey = "principal"
+
+// authenticator verifies bearer tokens, either a static API key or a JWT signed by a key in the JWKS
+type authenticator struct {
+	apiKeys  map[string]principal // keyed by the hex sha256 of the API key
+	jwks     map[string]crypto.PublicKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// configureAuth loads the API key file and JWKS file the routers of s check tokens
+// against, an empty path skips that source. At least one source is required unless
+// insecureNoAuth turns authentication off.
+func (s *Server) configureAuth(apiKeysPath string, jwksPath string, issuer string, audience string, insecureNoAuth bool) error {
+	if apiKeysPath == "" && jwksPath == "" {
+		if !insecureNoAuth {
+			return errors.New("no credentials configured, set -api-keys or -jwks, or -insecure-no-auth to let every caller change everything")
+		}
+		log.Printf("authentication is disabled by -insecure-no-auth, every caller has the admin role")
+		s.auth = nil
+		return nil
+	}
+	if insecureNoAuth {
+		return errors.New("-insecure-no-auth can't be combined with -api-keys or -jwks")
+	}
+	authn := &authenticator{apiKeys: map[string]principal{}, jwks: map[string]crypto.PublicKey{}, issuer: issuer, audience: audience, now: time.Now}
+	if apiKeysPath != "" {
+		if err := authn.loadAPIKeys(apiKeysPath); err != nil {
+			return err
+		}
+	}
+	if jwksPath != "" {
+		if err := authn.loadJWKS(jwksPath); err != nil {
+			return err
+		}
+	}
+	s.auth = authn
+	return nil
+}
+
+// apiKeyFile is the YAML (or JSON) file listing static API keys
+type apiKeyFile struct {
+	Keys []struct {
+		Name string `yaml:"name"`
+		Key  string `yaml:"key"`
+		Role string `yaml:"role"`
+	} `yaml:"keys"`
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (authn *authenticator) loadAPIKeys(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read api keys: %w", err)
+	}
+	var file apiKeyFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("failed to parse api keys %s: %w", path, err)
+	}
+	for i, entry := range file.Keys {
+		keyRole, found := roleNames[entry.Role]
+		if entry.Key == "" || !found {
+			return fmt.Errorf("api key %d (%s) needs a key and a role of viewer, editor or admin", i, entry.Name)
+		}
+		authn.apiKeys[hashAPIKey(entry.Key)] = principal{Subject: entry.Name, Role: keyRole}
+	}
+	return nil
+}
+
+// jwk is the subset of RFC 7517 JSON web keys supported for RS256 and ES256
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+func (key jwk) publicKey() (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if key.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", key.Crv)
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", key.Kty)
+}
+
+func (authn *authenticator) loadJWKS(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read jwks: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return fmt.Errorf("failed to parse jwks %s: %w", path, err)
+	}
+	for i, key := range set.Keys {
+		publicKey, err := key.publicKey()
+		if err != nil {
+			return fmt.Errorf("jwks key %d (%s): %w", i, key.Kid, err)
+		}
+		authn.jwks[key.Kid] = publicKey
+	}
+	return nil
+}
+
+// jwtClaims are the registered claims checked by verifyJWT plus the role claims
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+	Role      string   `json:"role"`
+	Roles     []string `json:"roles"`
+}
+
+// audience accepts both forms of the aud claim, a string or an array of strings
+type audience []string
+
+func (aud *audience) UnmarshalJSON(raw []byte) error {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		*aud = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(raw, &many); err != nil {
+		return err
+	}
+	*aud = many
+	return nil
+}
+
+// verifyJWT checks the signature and claims of a compact serialized JWT
+func (authn *authenticator) verifyJWT(token string) (principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return principal{}, fmt.Errorf("token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return principal{}, fmt.Errorf("invalid JWT header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return principal{}, fmt.Errorf("invalid JWT signature: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	candidates := make([]crypto.PublicKey, 0, 1)
+	if key, found := authn.jwks[header.Kid]; found {
+		candidates = append(candidates, key)
+	} else if header.Kid == "" {
+		for _, key := range authn.jwks {
+			candidates = append(candidates, key)
+		}
+	}
+	verified := false
+	for _, key := range candidates {
+		if verifySignature(header.Alg, key, digest[:], signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return principal{}, fmt.Errorf("JWT signature does not match a configured key")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return principal{}, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	now := authn.now().Unix()
+	if claims.ExpiresAt == nil || now >= *claims.ExpiresAt {
+		return principal{}, fmt.Errorf("JWT is expired")
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return principal{}, fmt.Errorf("JWT is not valid yet")
+	}
+	if authn.issuer != "" && claims.Issuer != authn.issuer {
+		return principal{}, fmt.Errorf("JWT issuer %q is not trusted", claims.Issuer)
+	}
+	if authn.audience != "" && !containsString(claims.Audience, authn.audience) {
+		return principal{}, fmt.Errorf("JWT is not intended for %q", authn.audience)
+	}
+
+	caller := principal{Subject: claims.Subject}
+	for _, name := range append(claims.Roles, claims.Role) {
+		if tokenRole := roleNames[name]; tokenRole > caller.Role {
+			caller.Role = tokenRole
+		}
+	}
+	return caller, nil
+}
+
+func decodeSegment(segment string, into any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, into)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, digest []byte, signature []byte) bool {
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		return alg == "RS256" && rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest, signature) == nil
+	case *ecdsa.PublicKey:
+		if alg != "ES256" || len(signature) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		return ecdsa.Verify(publicKey, digest, r, s)
+	}
+	return false
+}
+
+func containsString(values []string, want string) bool {
+	for _, value := range values {
+		if value == want {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate resolves the bearer token of a request to the caller
+func (authn *authenticator) authenticate(c *gin.Context) (principal, error) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return principal{}, unauthorized("missing_token", "a bearer token is required")
+	}
+	if caller, found := authn.apiKeys[hashAPIKey(token)]; found {
+		return caller, nil
+	}
+	if len(authn.jwks) > 0 {
+		caller, err := authn.verifyJWT(token)
+		if err == nil {
+			return caller, nil
+		}
+		return principal{}, unauthorized("invalid_token", err.Error())
+	}
+	return principal{}, unauthorized("invalid_token", "the bearer token is not a known API key")
+}
+
+// authorize rejects requests from callers without at least the minimum role
+// with a 401 (no or bad token) or 403 (role too low)
+func (s *Server) authorize(minimum role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.auth == nil {
+			c.Next()
+			return
+		}
+		caller, err := s.auth.authenticate(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if caller.Role < minimum {
+			writeError(c, forbidden("insufficient_role", fmt.Sprintf("%s role required, token has %s", minimum, caller.Role)))
+			return
+		}
+		c.Set(principalKey, caller)
+		c.Next()
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+var (
+	testRSAKey, _  = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _   = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+)
+
+func encodeSegment(value any) string {
+	raw, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// signJWT builds a compact JWT signed with an RSA (RS256) or EC (ES256) private key
+func signJWT(t *testing.T, key crypto.Signer, kid string, claims map[string]any) string {
+	alg := "RS256"
+	if _, isEC := key.(*ecdsa.PrivateKey); isEC {
+		alg = "ES256"
+	}
+	signingInput := encodeSegment(map[string]string{"alg": alg, "typ": "JWT", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch privateKey := key.(type) {
+	case *rsa.PrivateKey:
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+// useAuth writes an API key file and JWKS to a temp dir and enables authentication on s
+func useAuth(t *testing.T, s *Server) {
+	dir := t.TempDir()
+	keysPath := filepath.Join(dir, "keys.yaml")
+	keys := "keys:\n" +
+		"  - {name: dashboard, key: viewer-key, role: viewer}\n" +
+		"  - {name: ci, key: editor-key, role: editor}\n" +
+		"  - {name: ops-team, key: admin-key, role: admin}\n"
+	if err := os.WriteFile(keysPath, []byte(keys), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	jwksPath := filepath.Join(dir, "jwks.json")
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": encodeBigInt(testRSAKey.N), "e": encodeBigInt(big.NewInt(int64(testRSAKey.E)))},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encodeBigInt(testECKey.X), "y": encodeBigInt(testECKey.Y)},
+	}})
+	if err := os.WriteFile(jwksPath, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.configureAuth(keysPath, jwksPath, "devops-idp", "devops-api", false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	useAuth(t, s)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	router := newTestRouter(t, s)
+
+	valid := func(extra map[string]any) map[string]any {
+		claims := map[string]any{"sub": "alice", "iss": "devops-idp", "aud": "devops-api", "exp": time.Now().Add(time.Hour).Unix()}
+		for claim, value := range extra {
+			claims[claim] = value
+		}
+		return claims
+	}
+
+	authTests := []struct {
+		description string
+		method      string
+		url         string
+		token       string
+		expected    int
+	}{
+		{"no token", "GET", "/engineers", "", http.StatusUnauthorized},
+		{"unknown api key", "GET", "/engineers", "nope", http.StatusUnauthorized},
+		{"spec is public", "GET", "/openapi.json", "", http.StatusOK},
+		{"viewer can read", "GET", "/engineers/id/E1", "viewer-key", http.StatusOK},
+		{"viewer cannot delete", "DELETE", "/engineers/E1", "viewer-key", http.StatusForbidden},
+		{"viewer cannot update", "PUT", "/devops/DO1", "viewer-key", http.StatusForbidden},
+		{"editor can create", "POST", "/engineers", "editor-key", http.StatusCreated},
+		{"editor cannot import", "POST", "/import", "editor-key", http.StatusForbidden},
+		{"admin can import", "POST", "/import", "admin-key", http.StatusOK},
+		{"rs256 editor jwt", "POST", "/dev", signJWT(t, testRSAKey, "rsa-1", valid(map[string]any{"role": "editor"})), http.StatusCreated},
+		{"es256 viewer jwt", "GET", "/dev", signJWT(t, testECKey, "ec-1", valid(map[string]any{"roles": []string{"viewer"}})), http.StatusOK},
+		{"jwt without role", "GET", "/dev", signJWT(t, testRSAKey, "rsa-1", valid(nil)), http.StatusForbidden},
+		{"expired jwt", "GET", "/dev", signJWT(t, testRSAKey, "rsa-1", valid(map[string]any{"role": "admin", "exp": time.Now().Add(-time.Minute).Unix()})), http.StatusUnauthorized},
+		{"wrong issuer", "GET", "/dev", signJWT(t, testRSAKey, "rsa-1", valid(map[string]any{"role": "admin", "iss": "elsewhere"})), http.StatusUnauthorized},
+		{"wrong audience", "GET", "/dev", signJWT(t, testRSAKey, "rsa-1", valid(map[string]any{"role": "admin", "aud": []string{"other-api"}})), http.StatusUnauthorized},
+		{"signed by unknown key", "GET", "/dev", signJWT(t, otherRSAKey, "rsa-1", valid(map[string]any{"role": "admin"})), http.StatusUnauthorized},
+	}
+
+	bodies := map[string]string{
+		"/engineers":  `{"name": "alice", "email": "alice@bob.com"}`,
+		"/dev":        `{"name": "dev_ferrets"}`,
+		"/import":     `{"engineers": [{"id": "E1", "name": "bob", "email": "bob@bob.com"}]}`,
+		"/devops/DO1": `{}`,
+	}
+	for _, test := range authTests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.url, strings.NewReader(bodies[test.url]))
+		req.Header.Set("Content-Type", "application/json")
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		router.ServeHTTP(w, req)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("\nTest: %s\nError: Expected a WWW-Authenticate header on 401", test.description)
+		}
+	}
+}
+
+func TestConfigureAuthRejectsBadKeys(t *testing.T) {
+	s := newTestServer(t)
+	keysPath := filepath.Join(t.TempDir(), "keys.yaml")
+	os.WriteFile(keysPath, []byte("keys:\n  - {name: ci, key: editor-key, role: owner}\n"), 0o600)
+	if err := s.configureAuth(keysPath, "", "", "", false); err == nil {
+		t.Errorf("Error: Expected Errors, recieved none.")
+	}
+}
+
+func TestConfigureAuthNeedsCredentialsOrOptOut(t *testing.T) {
+	s := newTestServer(t)
+	keysPath := filepath.Join(t.TempDir(), "keys.yaml")
+	os.WriteFile(keysPath, []byte("keys:\n  - {name: ci, key: editor-key, role: editor}\n"), 0o600)
+
+	if err := s.configureAuth("", "", "", "", false); err == nil {
+		t.Errorf("Expected starting without credentials to be refused, Received: no error")
+	}
+	if err := s.configureAuth(keysPath, "", "", "", true); err == nil {
+		t.Errorf("Expected -insecure-no-auth with an api key file to be refused, Received: no error")
+	}
+	if err := s.configureAuth("", "", "", "", true); err != nil || s.auth != nil {
+		t.Errorf("Expected -insecure-no-auth to disable authentication, Received: %v", err)
+	}
+	if err := s.configureAuth(keysPath, "", "", "", false); err != nil || s.auth == nil {
+		t.Errorf("Expected authentication with an api key file, Received: %v", err)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
+	"gopkg.in/yaml.v3"
+)
+
+// orgChart is the export/import document, memberships are listed as IDs
+type orgChart struct {
+	Engineers []*devops_resource.Engineer `json:"engineers" yaml:"engineers"`
+	Devs      []chartGroup                `json:"dev" yaml:"dev"`
+	Ops       []chartGroup                `json:"ops" yaml:"ops"`
+	DevOps    []chartDevOps               `json:"devops" yaml:"devops"`
+}
+
+type chartGroup struct {
+	Id        string   `json:"id" yaml:"id"`
+	Name      string   `json:"name" yaml:"name"`
+	Engineers []string `json:"engineers" yaml:"engineers"`
+}
+
+type chartDevOps struct {
+	Id   string   `json:"id" yaml:"id"`
+	Devs []string `json:"dev" yaml:"dev"`
+	Ops  []string `json:"ops" yaml:"ops"`
+}
+
+func isYAML(contentType string) bool {
+	switch contentType {
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// exportOrgChart snapshots every store into one document. The archive is not part of it,
+// an import clears the archive instead.
+func (s *Server) exportOrgChart() *orgChart {
+	return exportStores(s.engineerStore, s.devStore, s.opsStore, s.devOpsStore)
+}
+
+// exportStores returns the contents of the given stores as an org chart
+func exportStores(engineers EngineerStorage, devs DevStorage, ops OpsStorage, devOps DevOpsStorage) *orgChart {
+	chart := &orgChart{
+		Engineers: engineers.List(),
+		Devs:      make([]chartGroup, 0),
+		Ops:       make([]chartGroup, 0),
+		DevOps:    make([]chartDevOps, 0),
+	}
+	for _, dev := range devs.List() {
+		chart.Devs = append(chart.Devs, devChart(dev))
+	}
+	for _, op := range ops.List() {
+		chart.Ops = append(chart.Ops, opsChart(op))
+	}
+	for _, devops := range devOps.List() {
+		chart.DevOps = append(chart.DevOps, devOpsChart(devops))
+	}
+	return chart
+}
+
+// devChart, opsChart and devOpsChart convert a group into its document shape
+func devChart(dev *devops_resource.Dev) chartGroup {
+	return chartGroup{Id: dev.Id, Name: dev.Name, Engineers: engineerIDs(dev.Engineers)}
+}
+
+func opsChart(op *devops_resource.Ops) chartGroup {
+	return chartGroup{Id: op.Id, Name: op.Name, Engineers: engineerIDs(op.Engineers)}
+}
+
+func devOpsChart(devops *devops_resource.DevOps) chartDevOps {
+	return chartDevOps{Id: devops.Id, Devs: devIDs(devops.Devs), Ops: opsIDs(devops.Ops)}
+}
+
+// validate checks the document on its own: unique ids and names, valid fields and
+// memberships that only reference resources defined in the same document
+func (chart *orgChart) validate() []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	checkGroupIDs := func(kind string, ids []string) map[string]bool {
+		seen := map[string]bool{}
+		for i, id := range ids {
+			if id == "" {
+				fail("%s[%d]: id is required", kind, i)
+			} else if seen[id] {
+				fail("%s[%d]: duplicate id %s", kind, i, id)
+			}
+			seen[id] = true
+		}
+		return seen
+	}
+
+	engineerIDs := make([]string, 0, len(chart.Engineers))
+	engineerNames := map[string]bool{}
+	for i, engineer := range chart.Engineers {
+		if engineer == nil {
+			fail("engineers[%d]: must be an object", i)
+			engineerIDs = append(engineerIDs, "")
+			continue
+		}
+		engineerIDs = append(engineerIDs, engineer.Id)
+		if engineer.Name != "" && engineerNames[engineer.Name] {
+			fail("engineers[%d]: duplicate name %s", i, engineer.Name)
+		}
+		engineerNames[engineer.Name] = true
+		for _, err := range validation.Engineer(engineer) {
+			fail("engineers[%d].%s: %s", i, err.Field, err.Message)
+		}
+	}
+	engineers := checkGroupIDs("engineers", engineerIDs)
+	byID := make(map[string]*devops_resource.Engineer, len(chart.Engineers))
+	for _, engineer := range chart.Engineers {
+		if engineer != nil {
+			byID[engineer.Id] = engineer
+		}
+	}
+	findEngineer := func(id string) (*devops_resource.Engineer, bool) {
+		engineer, found := byID[id]
+		return engineer, found
+	}
+	for i, engineer := range chart.Engineers {
+		if engineer == nil || engineer.ManagerId == "" {
+			continue
+		}
+		if manager, found := byID[engineer.ManagerId]; !found {
+			fail("engineers[%d].manager_id: engineer %s does not exist", i, engineer.ManagerId)
+		} else if reportsTo(manager, engineer.Id, findEngineer) {
+			fail("engineers[%d].manager_id: %s already reports to %s", i, engineer.ManagerId, engineer.Id)
+		}
+	}
+
+	checkGroups := func(kind string, groups []chartGroup) map[string]bool {
+		ids := make([]string, 0, len(groups))
+		names := map[string]bool{}
+		for i, group := range groups {
+			ids = append(ids, group.Id)
+			if group.Name == "" {
+				fail("%s[%d]: name cannot be empty", kind, i)
+			} else if names[group.Name] {
+				fail("%s[%d]: duplicate name %s", kind, i, group.Name)
+			}
+			names[group.Name] = true
+			for j, engineerID := range group.Engineers {
+				if !engineers[engineerID] {
+					fail("%s[%d].engineers[%d]: engineer %s does not exist", kind, i, j, engineerID)
+				}
+			}
+		}
+		return checkGroupIDs(kind, ids)
+	}
+	devs := checkGroups("dev", chart.Devs)
+	ops := checkGroups("ops", chart.Ops)
+
+	devopsIDs := make([]string, 0, len(chart.DevOps))
+	for i, devops := range chart.DevOps {
+		devopsIDs = append(devopsIDs, devops.Id)
+		for j, devID := range devops.Devs {
+			if !devs[devID] {
+				fail("devops[%d].dev[%d]: dev group %s does not exist", i, j, devID)
+			}
+		}
+		for j, opsID := range devops.Ops {
+			if !ops[opsID] {
+				fail("devops[%d].ops[%d]: ops group %s does not exist", i, j, opsID)
+			}
+		}
+	}
+	checkGroupIDs("devops", devopsIDs)
+	return problems
+}
+
+// load writes the document into the (empty) stores of uow
+func (chart *orgChart) load(uow *unitOfWork) error {
+	for _, engineer := range chart.Engineers {
+		if err := uow.engineers.Add(engineer); err != nil {
+			return err
+		}
+	}
+	for _, group := range chart.Devs {
+		dev := &devops_resource.Dev{Id: group.Id, Name: group.Name, Engineers: make([]*devops_resource.Engineer, 0)}
+		for _, engineerID := range group.Engineers {
+			dev.Engineers = append(dev.Engineers, &devops_resource.Engineer{Id: engineerID})
+		}
+		if err := uow.devs.Add(dev); err != nil {
+			return err
+		}
+	}
+	for _, group := range chart.Ops {
+		op := &devops_resource.Ops{Id: group.Id, Name: group.Name, Engineers: make([]*devops_resource.Engineer, 0)}
+		for _, engineerID := range group.Engineers {
+			op.Engineers = append(op.Engineers, &devops_resource.Engineer{Id: engineerID})
+		}
+		if err := uow.ops.Add(op); err != nil {
+			return err
+		}
+	}
+	for _, group := range chart.DevOps {
+		devops := &devops_resource.DevOps{Id: group.Id, Devs: make([]*devops_resource.Dev, 0), Ops: make([]*devops_resource.Ops, 0)}
+		for _, devID := range group.Devs {
+			devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: devID})
+		}
+		for _, opsID := range group.Ops {
+			devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: opsID})
+		}
+		if err := uow.devops.Add(devops); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importOrgChart replaces the contents of every store with chart. The document is
+// validated up front and the previous contents stay in place if loading fails part way.
+// hooks run in the unit of work replacing them.
+func (s *Server) importOrgChart(chart *orgChart, hooks ...unitOfWorkHook) error {
+	if problems := chart.validate(); len(problems) > 0 {
+		return &apiError{kind: ErrValidation, code: "import_invalid", message: "import document is invalid", details: problems}
+	}
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		uow.devops.Clear()
+		uow.devs.Clear()
+		uow.ops.Clear()
+		uow.engineers.Clear()
+		// archived resources would come back next to the imported ones, into groups and
+		// under managers the document knows nothing about
+		uow.archive.Clear()
+		if err := chart.load(uow); err != nil {
+			return fmt.Errorf("import failed: %w", err)
+		}
+		uow.publish(OrgChartImported, orgChartImported{Engineers: len(chart.Engineers), Devs: len(chart.Devs), Ops: len(chart.Ops), DevOps: len(chart.DevOps)})
+		return nil
+	}, hooks...)
+	return err
+}
+
+// server handlers for GET /export and POST /import
+func (s *Server) getExport(c *gin.Context) {
+	v := s.forRequest(c)
+	chart := exportStores(v.engineerStore, v.devStore, v.opsStore, v.devOpsStore)
+	if c.Query("format") == "yaml" || strings.Contains(c.GetHeader("Accept"), "yaml") {
+		c.YAML(http.StatusOK, chart)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, chart)
+}
+
+func (s *Server) postImport(c *gin.Context) {
+	raw, err := readBody(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	var chart orgChart
+	if isYAML(c.ContentType()) {
+		err = yaml.Unmarshal(raw, &chart)
+	} else {
+		err = json.Unmarshal(raw, &chart)
+	}
+	if err != nil {
+		writeError(c, malformedBody(err))
+		return
+	}
+	if err := s.importOrgChart(&chart, unitOfWorkHooks(c)...); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"engineers": len(chart.Engineers),
+		"dev":       len(chart.Devs),
+		"ops":       len(chart.Ops),
+		"devops":    len(chart.DevOps),
+	})
+}
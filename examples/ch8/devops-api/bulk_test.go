@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"gopkg.in/yaml.v3"
+)
+
+func seedOrgChart(s *Server) {
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "alice", Id: "E2", Email: "alice@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1", Engineers: []*devops_resource.Engineer{{Id: "E1"}, {Id: "E2"}}})
+	s.opsStore.Add(&devops_resource.Ops{Name: "op_ferrets", Id: "O1", Engineers: []*devops_resource.Engineer{{Id: "E2"}}})
+	s.devOpsStore.Add(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{{Id: "D1"}}, Ops: []*devops_resource.Ops{{Id: "O1"}}})
+}
+
+func mockBulkRequest(router *gin.Engine, method string, url string, contentType string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedOrgChart(s)
+	router := newTestRouter(t, s)
+
+	for _, format := range []struct {
+		description string
+		query       string
+		contentType string
+		decode      func([]byte, any) error
+	}{
+		{"json", "", "application/json", json.Unmarshal},
+		{"yaml", "?format=yaml", "application/yaml", yaml.Unmarshal},
+	} {
+		w := mockBulkRequest(router, "GET", "/export"+format.query, "", "")
+		exported := w.Body.String()
+		var chart orgChart
+		if err := format.decode(w.Body.Bytes(), &chart); err != nil || len(chart.Engineers) != 2 || len(chart.Devs[0].Engineers) != 2 {
+			t.Errorf("\nTest: export %s\nExpected: the seeded org chart, Received: %s", format.description, exported)
+			continue
+		}
+
+		s.importOrgChart(&orgChart{})
+		w = mockBulkRequest(router, "POST", "/import", format.contentType, exported)
+		if w.Code != http.StatusOK {
+			t.Errorf("\nTest: import %s\nExpected: Status Code 200, Received: Status Code %d\nBody: %s", format.description, w.Code, w.Body.String())
+		}
+		found, err := s.DevOps.Get("DO1")
+		if err != nil || found.Devs[0].Engineers[1].Name != "alice" || found.Ops[0].Engineers[0].Email != "alice@bob.com" {
+			t.Errorf("\nTest: import %s\nExpected: memberships to be restored, Received: %v %v", format.description, found, err)
+		}
+	}
+}
+
+var importTests = []struct {
+	description string
+	contentType string
+	body        string
+	expected    int
+}{
+	{"engineer missing from document", "application/json", `{"engineers": [], "dev": [{"id": "D2", "name": "dev_bengal", "engineers": ["E9"]}]}`, http.StatusUnprocessableEntity},
+	{"dev group missing from document", "application/yaml", "devops:\n  - id: DO2\n    dev: [D9]\n", http.StatusUnprocessableEntity},
+	{"duplicate engineer ids", "application/yaml", "engineers:\n  - {id: E1, name: bob, email: bob@bob.com}\n  - {id: E1, name: alice, email: alice@bob.com}\n", http.StatusUnprocessableEntity},
+	{"invalid email", "application/yaml", "engineers:\n  - {id: E1, name: bob, email: bob}\n", http.StatusUnprocessableEntity},
+	{"unknown manager", "application/yaml", "engineers:\n  - {id: E1, name: bob, email: bob@bob.com, manager_id: E9}\n", http.StatusUnprocessableEntity},
+	{"reporting cycle", "application/yaml", "engineers:\n  - {id: E1, name: bob, email: bob@bob.com, manager_id: E2}\n  - {id: E2, name: alice, email: alice@bob.com, manager_id: E3}\n  - {id: E3, name: carol, email: carol@bob.com, manager_id: E1}\n", http.StatusUnprocessableEntity},
+	{"invalid time zone", "application/yaml", "engineers:\n  - {id: E1, name: bob, email: bob@bob.com, timezone: Mars/Olympus}\n", http.StatusUnprocessableEntity},
+	{"malformed json", "application/json", `{"engineers": `, http.StatusBadRequest},
+	{"malformed yaml", "application/yaml", "engineers: [", http.StatusBadRequest},
+}
+
+func TestImportIsAtomic(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedOrgChart(s)
+	router := newTestRouter(t, s)
+
+	for _, test := range importTests {
+		w := mockBulkRequest(router, "POST", "/import", test.contentType, test.body)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+		if len(s.engineerStore.List()) != 2 || len(s.devStore.List()) != 1 || len(s.devOpsStore.List()) != 1 {
+			t.Errorf("\nTest: %s\nError: Expected a rejected import to leave the stores untouched", test.description)
+		}
+	}
+}
+
+func TestImportClearsArchive(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedOrgChart(s)
+	router := newTestRouter(t, s)
+	if w := mockConditionalRequest(router, "DELETE", "/engineers/E2", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("\nTest: delete engineer\nExpected: Status Code 200, Received: %d %s", w.Code, w.Body.String())
+	}
+
+	w := mockBulkRequest(router, "POST", "/import", "application/yaml", "engineers: [{id: E1, name: bob, email: [}]\n")
+	if w.Code != http.StatusBadRequest || len(s.archiveStore.List("")) != 1 {
+		t.Errorf("\nTest: rejected import\nExpected: Status Code 400 and the archive untouched, Received: %d with %d records", w.Code, len(s.archiveStore.List("")))
+	}
+
+	// the archived alice would otherwise be restored into dev_ferrets of the new document
+	chart := "engineers:\n  - {id: E1, name: bob, email: bob@bob.com}\ndev:\n  - {id: D1, name: dev_ferrets, engineers: [E1]}\n"
+	if w := mockBulkRequest(router, "POST", "/import", "application/yaml", chart); w.Code != http.StatusOK {
+		t.Fatalf("\nTest: import\nExpected: Status Code 200, Received: %d %s", w.Code, w.Body.String())
+	}
+	if records := s.archiveStore.List(""); len(records) != 0 {
+		t.Errorf("\nTest: import\nExpected: an empty archive, Received: %+v", records)
+	}
+	if w := mockConditionalRequest(router, "POST", "/engineers/E2/restore", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("\nTest: restore after import\nExpected: Status Code 404, Received: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+)
+
+// TestClientCoversEveryRoute drives the Go client in devops-resources/client against the
+// real router and fails for every route the client never called
+func TestClientCoversEveryRoute(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedGraph(t, s)
+	router := newTestRouter(t, s)
+
+	var mu sync.Mutex
+	var called []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		called = append(called, r.Method+" "+r.URL.EscapedPath())
+		mu.Unlock()
+		router.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	api, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	must := func(description string, err error) {
+		if err != nil {
+			t.Errorf("\nTest: %s\nError: %v", description, err)
+		}
+	}
+	call := func(description string, fn func() error) { must(description, fn()) }
+
+	call("list and read engineers", func() error {
+		if _, err := api.ListEngineers(ctx, client.ListOptions{Limit: 2, Sort: "name"}); err != nil {
+			return err
+		}
+		if _, err := api.GetEngineerByName(ctx, "bob"); err != nil {
+			return err
+		}
+		_, err := api.GetEngineerByEmail(ctx, "alice@bob.com")
+		return err
+	})
+	call("engineer lifecycle", func() error {
+		var etag string
+		engineer, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "dave", Email: "dave@bob.com"}, client.ETag(&etag))
+		if err != nil {
+			return err
+		}
+		if engineer, err = api.UpdateEngineer(ctx, engineer.Id, devops_resource.Engineer{Name: "dave", Email: "dave@liatrio.com"}, client.IfMatch(etag)); err != nil {
+			return err
+		}
+		if _, err := api.PatchEngineer(ctx, engineer.Id, map[string]any{"manager_id": "E1"}); err != nil {
+			return err
+		}
+		if _, err := api.GetEngineer(ctx, engineer.Id); err != nil {
+			return err
+		}
+		if err := api.DeleteEngineer(ctx, engineer.Id); err != nil {
+			return err
+		}
+		_, err = api.RestoreEngineer(ctx, engineer.Id)
+		return err
+	})
+	call("dev lifecycle", func() error {
+		dev, err := api.CreateDev(ctx, devops_resource.Dev{Name: "dev_minks"})
+		if err != nil {
+			return err
+		}
+		if _, err := api.AddEngineerToDev(ctx, dev.Id, "E2"); err != nil {
+			return err
+		}
+		if _, err := api.UpdateDev(ctx, dev.Id, devops_resource.Dev{Name: "dev_minks", Engineers: []*devops_resource.Engineer{{Id: "E3"}}}); err != nil {
+			return err
+		}
+		if _, err := api.PatchDev(ctx, dev.Id, []client.PatchOperation{{Op: "add", Path: "/engineers/-", Value: "E1"}}); err != nil {
+			return err
+		}
+		if _, err := api.ListDevs(ctx, client.ListOptions{Member: "E1"}); err != nil {
+			return err
+		}
+		if _, err := api.GetDev(ctx, dev.Id); err != nil {
+			return err
+		}
+		if _, err := api.GetDevByName(ctx, "dev_minks"); err != nil {
+			return err
+		}
+		if err := api.DeleteDev(ctx, dev.Id); err != nil {
+			return err
+		}
+		_, err = api.RestoreDev(ctx, dev.Id)
+		return err
+	})
+	call("ops lifecycle", func() error {
+		ops, err := api.CreateOps(ctx, devops_resource.Ops{Name: "op_minks"})
+		if err != nil {
+			return err
+		}
+		if _, err := api.AddEngineerToOps(ctx, ops.Id, "E2"); err != nil {
+			return err
+		}
+		if _, err := api.UpdateOps(ctx, ops.Id, devops_resource.Ops{Name: "op_minks"}); err != nil {
+			return err
+		}
+		if _, err := api.PatchOps(ctx, ops.Id, map[string]any{"name": "op_weasels"}); err != nil {
+			return err
+		}
+		if _, err := api.ListOps(ctx, client.ListOptions{Name: "weasel"}); err != nil {
+			return err
+		}
+		if _, err := api.GetOps(ctx, ops.Id); err != nil {
+			return err
+		}
+		if _, err := api.GetOpsByName(ctx, "op_weasels"); err != nil {
+			return err
+		}
+		if err := api.DeleteOps(ctx, ops.Id); err != nil {
+			return err
+		}
+		_, err = api.RestoreOps(ctx, ops.Id)
+		return err
+	})
+	call("devops lifecycle", func() error {
+		devops, err := api.CreateDevOps(ctx, devops_resource.DevOps{})
+		if err != nil {
+			return err
+		}
+		if _, err := api.AddDevToDevOps(ctx, devops.Id, "D1"); err != nil {
+			return err
+		}
+		if _, err := api.AddOpsToDevOps(ctx, devops.Id, "O1"); err != nil {
+			return err
+		}
+		if _, err := api.UpdateDevOps(ctx, devops.Id, devops_resource.DevOps{Devs: []*devops_resource.Dev{{Id: "D2"}}}); err != nil {
+			return err
+		}
+		if _, err := api.PatchDevOps(ctx, devops.Id, map[string]any{"ops": []string{"O1"}}); err != nil {
+			return err
+		}
+		if _, err := api.ListDevOps(ctx, client.ListOptions{Dev: "D2"}); err != nil {
+			return err
+		}
+		if _, err := api.GetDevOps(ctx, devops.Id); err != nil {
+			return err
+		}
+		if _, err := api.DevOpsEngineers(ctx, devops.Id, client.ListOptions{}); err != nil {
+			return err
+		}
+		if err := api.DeleteDevOps(ctx, devops.Id); err != nil {
+			return err
+		}
+		_, err = api.RestoreDevOps(ctx, devops.Id)
+		return err
+	})
+	call("queries", func() error {
+		if _, err := api.EngineerMemberships(ctx, "E1"); err != nil {
+			return err
+		}
+		if _, err := api.Stats(ctx); err != nil {
+			return err
+		}
+		if _, err := api.Search(ctx, "ferrets", client.SearchOptions{Kind: "dev"}); err != nil {
+			return err
+		}
+		if _, err := api.Archive(ctx, "engineer", client.ListOptions{}); err != nil {
+			return err
+		}
+		if _, err := api.Audit(ctx, client.AuditOptions{Resource: "engineers", Since: time.Now().Add(-time.Hour)}); err != nil {
+			return err
+		}
+		if _, err := api.OpenAPI(ctx); err != nil {
+			return err
+		}
+		if _, err := api.Metrics(ctx); err != nil {
+			return err
+		}
+		if err := api.Healthy(ctx); err != nil {
+			return err
+		}
+		return api.Ready(ctx)
+	})
+	call("export and import", func() error {
+		chart, err := api.Export(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = api.Import(ctx, chart)
+		return err
+	})
+	call("events", func() error {
+		received := errors.New("received")
+		err := api.Events(ctx, client.EventOptions{After: s.events.lastID() - 1}, func(client.Event) error { return received })
+		if err != received {
+			return err
+		}
+		return nil
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, route := range router.Routes() {
+		pattern := regexp.MustCompile("^" + route.Method + " " + ginParam.ReplaceAllString(route.Path, "[^/]+") + "$")
+		found := false
+		for _, request := range called {
+			if found = pattern.MatchString(request); found {
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Route %s %s is never called by the client", route.Method, route.Path)
+		}
+	}
+}
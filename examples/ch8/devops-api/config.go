@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// config holds every setting of the API. Each setting comes from, in increasing order of
+// precedence, its default, the YAML file given with -config or DEVOPS_CONFIG, its DEVOPS_*
+// environment variable and its command line flag. The YAML key and environment variable
+// of a setting are named after its flag: -audit-path is audit_path and DEVOPS_AUDIT_PATH.
+type config struct {
+	Addr              string        `yaml:"addr"`
+	TLSCert           string        `yaml:"tls_cert"`
+	TLSKey            string        `yaml:"tls_key"`
+	ReadTimeout       time.Duration `yaml:"read_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+	TrustedProxies    []string      `yaml:"trusted_proxies"`
+	LogLevel          string        `yaml:"log_level"`
+	Storage           string        `yaml:"storage"`
+	DB                string        `yaml:"db"`
+	APIKeys           string        `yaml:"api_keys"`
+	JWKS              string        `yaml:"jwks"`
+	JWTIssuer         string        `yaml:"jwt_issuer"`
+	JWTAudience       string        `yaml:"jwt_audience"`
+	InsecureNoAuth    bool          `yaml:"insecure_no_auth"`
+	Audit             string        `yaml:"audit"`
+	AuditPath         string        `yaml:"audit_path"`
+	Webhooks          string        `yaml:"webhooks"`
+	Retention         string        `yaml:"retention"`
+	ReadRate          float64       `yaml:"read_rate"`
+	ReadBurst         int           `yaml:"read_burst"`
+	WriteRate         float64       `yaml:"write_rate"`
+	WriteBurst        int           `yaml:"write_burst"`
+	IdempotencyTTL    time.Duration `yaml:"idempotency_ttl"`
+	IDs               string        `yaml:"ids"`
+}
+
+// Log levels selectable with -log-level: debug also runs gin in debug mode, warn drops
+// the access log and keeps warnings and errors
+const (
+	logDebug = "debug"
+	logInfo  = "info"
+	logWarn  = "warn"
+)
+
+func defaultConfig() *config {
+	return &config{
+		Addr:              ":8080",
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+		ShutdownTimeout:   30 * time.Second,
+		LogLevel:          logInfo,
+		Storage:           storageMemory,
+		DB:                "devops.db",
+		Audit:             auditMemory,
+		ReadRate:          50,
+		ReadBurst:         100,
+		WriteRate:         10,
+		WriteBurst:        20,
+		IdempotencyTTL:    24 * time.Hour,
+		IDs:               idsULID,
+	}
+}
+
+// flags registers a flag for every setting, defaulting to its current value
+func (cfg *config) flags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Addr, "addr", cfg.Addr, "address to listen on, host:port")
+	fs.StringVar(&cfg.TLSCert, "tls-cert", cfg.TLSCert, "path to the TLS certificate, serves HTTPS with -tls-key")
+	fs.StringVar(&cfg.TLSKey, "tls-key", cfg.TLSKey, "path to the TLS private key")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "longest time to read a request, 0 for none")
+	fs.DurationVar(&cfg.ReadHeaderTimeout, "read-header-timeout", cfg.ReadHeaderTimeout, "longest time to read the headers of a request, 0 for none")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "longest time to write a response, 0 for none, event streams are exempt")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "how long to keep an idle connection open, 0 for none")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long to wait for requests to finish when shutting down")
+	fs.Var((*listFlag)(&cfg.TrustedProxies), "trusted-proxies", "comma separated IPs or CIDRs of proxies allowed to set X-Forwarded-For, none when empty")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "debug, info or warn")
+	fs.StringVar(&cfg.Storage, "storage", cfg.Storage, "storage backend: memory or sqlite")
+	fs.StringVar(&cfg.DB, "db", cfg.DB, "path to the sqlite database file")
+	fs.StringVar(&cfg.APIKeys, "api-keys", cfg.APIKeys, "path to a YAML file of bearer API keys and their roles")
+	fs.StringVar(&cfg.JWKS, "jwks", cfg.JWKS, "path to a JWKS file with the keys JWTs are signed with")
+	fs.StringVar(&cfg.JWTIssuer, "jwt-issuer", cfg.JWTIssuer, "required iss claim of JWTs, any issuer when empty")
+	fs.StringVar(&cfg.JWTAudience, "jwt-audience", cfg.JWTAudience, "required aud claim of JWTs, any audience when empty")
+	fs.BoolVar(&cfg.InsecureNoAuth, "insecure-no-auth", cfg.InsecureNoAuth, "turn authentication off so every caller is an admin, for local development only")
+	fs.StringVar(&cfg.Audit, "audit", cfg.Audit, "audit sink: memory, file (JSON lines) or sqlite")
+	fs.StringVar(&cfg.AuditPath, "audit-path", cfg.AuditPath, "path of the audit log file or database")
+	fs.StringVar(&cfg.Webhooks, "webhooks", cfg.Webhooks, "path to a YAML file of webhooks to send domain events to")
+	fs.StringVar(&cfg.Retention, "retention", cfg.Retention, "how long deleted resources can be restored before they are purged, such as 720h, forever when empty")
+	fs.Float64Var(&cfg.ReadRate, "read-rate", cfg.ReadRate, "reads each client can send per second once its burst is used up, 0 for no limit")
+	fs.IntVar(&cfg.ReadBurst, "read-burst", cfg.ReadBurst, "reads each client can send at once")
+	fs.Float64Var(&cfg.WriteRate, "write-rate", cfg.WriteRate, "changes each client can send per second once its burst is used up, 0 for no limit")
+	fs.IntVar(&cfg.WriteBurst, "write-burst", cfg.WriteBurst, "changes each client can send at once")
+	fs.DurationVar(&cfg.IdempotencyTTL, "idempotency-ttl", cfg.IdempotencyTTL, "how long the response to a POST with an Idempotency-Key is kept for retries, 0 to ignore the header")
+	fs.StringVar(&cfg.IDs, "ids", cfg.IDs, "format of the ids of new resources: ulid or uuidv7")
+}
+
+func (cfg *config) readLimit() rateLimit {
+	return rateLimit{Rate: cfg.ReadRate, Burst: cfg.ReadBurst}
+}
+
+func (cfg *config) writeLimit() rateLimit {
+	return rateLimit{Rate: cfg.WriteRate, Burst: cfg.WriteBurst}
+}
+
+// listFlag is a comma separated list, setting it replaces the whole list
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	*l = nil
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// envName is the environment variable of the flag name
+func envName(name string) string {
+	return "DEVOPS_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// loadConfig builds the config from the command line args and the environment. It
+// returns flag.ErrHelp after printing the usage for -h.
+func loadConfig(args []string, getenv func(string) string, usage io.Writer) (*config, error) {
+	// the flags are parsed first to find -config, and applied last
+	probe := flag.NewFlagSet("devops-api", flag.ContinueOnError)
+	probe.SetOutput(usage)
+	defaultConfig().flags(probe)
+	path := probe.String("config", getenv("DEVOPS_CONFIG"), "path to a YAML file of settings, named like the flags with _ for -")
+	if err := probe.Parse(args); err != nil {
+		return nil, err
+	}
+	if probe.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", probe.Arg(0))
+	}
+
+	cfg := defaultConfig()
+	if *path != "" {
+		raw, err := os.ReadFile(*path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+		decoder := yaml.NewDecoder(bytes.NewReader(raw))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to parse config %s: %w", *path, err)
+		}
+	}
+
+	fs := flag.NewFlagSet("devops-api", flag.ContinueOnError)
+	cfg.flags(fs)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if value := getenv(envName(f.Name)); value != "" && err == nil {
+			if setErr := fs.Set(f.Name, value); setErr != nil {
+				err = fmt.Errorf("invalid %s: %w", envName(f.Name), setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	probe.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			fs.Set(f.Name, f.Value.String())
+		}
+	})
+	return cfg, cfg.validate()
+}
+
+// validate checks the settings that the configure functions don't
+func (cfg *config) validate() error {
+	switch cfg.LogLevel {
+	case logDebug, logInfo, logWarn:
+	default:
+		return fmt.Errorf("log level must be debug, info or warn, got %q", cfg.LogLevel)
+	}
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return errors.New("tls-cert and tls-key must be set together")
+	}
+	for name, timeout := range map[string]time.Duration{
+		"read-timeout": cfg.ReadTimeout, "read-header-timeout": cfg.ReadHeaderTimeout,
+		"write-timeout": cfg.WriteTimeout, "idle-timeout": cfg.IdleTimeout,
+	} {
+		if timeout < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		return errors.New("shutdown-timeout must be positive")
+	}
+	if err := validateProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	return nil
+}
+
+// validateProxies checks that every trusted proxy is an IP or a CIDR
+func validateProxies(proxies []string) error {
+	for _, proxy := range proxies {
+		if _, _, err := net.ParseCIDR(proxy); err == nil {
+			continue
+		}
+		if net.ParseIP(proxy) == nil {
+			return fmt.Errorf("%q is neither an IP nor a CIDR", proxy)
+		}
+	}
+	return nil
+}
+
+// configureLogging turns the access log of s on or off for level. It also sets the gin
+// mode, which gin keeps for the whole process.
+func (s *Server) configureLogging(level string) {
+	if level == logDebug {
+		gin.SetMode(gin.DebugMode)
+	} else {
+		gin.SetMode(gin.ReleaseMode)
+	}
+	s.accessLog = level != logWarn
+}
+
+// configureProxies sets the proxies that may set X-Forwarded-For and X-Real-IP, the
+// client IP of the audit log and rate limits is the address of the connection for
+// everyone else
+func (s *Server) configureProxies(proxies []string) error {
+	if err := validateProxies(proxies); err != nil {
+		return err
+	}
+	s.trustedProxies = proxies
+	return nil
+}
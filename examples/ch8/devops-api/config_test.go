@@ -0,0 +1,130 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devops.yaml")
+	file := `
+addr: ":9000"
+read_timeout: 5s
+write_timeout: 1m
+trusted_proxies: [10.0.0.0/8]
+log_level: debug
+storage: sqlite
+db: file.db
+`
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		args        []string
+		env         map[string]string
+		check       func(cfg *config) bool
+	}{
+		{
+			"defaults",
+			nil,
+			nil,
+			func(cfg *config) bool { return reflect.DeepEqual(cfg, defaultConfig()) },
+		},
+		{
+			"file overrides defaults",
+			[]string{"-config", path},
+			nil,
+			func(cfg *config) bool {
+				return cfg.Addr == ":9000" && cfg.ReadTimeout == 5*time.Second && cfg.WriteTimeout == time.Minute &&
+					cfg.IdleTimeout == 2*time.Minute && reflect.DeepEqual(cfg.TrustedProxies, []string{"10.0.0.0/8"}) &&
+					cfg.LogLevel == logDebug && cfg.Storage == storageSQLite && cfg.DB == "file.db"
+			},
+		},
+		{
+			"file from the environment",
+			nil,
+			map[string]string{"DEVOPS_CONFIG": path},
+			func(cfg *config) bool { return cfg.Addr == ":9000" },
+		},
+		{
+			"environment overrides the file",
+			[]string{"-config", path},
+			map[string]string{"DEVOPS_ADDR": ":9001", "DEVOPS_READ_TIMEOUT": "7s", "DEVOPS_TRUSTED_PROXIES": "127.0.0.1, ::1"},
+			func(cfg *config) bool {
+				return cfg.Addr == ":9001" && cfg.ReadTimeout == 7*time.Second && cfg.WriteTimeout == time.Minute &&
+					reflect.DeepEqual(cfg.TrustedProxies, []string{"127.0.0.1", "::1"})
+			},
+		},
+		{
+			"flags override the environment",
+			[]string{"-config", path, "-addr", ":9002", "-trusted-proxies", ""},
+			map[string]string{"DEVOPS_ADDR": ":9001", "DEVOPS_TRUSTED_PROXIES": "127.0.0.1"},
+			func(cfg *config) bool {
+				return cfg.Addr == ":9002" && len(cfg.TrustedProxies) == 0 && cfg.DB == "file.db"
+			},
+		},
+		{
+			"existing settings keep their environment variables",
+			nil,
+			map[string]string{"DEVOPS_AUDIT_PATH": "audit.jsonl", "DEVOPS_JWT_ISSUER": "https://issuer"},
+			func(cfg *config) bool { return cfg.AuditPath == "audit.jsonl" && cfg.JWTIssuer == "https://issuer" },
+		},
+	}
+
+	for _, test := range tests {
+		cfg, err := loadConfig(test.args, func(key string) string { return test.env[key] }, io.Discard)
+		if err != nil || !test.check(cfg) {
+			t.Errorf("\nTest: %s\nExpected: the config to match, Received: %+v %v", test.description, cfg, err)
+		}
+	}
+}
+
+func TestLoadConfigInvalid(t *testing.T) {
+	unknown := filepath.Join(t.TempDir(), "devops.yaml")
+	if err := os.WriteFile(unknown, []byte("listen: :9000\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		description string
+		args        []string
+		env         map[string]string
+		expected    string
+	}{
+		{"unknown key in the file", []string{"-config", unknown}, nil, "field listen not found"},
+		{"missing file", []string{"-config", unknown + ".missing"}, nil, "failed to read config"},
+		{"invalid duration in the environment", nil, map[string]string{"DEVOPS_IDLE_TIMEOUT": "soon"}, "invalid DEVOPS_IDLE_TIMEOUT"},
+		{"unknown log level", []string{"-log-level", "verbose"}, nil, "log level must be debug, info or warn"},
+		{"certificate without key", []string{"-tls-cert", "cert.pem"}, nil, "must be set together"},
+		{"negative timeout", []string{"-write-timeout", "-1s"}, nil, "write-timeout must not be negative"},
+		{"no shutdown timeout", []string{"-shutdown-timeout", "0"}, nil, "shutdown-timeout must be positive"},
+		{"invalid proxy", []string{"-trusted-proxies", "10.0.0.0/99"}, nil, "invalid trusted proxies"},
+		{"proxy given by host name", []string{"-trusted-proxies", "proxy.internal"}, nil, "invalid trusted proxies"},
+		{"stray argument", []string{"serve"}, nil, `unexpected argument "serve"`},
+	}
+
+	for _, test := range tests {
+		_, err := loadConfig(test.args, func(key string) string { return test.env[key] }, io.Discard)
+		if err == nil || !strings.Contains(err.Error(), test.expected) {
+			t.Errorf("\nTest: %s\nExpected: an error containing %q, Received: %v", test.description, test.expected, err)
+		}
+	}
+}
+
+func TestInvalidProxiesFailStartup(t *testing.T) {
+	s := newTestServer(t)
+	if err := s.configureProxies([]string{"10.0.0.1", "10.0.0.0/99"}); err == nil || len(s.trustedProxies) != 0 {
+		t.Errorf("\nTest: configure an invalid proxy\nExpected: an error and no proxies, Received: %v %v", err, s.trustedProxies)
+	}
+	s.trustedProxies = []string{"proxy.internal"}
+	if _, err := NewRouter(s); err == nil || !strings.Contains(err.Error(), "invalid trusted proxies") {
+		t.Errorf("\nTest: router with an invalid proxy\nExpected: an invalid trusted proxies error, Received: %v", err)
+	}
+}
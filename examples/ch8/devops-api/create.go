@@ -6,119 +6,202 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
 )
 
-func newDevOps(newDevOps devops_resource.DevOps) (*devops_resource.DevOps, error) {
-	devOpsGroup := devops_resource.DevOps{Id: getRandId(5)}
+func (s devOpsService) Create(newDevOps devops_resource.DevOps) (*devops_resource.DevOps, error) {
+	devOpsGroup := devops_resource.DevOps{}
 	devOpsGroup.Ops = make([]*devops_resource.Ops, 0)
 	devOpsGroup.Devs = make([]*devops_resource.Dev, 0)
-	for _, newDev := range newDevOps.Devs {
-		dev, err := findDev_by_Id(newDev.Id)
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		id, err := uow.newID(s.ids, archivedDevOps)
 		if err != nil {
-			return nil, errors.New(" Dev group doesnt exists ")
+			return err
 		}
-		devOpsGroup.Devs = append(devOpsGroup.Devs, dev)
-	}
-	for _, newOp := range newDevOps.Ops {
-		op, err := findOp_by_Id(newOp.Id)
-		if err != nil {
-			return nil, errors.New(" Ops group doesnt exists ")
+		devOpsGroup.Id = id
+		for _, newDev := range newDevOps.Devs {
+			if _, found := uow.devs.FindByID(newDev.Id); !found {
+				return invalid("dev_not_found", "dev group "+newDev.Id+" does not exist")
+			}
+			devOpsGroup.Devs = append(devOpsGroup.Devs, &devops_resource.Dev{Id: newDev.Id})
+		}
+		for _, newOp := range newDevOps.Ops {
+			if _, found := uow.ops.FindByID(newOp.Id); !found {
+				return invalid("ops_not_found", "ops group "+newOp.Id+" does not exist")
+			}
+			devOpsGroup.Ops = append(devOpsGroup.Ops, &devops_resource.Ops{Id: newOp.Id})
+		}
+		if err := uow.devops.Add(&devOpsGroup); err != nil {
+			return err
 		}
-		devOpsGroup.Ops = append(devOpsGroup.Ops, op)
+		uow.publish(DevOpsCreated, devOpsChart(&devOpsGroup))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	devOpsStore.Add(&devOpsGroup)
-	return &devOpsGroup, nil
+	return s.resolveDevOps(&devOpsGroup), nil
 }
 
-func newDev(newDev devops_resource.Dev) (*devops_resource.Dev, error) {
+func (s devService) Create(newDev devops_resource.Dev) (*devops_resource.Dev, error) {
 	if newDev.Name == "" {
-		return nil, errors.New(" Name cannot be empty ")
-	}
-	// Check for duplicate using store
-	if _, found := devStore.FindByName(newDev.Name); found {
-		return nil, errors.New(" Dev group already exists ")
+		return nil, invalid("name_required", "name cannot be empty")
 	}
-	devGroup := devops_resource.Dev{Name: newDev.Name, Id: getRandId(5)}
+	devGroup := devops_resource.Dev{Name: newDev.Name}
 	devGroup.Engineers = make([]*devops_resource.Engineer, 0)
-	for _, eng := range newDev.Engineers {
-		newEngineer, err := findEngineer_by_Id(eng.Id)
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		// Check for duplicate using store
+		if _, found := uow.devs.FindByName(newDev.Name); found {
+			return conflict("dev_exists", "dev group "+newDev.Name+" already exists")
+		}
+		id, err := uow.newID(s.ids, archivedDev)
 		if err != nil {
-			return nil, errors.New(" engineer doesnt exists ")
+			return err
+		}
+		devGroup.Id = id
+		for _, eng := range newDev.Engineers {
+			if _, found := uow.engineers.FindByID(eng.Id); !found {
+				return invalid("engineer_not_found", "engineer "+eng.Id+" does not exist")
+			}
+			devGroup.Engineers = append(devGroup.Engineers, &devops_resource.Engineer{Id: eng.Id})
 		}
-		devGroup.Engineers = append(devGroup.Engineers, newEngineer)
+		if err := uow.devs.Add(&devGroup); err != nil {
+			return err
+		}
+		uow.publish(DevCreated, devChart(&devGroup))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	devStore.Add(&devGroup)
-	return &devGroup, nil
+	return s.resolveDev(&devGroup), nil
 }
 
-func newOp(newOp devops_resource.Ops) (*devops_resource.Ops, error) {
+func (s opsService) Create(newOp devops_resource.Ops) (*devops_resource.Ops, error) {
 	if newOp.Name == "" {
-		return nil, errors.New(" Name cannot be empty ")
-	}
-	// Check for duplicate using store
-	if _, found := opsStore.FindByName(newOp.Name); found {
-		return nil, errors.New(" Dev group already exists ")
+		return nil, invalid("name_required", "name cannot be empty")
 	}
-	opsGroup := devops_resource.Ops{Name: newOp.Name, Id: getRandId(5)}
+	opsGroup := devops_resource.Ops{Name: newOp.Name}
 	opsGroup.Engineers = make([]*devops_resource.Engineer, 0)
-	for _, eng := range newOp.Engineers {
-		newEngineer, err := findEngineer_by_Id(eng.Id)
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		// Check for duplicate using store
+		if _, found := uow.ops.FindByName(newOp.Name); found {
+			return conflict("ops_exists", "ops group "+newOp.Name+" already exists")
+		}
+		id, err := uow.newID(s.ids, archivedOps)
 		if err != nil {
-			return nil, errors.New(" engineer doesnt exists ")
+			return err
+		}
+		opsGroup.Id = id
+		for _, eng := range newOp.Engineers {
+			if _, found := uow.engineers.FindByID(eng.Id); !found {
+				return invalid("engineer_not_found", "engineer "+eng.Id+" does not exist")
+			}
+			opsGroup.Engineers = append(opsGroup.Engineers, &devops_resource.Engineer{Id: eng.Id})
 		}
-		opsGroup.Engineers = append(opsGroup.Engineers, newEngineer)
+		if err := uow.ops.Add(&opsGroup); err != nil {
+			return err
+		}
+		uow.publish(OpsCreated, opsChart(&opsGroup))
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	opsStore.Add(&opsGroup)
-	return &opsGroup, nil
+	return s.resolveOps(&opsGroup), nil
 }
 
-func newEngineer(name string, email string) (*devops_resource.Engineer, error) {
-	if name == "" {
-		return nil, errors.New(" Name cannot be empty ")
+// checkManager makes sure the manager of engineer exists and doesn't report to
+// engineer, directly or through other managers
+func checkManager(uow *unitOfWork, engineer *devops_resource.Engineer) error {
+	if engineer.ManagerId == "" {
+		return nil
+	}
+	manager, found := uow.engineers.FindByID(engineer.ManagerId)
+	if !found {
+		return invalid("manager_not_found", "no engineer with id "+engineer.ManagerId)
 	}
-	// Check for duplicate using store
-	if _, found := engineerStore.FindByName(name); found {
-		return nil, errors.New(" Engineer already exists ")
+	if reportsTo(manager, engineer.Id, uow.engineers.FindByID) {
+		return invalid("manager_cycle", engineer.ManagerId+" already reports to "+engineer.Id)
+	}
+	return nil
+}
+
+// reportsTo walks up the chain of managers above manager, looked up with find, and
+// reports whether engineerID is one of them
+func reportsTo(manager *devops_resource.Engineer, engineerID string, find func(id string) (*devops_resource.Engineer, bool)) bool {
+	seen := map[string]bool{manager.Id: true}
+	for manager.ManagerId != "" && !seen[manager.ManagerId] {
+		if manager.ManagerId == engineerID {
+			return true
+		}
+		seen[manager.ManagerId] = true
+		var found bool
+		if manager, found = find(manager.ManagerId); !found {
+			break
+		}
 	}
-	if !verifyEmail(email) {
-		return nil, errors.New(" Email is invalid ")
+	return false
+}
+
+func (s engineerService) Create(engineer devops_resource.Engineer) (*devops_resource.Engineer, error) {
+	engineer.Id = ""
+	if errs := validation.Engineer(&engineer); len(errs) > 0 {
+		return nil, invalidFields("engineer_invalid", "engineer "+engineer.Name+" is invalid", errs)
 	}
-	p := devops_resource.Engineer{Name: name, Id: getRandId(5)}
-	p.Email = email
+	p := *cloneEngineer(&engineer)
 
-	// Add to store instead of global slice
-	engineerStore.Add(&p)
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		// Check for duplicate using store
+		if _, found := uow.engineers.FindByName(p.Name); found {
+			return conflict("engineer_exists", "engineer "+p.Name+" already exists")
+		}
+		id, err := uow.newID(s.ids, archivedEngineer)
+		if err != nil {
+			return err
+		}
+		p.Id = id
+		if err := checkManager(uow, &p); err != nil {
+			return err
+		}
+		if err := uow.engineers.Add(&p); err != nil {
+			return err
+		}
+		uow.publish(EngineerCreated, cloneEngineer(&p))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &p, nil
 }
 
-func findEngineer_by_Id(engineer_id string) (*devops_resource.Engineer, error) {
-	if engineer, found := engineerStore.FindByID(engineer_id); found {
+func (s engineerService) Get(engineer_id string) (*devops_resource.Engineer, error) {
+	if engineer, found := s.engineerStore.FindByID(engineer_id); found {
 		return engineer, nil
 	}
-	return nil, errors.New(" No engineer with that ID ")
+	return nil, s.missing(notFound("engineer_not_found", "no engineer with id "+engineer_id))
 }
 
-func findOp_by_Id(op_id string) (*devops_resource.Ops, error) {
-	if ops, found := opsStore.FindByID(op_id); found {
-		return ops, nil
+func (s opsService) Get(op_id string) (*devops_resource.Ops, error) {
+	if ops, found := s.opsStore.FindByID(op_id); found {
+		return s.resolveOps(ops), nil
 	}
-	return nil, errors.New(" No ops group with that ID ")
+	return nil, s.missing(notFound("ops_not_found", "no ops group with id "+op_id))
 }
 
-func findDev_by_Id(dev_id string) (*devops_resource.Dev, error) {
-	if dev, found := devStore.FindByID(dev_id); found {
-		return dev, nil
+func (s devService) Get(dev_id string) (*devops_resource.Dev, error) {
+	if dev, found := s.devStore.FindByID(dev_id); found {
+		return s.resolveDev(dev), nil
 	}
-	return nil, errors.New(" No dev group with that ID ")
+	return nil, s.missing(notFound("dev_not_found", "no dev group with id "+dev_id))
 }
 
-func findDevOps_by_Id(devops_id string) (*devops_resource.DevOps, error) {
-	if devops, found := devOpsStore.FindByID(devops_id); found {
-		return devops, nil
+func (s devOpsService) Get(devops_id string) (*devops_resource.DevOps, error) {
+	if devops, found := s.devOpsStore.FindByID(devops_id); found {
+		return s.resolveDevOps(devops), nil
 	}
-	return nil, errors.New(" No devops group with that ID ")
+	return nil, s.missing(notFound("devops_not_found", "no devops group with id "+devops_id))
 }
 
 func findEngineerInOp_by_Id(op *devops_resource.Ops, engineer_id string) (*devops_resource.Engineer, error) {
@@ -127,7 +210,7 @@ func findEngineerInOp_by_Id(op *devops_resource.Ops, engineer_id string) (*devop
 			return newEngineer, nil
 		}
 	}
-	return nil, errors.New(" No engineer with that ID in this ops group")
+	return nil, notFound("engineer_not_member", "engineer "+engineer_id+" is not in ops group "+op.Id)
 }
 
 func findEngineerInDev_by_Id(dev *devops_resource.Dev, engineer_id string) (*devops_resource.Engineer, error) {
@@ -136,7 +219,7 @@ func findEngineerInDev_by_Id(dev *devops_resource.Dev, engineer_id string) (*dev
 			return newEngineer, nil
 		}
 	}
-	return nil, errors.New(" No engineer with that ID in this dev group ")
+	return nil, notFound("engineer_not_member", "engineer "+engineer_id+" is not in dev group "+dev.Id)
 }
 
 func findDevInDevOps_by_Id(devops *devops_resource.DevOps, dev_id string) (*devops_resource.Dev, error) {
@@ -145,7 +228,7 @@ func findDevInDevOps_by_Id(devops *devops_resource.DevOps, dev_id string) (*devo
 			return newDev, nil
 		}
 	}
-	return nil, errors.New(" No dev with that ID in this devops group")
+	return nil, notFound("dev_not_member", "dev group "+dev_id+" is not in devops group "+devops.Id)
 }
 
 func findOpInDevOps_by_Id(devops *devops_resource.DevOps, op_id string) (*devops_resource.Ops, error) {
@@ -154,290 +237,218 @@ func findOpInDevOps_by_Id(devops *devops_resource.DevOps, op_id string) (*devops
 			return newOp, nil
 		}
 	}
-	return nil, errors.New(" No op with that ID in this devops group")
+	return nil, notFound("ops_not_member", "ops group "+op_id+" is not in devops group "+devops.Id)
 }
 
 // functions to add resources to other resources//
-func addEngineerTo_Op(ops_id string, engineer_id string) (bool, error) {
-
-	engineer_val, err := findEngineer_by_Id(engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exist ")
-	}
-	op_val, err := findOp_by_Id(ops_id)
-	if err != nil {
-		return false, errors.New(" Operations group doesn't exist ")
-	}
-	_, err = findEngineerInOp_by_Id(op_val, engineer_id)
-	if err == nil {
-		return false, errors.New(" Engineer already exists inside specified Operations group ")
-	}
-
-	// Use thread-safe method to add engineer to operation
-	if !opsStore.AddEngineerToOp(ops_id, engineer_val) {
-		return false, errors.New(" Failed to add engineer to operations group ")
-	}
-
-	return true, nil
-
+func (s opsService) AddEngineer(ops_id string, engineer_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		op, found := uow.ops.FindByID(ops_id)
+		if !found {
+			return notFound("ops_not_found", "no ops group with id "+ops_id)
+		}
+		engineer, found := uow.engineers.FindByID(engineer_id)
+		if !found {
+			return invalid("engineer_not_found", "engineer "+engineer_id+" does not exist")
+		}
+		if hasEngineer(op.Engineers, engineer_id) {
+			return conflict("engineer_already_member", "engineer "+engineer_id+" is already in ops group "+ops_id)
+		}
+		if !uow.ops.AddEngineerToOp(ops_id, engineer) {
+			return errors.New("failed to add engineer to operations group")
+		}
+		uow.publish(EngineerAddedToOps, membershipChange{GroupID: ops_id, MemberID: engineer_id})
+		return nil
+	})
 }
 
-func addEngineerTo_Dev(dev_id string, engineer_id string) (bool, error) {
-
-	engineer_val, err := findEngineer_by_Id(engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exist ")
-	}
-	dev_val, err := findDev_by_Id(dev_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-	_, err = findEngineerInDev_by_Id(dev_val, engineer_id)
-	if err == nil {
-		return false, errors.New(" Engineer already exists inside specified Developer group ")
-	}
-
-	// Use thread-safe method to add engineer to dev
-	if !devStore.AddEngineerToDev(dev_id, engineer_val) {
-		return false, errors.New(" Failed to add engineer to developer group ")
-	}
-
-	return true, nil
-
+func (s devService) AddEngineer(dev_id string, engineer_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		dev, found := uow.devs.FindByID(dev_id)
+		if !found {
+			return notFound("dev_not_found", "no dev group with id "+dev_id)
+		}
+		engineer, found := uow.engineers.FindByID(engineer_id)
+		if !found {
+			return invalid("engineer_not_found", "engineer "+engineer_id+" does not exist")
+		}
+		if hasEngineer(dev.Engineers, engineer_id) {
+			return conflict("engineer_already_member", "engineer "+engineer_id+" is already in dev group "+dev_id)
+		}
+		if !uow.devs.AddEngineerToDev(dev_id, engineer) {
+			return errors.New("failed to add engineer to developer group")
+		}
+		uow.publish(EngineerAddedToDev, membershipChange{GroupID: dev_id, MemberID: engineer_id})
+		return nil
+	})
 }
 
-func addDevTo_DevOps(devops_id string, dev_id string) (bool, error) {
-
-	dev_val, err := findDev_by_Id(dev_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-	devops_val, err := findDevOps_by_Id(devops_id)
-	if err != nil {
-		return false, errors.New(" Developer Operations group doesn't exist ")
-	}
-	_, err = findDevInDevOps_by_Id(devops_val, dev_id)
-	if err == nil {
-		return false, errors.New(" Developer already exists inside specified Developer Operations group ")
-	}
-
-	// Use thread-safe method to add dev to devops
-	if !devOpsStore.AddDevToDevOps(devops_id, dev_val) {
-		return false, errors.New(" Failed to add dev to devops group ")
-	}
-
-	return true, nil
-
+func (s devOpsService) AddDev(devops_id string, dev_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops, found := uow.devops.FindByID(devops_id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		dev, found := uow.devs.FindByID(dev_id)
+		if !found {
+			return invalid("dev_not_found", "dev group "+dev_id+" does not exist")
+		}
+		if containsID(devIDs(devops.Devs), dev_id) {
+			return conflict("dev_already_member", "dev group "+dev_id+" is already in devops group "+devops_id)
+		}
+		if !uow.devops.AddDevToDevOps(devops_id, dev) {
+			return errors.New("failed to add dev to devops group")
+		}
+		uow.publish(DevAddedToDevOps, membershipChange{GroupID: devops_id, MemberID: dev_id})
+		return nil
+	})
 }
 
-func addOpTo_DevOps(devops_id string, op_id string) (bool, error) {
-
-	op_val, err := findOp_by_Id(op_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-	devops_val, err := findDevOps_by_Id(devops_id)
-	if err != nil {
-		return false, errors.New(" Developer Operations group doesn't exist ")
-	}
-	_, err = findOpInDevOps_by_Id(devops_val, op_id)
-	if err == nil {
-		return false, errors.New(" Developer already exists inside specified Developer Operations group ")
-	}
-
-	// Use thread-safe method to add ops to devops
-	if !devOpsStore.AddOpsToDevOps(devops_id, op_val) {
-		return false, errors.New(" Failed to add ops to devops group ")
-	}
-
-	return true, nil
-
+func (s devOpsService) AddOps(devops_id string, op_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops, found := uow.devops.FindByID(devops_id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		op, found := uow.ops.FindByID(op_id)
+		if !found {
+			return invalid("ops_not_found", "ops group "+op_id+" does not exist")
+		}
+		if containsID(opsIDs(devops.Ops), op_id) {
+			return conflict("ops_already_member", "ops group "+op_id+" is already in devops group "+devops_id)
+		}
+		if !uow.devops.AddOpsToDevOps(devops_id, op) {
+			return errors.New("failed to add ops to devops group")
+		}
+		uow.publish(OpsAddedToDevOps, membershipChange{GroupID: devops_id, MemberID: op_id})
+		return nil
+	})
 }
 
-// server POST handlers
-func postEngineer(c *gin.Context) {
-	var jsonData devops_resource.Engineer     //object that gets name and email from POST request
-	var curEngineer *devops_resource.Engineer //object recieved from newEngineer
+// server POST handlers, the responders write the response as the change commits
+func (s *Server) postEngineer(c *gin.Context) {
+	var jsonData devops_resource.Engineer //object that gets name and email from POST request
 
-	err := c.BindJSON(&jsonData)
+	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	curEngineer, err = newEngineer(jsonData.Name, jsonData.Email)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	engineer, err := findEngineer_by_Id(curEngineer.Id)
+	_, err = s.forRequest(c, s.respondWithEngineer(c, http.StatusCreated, "")).Engineers.Create(jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusCreated, engineer)
 }
 
-func postDev(c *gin.Context) {
+func (s *Server) postDev(c *gin.Context) {
 	var jsonData devops_resource.Dev //object that gets dev data from POST request
-	var curDev *devops_resource.Dev  //object recieved from newDev
 
-	err := c.BindJSON(&jsonData)
+	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	curDev, err = newDev(jsonData)
+	_, err = s.forRequest(c, s.respondWithDev(c, http.StatusCreated, "", expandAll)).Devs.Create(jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-	c.IndentedJSON(http.StatusCreated, curDev)
 }
 
-func postOp(c *gin.Context) {
+func (s *Server) postOp(c *gin.Context) {
 	var jsonData devops_resource.Ops //object that gets dev data from POST request
-	var curOp *devops_resource.Ops   //object recieved from newOp
 
-	err := c.BindJSON(&jsonData)
+	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	curOp, err = newOp(jsonData)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	op, err := findOp_by_Id(curOp.Id)
+	_, err = s.forRequest(c, s.respondWithOps(c, http.StatusCreated, "", expandAll)).Ops.Create(jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-	c.IndentedJSON(http.StatusCreated, op)
-
 }
 
-func postDevOps(c *gin.Context) {
+func (s *Server) postDevOps(c *gin.Context) {
 	var jsonData devops_resource.DevOps
-	err := c.BindJSON(&jsonData)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	curDevOps, err := newDevOps(jsonData)
+	err := c.ShouldBindJSON(&jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
-	devops, err := findDevOps_by_Id(curDevOps.Id)
+	_, err = s.forRequest(c, s.respondWithDevOps(c, http.StatusCreated, "", expandAll)).DevOps.Create(jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-	c.IndentedJSON(http.StatusCreated, devops)
 }
 
-func postDevEngineer(c *gin.Context) {
+func (s *Server) postDevEngineer(c *gin.Context) {
 	id := c.Param("id") //dev id
 	var jsonData devops_resource.Engineer
 	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = addEngineerTo_Dev(id, jsonData.Id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	dev, err := findDev_by_Id(id)
+	err = s.forRequest(c, s.respondWithDev(c, http.StatusOK, id, expandAll)).Devs.AddEngineer(id, jsonData.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, dev)
 }
 
-func postOpEngineer(c *gin.Context) {
+func (s *Server) postOpEngineer(c *gin.Context) {
 	id := c.Param("id") //op id
 	var jsonData devops_resource.Engineer
 	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = addEngineerTo_Op(id, jsonData.Id)
+	err = s.forRequest(c, s.respondWithOps(c, http.StatusOK, id, expandAll)).Ops.AddEngineer(id, jsonData.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-	op, err := findOp_by_Id(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, op)
 }
 
-func postDevOpsDev(c *gin.Context) {
+func (s *Server) postDevOpsDev(c *gin.Context) {
 	id := c.Param("id") //devops id
 	var jsonData devops_resource.Dev
 
 	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = addDevTo_DevOps(id, jsonData.Id)
+	err = s.forRequest(c, s.respondWithDevOps(c, http.StatusOK, id, expandAll)).DevOps.AddDev(id, jsonData.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-	devops, err := findDevOps_by_Id(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.IndentedJSON(http.StatusOK, devops)
 }
 
-func postDevOpsOp(c *gin.Context) {
+func (s *Server) postDevOpsOp(c *gin.Context) {
 	id := c.Param("id") //devops id
 	var jsonData devops_resource.Ops
 
 	err := c.ShouldBindJSON(&jsonData)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = addOpTo_DevOps(id, jsonData.Id)
+	err = s.forRequest(c, s.respondWithDevOps(c, http.StatusOK, id, expandAll)).DevOps.AddOps(id, jsonData.Id)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-	devops, err := findDevOps_by_Id(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
-	}
-
-	c.IndentedJSON(http.StatusOK, devops)
 }
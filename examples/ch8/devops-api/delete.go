@@ -1,278 +1,296 @@
 package main
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
 )
 
-func removeEngineerElement(engineers []*devops_resource.Engineer, engineer_id string) ([]*devops_resource.Engineer, error) {
-	for i := range engineers {
-		if engineers[i].Id == engineer_id {
-			if len(engineers) == 1 {
-				var empty []*devops_resource.Engineer
-				return empty, nil
-			}
-			engineers[i] = engineers[len(engineers)-1]
-			return engineers[:len(engineers)-1], nil
+// functions to delete resources from other resources//
+func (s opsService) RemoveEngineer(op_id string, engineer_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		op_val, found := uow.ops.FindByID(op_id)
+		if !found {
+			return notFound("ops_not_found", "no ops group with id "+op_id)
 		}
-	}
-	return nil, errors.New("engineer not found and not deleted")
-}
-
-func removeDevElement(devs []*devops_resource.Dev, dev_id string) ([]*devops_resource.Dev, error) {
-	for i := range devs {
-		if devs[i].Id == dev_id {
-			devs[i] = devs[len(devs)-1]
-			return devs[:len(devs)-1], nil
+		if _, err := findEngineerInOp_by_Id(op_val, engineer_id); err != nil {
+			return err
 		}
-	}
-	return nil, errors.New("developer not found and not deleted")
-}
 
-func removeOpElement(ops []*devops_resource.Ops, op_id string) ([]*devops_resource.Ops, error) {
-	for i := range ops {
-		if ops[i].Id == op_id {
-			ops[i] = ops[len(ops)-1]
-			return ops[:len(ops)-1], nil
+		// Remove engineer from operation using store method
+		if err := uow.ops.RemoveEngineerFromOp(op_id, engineer_id); err != nil {
+			return err
 		}
-	}
-	return nil, errors.New("engineer not found and not deleted")
-}
-
-// functions to delete resources from other resources//
-func deleteEngineerFrom_Op(op_id string, engineer_id string) (bool, error) {
-
-	_, err := findEngineer_by_Id(engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exist ")
-	}
-	op_val, err := findOp_by_Id(op_id)
-	if err != nil {
-		return false, errors.New(" Operations group doesn't exist ")
-	}
-	_, err = findEngineerInOp_by_Id(op_val, engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exists inside specified Operations group ")
-	}
-
-	// Remove engineer from operation using store method
-	err = opsStore.RemoveEngineerFromOp(op_id, engineer_id)
-	if err != nil {
-		return false, errors.New(" Error: " + err.Error())
-	}
-
-	// Also remove from devops operations
-	devOpsStore.RemoveEngineerFromAll(engineer_id)
-
-	return true, nil
-
+		uow.publish(EngineerRemovedFromOps, membershipChange{GroupID: op_id, MemberID: engineer_id})
+		return nil
+	})
 }
 
-func deleteEngineerFrom_Dev(dev_id string, engineer_id string) (bool, error) {
-
-	_, err := findEngineer_by_Id(engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exist ")
-	}
-	dev_val, err := findDev_by_Id(dev_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-	_, err = findEngineerInDev_by_Id(dev_val, engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exists inside specified Developer group ")
-	}
-
-	// Remove engineer from dev using store method
-	err = devStore.RemoveEngineerFromDev(dev_id, engineer_id)
-	if err != nil {
-		return false, errors.New(" Error: " + err.Error())
-	}
-
-	// Also remove from devops devs
-	devOpsStore.RemoveEngineerFromAll(engineer_id)
-
-	return true, nil
+func (s devService) RemoveEngineer(dev_id string, engineer_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		dev_val, found := uow.devs.FindByID(dev_id)
+		if !found {
+			return notFound("dev_not_found", "no dev group with id "+dev_id)
+		}
+		if _, err := findEngineerInDev_by_Id(dev_val, engineer_id); err != nil {
+			return err
+		}
 
+		// Remove engineer from dev using store method
+		if err := uow.devs.RemoveEngineerFromDev(dev_id, engineer_id); err != nil {
+			return err
+		}
+		uow.publish(EngineerRemovedFromDev, membershipChange{GroupID: dev_id, MemberID: engineer_id})
+		return nil
+	})
 }
 
-func deleteDevFrom_DevOps(devops_id string, dev_id string) (bool, error) {
-
-	_, err := findDev_by_Id(dev_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-	devops_val, err := findDevOps_by_Id(devops_id)
-	if err != nil {
-		return false, errors.New(" Developer Operations group doesn't exist ")
-	}
-	_, err = findDevInDevOps_by_Id(devops_val, dev_id)
-	if err != nil {
-		return false, errors.New(" Developer doesn't exists inside specified Developer Operations group ")
-	}
-
-	// Remove dev from devops using store method
-	err = devOpsStore.RemoveDevFromDevOps(devops_id, dev_id)
-	if err != nil {
-		return false, errors.New(" Error: " + err.Error())
-	}
-
-	return true, nil
+func (s devOpsService) RemoveDev(devops_id string, dev_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops_val, found := uow.devops.FindByID(devops_id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		if _, err := findDevInDevOps_by_Id(devops_val, dev_id); err != nil {
+			return err
+		}
 
+		// Remove dev from devops using store method
+		if err := uow.devops.RemoveDevFromDevOps(devops_id, dev_id); err != nil {
+			return err
+		}
+		uow.publish(DevRemovedFromDevOps, membershipChange{GroupID: devops_id, MemberID: dev_id})
+		return nil
+	})
 }
 
-func deleteOpFrom_DevOps(devops_id string, op_id string) (bool, error) {
-
-	_, err := findOp_by_Id(op_id)
-	if err != nil {
-		return false, errors.New(" Operations group doesn't exist ")
-	}
-	devops_val, err := findDevOps_by_Id(devops_id)
-	if err != nil {
-		return false, errors.New(" Developer Operations group doesn't exist ")
-	}
-	_, err = findOpInDevOps_by_Id(devops_val, op_id)
-	if err != nil {
-		return false, errors.New(" Operations group doesn't exists inside specified Developer Operations group ")
-	}
-
-	// Remove ops from devops using store method
-	err = devOpsStore.RemoveOpsFromDevOps(devops_id, op_id)
-	if err != nil {
-		return false, errors.New(" Error: " + err.Error())
-	}
-
-	return true, nil
+func (s devOpsService) RemoveOps(devops_id string, op_id string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops_val, found := uow.devops.FindByID(devops_id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		if _, err := findOpInDevOps_by_Id(devops_val, op_id); err != nil {
+			return err
+		}
 
+		// Remove ops from devops using store method
+		if err := uow.devops.RemoveOpsFromDevOps(devops_id, op_id); err != nil {
+			return err
+		}
+		uow.publish(OpsRemovedFromDevOps, membershipChange{GroupID: devops_id, MemberID: op_id})
+		return nil
+	})
 }
 
 // **************************************************//
-// functions to delete resources//
-func deleteDevOps(devops_id string) (bool, error) {
-	_, err := findDevOps_by_Id(devops_id)
-	if err != nil {
-		return false, errors.New(" Developer Operations group doesn't exist ")
-	}
-
-	// Remove devops from main store
-	if !devOpsStore.DeleteByID(devops_id) {
-		return false, errors.New(" Error: devops not found in store ")
-	}
+// functions to delete resources, version is the version the resource must still be at.
+// A deleted resource is archived by actor along with the groups it is removed from, so it
+// can be restored later. All of it happens in one unit of work.
+func (s devOpsService) Delete(devops_id string, version int, actor string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops, found := uow.devops.FindByID(devops_id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		if err := checkVersion(devops_id, uow.devops.Version(devops_id), version); err != nil {
+			return err
+		}
+		if err := archiveResource(uow, archivedDevOps, devops_id, actor, devOpsChart(devops), map[string][]string{}); err != nil {
+			return err
+		}
 
-	return true, nil
+		// Remove devops from main store
+		if !uow.devops.DeleteByID(devops_id) {
+			return notFound("devops_not_found", "no devops group with id "+devops_id)
+		}
+		uow.publish(DevOpsDeleted, deletedResource{Id: devops_id})
+		return nil
+	})
 }
 
-func deleteDev(dev_id string) (bool, error) {
-	_, err := findDev_by_Id(dev_id)
-	if err != nil {
-		return false, errors.New(" Developer group doesn't exist ")
-	}
-
-	// Remove dev from all devops
-	for _, devops := range devOpsStore.List() {
-		devOpsStore.RemoveDevFromDevOps(devops.Id, dev_id)
-	}
+func (s devService) Delete(dev_id string, version int, actor string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		dev, found := uow.devs.FindByID(dev_id)
+		if !found {
+			return notFound("dev_not_found", "no dev group with id "+dev_id)
+		}
+		if err := checkVersion(dev_id, uow.devs.Version(dev_id), version); err != nil {
+			return err
+		}
 
-	// Remove dev from main store
-	if !devStore.DeleteByID(dev_id) {
-		return false, errors.New(" Error: dev not found in store ")
-	}
+		// Remove dev from all devops
+		memberships := map[string][]string{archivedDevOps: {}}
+		for _, devops := range uow.devops.FindByDev(dev_id) {
+			if err := uow.devops.RemoveDevFromDevOps(devops.Id, dev_id); err != nil {
+				return err
+			}
+			memberships[archivedDevOps] = append(memberships[archivedDevOps], devops.Id)
+			uow.publish(DevRemovedFromDevOps, membershipChange{GroupID: devops.Id, MemberID: dev_id})
+		}
+		if err := archiveResource(uow, archivedDev, dev_id, actor, devChart(dev), memberships); err != nil {
+			return err
+		}
 
-	return true, nil
+		// Remove dev from main store
+		if !uow.devs.DeleteByID(dev_id) {
+			return notFound("dev_not_found", "no dev group with id "+dev_id)
+		}
+		uow.publish(DevDeleted, deletedResource{Id: dev_id})
+		return nil
+	})
 }
 
-func deleteOp(op_id string) (bool, error) {
-	_, err := findOp_by_Id(op_id)
-	if err != nil {
-		return false, errors.New(" Operations group doesn't exist ")
-	}
-
-	// Remove ops from all devops
-	for _, devops := range devOpsStore.List() {
-		devOpsStore.RemoveOpsFromDevOps(devops.Id, op_id)
-	}
+func (s opsService) Delete(op_id string, version int, actor string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		op, found := uow.ops.FindByID(op_id)
+		if !found {
+			return notFound("ops_not_found", "no ops group with id "+op_id)
+		}
+		if err := checkVersion(op_id, uow.ops.Version(op_id), version); err != nil {
+			return err
+		}
 
-	// Remove ops from main store
-	if !opsStore.DeleteByID(op_id) {
-		return false, errors.New(" Error: ops not found in store ")
-	}
+		// Remove ops from all devops
+		memberships := map[string][]string{archivedDevOps: {}}
+		for _, devops := range uow.devops.FindByOps(op_id) {
+			if err := uow.devops.RemoveOpsFromDevOps(devops.Id, op_id); err != nil {
+				return err
+			}
+			memberships[archivedDevOps] = append(memberships[archivedDevOps], devops.Id)
+			uow.publish(OpsRemovedFromDevOps, membershipChange{GroupID: devops.Id, MemberID: op_id})
+		}
+		if err := archiveResource(uow, archivedOps, op_id, actor, opsChart(op), memberships); err != nil {
+			return err
+		}
 
-	return true, nil
+		// Remove ops from main store
+		if !uow.ops.DeleteByID(op_id) {
+			return notFound("ops_not_found", "no ops group with id "+op_id)
+		}
+		uow.publish(OpsDeleted, deletedResource{Id: op_id})
+		return nil
+	})
 }
 
-func deleteEngineer(engineer_id string) (bool, error) {
-	_, err := findEngineer_by_Id(engineer_id)
-	if err != nil {
-		return false, errors.New(" Engineer doesn't exist ")
-	}
-
-	// Remove engineer from all devs
-	for _, dev := range devStore.List() {
-		devStore.RemoveEngineerFromDev(dev.Id, engineer_id)
-	}
+func (s engineerService) Delete(engineer_id string, version int, actor string) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		engineer, found := uow.engineers.FindByID(engineer_id)
+		if !found {
+			return notFound("engineer_not_found", "no engineer with id "+engineer_id)
+		}
+		if err := checkVersion(engineer_id, uow.engineers.Version(engineer_id), version); err != nil {
+			return err
+		}
+		memberships := map[string][]string{archivedDev: {}, archivedOps: {}, archivedReports: {}}
 
-	// Remove engineer from all ops
-	for _, op := range opsStore.List() {
-		opsStore.RemoveEngineerFromOp(op.Id, engineer_id)
-	}
+		// Remove engineer from all devs
+		for _, dev := range uow.devs.FindByEngineer(engineer_id) {
+			if err := uow.devs.RemoveEngineerFromDev(dev.Id, engineer_id); err != nil {
+				return err
+			}
+			memberships[archivedDev] = append(memberships[archivedDev], dev.Id)
+			uow.publish(EngineerRemovedFromDev, membershipChange{GroupID: dev.Id, MemberID: engineer_id})
+		}
 
-	// Remove engineer from all devops
-	devOpsStore.RemoveEngineerFromAll(engineer_id)
+		// Remove engineer from all ops
+		for _, op := range uow.ops.FindByEngineer(engineer_id) {
+			if err := uow.ops.RemoveEngineerFromOp(op.Id, engineer_id); err != nil {
+				return err
+			}
+			memberships[archivedOps] = append(memberships[archivedOps], op.Id)
+			uow.publish(EngineerRemovedFromOps, membershipChange{GroupID: op.Id, MemberID: engineer_id})
+		}
 
-	// Remove engineer from main store
-	if !engineerStore.DeleteByID(engineer_id) {
-		return false, errors.New(" Error: engineer not found in store ")
-	}
+		// Engineers reporting to the engineer are left without a manager until it is restored
+		for _, report := range uow.engineers.FindByManager(engineer_id) {
+			report.ManagerId = ""
+			if err := uow.engineers.Update(report, anyVersion); err != nil {
+				return err
+			}
+			memberships[archivedReports] = append(memberships[archivedReports], report.Id)
+			uow.publish(EngineerUpdated, report)
+		}
+		if err := archiveResource(uow, archivedEngineer, engineer_id, actor, engineer, memberships); err != nil {
+			return err
+		}
 
-	return true, nil
+		// Remove engineer from main store
+		if !uow.engineers.DeleteByID(engineer_id) {
+			return notFound("engineer_not_found", "no engineer with id "+engineer_id)
+		}
+		uow.publish(EngineerDeleted, deletedResource{Id: engineer_id})
+		return nil
+	})
 }
 
 // server DELETE handler
-func deleteRequestEngineer(c *gin.Context) {
+func (s *Server) deleteRequestEngineer(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
-	_, err := deleteEngineer(id)
+	version, err := ifMatch(c, id, v.Engineers.Version(id))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	err = v.Engineers.Delete(id, version, actorOf(c))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": "engineer resource deleted"})
 }
 
-func deleteRequestDev(c *gin.Context) {
+func (s *Server) deleteRequestDev(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
-	_, err := deleteDev(id)
+	version, err := ifMatch(c, id, v.Devs.Version(id))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	err = v.Devs.Delete(id, version, actorOf(c))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": "developer resource deleted"})
 }
 
-func deleteRequestOp(c *gin.Context) {
+func (s *Server) deleteRequestOp(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
-	_, err := deleteOp(id)
+	version, err := ifMatch(c, id, v.Ops.Version(id))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	err = v.Ops.Delete(id, version, actorOf(c))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"success": "operations resource deleted"})
 }
 
-func deleteRequestDevOps(c *gin.Context) {
+func (s *Server) deleteRequestDevOps(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
-	_, err := deleteDevOps(id)
+	version, err := ifMatch(c, id, v.DevOps.Version(id))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	err = v.DevOps.Delete(id, version, actorOf(c))
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
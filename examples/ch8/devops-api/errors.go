@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
+)
+
+// Sentinel errors describing what went wrong, match them with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrPrecondition = errors.New("precondition failed")
+	ErrMediaType    = errors.New("unsupported media type")
+	ErrTooLarge     = errors.New("content too large")
+	ErrUnavailable  = errors.New("service unavailable")
+	ErrRateLimited  = errors.New("too many requests")
+)
+
+// apiError wraps one of the sentinels with a machine-readable code and a message for humans
+type apiError struct {
+	kind    error
+	code    string
+	message string
+	details []string
+}
+
+func (e *apiError) Error() string { return e.message }
+func (e *apiError) Unwrap() error { return e.kind }
+
+func badRequest(code string, message string) error {
+	return &apiError{kind: ErrBadRequest, code: code, message: message}
+}
+
+func notFound(code string, message string) error {
+	return &apiError{kind: ErrNotFound, code: code, message: message}
+}
+
+func conflict(code string, message string) error {
+	return &apiError{kind: ErrConflict, code: code, message: message}
+}
+
+func invalid(code string, message string) error {
+	return &apiError{kind: ErrValidation, code: code, message: message}
+}
+
+func unauthorized(code string, message string) error {
+	return &apiError{kind: ErrUnauthorized, code: code, message: message}
+}
+
+func forbidden(code string, message string) error {
+	return &apiError{kind: ErrForbidden, code: code, message: message}
+}
+
+func preconditionFailed(code string, message string) error {
+	return &apiError{kind: ErrPrecondition, code: code, message: message}
+}
+
+func unsupportedMediaType(code string, message string) error {
+	return &apiError{kind: ErrMediaType, code: code, message: message}
+}
+
+func contentTooLarge(code string, message string) error {
+	return &apiError{kind: ErrTooLarge, code: code, message: message}
+}
+
+func unavailable(code string, message string, details ...string) error {
+	return &apiError{kind: ErrUnavailable, code: code, message: message, details: details}
+}
+
+func tooManyRequests(code string, message string) error {
+	return &apiError{kind: ErrRateLimited, code: code, message: message}
+}
+
+// invalidFields reports every problem the validation package found with a resource,
+// one "field: message" entry per problem
+func invalidFields(code string, message string, errs validation.Errors) error {
+	details := make([]string, 0, len(errs))
+	for _, err := range errs {
+		details = append(details, err.Error())
+	}
+	return &apiError{kind: ErrValidation, code: code, message: message, details: details}
+}
+
+// versionMismatch reports a write based on a stale version of a resource
+func versionMismatch(id string, current int, expected int) error {
+	return preconditionFailed("version_mismatch", fmt.Sprintf("%s is at version %d, not %d", id, current, expected))
+}
+
+// malformedBody reports a request body that could not be decoded
+func malformedBody(err error) error {
+	return badRequest("malformed_body", "request body is not valid JSON: "+err.Error())
+}
+
+// problem is an RFC 7807 problem details body
+type problem struct {
+	Type     string   `json:"type"`
+	Title    string   `json:"title"`
+	Status   int      `json:"status"`
+	Detail   string   `json:"detail,omitempty"`
+	Instance string   `json:"instance,omitempty"`
+	Code     string   `json:"code"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// statusFor maps an error to its HTTP status code
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrValidation):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrPrecondition):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, ErrMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrRateLimited):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// newProblem builds the problem details for err, hiding the message of unexpected errors
+func newProblem(c *gin.Context, err error) problem {
+	status := statusFor(err)
+	body := problem{Status: status, Title: http.StatusText(status)}
+	if c.Request.URL != nil {
+		body.Instance = c.Request.URL.Path
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		body.Code = apiErr.code
+		body.Detail = apiErr.message
+		body.Errors = apiErr.details
+	} else {
+		log.Printf("internal error on %s %s: %v", c.Request.Method, body.Instance, err)
+		body.Code = "internal_error"
+		body.Detail = "an unexpected error occurred"
+	}
+	body.Type = "urn:devops-api:problem:" + body.Code
+	return body
+}
+
+// writeError responds with an application/problem+json body describing err
+func writeError(c *gin.Context, err error) {
+	body := newProblem(c, err)
+	if body.Status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Bearer realm="devops-api"`)
+	}
+	c.Header("Content-Type", "application/problem+json")
+	c.AbortWithStatusJSON(body.Status, body)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+var statusForTests = []struct {
+	description string
+	err         error
+	expected    int
+}{
+	{"malformed request", badRequest("malformed_body", "bad json"), http.StatusBadRequest},
+	{"missing resource", notFound("engineer_not_found", "no engineer"), http.StatusNotFound},
+	{"duplicate resource", conflict("engineer_exists", "engineer exists"), http.StatusConflict},
+	{"invalid field", invalid("email_invalid", "email is invalid"), http.StatusUnprocessableEntity},
+	{"unexpected error", errors.New("disk on fire"), http.StatusInternalServerError},
+}
+
+func TestStatusFor(t *testing.T) {
+	for _, test := range statusForTests {
+		if result := statusFor(test.err); result != test.expected {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, result)
+		}
+	}
+}
+
+var problemTests = []struct {
+	description string
+	method      string
+	url         string
+	body        string
+	status      int
+	code        string
+}{
+	{"unknown engineer", "GET", "/engineers/id/NOPE", "", http.StatusNotFound, "engineer_not_found"},
+	{"duplicate dev group", "POST", "/dev", `{"name": "dev_ferrets"}`, http.StatusConflict, "dev_exists"},
+	{"unknown engineer in body", "POST", "/dev", `{"name": "dev_bengal", "engineers": [{"id": "NOPE"}]}`, http.StatusUnprocessableEntity, "engineer_not_found"},
+	{"engineer already in group", "POST", "/dev/D1", `{"id": "E1"}`, http.StatusConflict, "engineer_already_member"},
+	{"unknown dev group", "POST", "/dev/NOPE", `{"id": "E1"}`, http.StatusNotFound, "dev_not_found"},
+	{"bad paging parameters", "GET", "/engineers?limit=0", "", http.StatusBadRequest, "invalid_limit"},
+}
+
+func TestProblemResponses(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1", Engineers: []*devops_resource.Engineer{{Id: "E1"}}})
+	router := newTestRouter(t, s)
+
+	for _, test := range problemTests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		var body problem
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != test.status || body.Status != test.status || body.Code != test.code {
+			t.Errorf("\nTest: %s\nExpected: %d %s, Received: %d %s", test.description, test.status, test.code, w.Code, body.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/problem+json") {
+			t.Errorf("\nTest: %s\nExpected problem+json content type, Received: %s", test.description, contentType)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The ETag of a resource is its quoted version, e.g. "3". It changes whenever the
+// resource's own fields or memberships change.
+func etagFor(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(c *gin.Context, version int) {
+	if version != anyVersion {
+		c.Header("ETag", etagFor(version))
+	}
+}
+
+// checkVersion fails when expected is set and differs from current
+func checkVersion(id string, current int, expected int) error {
+	if expected != anyVersion && current != expected {
+		return versionMismatch(id, current, expected)
+	}
+	return nil
+}
+
+// ifMatch evaluates the If-Match header against the current version of id and returns the
+// version the write must still apply to, anyVersion when the header is absent or "*".
+// ETags are compared strongly, so a weak W/ tag never matches. A header that doesn't list
+// the current ETag, or "*" when there is no resource id, is a 412.
+func ifMatch(c *gin.Context, id string, current int) (int, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return anyVersion, nil
+	}
+	if header == "*" {
+		if current == anyVersion {
+			return anyVersion, preconditionFailed("version_mismatch", "If-Match * requires "+id+" to exist")
+		}
+		return anyVersion, nil
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if current != anyVersion && strings.TrimSpace(tag) == etagFor(current) {
+			return current, nil
+		}
+	}
+	return anyVersion, preconditionFailed("version_mismatch", "If-Match "+header+" does not match the current ETag "+etagFor(current)+" of "+id)
+}
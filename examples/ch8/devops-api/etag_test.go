@@ -0,0 +1,112 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+func mockConditionalRequest(router *gin.Engine, method string, url string, ifMatch string, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	router.ServeHTTP(w, req)
+	return w
+}
+
+var conditionalTests = []struct {
+	description string
+	method      string
+	url         string
+	ifMatch     string
+	body        string
+	expected    int
+	etag        string
+}{
+	{"read engineer", "GET", "/engineers/id/E1", "", "", http.StatusOK, `"1"`},
+	{"update with current etag", "PUT", "/engineers/E1", `"1"`, `{"name": "rob", "email": "bob@bob.com"}`, http.StatusOK, `"2"`},
+	{"update with stale etag", "PUT", "/engineers/E1", `"1"`, `{"name": "alice", "email": "bob@bob.com"}`, http.StatusPreconditionFailed, ""},
+	{"update with a weak etag", "PUT", "/engineers/E1", `W/"2"`, `{"name": "alice", "email": "bob@bob.com"}`, http.StatusPreconditionFailed, ""},
+	{"update with one of several etags", "PUT", "/engineers/E1", `"7", "2"`, `{"name": "alice", "email": "bob@bob.com"}`, http.StatusOK, `"3"`},
+	{"update without if-match", "PUT", "/engineers/E1", "", `{"name": "bob", "email": "bob@bob.com"}`, http.StatusOK, `"4"`},
+	{"read dev group", "GET", "/dev/id/D1", "", "", http.StatusOK, `"1"`},
+	{"adding a member bumps the version", "POST", "/dev/D1", "", `{"id": "E1"}`, http.StatusOK, `"2"`},
+	{"rename with pre-membership etag", "PUT", "/dev/D1", `"1"`, `{"name": "dev_bengal"}`, http.StatusPreconditionFailed, ""},
+	{"delete with stale etag", "DELETE", "/dev/D1", `"1"`, "", http.StatusPreconditionFailed, ""},
+	{"delete with current etag", "DELETE", "/dev/D1", `"2"`, "", http.StatusOK, ""},
+	{"delete anything with star", "DELETE", "/engineers/E1", "*", "", http.StatusOK, ""},
+	{"update a missing engineer with star", "PUT", "/engineers/E1", "*", `{"name": "bob", "email": "bob@bob.com"}`, http.StatusPreconditionFailed, ""},
+	{"delete a missing dev group with star", "DELETE", "/dev/D1", "*", "", http.StatusPreconditionFailed, ""},
+}
+
+func TestConditionalRequests(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1"})
+	router := newTestRouter(t, s)
+
+	for _, test := range conditionalTests {
+		w := mockConditionalRequest(router, test.method, test.url, test.ifMatch, test.body)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+		if etag := w.Header().Get("ETag"); test.etag != "" && etag != test.etag {
+			t.Errorf("\nTest: %s\nExpected: ETag %s, Received: %s", test.description, test.etag, etag)
+		}
+	}
+}
+
+func TestStoreUpdateChecksVersion(t *testing.T) {
+	dir := t.TempDir()
+	db, err := openSQLite(filepath.Join(dir, "devops.db"), sqliteSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := []struct {
+		description string
+		store       EngineerStorage
+	}{
+		{"memory", newEngineerStore()},
+		{"sqlite", &SQLiteEngineerStore{db: db}},
+	}
+	for _, test := range stores {
+		test.store.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+		if err := test.store.Update(&devops_resource.Engineer{Name: "rob", Id: "E1", Email: "bob@bob.com"}, 1); err != nil {
+			t.Errorf("\nTest: %s\nExpected: update at version 1 to succeed, Received: %v", test.description, err)
+		}
+		err := test.store.Update(&devops_resource.Engineer{Name: "alice", Id: "E1", Email: "bob@bob.com"}, 1)
+		if !errors.Is(err, ErrPrecondition) {
+			t.Errorf("\nTest: %s\nExpected: a stale update to fail with a precondition error, Received: %v", test.description, err)
+		}
+		if engineer, _ := test.store.FindByID("E1"); engineer.Name != "rob" || test.store.Version("E1") != 2 {
+			t.Errorf("\nTest: %s\nExpected: rob at version 2, Received: %s at version %d", test.description, engineer.Name, test.store.Version("E1"))
+		}
+	}
+}
+
+func TestSQLiteMigratesVersionColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	old, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Exec("CREATE TABLE engineers (id TEXT PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL)")
+	old.Exec("INSERT INTO engineers (id, name, email) VALUES ('E1', 'bob', 'bob@bob.com')")
+	old.Close()
+
+	s := newSQLiteServer(t, path)
+	if version := s.engineerStore.Version("E1"); version != 1 {
+		t.Errorf("Expected: existing engineers to start at version 1, Received: %d", version)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// eventType names a domain event, published after the change it describes is stored
+type eventType string
+
+const (
+	EngineerCreated        eventType = "EngineerCreated"
+	EngineerUpdated        eventType = "EngineerUpdated"
+	EngineerDeleted        eventType = "EngineerDeleted"
+	EngineerRestored       eventType = "EngineerRestored"
+	DevCreated             eventType = "DevCreated"
+	DevUpdated             eventType = "DevUpdated"
+	DevDeleted             eventType = "DevDeleted"
+	DevRestored            eventType = "DevRestored"
+	OpsCreated             eventType = "OpsCreated"
+	OpsUpdated             eventType = "OpsUpdated"
+	OpsDeleted             eventType = "OpsDeleted"
+	OpsRestored            eventType = "OpsRestored"
+	DevOpsCreated          eventType = "DevOpsCreated"
+	DevOpsUpdated          eventType = "DevOpsUpdated"
+	DevOpsDeleted          eventType = "DevOpsDeleted"
+	DevOpsRestored         eventType = "DevOpsRestored"
+	EngineerAddedToDev     eventType = "EngineerAddedToDev"
+	EngineerRemovedFromDev eventType = "EngineerRemovedFromDev"
+	EngineerAddedToOps     eventType = "EngineerAddedToOps"
+	EngineerRemovedFromOps eventType = "EngineerRemovedFromOps"
+	DevAddedToDevOps       eventType = "DevAddedToDevOps"
+	DevRemovedFromDevOps   eventType = "DevRemovedFromDevOps"
+	OpsAddedToDevOps       eventType = "OpsAddedToDevOps"
+	OpsRemovedFromDevOps   eventType = "OpsRemovedFromDevOps"
+	OrgChartImported       eventType = "OrgChartImported"
+)
+
+var eventTypes = []eventType{
+	EngineerCreated, EngineerUpdated, EngineerDeleted, EngineerRestored,
+	DevCreated, DevUpdated, DevDeleted, DevRestored,
+	OpsCreated, OpsUpdated, OpsDeleted, OpsRestored,
+	DevOpsCreated, DevOpsUpdated, DevOpsDeleted, DevOpsRestored,
+	EngineerAddedToDev, EngineerRemovedFromDev,
+	EngineerAddedToOps, EngineerRemovedFromOps,
+	DevAddedToDevOps, DevRemovedFromDevOps,
+	OpsAddedToDevOps, OpsRemovedFromDevOps,
+	OrgChartImported,
+}
+
+// domainEvent is the envelope sent to /events subscribers and webhooks
+type domainEvent struct {
+	ID   int64           `json:"id"`
+	Type eventType       `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Event payloads, created and updated events carry the same snapshot as the audit log
+type deletedResource struct {
+	Id string `json:"id"`
+}
+
+type membershipChange struct {
+	GroupID  string `json:"group_id"`
+	MemberID string `json:"member_id"`
+}
+
+type orgChartImported struct {
+	Engineers int `json:"engineers"`
+	Devs      int `json:"dev"`
+	Ops       int `json:"ops"`
+	DevOps    int `json:"devops"`
+}
+
+// eventBus fans published events out to subscribers and keeps the most recent ones so
+// a subscriber can resume after the last event it saw
+type eventBus struct {
+	mu          sync.Mutex
+	seq         int64
+	history     []domainEvent
+	subscribers map[chan domainEvent]bool
+	now         func() time.Time
+}
+
+// fromNow subscribes without replaying history
+const fromNow = -1
+
+const (
+	eventHistorySize = 256
+	subscriberBuffer = 64
+)
+
+// newEventBus returns a bus timestamping events with now
+func newEventBus(now func() time.Time) *eventBus {
+	return &eventBus{history: make([]domainEvent, 0, eventHistorySize), subscribers: map[chan domainEvent]bool{}, now: now}
+}
+
+// publish stores and delivers an event. A subscriber whose buffer is full is dropped,
+// its channel is closed and it can resubscribe from the last ID it received.
+func (b *eventBus) publish(kind eventType, data any) domainEvent {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("events: failed to encode %s: %v", kind, err)
+		raw = json.RawMessage("null")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	event := domainEvent{ID: b.seq, Type: kind, Time: b.now().UTC(), Data: raw}
+	if len(b.history) == eventHistorySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, event)
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+	return event
+}
+
+// lastID is the ID of the most recently published event
+func (b *eventBus) lastID() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seq
+}
+
+// subscribe returns the retained events after the given ID and a channel of every later
+// event. Call the returned function to unsubscribe.
+func (b *eventBus) subscribe(after int64, buffer int) ([]domainEvent, <-chan domainEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	replay := make([]domainEvent, 0)
+	if after != fromNow {
+		if len(b.history) > 0 && b.history[0].ID > after+1 {
+			log.Printf("events: events %d to %d are no longer retained", after+1, b.history[0].ID-1)
+		}
+		for _, event := range b.history {
+			if event.ID > after {
+				replay = append(replay, event)
+			}
+		}
+	}
+	subscriber := make(chan domainEvent, buffer)
+	b.subscribers[subscriber] = true
+	return replay, subscriber, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.subscribers[subscriber] {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+func containsID(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// eventFilter selects event types, an empty filter matches every type
+type eventFilter map[eventType]bool
+
+func parseEventFilter(types []eventType) (eventFilter, error) {
+	filter := eventFilter{}
+	for _, kind := range types {
+		if !containsEventType(kind) {
+			return nil, badRequest("unknown_event_type", "unknown event type "+string(kind))
+		}
+		filter[kind] = true
+	}
+	return filter, nil
+}
+
+func (f eventFilter) matches(kind eventType) bool {
+	return len(f) == 0 || f[kind]
+}
+
+func containsEventType(kind eventType) bool {
+	for _, known := range eventTypes {
+		if known == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// eventHeartbeat is how often an idle stream sends a comment so proxies keep it open
+var eventHeartbeat = 15 * time.Second
+
+// server handler for GET /events, a Server-Sent Events stream. ?types= limits the stream
+// to a comma separated list of event types and Last-Event-ID (or ?after=) resumes it.
+func (s *Server) getEvents(c *gin.Context) {
+	var types []eventType
+	for _, kind := range strings.Split(c.Query("types"), ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			types = append(types, eventType(kind))
+		}
+	}
+	filter, err := parseEventFilter(types)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	after := int64(fromNow)
+	if value := orDefault(c.GetHeader("Last-Event-ID"), c.Query("after")); value != "" {
+		if after, err = strconv.ParseInt(value, 10, 64); err != nil || after < 0 {
+			writeError(c, badRequest("invalid_last_event_id", "Last-Event-ID must be the id of an earlier event"))
+			return
+		}
+	}
+
+	replay, stream, unsubscribe := s.events.subscribe(after, subscriberBuffer)
+	defer unsubscribe()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	// the stream outlives the write timeout of the server
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	send := func(event domainEvent) {
+		if filter.matches(event.Type) {
+			raw, _ := json.Marshal(event)
+			fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, raw)
+		}
+	}
+	for _, event := range replay {
+		send(event)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-s.stopping:
+			// shutting down, the client reconnects with Last-Event-ID to another instance
+			return
+		case event, open := <-stream:
+			if !open {
+				// too slow to keep up, the client reconnects with Last-Event-ID
+				return
+			}
+			send(event)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
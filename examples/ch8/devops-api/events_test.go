@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// drainEvents returns the types of the events already waiting on stream
+func drainEvents(stream <-chan domainEvent) []eventType {
+	types := make([]eventType, 0)
+	for {
+		select {
+		case event := <-stream:
+			types = append(types, event.Type)
+		default:
+			return types
+		}
+	}
+}
+
+var domainEventTests = []struct {
+	description string
+	method      string
+	url         string
+	body        string
+	expected    []eventType
+}{
+	{"create engineer", "POST", "/engineers", `{"name": "alice", "email": "alice@bob.com"}`, []eventType{EngineerCreated}},
+	{"add member", "POST", "/dev/D1", `{"id": "E1"}`, []eventType{EngineerAddedToDev}},
+	{"update engineer", "PUT", "/engineers/E1", `{"name": "rob", "email": "bob@bob.com"}`, []eventType{EngineerUpdated}},
+	{"replace devops members", "PUT", "/devops/DO1", `{"dev": [], "ops": [{"id": "O1"}]}`, []eventType{DevOpsUpdated, DevRemovedFromDevOps, OpsAddedToDevOps}},
+	{"patch out member", "PATCH", "/devops/DO1", `{"ops": []}`, []eventType{DevOpsUpdated, OpsRemovedFromDevOps}},
+	{"delete cascades", "DELETE", "/engineers/E1", "", []eventType{EngineerRemovedFromDev, EngineerDeleted}},
+	{"restore rejoins groups", "POST", "/engineers/E1/restore", "", []eventType{EngineerRestored, EngineerAddedToDev}},
+	{"rejected change publishes nothing", "POST", "/dev", `{"name": ""}`, []eventType{}},
+}
+
+func TestDomainEventsPublished(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1"})
+	s.opsStore.Add(&devops_resource.Ops{Name: "op_ferrets", Id: "O1"})
+	s.devOpsStore.Add(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{{Id: "D1"}}})
+	router := newTestRouter(t, s)
+	_, stream, unsubscribe := s.events.subscribe(fromNow, subscriberBuffer)
+	defer unsubscribe()
+
+	for _, test := range domainEventTests {
+		mockConditionalRequest(router, test.method, test.url, "", test.body)
+		if types := drainEvents(stream); !reflect.DeepEqual(types, test.expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.expected, types)
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	first := s.events.publish(EngineerCreated, deletedResource{Id: "E1"}).ID
+	s.events.publish(DevDeleted, deletedResource{Id: "D1"})
+	s.events.publish(EngineerDeleted, deletedResource{Id: "E1"})
+
+	// a cancelled request still receives the replay before the stream ends
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/events?types=EngineerCreated,EngineerDeleted", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "0")
+	router.ServeHTTP(w, req)
+	body := w.Body.String()
+	if w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Expected: Content-Type text/event-stream, Received: %s", w.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(body, "event: EngineerCreated") || !strings.Contains(body, "event: EngineerDeleted") || strings.Contains(body, "event: DevDeleted") {
+		t.Errorf("Expected: only the EngineerCreated and EngineerDeleted events, Received:\n%s", body)
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/events", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", strconv.FormatInt(first, 10))
+	router.ServeHTTP(w, req)
+	if body := w.Body.String(); strings.Contains(body, "event: EngineerCreated") || !strings.Contains(body, "event: DevDeleted") {
+		t.Errorf("Expected: events after %d only, Received:\n%s", first, body)
+	}
+
+	for _, url := range []string{"/events?types=NotAnEvent", "/events?after=soon"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", url, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestEventBusDropsSlowSubscribers(t *testing.T) {
+	bus := newEventBus(time.Now)
+	_, stream, unsubscribe := bus.subscribe(fromNow, 1)
+	defer unsubscribe()
+	bus.publish(EngineerCreated, nil)
+	bus.publish(EngineerUpdated, nil)
+	<-stream
+	if _, open := <-stream; open {
+		t.Errorf("Expected: the stream of a subscriber that fell behind to be closed")
+	}
+	replay, _, unsubscribeAgain := bus.subscribe(1, 1)
+	defer unsubscribeAgain()
+	if len(replay) != 1 || replay[0].Type != EngineerUpdated {
+		t.Errorf("Expected: resubscribing after event 1 to replay EngineerUpdated, Received: %v", replay)
+	}
+}
+
+// webhookReceiver answers each delivery with the next status in statuses, then 204
+type webhookReceiver struct {
+	mu         sync.Mutex
+	statuses   []int
+	deliveries []*http.Request
+	bodies     [][]byte
+	received   chan struct{}
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	status := http.StatusNoContent
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	r.deliveries = append(r.deliveries, req)
+	r.bodies = append(r.bodies, body)
+	r.mu.Unlock()
+	w.WriteHeader(status)
+	r.received <- struct{}{}
+}
+
+var webhookTests = []struct {
+	description string
+	statuses    []int
+	attempts    int
+}{
+	{"accepted first time", nil, 1},
+	{"retried after server errors", []int{http.StatusInternalServerError, http.StatusTooManyRequests}, 3},
+	{"rejected deliveries are not retried", []int{http.StatusBadRequest}, 1},
+	{"gives up after max attempts", []int{500, 500, 500, 500}, 3},
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	s := newTestServer(t)
+	for _, test := range webhookTests {
+		receiver := &webhookReceiver{statuses: test.statuses, received: make(chan struct{}, 10)}
+		server := httptest.NewServer(receiver)
+		path := filepath.Join(t.TempDir(), "webhooks.yaml")
+		config := "max_attempts: 3\ninitial_backoff: 1ms\nmax_backoff: 2ms\nwebhooks:\n" +
+			"  - url: " + server.URL + "\n    secret: s3cret\n    events: [DevRemovedFromDevOps]\n"
+		os.WriteFile(path, []byte(config), 0o600)
+		if err := s.configureWebhooks(path); err != nil {
+			t.Fatal(err)
+		}
+
+		s.events.publish(EngineerCreated, deletedResource{Id: "E1"})
+		event := s.events.publish(DevRemovedFromDevOps, membershipChange{GroupID: "DO1", MemberID: "D1"})
+		for i := 0; i < test.attempts; i++ {
+			select {
+			case <-receiver.received:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("\nTest: %s\nExpected: %d deliveries, Received: %d", test.description, test.attempts, i)
+			}
+		}
+		s.configureWebhooks("")
+		server.Close()
+
+		if len(receiver.deliveries) != test.attempts {
+			t.Errorf("\nTest: %s\nExpected: %d deliveries, Received: %d", test.description, test.attempts, len(receiver.deliveries))
+		}
+		request, body := receiver.deliveries[0], receiver.bodies[0]
+		if request.Header.Get(webhookEventHeader) != string(DevRemovedFromDevOps) || request.Header.Get(webhookDeliveryHeader) != strconv.FormatInt(event.ID, 10) {
+			t.Errorf("\nTest: %s\nExpected: a DevRemovedFromDevOps delivery of event %d, Received: %s %s", test.description, event.ID, request.Header.Get(webhookEventHeader), request.Header.Get(webhookDeliveryHeader))
+		}
+		if signature := request.Header.Get(webhookSignatureHeader); signature != signWebhook("s3cret", body) {
+			t.Errorf("\nTest: %s\nExpected: signature %s, Received: %s", test.description, signWebhook("s3cret", body), signature)
+		}
+	}
+}
+
+func TestConfigureWebhooksRejectsBadConfig(t *testing.T) {
+	s := newTestServer(t)
+	for _, config := range []string{
+		"webhooks:\n  - url: ftp://example.com\n    secret: s3cret\n",
+		"webhooks:\n  - url: https://example.com\n",
+		"webhooks:\n  - url: https://example.com\n    secret: s3cret\n    events: [EngineerHired]\n",
+		"max_attempts: 0\nwebhooks: []\n",
+	} {
+		path := filepath.Join(t.TempDir(), "webhooks.yaml")
+		os.WriteFile(path, []byte(config), 0o600)
+		if err := s.configureWebhooks(path); err == nil {
+			s.configureWebhooks("")
+			t.Errorf("\nTest: %q\nError: Expected Errors, recieved none.", config)
+		}
+	}
+}
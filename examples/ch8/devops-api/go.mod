@@ -5,6 +5,8 @@ go 1.25.0
 require (
 	github.com/gin-gonic/gin v1.9.1
 	github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources v0.0.0-20230921193819-569bb9d9dbdd
+	github.com/mattn/go-sqlite3 v1.14.18
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -31,5 +33,7 @@ require (
 	golang.org/x/sys v0.45.0 // indirect
 	golang.org/x/text v0.37.0 // indirect
 	google.golang.org/protobuf v1.33.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+// devops-resources is developed alongside the API, build against the copy in this repository
+replace github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources => ../devops-resources
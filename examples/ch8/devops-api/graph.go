@@ -0,0 +1,193 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// groupRef names a dev or ops group in graph responses
+type groupRef struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// devOpsMembership is a devops group reaching an engineer, through the engineer's
+// dev and ops groups listed in Devs and Ops
+type devOpsMembership struct {
+	Id   string   `json:"id"`
+	Devs []string `json:"dev"`
+	Ops  []string `json:"ops"`
+}
+
+type engineerMemberships struct {
+	Engineer *devops_resource.Engineer `json:"engineer"`
+	Devs     []groupRef                `json:"dev"`
+	Ops      []groupRef                `json:"ops"`
+	DevOps   []*devOpsMembership       `json:"devops"`
+}
+
+// groupSize is the number of distinct engineers in a group, for devops groups the
+// engineers of all its dev and ops groups
+type groupSize struct {
+	Kind      string `json:"kind"`
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Engineers int    `json:"engineers"`
+}
+
+type orgStats struct {
+	Engineers            int         `json:"engineers"`
+	Devs                 int         `json:"dev"`
+	Ops                  int         `json:"ops"`
+	DevOps               int         `json:"devops"`
+	GroupSizes           []groupSize `json:"group_sizes"`
+	OrphanedEngineers    []string    `json:"orphaned_engineers"`
+	EngineersInDevAndOps []string    `json:"engineers_in_dev_and_ops"`
+}
+
+// findMemberships walks the reverse indexes from an engineer up to its devops groups
+func (s serviceScope) findMemberships(engineer_id string) (*engineerMemberships, error) {
+	engineer, err := s.Engineers.Get(engineer_id)
+	if err != nil {
+		return nil, err
+	}
+	memberships := &engineerMemberships{
+		Engineer: engineer,
+		Devs:     make([]groupRef, 0),
+		Ops:      make([]groupRef, 0),
+		DevOps:   make([]*devOpsMembership, 0),
+	}
+	reached := map[string]*devOpsMembership{}
+	reach := func(devops_id string) *devOpsMembership {
+		if membership, found := reached[devops_id]; found {
+			return membership
+		}
+		membership := &devOpsMembership{Id: devops_id, Devs: make([]string, 0), Ops: make([]string, 0)}
+		reached[devops_id] = membership
+		memberships.DevOps = append(memberships.DevOps, membership)
+		return membership
+	}
+	for _, dev := range s.devStore.FindByEngineer(engineer_id) {
+		memberships.Devs = append(memberships.Devs, groupRef{Id: dev.Id, Name: dev.Name})
+		for _, devops := range s.devOpsStore.FindByDev(dev.Id) {
+			membership := reach(devops.Id)
+			membership.Devs = append(membership.Devs, dev.Id)
+		}
+	}
+	for _, op := range s.opsStore.FindByEngineer(engineer_id) {
+		memberships.Ops = append(memberships.Ops, groupRef{Id: op.Id, Name: op.Name})
+		for _, devops := range s.devOpsStore.FindByOps(op.Id) {
+			membership := reach(devops.Id)
+			membership.Ops = append(membership.Ops, op.Id)
+		}
+	}
+	return memberships, nil
+}
+
+// devOpsRoster returns the IDs of the engineers in any dev or ops group of devops,
+// each once, in the order they are first reached
+func (s serviceScope) devOpsRoster(devops *devops_resource.DevOps) []string {
+	roster := make([]string, 0)
+	seen := map[string]bool{}
+	add := func(engineers []*devops_resource.Engineer) {
+		for _, engineer := range engineers {
+			if !seen[engineer.Id] {
+				seen[engineer.Id] = true
+				roster = append(roster, engineer.Id)
+			}
+		}
+	}
+	for _, ref := range devops.Devs {
+		if dev, found := s.devStore.FindByID(ref.Id); found {
+			add(dev.Engineers)
+		}
+	}
+	for _, ref := range devops.Ops {
+		if op, found := s.opsStore.FindByID(ref.Id); found {
+			add(op.Engineers)
+		}
+	}
+	return roster
+}
+
+// computeStats counts every group's engineers and uses the reverse indexes to find the
+// engineers in no group and the engineers in both a dev and an ops group
+func (s serviceScope) computeStats() *orgStats {
+	engineers, devs, ops, devops := s.engineerStore.List(), s.devStore.List(), s.opsStore.List(), s.devOpsStore.List()
+	stats := &orgStats{
+		Engineers:            len(engineers),
+		Devs:                 len(devs),
+		Ops:                  len(ops),
+		DevOps:               len(devops),
+		GroupSizes:           make([]groupSize, 0, len(devs)+len(ops)+len(devops)),
+		OrphanedEngineers:    make([]string, 0),
+		EngineersInDevAndOps: make([]string, 0),
+	}
+	for _, dev := range devs {
+		stats.GroupSizes = append(stats.GroupSizes, groupSize{Kind: archivedDev, Id: dev.Id, Name: dev.Name, Engineers: len(dev.Engineers)})
+	}
+	for _, op := range ops {
+		stats.GroupSizes = append(stats.GroupSizes, groupSize{Kind: archivedOps, Id: op.Id, Name: op.Name, Engineers: len(op.Engineers)})
+	}
+	for _, group := range devops {
+		stats.GroupSizes = append(stats.GroupSizes, groupSize{Kind: archivedDevOps, Id: group.Id, Engineers: len(s.devOpsRoster(group))})
+	}
+	for _, engineer := range engineers {
+		inDev, inOps := len(s.devStore.FindByEngineer(engineer.Id)) > 0, len(s.opsStore.FindByEngineer(engineer.Id)) > 0
+		if !inDev && !inOps {
+			stats.OrphanedEngineers = append(stats.OrphanedEngineers, engineer.Id)
+		}
+		if inDev && inOps {
+			stats.EngineersInDevAndOps = append(stats.EngineersInDevAndOps, engineer.Id)
+		}
+	}
+	return stats
+}
+
+// server handler for GET /engineers/:id/memberships, the dev and ops groups the
+// engineer is in and the devops groups reaching it through them
+func (s *Server) getEngineerMemberships(c *gin.Context) {
+	v := s.forRequest(c)
+	memberships, err := v.findMemberships(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, memberships)
+}
+
+// server handler for GET /devops/:id/engineers, every engineer of the devops group
+// once. Accepts the paging and sort parameters of /engineers.
+func (s *Server) getDevOpsEngineers(c *gin.Context) {
+	v := s.forRequest(c)
+	devops, err := v.DevOps.Get(c.Param("id"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	params, err := parseListParams(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	engineers := make([]*devops_resource.Engineer, 0)
+	for _, engineer_id := range v.devOpsRoster(devops) {
+		if engineer, found := v.engineerStore.FindByID(engineer_id); found {
+			engineers = append(engineers, engineer)
+		}
+	}
+	page, err := paginate(c, engineers, params, engineerSortFields, engineerSortFields["id"])
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// server handler for GET /stats
+func (s *Server) getStats(c *gin.Context) {
+	v := s.forRequest(c)
+	c.IndentedJSON(http.StatusOK, v.computeStats())
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// seedGraph stores bob in D1 and O1, carol in D1 and D2 and an unattached alice.
+// DO1 holds D1, D2 and O1, DO2 holds D2 only.
+func seedGraph(t *testing.T, s *Server) {
+	chart := &orgChart{
+		Engineers: []*devops_resource.Engineer{
+			{Id: "E1", Name: "bob", Email: "bob@bob.com"},
+			{Id: "E2", Name: "alice", Email: "alice@bob.com"},
+			{Id: "E3", Name: "carol", Email: "carol@bob.com"},
+		},
+		Devs: []chartGroup{
+			{Id: "D1", Name: "dev_ferrets", Engineers: []string{"E1", "E3"}},
+			{Id: "D2", Name: "dev_stoats", Engineers: []string{"E3"}},
+		},
+		Ops:    []chartGroup{{Id: "O1", Name: "op_ferrets", Engineers: []string{"E1"}}},
+		DevOps: []chartDevOps{{Id: "DO1", Devs: []string{"D1", "D2"}, Ops: []string{"O1"}}, {Id: "DO2", Devs: []string{"D2"}, Ops: []string{}}},
+	}
+	if err := s.importOrgChart(chart); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+}
+
+var membershipTests = []struct {
+	description string
+	id          string
+	expected    *engineerMemberships
+}{
+	{"dev and ops member", "E1", &engineerMemberships{
+		Devs:   []groupRef{{Id: "D1", Name: "dev_ferrets"}},
+		Ops:    []groupRef{{Id: "O1", Name: "op_ferrets"}},
+		DevOps: []*devOpsMembership{{Id: "DO1", Devs: []string{"D1"}, Ops: []string{"O1"}}},
+	}},
+	{"reached through two dev groups", "E3", &engineerMemberships{
+		Devs: []groupRef{{Id: "D1", Name: "dev_ferrets"}, {Id: "D2", Name: "dev_stoats"}},
+		Ops:  []groupRef{},
+		DevOps: []*devOpsMembership{
+			{Id: "DO1", Devs: []string{"D1", "D2"}, Ops: []string{}},
+			{Id: "DO2", Devs: []string{"D2"}, Ops: []string{}},
+		},
+	}},
+	{"in no group", "E2", &engineerMemberships{Devs: []groupRef{}, Ops: []groupRef{}, DevOps: []*devOpsMembership{}}},
+}
+
+func testGraphQueries(t *testing.T, s *Server) {
+	gin.SetMode(gin.TestMode)
+	seedGraph(t, s)
+	router := newTestRouter(t, s)
+
+	for _, test := range membershipTests {
+		w := mockConditionalRequest(router, "GET", "/engineers/"+test.id+"/memberships", "", "")
+		var memberships engineerMemberships
+		if err := json.Unmarshal(w.Body.Bytes(), &memberships); w.Code != http.StatusOK || err != nil {
+			t.Fatalf("\nTest: %s\nExpected: Status Code 200, Received: Status Code %d\nBody: %s", test.description, w.Code, w.Body.String())
+		}
+		test.expected.Engineer, _ = s.engineerStore.FindByID(test.id)
+		if !reflect.DeepEqual(&memberships, test.expected) {
+			t.Errorf("\nTest: %s\nExpected: %s, Received: %s", test.description, mustJSON(test.expected), mustJSON(&memberships))
+		}
+	}
+	if w := mockConditionalRequest(router, "GET", "/engineers/E9/memberships", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected: Status Code %d, Received: Status Code %d", http.StatusNotFound, w.Code)
+	}
+
+	w := mockConditionalRequest(router, "GET", "/devops/DO1/engineers", "", "")
+	if roster := engineerIDsOf(t, w.Body.Bytes()); !reflect.DeepEqual(roster, []string{"E1", "E3"}) {
+		t.Errorf("Expected: roster [E1 E3], Received: %v", roster)
+	}
+	w = mockConditionalRequest(router, "GET", "/devops/DO1/engineers?sort=-name&limit=1", "", "")
+	if roster := engineerIDsOf(t, w.Body.Bytes()); !reflect.DeepEqual(roster, []string{"E3"}) || w.Header().Get("X-Total-Count") != "2" {
+		t.Errorf("Expected: roster [E3] of 2, Received: %v of %s", roster, w.Header().Get("X-Total-Count"))
+	}
+	if w := mockConditionalRequest(router, "GET", "/devops/DO9/engineers", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected: Status Code %d, Received: Status Code %d", http.StatusNotFound, w.Code)
+	}
+
+	w = mockConditionalRequest(router, "GET", "/stats", "", "")
+	var stats orgStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Error: %v\nBody: %s", err, w.Body.String())
+	}
+	expected := orgStats{
+		Engineers: 3, Devs: 2, Ops: 1, DevOps: 2,
+		GroupSizes: []groupSize{
+			{Kind: "dev", Id: "D1", Name: "dev_ferrets", Engineers: 2},
+			{Kind: "dev", Id: "D2", Name: "dev_stoats", Engineers: 1},
+			{Kind: "ops", Id: "O1", Name: "op_ferrets", Engineers: 1},
+			{Kind: "devops", Id: "DO1", Engineers: 2},
+			{Kind: "devops", Id: "DO2", Engineers: 1},
+		},
+		OrphanedEngineers:    []string{"E2"},
+		EngineersInDevAndOps: []string{"E1"},
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected: %+v, Received: %+v", expected, stats)
+	}
+}
+
+func TestGraphQueries(t *testing.T) {
+	s := newTestServer(t)
+	testGraphQueries(t, s)
+}
+
+func TestSQLiteGraphQueries(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testGraphQueries(t, s)
+}
+
+// reverse index lookups must follow every way a membership changes, including rollbacks
+var reverseIndexTests = []struct {
+	description string
+	change      func(uow *unitOfWork) error
+	devs        []string
+	devops      []string
+}{
+	{"seeded", func(uow *unitOfWork) error { return nil }, []string{"D1", "D2"}, []string{"DO1", "DO2"}},
+	{"engineer removed", func(uow *unitOfWork) error {
+		return uow.devs.RemoveEngineerFromDev("D1", "E3")
+	}, []string{"D2"}, []string{"DO1", "DO2"}},
+	{"engineer added back", func(uow *unitOfWork) error {
+		uow.devs.AddEngineerToDev("D1", &devops_resource.Engineer{Id: "E3"})
+		return nil
+	}, []string{"D2", "D1"}, []string{"DO1", "DO2"}},
+	{"group replaced", func(uow *unitOfWork) error {
+		uow.devops.Update(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{{Id: "D1"}}}, anyVersion)
+		return uow.devs.Update(&devops_resource.Dev{Id: "D2", Name: "dev_stoats", Engineers: []*devops_resource.Engineer{}}, anyVersion)
+	}, []string{"D1"}, []string{"DO2"}},
+	{"rolled back", func(uow *unitOfWork) error {
+		uow.devops.DeleteByID("DO2")
+		uow.devs.DeleteByID("D1")
+		return errAbandoned
+	}, []string{"D1"}, []string{"DO2"}},
+	{"group deleted", func(uow *unitOfWork) error {
+		uow.devops.DeleteByID("DO2")
+		return nil
+	}, []string{"D1"}, []string{}},
+}
+
+func testReverseIndexes(t *testing.T, s *Server) {
+	seedGraph(t, s)
+	for _, test := range reverseIndexTests {
+		s.inTransaction(test.change)
+		devs := make([]string, 0)
+		for _, dev := range s.devStore.FindByEngineer("E3") {
+			devs = append(devs, dev.Id)
+		}
+		if !reflect.DeepEqual(devs, test.devs) {
+			t.Errorf("\nTest: %s\nExpected: carol in %v, Received: %v", test.description, test.devs, devs)
+		}
+		devops := make([]string, 0)
+		for _, group := range s.devOpsStore.FindByDev("D2") {
+			devops = append(devops, group.Id)
+		}
+		if !reflect.DeepEqual(devops, test.devops) {
+			t.Errorf("\nTest: %s\nExpected: D2 in %v, Received: %v", test.description, test.devops, devops)
+		}
+	}
+}
+
+func TestReverseIndexes(t *testing.T) {
+	s := newTestServer(t)
+	testReverseIndexes(t, s)
+}
+
+func TestSQLiteReverseIndexes(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testReverseIndexes(t, s)
+}
+
+func engineerIDsOf(t *testing.T, body []byte) []string {
+	var engineers []*devops_resource.Engineer
+	if err := json.Unmarshal(body, &engineers); err != nil {
+		t.Fatalf("Error: %v\nBody: %s", err, body)
+	}
+	ids := make([]string, 0, len(engineers))
+	for _, engineer := range engineers {
+		ids = append(ids, engineer.Id)
+	}
+	return ids
+}
+
+func mustJSON(v any) string {
+	out, _ := json.Marshal(v)
+	return string(out)
+}
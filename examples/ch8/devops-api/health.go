@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessTimeout bounds each check of GET /readyz, a store that can't answer in time
+// is not ready
+const readinessTimeout = 2 * time.Second
+
+// pinger is implemented by backends that can fail independently of the API, such as a
+// database. The memory stores are always ready.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// readinessChecks names the backends GET /readyz checks
+func (s *Server) readinessChecks() map[string]any {
+	return map[string]any{
+		"storage": s.engineerStore, // the other stores share its database
+		"audit":   s.audit,
+	}
+}
+
+// getHealthz answers as long as the API can serve requests, for liveness probes
+func getHealthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// getReadyz checks every backend the API depends on, for readiness probes. It answers
+// 503 listing the failing checks when a backend is unavailable.
+func (s *Server) getReadyz(c *gin.Context) {
+	checks := map[string]string{}
+	var failed []string
+	for name, backend := range s.readinessChecks() {
+		checks[name] = "ok"
+		p, canFail := backend.(pinger)
+		if !canFail {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		err := p.Ping(ctx)
+		cancel()
+		if err != nil {
+			failed = append(failed, name+": "+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		writeError(c, unavailable("not_ready", "a backend the api depends on is unavailable", failed...))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
+
+// pingDB pings q when it is a database, the stores of a unit of work hold its
+// transaction instead and are as healthy as the database they were started from
+func pingDB(ctx context.Context, q queryer) error {
+	if db, isDB := q.(*sqliteDB); isDB {
+		return db.PingContext(ctx)
+	}
+	return nil
+}
+
+func (s *SQLiteEngineerStore) Ping(ctx context.Context) error {
+	return pingDB(ctx, s.db)
+}
+
+func (s *SQLiteAuditSink) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Ping checks the audit log file is still open
+func (s *FileAuditSink) Ping(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.file.Stat()
+	return err
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// idempotencyKeyHeader lets a client retry a POST without applying it twice, the retry
+// gets the response of the first request
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// responseRecorder keeps a copy of the response body so it can be replayed
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// maxIdempotencyKey is the longest key accepted, enough for a UUID with a prefix
+const maxIdempotencyKey = 255
+
+// replayedHeaders are the headers of a cached response sent again with its body, the
+// others describe the retry itself, such as its rate limit
+var replayedHeaders = []string{"Content-Type", "ETag"}
+
+// configureIdempotency sets how long responses are kept for retries, forgetting the
+// ones kept so far. A ttl of 0 turns Idempotency-Key support off.
+func (s *Server) configureIdempotency(ttl time.Duration) error {
+	if ttl < 0 {
+		return errors.New("idempotency ttl must not be negative")
+	}
+	s.idempotency = newIdempotencyStore(ttl, s.now)
+	return nil
+}
+
+// maxIdempotencyKeysPerClient bounds the responses kept for one client, a new key
+// replaces its oldest finished one
+const maxIdempotencyKeysPerClient = 1000
+
+// idempotencyStore keeps the response to each key of a client until it expires. A key
+// is pending while its first request is being handled.
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	clients map[string]map[string]*idempotentResponse
+	swept   time.Time
+}
+
+type idempotentResponse struct {
+	fingerprint [sha256.Size]byte
+	pending     bool
+	expires     time.Time
+	status      int
+	header      http.Header
+	body        []byte
+}
+
+func newIdempotencyStore(ttl time.Duration, now func() time.Time) *idempotencyStore {
+	return &idempotencyStore{ttl: ttl, now: now, clients: map[string]map[string]*idempotentResponse{}, swept: now()}
+}
+
+// begin claims key of client for a request with fingerprint. It returns the response to
+// replay when key was used before by the same request, and fails when key is pending or
+// was used by a different request, or when every key the client may keep is pending.
+func (s *idempotencyStore) begin(client string, key string, fingerprint [sha256.Size]byte) (*idempotentResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.swept) >= time.Minute {
+		for other, entries := range s.clients {
+			s.expire(other, entries, now)
+		}
+		s.swept = now
+	}
+
+	entries := s.clients[client]
+	entry, found := entries[key]
+	if found && !entry.pending && !now.Before(entry.expires) {
+		found = false
+	}
+	if !found {
+		if len(entries) >= maxIdempotencyKeysPerClient {
+			s.expire(client, entries, now)
+		}
+		if entries = s.clients[client]; entries == nil {
+			entries = map[string]*idempotentResponse{}
+			s.clients[client] = entries
+		}
+		if len(entries) >= maxIdempotencyKeysPerClient && !s.evictOldest(entries) {
+			return nil, conflict("idempotency_keys_in_use", "too many requests with an Idempotency-Key are still being handled")
+		}
+		entries[key] = &idempotentResponse{fingerprint: fingerprint, pending: true}
+		return nil, nil
+	}
+	if entry.fingerprint != fingerprint {
+		return nil, invalid("idempotency_key_reused", "the Idempotency-Key was already used for a different request")
+	}
+	if entry.pending {
+		return nil, conflict("idempotency_key_in_use", "a request with this Idempotency-Key is still being handled")
+	}
+	return entry, nil
+}
+
+// expire forgets the expired responses of client
+func (s *idempotencyStore) expire(client string, entries map[string]*idempotentResponse, now time.Time) {
+	for key, entry := range entries {
+		if !entry.pending && !now.Before(entry.expires) {
+			delete(entries, key)
+		}
+	}
+	if len(entries) == 0 {
+		delete(s.clients, client)
+	}
+}
+
+// evictOldest forgets the finished response expiring first, reporting whether there was one
+func (s *idempotencyStore) evictOldest(entries map[string]*idempotentResponse) bool {
+	oldest := ""
+	for key, entry := range entries {
+		if !entry.pending && (oldest == "" || entry.expires.Before(entries[oldest].expires)) {
+			oldest = key
+		}
+	}
+	delete(entries, oldest)
+	return oldest != ""
+}
+
+// finish keeps the response to the request that claimed key of client
+func (s *idempotencyStore) finish(client string, key string, status int, header http.Header, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entry, found := s.clients[client][key]; found {
+		entry.pending = false
+		entry.expires = s.now().Add(s.ttl)
+		entry.status = status
+		entry.header = header
+		entry.body = body
+	}
+}
+
+// release forgets key of client, so a retry is handled again
+func (s *idempotencyStore) release(client string, key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if entries, found := s.clients[client]; found {
+		delete(entries, key)
+		if len(entries) == 0 {
+			delete(s.clients, client)
+		}
+	}
+}
+
+// idempotent replays the response to an earlier POST sent by the same client with the
+// same Idempotency-Key, method, path and body instead of handling it again, so retrying
+// a create doesn't create a duplicate. Reusing a key for a different request is rejected
+// with a 422. Server errors are not kept, so the retry of a request that failed is
+// handled again. It runs after authorize so each caller has its own keys.
+func (s *Server) idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(idempotencyKeyHeader)
+		store := s.idempotency
+		if c.Request.Method != http.MethodPost || key == "" || store.ttl == 0 {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeError(c, badRequest("invalid_idempotency_key", "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKey)+" characters"))
+			return
+		}
+		raw, err := readBody(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		hash := sha256.New()
+		for _, part := range []string{c.Request.URL.Path, c.ContentType(), string(raw)} {
+			hash.Write([]byte(part))
+			hash.Write([]byte{0})
+		}
+		var fingerprint [sha256.Size]byte
+		copy(fingerprint[:], hash.Sum(nil))
+
+		client := clientOf(c)
+		cached, err := store.begin(client, key, fingerprint)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if cached != nil {
+			for name, values := range cached.header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Writer.WriteHeader(cached.status)
+			c.Writer.Write(cached.body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		handled := false
+		defer func() {
+			// a handler that panicked is answered with a 500 by the recovery middleware
+			status := recorder.Status()
+			if !handled || status >= http.StatusInternalServerError {
+				store.release(client, key)
+				return
+			}
+			header := http.Header{}
+			for _, name := range replayedHeaders {
+				for _, value := range recorder.Header().Values(name) {
+					header.Add(name, value)
+				}
+			}
+			store.finish(client, key, status, header, recorder.body.Bytes())
+		}()
+		c.Next()
+		handled = true
+	}
+}
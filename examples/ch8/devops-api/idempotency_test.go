@@ -0,0 +1,169 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// mockIdempotentRequest sends a JSON request with an Idempotency-Key from remoteAddr
+func mockIdempotentRequest(router *gin.Engine, url string, key string, body string, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", url, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, key)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.configureIdempotency(time.Hour)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1"})
+	router := newTestRouter(t, s)
+	const client = "10.0.0.1:1234"
+
+	first := mockIdempotentRequest(router, "/engineers", "create-alice", `{"name": "alice", "email": "alice@bob.com"}`, client)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected: Status Code 201, Received: %d %s", first.Code, first.Body.String())
+	}
+
+	tests := []struct {
+		description string
+		url         string
+		key         string
+		body        string
+		remoteAddr  string
+		status      int
+		replayed    bool
+		code        string
+	}{
+		{"retry replays the response", "/engineers", "create-alice", `{"name": "alice", "email": "alice@bob.com"}`, client, http.StatusCreated, true, ""},
+		{"another body is rejected", "/engineers", "create-alice", `{"name": "carol", "email": "carol@bob.com"}`, client, http.StatusUnprocessableEntity, false, "idempotency_key_reused"},
+		{"another route is rejected", "/dev", "create-alice", `{"name": "alice", "email": "alice@bob.com"}`, client, http.StatusUnprocessableEntity, false, "idempotency_key_reused"},
+		{"another client has its own keys", "/engineers", "create-alice", `{"name": "alice", "email": "alice@bob.com"}`, "10.0.0.2:1234", http.StatusConflict, false, "engineer_exists"},
+		{"first membership change", "/dev/D1", "add-bob", `{"id": "E1"}`, client, http.StatusOK, false, ""},
+		{"retried membership change is replayed", "/dev/D1", "add-bob", `{"id": "E1"}`, client, http.StatusOK, true, ""},
+		{"without a key the retry is handled again", "/dev/D1", "", `{"id": "E1"}`, client, http.StatusConflict, false, "engineer_already_member"},
+		{"failed request", "/dev", "bad-dev", `{"name": ""}`, client, http.StatusUnprocessableEntity, false, "schema_violation"},
+		{"failed retry", "/dev", "bad-dev", `{"name": ""}`, client, http.StatusUnprocessableEntity, true, "schema_violation"},
+		{"keys are bounded", "/dev", strings.Repeat("k", 256), `{"name": "dev_stoats"}`, client, http.StatusBadRequest, false, "invalid_idempotency_key"},
+	}
+
+	for _, test := range tests {
+		w := mockIdempotentRequest(router, test.url, test.key, test.body, test.remoteAddr)
+		var body problem
+		json.Unmarshal(w.Body.Bytes(), &body)
+		replayed := w.Header().Get("Idempotent-Replayed") == "true"
+		if w.Code != test.status || replayed != test.replayed || body.Code != test.code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d replayed %v code %q, Received: %d replayed %v %s",
+				test.description, test.status, test.replayed, test.code, w.Code, replayed, w.Body.String())
+		}
+	}
+
+	retry := mockIdempotentRequest(router, "/engineers", "create-alice", `{"name": "alice", "email": "alice@bob.com"}`, client)
+	if retry.Body.String() != first.Body.String() || retry.Header().Get("ETag") != first.Header().Get("ETag") {
+		t.Errorf("Expected: the replay to match the first response, Received:\n%s\n%s", first.Body.String(), retry.Body.String())
+	}
+	if engineers := s.engineerStore.List(); len(engineers) != 2 {
+		t.Errorf("Expected: alice to be created once, Received: %d engineers", len(engineers))
+	}
+}
+
+func TestIdempotencyStore(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := newIdempotencyStore(time.Hour, func() time.Time { return now })
+	request := sha256.Sum256([]byte("request"))
+
+	if cached, err := store.begin("10.0.0.1", "key", request); cached != nil || err != nil {
+		t.Fatalf("Expected: a new key to be claimed, Received: %v %v", cached, err)
+	}
+	if _, err := store.begin("10.0.0.1", "key", request); !errors.Is(err, ErrConflict) {
+		t.Errorf("\nTest: pending key\nExpected: a conflict, Received: %v", err)
+	}
+	store.release("10.0.0.1", "key")
+	if cached, err := store.begin("10.0.0.1", "key", request); cached != nil || err != nil {
+		t.Errorf("\nTest: released key\nExpected: the key to be claimed again, Received: %v %v", cached, err)
+	}
+	store.finish("10.0.0.1", "key", http.StatusCreated, http.Header{}, []byte("{}"))
+	if cached, err := store.begin("10.0.0.1", "key", request); err != nil || cached == nil || cached.status != http.StatusCreated {
+		t.Errorf("\nTest: finished key\nExpected: the response to replay, Received: %v %v", cached, err)
+	}
+	now = now.Add(time.Hour)
+	if cached, err := store.begin("10.0.0.1", "key", request); cached != nil || err != nil {
+		t.Errorf("\nTest: expired key\nExpected: the key to be claimed again, Received: %v %v", cached, err)
+	}
+}
+
+func TestIdempotencyStoreKeysPerClient(t *testing.T) {
+	now := time.Unix(0, 0)
+	store := newIdempotencyStore(time.Hour, func() time.Time { return now })
+	request := sha256.Sum256([]byte("request"))
+
+	for i := 0; i < maxIdempotencyKeysPerClient; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if _, err := store.begin("10.0.0.1", key, request); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+		if i > 0 {
+			store.finish("10.0.0.1", key, http.StatusCreated, http.Header{}, []byte("{}"))
+		}
+		now = now.Add(time.Millisecond)
+	}
+	// key-0 is still pending, so key-1 is the oldest finished key
+	if _, err := store.begin("10.0.0.1", "one-more", request); err != nil {
+		t.Fatalf("\nTest: full client\nExpected: the new key to be claimed, Received: %v", err)
+	}
+	if cached, _ := store.begin("10.0.0.1", "key-2", request); cached == nil {
+		t.Errorf("\nTest: full client\nExpected: the newer keys to be kept, Received: key-2 forgotten")
+	}
+	if cached, _ := store.begin("10.0.0.1", "key-1", request); cached != nil {
+		t.Errorf("\nTest: full client\nExpected: the oldest finished key to be forgotten, Received: its response")
+	}
+	if _, err := store.begin("10.0.0.2", "key-1", request); err != nil {
+		t.Errorf("\nTest: another client\nExpected: its own keys, Received: %v", err)
+	}
+}
+
+func TestIdempotencyStoreAllKeysPending(t *testing.T) {
+	store := newIdempotencyStore(time.Hour, time.Now)
+	request := sha256.Sum256([]byte("request"))
+	for i := 0; i < maxIdempotencyKeysPerClient; i++ {
+		if _, err := store.begin("10.0.0.1", fmt.Sprintf("key-%d", i), request); err != nil {
+			t.Fatalf("Error: %v", err)
+		}
+	}
+	if _, err := store.begin("10.0.0.1", "one-more", request); !errors.Is(err, ErrConflict) {
+		t.Errorf("\nTest: every key pending\nExpected: a conflict, Received: %v", err)
+	}
+}
+
+func TestIdempotencyKeyBodyTooLarge(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.configureIdempotency(time.Hour)
+	router := newTestRouter(t, s)
+
+	body := `{"name": "alice", "email": "alice@bob.com", "pad": "` + strings.Repeat("x", maxRequestBody) + `"}`
+	w := mockIdempotentRequest(router, "/engineers", "create-alice", body, "10.0.0.1:1234")
+	var problem problem
+	json.Unmarshal(w.Body.Bytes(), &problem)
+	if w.Code != http.StatusRequestEntityTooLarge || problem.Code != "body_too_large" {
+		t.Errorf("Expected: Status Code 413 body_too_large, Received: %d %s", w.Code, w.Body.String())
+	}
+	if engineers := s.engineerStore.List(); len(engineers) != 0 {
+		t.Errorf("Expected: no engineer created, Received: %d", len(engineers))
+	}
+}
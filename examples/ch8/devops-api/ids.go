@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	mathrand "math/rand"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// idGenerator picks the id of every new resource, ids sent by clients on create are
+// ignored. Ids only need to be unique in practice, the create functions still skip an
+// id that a resource of the same kind already has.
+type idGenerator interface {
+	NewID() (string, error)
+}
+
+// ID formats selectable with -ids or DEVOPS_IDS
+const (
+	idsULID   = "ulid"
+	idsUUIDv7 = "uuidv7"
+)
+
+// newIDGenerator returns a generator of ids in the requested format, timestamped with now
+func newIDGenerator(kind string, now func() time.Time) (idGenerator, error) {
+	switch kind {
+	case idsULID:
+		return newULIDGenerator(now, rand.Reader), nil
+	case idsUUIDv7:
+		return newUUIDv7Generator(now, rand.Reader), nil
+	}
+	return nil, errors.New("unknown id format " + kind)
+}
+
+// crockford is the base32 alphabet of ULIDs, without I, L, O and U
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidGenerator makes ULIDs: 26 characters holding a millisecond timestamp and 80 random
+// bits, so ids sort by creation time. Ids made in the same millisecond increment the
+// random bits of the previous one and keep sorting.
+type ulidGenerator struct {
+	now    func() time.Time
+	random io.Reader
+
+	mu      sync.Mutex
+	lastMS  uint64
+	entropy [10]byte
+}
+
+func newULIDGenerator(now func() time.Time, random io.Reader) *ulidGenerator {
+	return &ulidGenerator{now: now, random: random}
+}
+
+func (g *ulidGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	ms := uint64(g.now().UnixMilli())
+	if ms > g.lastMS {
+		if _, err := io.ReadFull(g.random, g.entropy[:]); err != nil {
+			return "", errors.New("failed to generate id: " + err.Error())
+		}
+		g.lastMS = ms
+	} else if !increment(g.entropy[:]) {
+		return "", errors.New("failed to generate id: too many ids in one millisecond")
+	}
+
+	// 128 bits: the timestamp in the top 48, the entropy below, written 5 bits at a time
+	hi := g.lastMS<<16 | uint64(g.entropy[0])<<8 | uint64(g.entropy[1])
+	var lo uint64
+	for _, b := range g.entropy[2:] {
+		lo = lo<<8 | uint64(b)
+	}
+	var id [26]byte
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockford[lo&31]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id[:]), nil
+}
+
+// increment adds one to the big-endian number in b, reporting false when it overflows
+func increment(b []byte) bool {
+	for i := len(b) - 1; i >= 0; i-- {
+		b[i]++
+		if b[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// uuidV7Generator makes version 7 UUIDs, a millisecond timestamp followed by 74 random
+// bits, for clients that expect ids in the UUID format
+type uuidV7Generator struct {
+	now    func() time.Time
+	random io.Reader
+	mu     sync.Mutex
+}
+
+func newUUIDv7Generator(now func() time.Time, random io.Reader) *uuidV7Generator {
+	return &uuidV7Generator{now: now, random: random}
+}
+
+func (g *uuidV7Generator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var uuid [16]byte
+	if _, err := io.ReadFull(g.random, uuid[6:]); err != nil {
+		return "", errors.New("failed to generate id: " + err.Error())
+	}
+	ms := uint64(g.now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		uuid[i] = byte(ms >> (40 - 8*i))
+	}
+	uuid[6] = 0x70 | uuid[6]&0x0f // version 7
+	uuid[8] = 0x80 | uuid[8]&0x3f // RFC 4122 variant
+	text := hex.EncodeToString(uuid[:])
+	return text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:], nil
+}
+
+// sequentialGenerator counts up from 1, so tests know the ids they will get
+type sequentialGenerator struct {
+	mu   sync.Mutex
+	last int
+}
+
+func newSequentialGenerator() *sequentialGenerator {
+	return &sequentialGenerator{}
+}
+
+func (g *sequentialGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.last++
+	return strconv.Itoa(g.last), nil
+}
+
+// seededGenerator makes short random looking ids that repeat for the same seed, short
+// enough that tests can make them collide
+type seededGenerator struct {
+	mu     sync.Mutex
+	random *mathrand.Rand
+	length int
+}
+
+func newSeededGenerator(seed int64, length int) *seededGenerator {
+	return &seededGenerator{random: mathrand.New(mathrand.NewSource(seed)), length: length}
+}
+
+func (g *seededGenerator) NewID() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := make([]byte, g.length)
+	for i := range id {
+		id[i] = crockford[g.random.Intn(len(crockford))]
+	}
+	return string(id), nil
+}
+
+// maxIDAttempts bounds how many taken ids are skipped before a create gives up
+const maxIDAttempts = 16
+
+// newID draws ids from ids until one is free for a resource of kind, one of the
+// archived kinds. An archived resource keeps its id, so it can still be restored.
+func (uow *unitOfWork) newID(ids idGenerator, kind string) (string, error) {
+	for attempt := 0; attempt < maxIDAttempts; attempt++ {
+		id, err := ids.NewID()
+		if err != nil {
+			return "", err
+		}
+		if !uow.idTaken(kind, id) {
+			uow.created[kind] = id
+			return id, nil
+		}
+	}
+	return "", errors.New("failed to generate id: every id tried is taken")
+}
+
+func (uow *unitOfWork) idTaken(kind string, id string) bool {
+	if _, archived := uow.archive.Find(kind, id); archived {
+		return true
+	}
+	var found bool
+	switch kind {
+	case archivedEngineer:
+		_, found = uow.engineers.FindByID(id)
+	case archivedDev:
+		_, found = uow.devs.FindByID(id)
+	case archivedOps:
+		_, found = uow.ops.FindByID(id)
+	case archivedDevOps:
+		_, found = uow.devops.FindByID(id)
+	}
+	return found
+}
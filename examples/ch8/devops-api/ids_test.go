@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// scriptedIDs hands out ids in order, repeating the last one when it runs out
+type scriptedIDs struct {
+	ids []string
+}
+
+func (s *scriptedIDs) NewID() (string, error) {
+	id := s.ids[0]
+	if len(s.ids) > 1 {
+		s.ids = s.ids[1:]
+	}
+	return id, nil
+}
+
+func TestULIDGenerator(t *testing.T) {
+	now := time.UnixMilli(1)
+	generator := newULIDGenerator(func() time.Time { return now }, bytes.NewReader(make([]byte, 20)))
+
+	tests := []struct {
+		description string
+		wait        time.Duration
+		expected    string
+	}{
+		{"timestamp then random bits", 0, "00000000010000000000000000"},
+		{"same millisecond increments", 0, "00000000010000000000000001"},
+		{"and keeps incrementing", 0, "00000000010000000000000002"},
+		{"clock going back keeps the order", -time.Millisecond, "00000000010000000000000003"},
+		{"next millisecond draws new bits", 2 * time.Millisecond, "00000000020000000000000000"},
+	}
+	for _, test := range tests {
+		now = now.Add(test.wait)
+		if id, err := generator.NewID(); id != test.expected || err != nil {
+			t.Errorf("\nTest: %s\nExpected: %s, Received: %s %v", test.description, test.expected, id, err)
+		}
+	}
+
+	random := newULIDGenerator(time.Now, rand.Reader)
+	previous := ""
+	for i := 0; i < 1000; i++ {
+		id, _ := random.NewID()
+		if !regexp.MustCompile(`^[0-9A-HJKMNP-TV-Z]{26}$`).MatchString(id) || id <= previous {
+			t.Fatalf("Expected: increasing ULIDs, Received: %s after %s", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestUUIDv7Generator(t *testing.T) {
+	generator := newUUIDv7Generator(func() time.Time { return time.UnixMilli(0x0123456789ab) }, bytes.NewReader(bytes.Repeat([]byte{0xff}, 10)))
+	if id, err := generator.NewID(); id != "01234567-89ab-7fff-bfff-ffffffffffff" || err != nil {
+		t.Errorf("Expected: a version 7 UUID holding the timestamp, Received: %s %v", id, err)
+	}
+}
+
+func TestSeededGenerator(t *testing.T) {
+	first, second := newSeededGenerator(42, 5), newSeededGenerator(42, 5)
+	for i := 0; i < 10; i++ {
+		a, _ := first.NewID()
+		b, _ := second.NewID()
+		if a != b || len(a) != 5 {
+			t.Fatalf("Expected: the same 5 character ids for the same seed, Received: %s and %s", a, b)
+		}
+	}
+}
+
+func TestNewIDSkipsTakenIDs(t *testing.T) {
+	scripted := &scriptedIDs{ids: []string{"A", "A", "B", "A", "B", "C"}}
+	s := NewServer(newMemoryStores(), scripted, time.Now)
+	defer s.Close()
+
+	first, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	second, _ := s.Engineers.Create(devops_resource.Engineer{Name: "alice", Email: "alice@bob.com"})
+	if first.Id != "A" || second.Id != "B" {
+		t.Errorf("\nTest: taken id is skipped\nExpected: A and B, Received: %s and %s", first.Id, second.Id)
+	}
+	// ids are per kind, a dev group can reuse the id of an engineer
+	scripted.ids = []string{"A"}
+	if dev, _ := s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets"}); dev.Id != "A" {
+		t.Errorf("\nTest: ids per kind\nExpected: A, Received: %s", dev.Id)
+	}
+
+	s.Engineers.Delete("A", anyVersion, "test")
+	scripted.ids = []string{"A", "B", "C"}
+	third, _ := s.Engineers.Create(devops_resource.Engineer{Name: "carol", Email: "carol@bob.com"})
+	if third.Id != "C" {
+		t.Errorf("\nTest: archived id is skipped so it can be restored\nExpected: C, Received: %s", third.Id)
+	}
+
+	scripted.ids = []string{"A"}
+	if _, err := s.Ops.Create(devops_resource.Ops{Name: "op_ferrets"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Ops.Create(devops_resource.Ops{Name: "op_stoats"}); err == nil {
+		t.Errorf("\nTest: every id taken\nExpected: an error, Received: none")
+	}
+	if _, found := s.opsStore.FindByName("op_stoats"); found {
+		t.Errorf("\nTest: every id taken\nExpected: nothing stored, Received: op_stoats")
+	}
+}
+
+func TestIDsAreServerSide(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+
+	tests := []struct {
+		url  string
+		body string
+	}{
+		{"/engineers", `{"id": "CLIENT", "name": "bob", "email": "bob@bob.com"}`},
+		{"/dev", `{"id": "CLIENT", "name": "dev_ferrets", "engineers": []}`},
+		{"/op", `{"id": "CLIENT", "name": "op_ferrets", "engineers": []}`},
+		{"/devops", `{"id": "CLIENT", "dev": [], "ops": []}`},
+	}
+	for i, test := range tests {
+		w := mockConditionalRequest(router, "POST", test.url, "", test.body)
+		var created struct {
+			Id string `json:"id"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+		if expected := string(rune('1' + i)); w.Code != http.StatusCreated || created.Id != expected {
+			t.Errorf("\nTest: POST %s\nExpected: Status Code 201 with id %s, Received: %d %s", test.url, expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	generator, err := newIDGenerator(idsUUIDv7, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := generator.NewID(); !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("Expected: a UUIDv7, Received: %s", id)
+	}
+	if _, err := newIDGenerator("random", time.Now); err == nil {
+		t.Errorf("Expected: an unknown format to be rejected, Received: no error")
+	}
+}
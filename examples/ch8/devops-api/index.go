@@ -0,0 +1,142 @@
+package main
+
+import "container/list"
+
+// orderedRecords holds records keyed by ID while remembering insertion order,
+// giving O(1) lookup, replace and delete with a stable List() order
+type orderedRecords[T any] struct {
+	order *list.List
+	byID  map[string]*list.Element
+}
+
+type orderedEntry[T any] struct {
+	id     string
+	record T
+}
+
+func newOrderedRecords[T any]() orderedRecords[T] {
+	return orderedRecords[T]{order: list.New(), byID: make(map[string]*list.Element)}
+}
+
+func (r *orderedRecords[T]) get(id string) (T, bool) {
+	if element, found := r.byID[id]; found {
+		return element.Value.(orderedEntry[T]).record, true
+	}
+	var zero T
+	return zero, false
+}
+
+// put appends a new record or replaces an existing one in place
+func (r *orderedRecords[T]) put(id string, record T) {
+	if element, found := r.byID[id]; found {
+		element.Value = orderedEntry[T]{id: id, record: record}
+		return
+	}
+	r.byID[id] = r.order.PushBack(orderedEntry[T]{id: id, record: record})
+}
+
+// insertBefore adds a record in front of the record with ID next, or at the end when
+// next is no longer held, so a removed record can be put back where it was
+func (r *orderedRecords[T]) insertBefore(next string, id string, record T) {
+	if mark, found := r.byID[next]; found {
+		r.byID[id] = r.order.InsertBefore(orderedEntry[T]{id: id, record: record}, mark)
+		return
+	}
+	r.byID[id] = r.order.PushBack(orderedEntry[T]{id: id, record: record})
+}
+
+// next returns the ID of the record after id, empty when id is the last record or missing
+func (r *orderedRecords[T]) next(id string) string {
+	if element, found := r.byID[id]; found && element.Next() != nil {
+		return element.Next().Value.(orderedEntry[T]).id
+	}
+	return ""
+}
+
+func (r *orderedRecords[T]) remove(id string) (T, bool) {
+	element, found := r.byID[id]
+	if !found {
+		var zero T
+		return zero, false
+	}
+	delete(r.byID, id)
+	return r.order.Remove(element).(orderedEntry[T]).record, true
+}
+
+func (r *orderedRecords[T]) len() int {
+	return len(r.byID)
+}
+
+// each visits records in insertion order
+func (r *orderedRecords[T]) each(fn func(T)) {
+	for element := r.order.Front(); element != nil; element = element.Next() {
+		fn(element.Value.(orderedEntry[T]).record)
+	}
+}
+
+// valueIndex maps a field value such as a name or email to the IDs holding it, oldest first
+type valueIndex map[string][]string
+
+func (x valueIndex) add(value string, id string) {
+	x[value] = append(x[value], id)
+}
+
+func (x valueIndex) remove(value string, id string) {
+	ids := x[value]
+	for i := range ids {
+		if ids[i] == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(x, value)
+		return
+	}
+	x[value] = ids
+}
+
+// addAll and removeAll index id under each of values, such as a group under the IDs of its members
+func (x valueIndex) addAll(values []string, id string) {
+	for _, value := range values {
+		x.add(value, id)
+	}
+}
+
+func (x valueIndex) removeAll(values []string, id string) {
+	for _, value := range values {
+		x.remove(value, id)
+	}
+}
+
+// addIfSet and removeIfSet leave empty values out of the index, for optional fields
+// such as a manager ID
+func (x valueIndex) addIfSet(value string, id string) {
+	if value != "" {
+		x.add(value, id)
+	}
+}
+
+func (x valueIndex) removeIfSet(value string, id string) {
+	if value != "" {
+		x.remove(value, id)
+	}
+}
+
+func (x valueIndex) first(value string) (string, bool) {
+	if ids := x[value]; len(ids) > 0 {
+		return ids[0], true
+	}
+	return "", false
+}
+
+// anyVersion makes Update skip the version check
+const anyVersion = 0
+
+// versionCounter tracks the version of every record, 0 means the record doesn't exist
+type versionCounter map[string]int
+
+// check fails when expected is set and differs from the current version of id
+func (v versionCounter) check(id string, expected int) error {
+	return checkVersion(id, v[id], expected)
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// mergePatch applies an RFC 7396 JSON merge patch to target: objects are merged
+// recursively, null removes a member and anything else replaces the target value
+func mergePatch(target any, patch any) any {
+	patchObject, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+	targetObject, isObject := target.(map[string]any)
+	if !isObject {
+		targetObject = map[string]any{}
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergePatch(targetObject[key], value)
+		}
+	}
+	return targetObject
+}
+
+// jsonPatchOperation is one RFC 6902 operation
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  *string          `json:"path"`
+	From  *string          `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// errPatchTest is returned when a test operation doesn't match
+var errPatchTest = errors.New("test operation failed")
+
+// parseJSONPatch decodes and checks the shape of an RFC 6902 document
+func parseJSONPatch(raw []byte) ([]jsonPatchOperation, error) {
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(raw, &operations); err != nil {
+		return nil, fmt.Errorf("a JSON patch must be an array of operations: %w", err)
+	}
+	for i, operation := range operations {
+		if operation.Path == nil {
+			return nil, fmt.Errorf("operation %d has no path", i)
+		}
+		switch operation.Op {
+		case "add", "replace", "test":
+			if operation.Value == nil {
+				return nil, fmt.Errorf("operation %d (%s) has no value", i, operation.Op)
+			}
+		case "move", "copy":
+			if operation.From == nil {
+				return nil, fmt.Errorf("operation %d (%s) has no from", i, operation.Op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("operation %d has unknown op %q", i, operation.Op)
+		}
+	}
+	return operations, nil
+}
+
+// applyJSONPatch applies the operations in order to a copy of doc, stopping at the first failure
+func applyJSONPatch(doc any, operations []jsonPatchOperation) (any, error) {
+	doc = deepCopyJSON(doc)
+	for i, operation := range operations {
+		var err error
+		var value any
+		if operation.Value != nil {
+			if err := json.Unmarshal(*operation.Value, &value); err != nil {
+				return nil, fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		switch operation.Op {
+		case "add":
+			doc, err = pointerAdd(doc, *operation.Path, value)
+		case "remove":
+			doc, _, err = pointerRemove(doc, *operation.Path)
+		case "replace":
+			if doc, _, err = pointerRemove(doc, *operation.Path); err == nil {
+				doc, err = pointerAdd(doc, *operation.Path, value)
+			}
+		case "move":
+			var moved any
+			if strings.HasPrefix(*operation.Path+"/", *operation.From+"/") && *operation.Path != *operation.From {
+				err = fmt.Errorf("cannot move %s into itself", *operation.From)
+			} else if doc, moved, err = pointerRemove(doc, *operation.From); err == nil {
+				doc, err = pointerAdd(doc, *operation.Path, moved)
+			}
+		case "copy":
+			var copied any
+			if copied, err = pointerGet(doc, *operation.From); err == nil {
+				doc, err = pointerAdd(doc, *operation.Path, deepCopyJSON(copied))
+			}
+		case "test":
+			var current any
+			if current, err = pointerGet(doc, *operation.Path); err == nil && !reflect.DeepEqual(current, value) {
+				err = errPatchTest
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, operation.Op, *operation.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+func deepCopyJSON(value any) any {
+	switch typed := value.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, item := range typed {
+			out[key] = deepCopyJSON(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, item := range typed {
+			out[i] = deepCopyJSON(item)
+		}
+		return out
+	}
+	return value
+}
+
+// splitPointer parses an RFC 6901 JSON pointer into unescaped reference tokens
+func splitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("path %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// arrayIndex parses an array index token, allowing "-" (one past the end) when appending
+func arrayIndex(token string, length int, appending bool) (int, error) {
+	if token == "-" && appending {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	limit := length - 1
+	if appending {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("index %d is out of range", index)
+	}
+	return index, nil
+}
+
+func pointerGet(doc any, pointer string) (any, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	current := doc
+	for _, token := range tokens {
+		switch container := current.(type) {
+		case map[string]any:
+			value, found := container[token]
+			if !found {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			current = value
+		case []any:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			current = container[index]
+		default:
+			return nil, fmt.Errorf("cannot descend into %q", token)
+		}
+	}
+	return current, nil
+}
+
+// pointerUpdate replaces the container that holds the last token of pointer with the result
+// of change, returning the new document since arrays may be reallocated
+func pointerUpdate(doc any, pointer string, change func(container any, token string) (any, error)) (any, error) {
+	tokens, err := splitPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return change(doc, "")
+	}
+	parentPointer := ""
+	for _, token := range tokens[:len(tokens)-1] {
+		parentPointer += "/" + strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+	}
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := change(parent, tokens[len(tokens)-1])
+	if err != nil {
+		return nil, err
+	}
+	if parentPointer == "" {
+		return updated, nil
+	}
+	// arrays are values, write the updated parent back into its own parent
+	return pointerUpdate(doc, parentPointer, func(grandparent any, token string) (any, error) {
+		switch container := grandparent.(type) {
+		case map[string]any:
+			container[token] = updated
+			return container, nil
+		case []any:
+			index, _ := arrayIndex(token, len(container), false)
+			container[index] = updated
+			return container, nil
+		}
+		return nil, fmt.Errorf("cannot descend into %q", token)
+	})
+}
+
+func pointerAdd(doc any, pointer string, value any) (any, error) {
+	return pointerUpdate(doc, pointer, func(container any, token string) (any, error) {
+		if pointer == "" {
+			return value, nil
+		}
+		switch typed := container.(type) {
+		case map[string]any:
+			typed[token] = value
+			return typed, nil
+		case []any:
+			index, err := arrayIndex(token, len(typed), true)
+			if err != nil {
+				return nil, err
+			}
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = value
+			return typed, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a scalar", token)
+	})
+}
+
+func pointerRemove(doc any, pointer string) (any, any, error) {
+	var removed any
+	updated, err := pointerUpdate(doc, pointer, func(container any, token string) (any, error) {
+		if pointer == "" {
+			removed = container
+			return nil, nil
+		}
+		switch typed := container.(type) {
+		case map[string]any:
+			value, found := typed[token]
+			if !found {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			removed = value
+			delete(typed, token)
+			return typed, nil
+		case []any:
+			index, err := arrayIndex(token, len(typed), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = typed[index]
+			return append(typed[:index], typed[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a scalar", token)
+	})
+	return updated, removed, err
+}
@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// List routes return defaultListLimit items per page unless ?limit= asks for up to
+// maxListLimit
+const (
+	maxListLimit     = 1000
+	defaultListLimit = min(100, maxListLimit)
+)
+
+// listParams holds the paging and ordering options shared by every list route
+type listParams struct {
+	limit int
+	after *listCursor // nil on the first page
+	sort  string
+}
+
+// listCursor points after the last item of a page: its sort key and its ID. Cursors are
+// opaque to clients and only valid with the sort they were made for.
+type listCursor struct {
+	Sort string `json:"s"`
+	Key  string `json:"k,omitempty"`
+	ID   string `json:"i"`
+}
+
+// parseListParams reads ?limit=, ?cursor= and ?sort= from the request
+func parseListParams(c *gin.Context) (listParams, error) {
+	params := listParams{limit: defaultListLimit, sort: c.Query("sort")}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			return params, badRequest("invalid_limit", "limit must be a number between 1 and "+strconv.Itoa(maxListLimit))
+		}
+		params.limit = limit
+	}
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := decodeCursor(value)
+		if err != nil {
+			return params, badRequest("invalid_cursor", "cursor is invalid")
+		}
+		if cursor.Sort != params.sort {
+			return params, badRequest("invalid_cursor", "cursor was made for another sort")
+		}
+		params.after = cursor
+	}
+	return params, nil
+}
+
+func encodeCursor(cursor listCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*listCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	return &cursor, nil
+}
+
+// sortKey returns the key of the field sortBy names and whether a leading '-' sorts
+// descending, or nil when sortBy is empty
+func sortKey[T any](sortBy string, fields map[string]func(T) string) (func(T) string, bool, error) {
+	if sortBy == "" {
+		return nil, false, nil
+	}
+	field, descending := strings.CutPrefix(sortBy, "-")
+	key, ok := fields[field]
+	if !ok {
+		return nil, false, badRequest("invalid_sort", "cannot sort by "+field)
+	}
+	return key, descending, nil
+}
+
+// precedes orders items by key, in either direction, and items with the same key by ID
+func precedes(key string, id string, otherKey string, otherID string, descending bool) bool {
+	if key != otherKey {
+		return (key < otherKey) != descending
+	}
+	return id < otherID
+}
+
+// paginate orders items by ?sort= and returns the page after the cursor, advertising the
+// next one through the Link, X-Next-Cursor and X-Total-Count headers. Without a sort the
+// items keep the order they were listed in, the store's insertion order. id tells the
+// items apart, so pages don't skip or repeat items when others are added or removed.
+func paginate[T any](c *gin.Context, items []T, params listParams, fields map[string]func(T) string, id func(T) string) ([]T, error) {
+	key, descending, err := sortKey(params.sort, fields)
+	if err != nil {
+		return nil, err
+	}
+	if key != nil {
+		sort.Slice(items, func(i, j int) bool {
+			return precedes(key(items[i]), id(items[i]), key(items[j]), id(items[j]), descending)
+		})
+	}
+	c.Header("X-Total-Count", strconv.Itoa(len(items)))
+	start := 0
+	if after := params.after; after != nil && key != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			return precedes(after.Key, after.ID, key(items[i]), id(items[i]), descending)
+		})
+	} else if after != nil {
+		start = slices.IndexFunc(items, func(item T) bool { return id(item) == after.ID }) + 1
+		if start == 0 {
+			return nil, badRequest("invalid_cursor", "the last item of the previous page is gone, sort the list to page through it while it changes")
+		}
+	}
+	if start >= len(items) {
+		return make([]T, 0), nil
+	}
+	end := min(start+params.limit, len(items))
+	if end < len(items) {
+		next := listCursor{Sort: params.sort, ID: id(items[end-1])}
+		if key != nil {
+			next.Key = key(items[end-1])
+		}
+		cursor := encodeCursor(next)
+		query := c.Request.URL.Query()
+		query.Set("cursor", cursor)
+		url := *c.Request.URL
+		url.RawQuery = query.Encode()
+		c.Header("Link", "<"+url.RequestURI()+">; rel=\"next\"")
+		c.Header("X-Next-Cursor", cursor)
+	}
+	return items[start:end], nil
+}
+
+// filterItems keeps the items accepted by every filter
+func filterItems[T any](items []T, filters ...func(T) bool) []T {
+	out := make([]T, 0, len(items))
+	for _, item := range items {
+		keep := true
+		for _, filter := range filters {
+			if !filter(item) {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func hasEngineer(engineers []*devops_resource.Engineer, engineerID string) bool {
+	for _, engineer := range engineers {
+		if engineer.Id == engineerID {
+			return true
+		}
+	}
+	return false
+}
+
+// devOpsHasEngineer reports whether the engineer belongs to any dev or ops group of devops
+func (s serviceScope) devOpsHasEngineer(devops *devops_resource.DevOps, engineerID string) bool {
+	for _, ref := range devops.Devs {
+		if dev, found := s.devStore.FindByID(ref.Id); found && hasEngineer(dev.Engineers, engineerID) {
+			return true
+		}
+	}
+	for _, ref := range devops.Ops {
+		if op, found := s.opsStore.FindByID(ref.Id); found && hasEngineer(op.Engineers, engineerID) {
+			return true
+		}
+	}
+	return false
+}
+
+var engineerSortFields = map[string]func(*devops_resource.Engineer) string{
+	"name":  func(e *devops_resource.Engineer) string { return e.Name },
+	"email": func(e *devops_resource.Engineer) string { return e.Email },
+	"id":    func(e *devops_resource.Engineer) string { return e.Id },
+}
+
+var devSortFields = map[string]func(*devops_resource.Dev) string{
+	"name": func(d *devops_resource.Dev) string { return d.Name },
+	"id":   func(d *devops_resource.Dev) string { return d.Id },
+}
+
+var opsSortFields = map[string]func(*devops_resource.Ops) string{
+	"name": func(o *devops_resource.Ops) string { return o.Name },
+	"id":   func(o *devops_resource.Ops) string { return o.Id },
+}
+
+var devOpsSortFields = map[string]func(*devops_resource.DevOps) string{
+	"id": func(d *devops_resource.DevOps) string { return d.Id },
+}
+
+// archivedItems are the archived resources a list includes, with their records
+type archivedItems[T comparable] map[T]*archivedRecord
+
+// withArchived appends the resources archived as kind to items when the request asks for
+// them with ?include_archived=true, so they are filtered, sorted and paged with the others
+func withArchived[T comparable](c *gin.Context, archive ArchiveStorage, kind string, items []T, decode func(record *archivedRecord) (T, error)) ([]T, archivedItems[T], error) {
+	value := c.Query("include_archived")
+	if value == "" {
+		return items, nil, nil
+	}
+	include, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, nil, badRequest("invalid_include_archived", "include_archived must be true or false")
+	}
+	if !include {
+		return items, nil, nil
+	}
+	archived := archivedItems[T]{}
+	for _, record := range archive.List(kind) {
+		item, err := decode(record)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode archived %s %s: %w", kind, record.Id, err)
+		}
+		items = append(items, item)
+		archived[item] = record
+	}
+	return items, archived, nil
+}
+
+func decodeArchivedEngineer(record *archivedRecord) (*devops_resource.Engineer, error) {
+	engineer := &devops_resource.Engineer{}
+	err := json.Unmarshal(record.Resource, engineer)
+	return engineer, err
+}
+
+func decodeArchivedDev(record *archivedRecord) (*devops_resource.Dev, error) {
+	var group chartGroup
+	err := json.Unmarshal(record.Resource, &group)
+	return &devops_resource.Dev{Id: group.Id, Name: group.Name, Engineers: engineerRefs(group.Engineers)}, err
+}
+
+func decodeArchivedOps(record *archivedRecord) (*devops_resource.Ops, error) {
+	var group chartGroup
+	err := json.Unmarshal(record.Resource, &group)
+	return &devops_resource.Ops{Id: group.Id, Name: group.Name, Engineers: engineerRefs(group.Engineers)}, err
+}
+
+func decodeArchivedDevOps(record *archivedRecord) (*devops_resource.DevOps, error) {
+	var group chartDevOps
+	err := json.Unmarshal(record.Resource, &group)
+	devops := &devops_resource.DevOps{Id: group.Id, Devs: make([]*devops_resource.Dev, 0), Ops: make([]*devops_resource.Ops, 0)}
+	for _, devID := range group.Devs {
+		devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: devID})
+	}
+	for _, opsID := range group.Ops {
+		devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: opsID})
+	}
+	return devops, err
+}
+
+// renderList renders every item of a list, adding archived_at and archived_by to the
+// archived ones
+func renderList[T comparable](items []T, archived archivedItems[T], render func(T) any) []any {
+	out := make([]any, 0, len(items))
+	for _, item := range items {
+		view := render(item)
+		if record, found := archived[item]; found {
+			view = archivedView{resource: view, archivedAt: record.ArchivedAt, archivedBy: record.ArchivedBy}
+		}
+		out = append(out, view)
+	}
+	return out
+}
+
+// archivedView is the view of an archived resource in a list: its fields followed by
+// when and by whom it was archived
+type archivedView struct {
+	resource   any
+	archivedAt time.Time
+	archivedBy string
+}
+
+func (v archivedView) MarshalJSON() ([]byte, error) {
+	resource, err := json.Marshal(v.resource)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := json.Marshal(struct {
+		ArchivedAt time.Time `json:"archived_at"`
+		ArchivedBy string    `json:"archived_by"`
+	}{v.archivedAt, v.archivedBy})
+	if err != nil {
+		return nil, err
+	}
+	if len(resource) < 2 || resource[0] != '{' {
+		return nil, fmt.Errorf("archived resource is not an object: %s", resource)
+	}
+	if len(resource) == 2 {
+		return stamp, nil
+	}
+	// both are objects, join their fields
+	joined := append(resource[:len(resource)-1:len(resource)-1], ',')
+	return append(joined, stamp[1:]...), nil
+}
+
+// listEngineers applies the ?include_archived=, ?email_domain= and ?name= filters,
+// sorting and paging
+func (s serviceScope) listEngineers(c *gin.Context) ([]*devops_resource.Engineer, archivedItems[*devops_resource.Engineer], error) {
+	params, err := parseListParams(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	engineers, archived, err := withArchived(c, s.archiveStore, archivedEngineer, s.engineerStore.List(), decodeArchivedEngineer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if domain := c.Query("email_domain"); domain != "" {
+		engineers = filterItems(engineers, func(e *devops_resource.Engineer) bool {
+			return strings.EqualFold(e.Email[strings.LastIndex(e.Email, "@")+1:], domain)
+		})
+	}
+	if name := c.Query("name"); name != "" {
+		engineers = filterItems(engineers, func(e *devops_resource.Engineer) bool {
+			return strings.Contains(strings.ToLower(e.Name), strings.ToLower(name))
+		})
+	}
+	page, err := paginate(c, engineers, params, engineerSortFields, engineerSortFields["id"])
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, archived, nil
+}
+
+// listDevs applies the ?include_archived=, ?member= and ?name= filters, sorting and paging
+func (s serviceScope) listDevs(c *gin.Context) ([]*devops_resource.Dev, archivedItems[*devops_resource.Dev], error) {
+	params, err := parseListParams(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	devs, archived, err := withArchived(c, s.archiveStore, archivedDev, s.devStore.List(), decodeArchivedDev)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member := c.Query("member"); member != "" {
+		devs = filterItems(devs, func(d *devops_resource.Dev) bool { return hasEngineer(d.Engineers, member) })
+	}
+	if name := c.Query("name"); name != "" {
+		devs = filterItems(devs, func(d *devops_resource.Dev) bool {
+			return strings.Contains(strings.ToLower(d.Name), strings.ToLower(name))
+		})
+	}
+	page, err := paginate(c, devs, params, devSortFields, devSortFields["id"])
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, archived, nil
+}
+
+// listOps applies the ?include_archived=, ?member= and ?name= filters, sorting and paging
+func (s serviceScope) listOps(c *gin.Context) ([]*devops_resource.Ops, archivedItems[*devops_resource.Ops], error) {
+	params, err := parseListParams(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	ops, archived, err := withArchived(c, s.archiveStore, archivedOps, s.opsStore.List(), decodeArchivedOps)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member := c.Query("member"); member != "" {
+		ops = filterItems(ops, func(o *devops_resource.Ops) bool { return hasEngineer(o.Engineers, member) })
+	}
+	if name := c.Query("name"); name != "" {
+		ops = filterItems(ops, func(o *devops_resource.Ops) bool {
+			return strings.Contains(strings.ToLower(o.Name), strings.ToLower(name))
+		})
+	}
+	page, err := paginate(c, ops, params, opsSortFields, opsSortFields["id"])
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, archived, nil
+}
+
+// listDevOps applies the ?include_archived=, ?member=, ?dev= and ?op= filters, sorting
+// and paging
+func (s serviceScope) listDevOps(c *gin.Context) ([]*devops_resource.DevOps, archivedItems[*devops_resource.DevOps], error) {
+	params, err := parseListParams(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	devops, archived, err := withArchived(c, s.archiveStore, archivedDevOps, s.devOpsStore.List(), decodeArchivedDevOps)
+	if err != nil {
+		return nil, nil, err
+	}
+	if member := c.Query("member"); member != "" {
+		devops = filterItems(devops, func(d *devops_resource.DevOps) bool { return s.devOpsHasEngineer(d, member) })
+	}
+	if devID := c.Query("dev"); devID != "" {
+		devops = filterItems(devops, func(d *devops_resource.DevOps) bool {
+			_, err := findDevInDevOps_by_Id(d, devID)
+			return err == nil
+		})
+	}
+	if opID := c.Query("op"); opID != "" {
+		devops = filterItems(devops, func(d *devops_resource.DevOps) bool {
+			_, err := findOpInDevOps_by_Id(d, opID)
+			return err == nil
+		})
+	}
+	page, err := paginate(c, devops, params, devOpsSortFields, devOpsSortFields["id"])
+	if err != nil {
+		return nil, nil, err
+	}
+	return page, archived, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+func mockGetList(handler gin.HandlerFunc, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, url, nil)
+	handler(c)
+	return w
+}
+
+func engineerNames(t *testing.T, w *httptest.ResponseRecorder) []string {
+	var engineers []devops_resource.Engineer
+	if err := json.Unmarshal(w.Body.Bytes(), &engineers); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	names := make([]string, 0, len(engineers))
+	for _, engineer := range engineers {
+		names = append(names, engineer.Name)
+	}
+	return names
+}
+
+func TestGetEngineerPagination(t *testing.T) {
+	s := newTestServer(t)
+	for _, name := range []string{"carol", "alice", "dave", "bob", "erin"} {
+		s.Engineers.Create(devops_resource.Engineer{Name: name, Email: name + "@bob.com"})
+	}
+
+	w := mockGetList(s.getEngineer, "/engineers?limit=2&sort=name")
+	if names := engineerNames(t, w); len(names) != 2 || names[0] != "alice" || names[1] != "bob" {
+		t.Fatalf("Expected first page [alice bob], Received: %v", names)
+	}
+	if w.Header().Get("X-Total-Count") != "5" || w.Header().Get("Link") == "" {
+		t.Fatalf("Expected paging headers, Received: %v", w.Header())
+	}
+
+	w = mockGetList(s.getEngineer, "/engineers?limit=2&sort=name&cursor="+w.Header().Get("X-Next-Cursor"))
+	if names := engineerNames(t, w); len(names) != 2 || names[0] != "carol" || names[1] != "dave" {
+		t.Errorf("Expected second page [carol dave], Received: %v", names)
+	}
+
+	w = mockGetList(s.getEngineer, "/engineers?limit=2&sort=name&cursor="+w.Header().Get("X-Next-Cursor"))
+	if names := engineerNames(t, w); len(names) != 1 || names[0] != "erin" {
+		t.Errorf("Expected last page [erin], Received: %v", names)
+	}
+	if w.Header().Get("Link") != "" {
+		t.Errorf("Expected no next link on the last page, Received: %s", w.Header().Get("Link"))
+	}
+
+	w = mockGetList(s.getEngineer, "/engineers?sort=-name&limit=1")
+	if names := engineerNames(t, w); len(names) != 1 || names[0] != "erin" {
+		t.Errorf("Expected descending sort to start with erin, Received: %v", names)
+	}
+}
+
+func TestGetEngineerPaginationWhileChanging(t *testing.T) {
+	s := newTestServer(t)
+	ids := map[string]string{}
+	for _, name := range []string{"carol", "alice", "dave", "bob", "erin"} {
+		engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: name, Email: name + "@bob.com"})
+		ids[name] = engineer.Id
+	}
+
+	// removing the last engineer of a page and adding one before it moves every offset
+	w := mockGetList(s.getEngineer, "/engineers?limit=2&sort=name")
+	sorted := w.Header().Get("X-Next-Cursor")
+	w = mockGetList(s.getEngineer, "/engineers?limit=2")
+	inserted := w.Header().Get("X-Next-Cursor")
+	if err := s.Engineers.Delete(ids["bob"], anyVersion, "test"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := s.Engineers.Delete(ids["carol"], anyVersion, "test"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	s.Engineers.Create(devops_resource.Engineer{Name: "aaron", Email: "aaron@bob.com"})
+
+	w = mockGetList(s.getEngineer, "/engineers?limit=2&sort=name&cursor="+sorted)
+	if names := engineerNames(t, w); len(names) != 2 || names[0] != "dave" || names[1] != "erin" {
+		t.Errorf("Expected second page by name [dave erin], Received: %v", names)
+	}
+	w = mockGetList(s.getEngineer, "/engineers?limit=2&cursor="+inserted)
+	if names := engineerNames(t, w); len(names) != 2 || names[0] != "dave" || names[1] != "erin" {
+		t.Errorf("Expected second page in insertion order [dave erin], Received: %v", names)
+	}
+
+	w = mockGetList(s.getEngineer, "/engineers?limit=2&sort=-name&cursor="+sorted)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a cursor of another sort to be rejected, Received: Status Code %d", w.Code)
+	}
+}
+
+func TestGetEngineerDefaultPageSize(t *testing.T) {
+	s := newTestServer(t)
+	for i := 0; i <= defaultListLimit; i++ {
+		name := fmt.Sprintf("engineer%03d", i)
+		s.Engineers.Create(devops_resource.Engineer{Name: name, Email: name + "@bob.com"})
+	}
+
+	w := mockGetList(s.getEngineer, "/engineers")
+	if names := engineerNames(t, w); len(names) != defaultListLimit || w.Header().Get("X-Next-Cursor") == "" {
+		t.Errorf("Expected a first page of %d and a next cursor, Received: %d %v", defaultListLimit, len(names), w.Header())
+	}
+	w = mockGetList(s.getEngineer, "/engineers?cursor="+w.Header().Get("X-Next-Cursor"))
+	if names := engineerNames(t, w); len(names) != 1 || names[0] != fmt.Sprintf("engineer%03d", defaultListLimit) {
+		t.Errorf("Expected the last engineer on the second page, Received: %v", names)
+	}
+}
+
+func TestGetEngineerFilterByEmailDomain(t *testing.T) {
+	s := newTestServer(t)
+	s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@liatrio.com"})
+	s.Engineers.Create(devops_resource.Engineer{Name: "alice", Email: "alice@gmail.com"})
+
+	w := mockGetList(s.getEngineer, "/engineers?email_domain=liatrio.com")
+	if names := engineerNames(t, w); len(names) != 1 || names[0] != "bob" {
+		t.Errorf("Expected only bob, Received: %v", names)
+	}
+}
+
+func TestGetDevFilterByMember(t *testing.T) {
+	s := newTestServer(t)
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	s.Devs.Create(devops_resource.Dev{Name: "dev_bengal"})
+
+	w := mockGetList(s.getDev, "/dev?member="+engineer.Id)
+	var devs []devops_resource.Dev
+	json.Unmarshal(w.Body.Bytes(), &devs)
+	if len(devs) != 1 || devs[0].Name != "dev_ferrets" {
+		t.Errorf("Expected only dev_ferrets, Received: %v", devs)
+	}
+}
+
+var badListRequests = []struct {
+	description string
+	url         string
+}{
+	{"limit is not a number", "/engineers?limit=ten"},
+	{"limit is too large", "/engineers?limit=100000"},
+	{"cursor is garbage", "/engineers?cursor=@@@"},
+	{"unknown sort field", "/engineers?sort=shoe_size"},
+}
+
+func TestGetEngineerBadListParams(t *testing.T) {
+	s := newTestServer(t)
+	for _, test := range badListRequests {
+		w := mockGetList(s.getEngineer, test.url)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, http.StatusBadRequest, w.Code)
+		}
+	}
+}
@@ -1,90 +1,177 @@
 package main
 
 import (
-	"crypto/rand"
-	"encoding/base32"
+	"context"
 	"errors"
-	"regexp"
-	"sync"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
 )
 
-// Thread-safe stores for each data type
+// Thread-safe stores for each data type, groups hold their members as ID references.
+// Records are indexed by ID (and by name/email where looked up) and keep insertion order.
+// Groups are also indexed by the IDs of their members, so the groups reaching an engineer
+// are found without scanning every group, and engineers by their manager's ID.
 type EngineerStore struct {
-	mu        sync.RWMutex
-	engineers []*devops_resource.Engineer
+	mu        timedRWMutex
+	engineers orderedRecords[*devops_resource.Engineer]
+	byName    valueIndex
+	byEmail   valueIndex
+	byManager valueIndex
+	versions  versionCounter
 }
 
 type DevStore struct {
-	mu         sync.RWMutex
-	developers []*devops_resource.Dev
+	mu         timedRWMutex
+	developers orderedRecords[*devops_resource.Dev]
+	byName     valueIndex
+	byEngineer valueIndex
+	versions   versionCounter
 }
 
 type OpsStore struct {
-	mu         sync.RWMutex
-	operations []*devops_resource.Ops
+	mu         timedRWMutex
+	operations orderedRecords[*devops_resource.Ops]
+	byName     valueIndex
+	byEngineer valueIndex
+	versions   versionCounter
 }
 
 type DevOpsStore struct {
-	mu                   sync.RWMutex
-	developer_operations []*devops_resource.DevOps
+	mu                   timedRWMutex
+	developer_operations orderedRecords[*devops_resource.DevOps]
+	byDev                valueIndex
+	byOps                valueIndex
+	versions             versionCounter
+}
+
+func newEngineerStore() *EngineerStore {
+	return &EngineerStore{
+		mu:        timedRWMutex{name: "engineers"},
+		engineers: newOrderedRecords[*devops_resource.Engineer](),
+		byName:    valueIndex{},
+		byEmail:   valueIndex{},
+		byManager: valueIndex{},
+		versions:  versionCounter{},
+	}
+}
+
+func newDevStore() *DevStore {
+	return &DevStore{mu: timedRWMutex{name: "dev"}, developers: newOrderedRecords[*devops_resource.Dev](), byName: valueIndex{}, byEngineer: valueIndex{}, versions: versionCounter{}}
+}
+
+func newOpsStore() *OpsStore {
+	return &OpsStore{mu: timedRWMutex{name: "ops"}, operations: newOrderedRecords[*devops_resource.Ops](), byName: valueIndex{}, byEngineer: valueIndex{}, versions: versionCounter{}}
 }
 
-// Global store instances
-var engineerStore = &EngineerStore{engineers: make([]*devops_resource.Engineer, 0)}
-var devStore = &DevStore{developers: make([]*devops_resource.Dev, 0)}
-var opsStore = &OpsStore{operations: make([]*devops_resource.Ops, 0)}
-var devOpsStore = &DevOpsStore{developer_operations: make([]*devops_resource.DevOps, 0)}
+func newDevOpsStore() *DevOpsStore {
+	return &DevOpsStore{mu: timedRWMutex{name: "devops"}, developer_operations: newOrderedRecords[*devops_resource.DevOps](), byDev: valueIndex{}, byOps: valueIndex{}, versions: versionCounter{}}
+}
 
 // Helper methods for testing - clear stores
 func (s *EngineerStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.engineers = make([]*devops_resource.Engineer, 0)
+	s.engineers = newOrderedRecords[*devops_resource.Engineer]()
+	s.versions = versionCounter{}
+	s.byName = valueIndex{}
+	s.byEmail = valueIndex{}
+	s.byManager = valueIndex{}
 }
 
 func (s *DevStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.developers = make([]*devops_resource.Dev, 0)
+	s.developers = newOrderedRecords[*devops_resource.Dev]()
+	s.versions = versionCounter{}
+	s.byName = valueIndex{}
+	s.byEngineer = valueIndex{}
 }
 
 func (s *OpsStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.operations = make([]*devops_resource.Ops, 0)
+	s.operations = newOrderedRecords[*devops_resource.Ops]()
+	s.versions = versionCounter{}
+	s.byName = valueIndex{}
+	s.byEngineer = valueIndex{}
 }
 
 func (s *DevOpsStore) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.developer_operations = make([]*devops_resource.DevOps, 0)
+	s.developer_operations = newOrderedRecords[*devops_resource.DevOps]()
+	s.versions = versionCounter{}
+	s.byDev = valueIndex{}
+	s.byOps = valueIndex{}
 }
 
 // EngineerStore methods
-func (s *EngineerStore) Add(engineer *devops_resource.Engineer) {
+func (s *EngineerStore) Add(engineer *devops_resource.Engineer) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.engineers = append(s.engineers, engineer)
+	if _, found := s.engineers.get(engineer.Id); found {
+		return errors.New("engineer id already exists in store")
+	}
+	s.engineers.put(engineer.Id, cloneEngineer(engineer))
+	s.byName.add(engineer.Name, engineer.Id)
+	s.byEmail.add(engineer.Email, engineer.Id)
+	s.byManager.addIfSet(engineer.ManagerId, engineer.Id)
+	s.versions[engineer.Id] = 1
+	return nil
+}
+
+func (s *EngineerStore) Update(engineer *devops_resource.Engineer, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.engineers.get(engineer.Id)
+	if !found {
+		return errors.New("engineer not found in store")
+	}
+	if err := s.versions.check(engineer.Id, version); err != nil {
+		return err
+	}
+	s.byName.remove(old.Name, old.Id)
+	s.byEmail.remove(old.Email, old.Id)
+	s.byManager.removeIfSet(old.ManagerId, old.Id)
+	s.engineers.put(engineer.Id, cloneEngineer(engineer))
+	s.byName.add(engineer.Name, engineer.Id)
+	s.byEmail.add(engineer.Email, engineer.Id)
+	s.byManager.addIfSet(engineer.ManagerId, engineer.Id)
+	s.versions[engineer.Id]++
+	return nil
+}
+
+func (s *EngineerStore) Version(id string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versions[id]
 }
 
 func (s *EngineerStore) List() []*devops_resource.Engineer {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]*devops_resource.Engineer, len(s.engineers))
-	copy(out, s.engineers)
+	out := make([]*devops_resource.Engineer, 0, s.engineers.len())
+	s.engineers.each(func(engineer *devops_resource.Engineer) {
+		out = append(out, cloneEngineer(engineer))
+	})
 	return out
 }
 
 func (s *EngineerStore) FindByID(id string) (*devops_resource.Engineer, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, engineer := range s.engineers {
-		if engineer.Id == id {
-			return engineer, true
-		}
+	if engineer, found := s.engineers.get(id); found {
+		return cloneEngineer(engineer), true
 	}
 	return nil, false
 }
@@ -92,10 +179,9 @@ func (s *EngineerStore) FindByID(id string) (*devops_resource.Engineer, bool) {
 func (s *EngineerStore) FindByName(name string) (*devops_resource.Engineer, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, engineer := range s.engineers {
-		if engineer.Name == name {
-			return engineer, true
-		}
+	if id, found := s.byName.first(name); found {
+		engineer, _ := s.engineers.get(id)
+		return cloneEngineer(engineer), true
 	}
 	return nil, false
 }
@@ -103,52 +189,92 @@ func (s *EngineerStore) FindByName(name string) (*devops_resource.Engineer, bool
 func (s *EngineerStore) FindByEmail(email string) (*devops_resource.Engineer, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, engineer := range s.engineers {
-		if engineer.Email == email {
-			return engineer, true
-		}
+	if id, found := s.byEmail.first(email); found {
+		engineer, _ := s.engineers.get(id)
+		return cloneEngineer(engineer), true
 	}
 	return nil, false
 }
 
+func (s *EngineerStore) FindByManager(managerID string) []*devops_resource.Engineer {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*devops_resource.Engineer, 0, len(s.byManager[managerID]))
+	for _, id := range s.byManager[managerID] {
+		engineer, _ := s.engineers.get(id)
+		out = append(out, cloneEngineer(engineer))
+	}
+	return out
+}
+
 func (s *EngineerStore) DeleteByID(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	for i := range s.engineers {
-		if s.engineers[i].Id == id {
-			last := len(s.engineers) - 1
-			s.engineers[i] = s.engineers[last]
-			s.engineers[last] = nil // avoid retaining pointer
-			s.engineers = s.engineers[:last]
-			return true
-		}
+	engineer, found := s.engineers.remove(id)
+	if !found {
+		return false
 	}
-	return false
+	s.byName.remove(engineer.Name, id)
+	s.byEmail.remove(engineer.Email, id)
+	s.byManager.removeIfSet(engineer.ManagerId, id)
+	delete(s.versions, id)
+	return true
 }
 
 // DevStore methods
-func (s *DevStore) Add(dev *devops_resource.Dev) {
+func (s *DevStore) Add(dev *devops_resource.Dev) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.developers = append(s.developers, dev)
+	if _, found := s.developers.get(dev.Id); found {
+		return errors.New("dev id already exists in store")
+	}
+	s.developers.put(dev.Id, normalizeDev(dev))
+	s.byName.add(dev.Name, dev.Id)
+	s.byEngineer.addAll(engineerIDs(dev.Engineers), dev.Id)
+	s.versions[dev.Id] = 1
+	return nil
+}
+
+func (s *DevStore) Update(dev *devops_resource.Dev, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.developers.get(dev.Id)
+	if !found {
+		return errors.New("dev not found in store")
+	}
+	if err := s.versions.check(dev.Id, version); err != nil {
+		return err
+	}
+	s.byName.remove(old.Name, old.Id)
+	s.byEngineer.removeAll(engineerIDs(old.Engineers), old.Id)
+	s.developers.put(dev.Id, normalizeDev(dev))
+	s.byName.add(dev.Name, dev.Id)
+	s.byEngineer.addAll(engineerIDs(dev.Engineers), dev.Id)
+	s.versions[dev.Id]++
+	return nil
+}
+
+func (s *DevStore) Version(id string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versions[id]
 }
 
 func (s *DevStore) List() []*devops_resource.Dev {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]*devops_resource.Dev, len(s.developers))
-	copy(out, s.developers)
+	out := make([]*devops_resource.Dev, 0, s.developers.len())
+	s.developers.each(func(dev *devops_resource.Dev) {
+		out = append(out, normalizeDev(dev))
+	})
 	return out
 }
 
 func (s *DevStore) FindByID(id string) (*devops_resource.Dev, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, dev := range s.developers {
-		if dev.Id == id {
-			return dev, true
-		}
+	if dev, found := s.developers.get(id); found {
+		return normalizeDev(dev), true
 	}
 	return nil, false
 }
@@ -156,52 +282,92 @@ func (s *DevStore) FindByID(id string) (*devops_resource.Dev, bool) {
 func (s *DevStore) FindByName(name string) (*devops_resource.Dev, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, dev := range s.developers {
-		if dev.Name == name {
-			return dev, true
-		}
+	if id, found := s.byName.first(name); found {
+		dev, _ := s.developers.get(id)
+		return normalizeDev(dev), true
 	}
 	return nil, false
 }
 
+// FindByEngineer returns the dev groups the engineer is in, in the order they gained the engineer
+func (s *DevStore) FindByEngineer(engineerID string) []*devops_resource.Dev {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*devops_resource.Dev, 0, len(s.byEngineer[engineerID]))
+	for _, id := range s.byEngineer[engineerID] {
+		dev, _ := s.developers.get(id)
+		out = append(out, normalizeDev(dev))
+	}
+	return out
+}
+
 func (s *DevStore) DeleteByID(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	for i := range s.developers {
-		if s.developers[i].Id == id {
-			last := len(s.developers) - 1
-			s.developers[i] = s.developers[last]
-			s.developers[last] = nil // avoid retaining pointer
-			s.developers = s.developers[:last]
-			return true
-		}
+	dev, found := s.developers.remove(id)
+	if !found {
+		return false
 	}
-	return false
+	s.byName.remove(dev.Name, id)
+	s.byEngineer.removeAll(engineerIDs(dev.Engineers), id)
+	delete(s.versions, id)
+	return true
 }
 
 // OpsStore methods
-func (s *OpsStore) Add(ops *devops_resource.Ops) {
+func (s *OpsStore) Add(ops *devops_resource.Ops) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.operations = append(s.operations, ops)
+	if _, found := s.operations.get(ops.Id); found {
+		return errors.New("ops id already exists in store")
+	}
+	s.operations.put(ops.Id, normalizeOps(ops))
+	s.byName.add(ops.Name, ops.Id)
+	s.byEngineer.addAll(engineerIDs(ops.Engineers), ops.Id)
+	s.versions[ops.Id] = 1
+	return nil
+}
+
+func (s *OpsStore) Update(ops *devops_resource.Ops, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.operations.get(ops.Id)
+	if !found {
+		return errors.New("ops not found in store")
+	}
+	if err := s.versions.check(ops.Id, version); err != nil {
+		return err
+	}
+	s.byName.remove(old.Name, old.Id)
+	s.byEngineer.removeAll(engineerIDs(old.Engineers), old.Id)
+	s.operations.put(ops.Id, normalizeOps(ops))
+	s.byName.add(ops.Name, ops.Id)
+	s.byEngineer.addAll(engineerIDs(ops.Engineers), ops.Id)
+	s.versions[ops.Id]++
+	return nil
+}
+
+func (s *OpsStore) Version(id string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versions[id]
 }
 
 func (s *OpsStore) List() []*devops_resource.Ops {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]*devops_resource.Ops, len(s.operations))
-	copy(out, s.operations)
+	out := make([]*devops_resource.Ops, 0, s.operations.len())
+	s.operations.each(func(ops *devops_resource.Ops) {
+		out = append(out, normalizeOps(ops))
+	})
 	return out
 }
 
 func (s *OpsStore) FindByID(id string) (*devops_resource.Ops, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, ops := range s.operations {
-		if ops.Id == id {
-			return ops, true
-		}
+	if ops, found := s.operations.get(id); found {
+		return normalizeOps(ops), true
 	}
 	return nil, false
 }
@@ -209,70 +375,129 @@ func (s *OpsStore) FindByID(id string) (*devops_resource.Ops, bool) {
 func (s *OpsStore) FindByName(name string) (*devops_resource.Ops, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, ops := range s.operations {
-		if ops.Name == name {
-			return ops, true
-		}
+	if id, found := s.byName.first(name); found {
+		ops, _ := s.operations.get(id)
+		return normalizeOps(ops), true
 	}
 	return nil, false
 }
 
+// FindByEngineer returns the ops groups the engineer is in, in the order they gained the engineer
+func (s *OpsStore) FindByEngineer(engineerID string) []*devops_resource.Ops {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]*devops_resource.Ops, 0, len(s.byEngineer[engineerID]))
+	for _, id := range s.byEngineer[engineerID] {
+		ops, _ := s.operations.get(id)
+		out = append(out, normalizeOps(ops))
+	}
+	return out
+}
+
 func (s *OpsStore) DeleteByID(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	for i := range s.operations {
-		if s.operations[i].Id == id {
-			last := len(s.operations) - 1
-			s.operations[i] = s.operations[last]
-			s.operations[last] = nil // avoid retaining pointer
-			s.operations = s.operations[:last]
-			return true
-		}
+	ops, found := s.operations.remove(id)
+	if !found {
+		return false
 	}
-	return false
+	s.byName.remove(ops.Name, id)
+	s.byEngineer.removeAll(engineerIDs(ops.Engineers), id)
+	delete(s.versions, id)
+	return true
 }
 
 // DevOpsStore methods
-func (s *DevOpsStore) Add(devops *devops_resource.DevOps) {
+func (s *DevOpsStore) Add(devops *devops_resource.DevOps) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.developer_operations = append(s.developer_operations, devops)
+	if _, found := s.developer_operations.get(devops.Id); found {
+		return errors.New("devops id already exists in store")
+	}
+	s.developer_operations.put(devops.Id, normalizeDevOps(devops))
+	s.byDev.addAll(devIDs(devops.Devs), devops.Id)
+	s.byOps.addAll(opsIDs(devops.Ops), devops.Id)
+	s.versions[devops.Id] = 1
+	return nil
+}
+
+func (s *DevOpsStore) Update(devops *devops_resource.DevOps, version int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old, found := s.developer_operations.get(devops.Id)
+	if !found {
+		return errors.New("devops not found in store")
+	}
+	if err := s.versions.check(devops.Id, version); err != nil {
+		return err
+	}
+	s.byDev.removeAll(devIDs(old.Devs), old.Id)
+	s.byOps.removeAll(opsIDs(old.Ops), old.Id)
+	s.developer_operations.put(devops.Id, normalizeDevOps(devops))
+	s.byDev.addAll(devIDs(devops.Devs), devops.Id)
+	s.byOps.addAll(opsIDs(devops.Ops), devops.Id)
+	s.versions[devops.Id]++
+	return nil
+}
+
+func (s *DevOpsStore) Version(id string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.versions[id]
 }
 
 func (s *DevOpsStore) List() []*devops_resource.DevOps {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	out := make([]*devops_resource.DevOps, len(s.developer_operations))
-	copy(out, s.developer_operations)
+	out := make([]*devops_resource.DevOps, 0, s.developer_operations.len())
+	s.developer_operations.each(func(devops *devops_resource.DevOps) {
+		out = append(out, normalizeDevOps(devops))
+	})
 	return out
 }
 
 func (s *DevOpsStore) FindByID(id string) (*devops_resource.DevOps, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	for _, devops := range s.developer_operations {
-		if devops.Id == id {
-			return devops, true
-		}
+	if devops, found := s.developer_operations.get(id); found {
+		return normalizeDevOps(devops), true
 	}
 	return nil, false
 }
 
+// FindByDev and FindByOps return the devops groups a dev or ops group is in
+func (s *DevOpsStore) FindByDev(devID string) []*devops_resource.DevOps {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.findIn(s.byDev[devID])
+}
+
+func (s *DevOpsStore) FindByOps(opsID string) []*devops_resource.DevOps {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.findIn(s.byOps[opsID])
+}
+
+func (s *DevOpsStore) findIn(ids []string) []*devops_resource.DevOps {
+	out := make([]*devops_resource.DevOps, 0, len(ids))
+	for _, id := range ids {
+		devops, _ := s.developer_operations.get(id)
+		out = append(out, normalizeDevOps(devops))
+	}
+	return out
+}
+
 func (s *DevOpsStore) DeleteByID(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-
-	for i := range s.developer_operations {
-		if s.developer_operations[i].Id == id {
-			last := len(s.developer_operations) - 1
-			s.developer_operations[i] = s.developer_operations[last]
-			s.developer_operations[last] = nil // avoid retaining pointer
-			s.developer_operations = s.developer_operations[:last]
-			return true
-		}
+	devops, found := s.developer_operations.remove(id)
+	if !found {
+		return false
 	}
-	return false
+	s.byDev.removeAll(devIDs(devops.Devs), id)
+	s.byOps.removeAll(opsIDs(devops.Ops), id)
+	delete(s.versions, id)
+	return true
 }
 
 // Helper method to add engineer to operation
@@ -280,11 +505,11 @@ func (s *OpsStore) AddEngineerToOp(opID string, engineer *devops_resource.Engine
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, op := range s.operations {
-		if op.Id == opID {
-			op.Engineers = append(op.Engineers, engineer)
-			return true
-		}
+	if op, found := s.operations.get(opID); found {
+		op.Engineers = append(op.Engineers, &devops_resource.Engineer{Id: engineer.Id})
+		s.byEngineer.add(engineer.Id, opID)
+		s.versions[opID]++
+		return true
 	}
 	return false
 }
@@ -294,11 +519,11 @@ func (s *DevStore) AddEngineerToDev(devID string, engineer *devops_resource.Engi
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, dev := range s.developers {
-		if dev.Id == devID {
-			dev.Engineers = append(dev.Engineers, engineer)
-			return true
-		}
+	if dev, found := s.developers.get(devID); found {
+		dev.Engineers = append(dev.Engineers, &devops_resource.Engineer{Id: engineer.Id})
+		s.byEngineer.add(engineer.Id, devID)
+		s.versions[devID]++
+		return true
 	}
 	return false
 }
@@ -308,11 +533,11 @@ func (s *DevOpsStore) AddDevToDevOps(devOpsID string, dev *devops_resource.Dev)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, devops := range s.developer_operations {
-		if devops.Id == devOpsID {
-			devops.Devs = append(devops.Devs, dev)
-			return true
-		}
+	if devops, found := s.developer_operations.get(devOpsID); found {
+		devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: dev.Id})
+		s.byDev.add(dev.Id, devOpsID)
+		s.versions[devOpsID]++
+		return true
 	}
 	return false
 }
@@ -322,31 +547,38 @@ func (s *DevOpsStore) AddOpsToDevOps(devOpsID string, ops *devops_resource.Ops)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, devops := range s.developer_operations {
-		if devops.Id == devOpsID {
-			devops.Ops = append(devops.Ops, ops)
-			return true
-		}
+	if devops, found := s.developer_operations.get(devOpsID); found {
+		devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: ops.Id})
+		s.byOps.add(ops.Id, devOpsID)
+		s.versions[devOpsID]++
+		return true
 	}
 	return false
 }
 
+// removeRef deletes the reference with the given ID while keeping the remaining order
+func removeRef[T any](refs []*T, id string, idOf func(*T) string) ([]*T, bool) {
+	for i := range refs {
+		if idOf(refs[i]) == id {
+			copy(refs[i:], refs[i+1:])
+			refs[len(refs)-1] = nil // avoid retaining pointer
+			return refs[:len(refs)-1], true
+		}
+	}
+	return refs, false
+}
+
 // Helper method to remove engineer from operation
 func (s *OpsStore) RemoveEngineerFromOp(opID string, engineerID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, op := range s.operations {
-		if op.Id == opID {
-			for i := range op.Engineers {
-				if op.Engineers[i].Id == engineerID {
-					last := len(op.Engineers) - 1
-					op.Engineers[i] = op.Engineers[last]
-					op.Engineers[last] = nil // avoid retaining pointer
-					op.Engineers = op.Engineers[:last]
-					return nil
-				}
-			}
+	if op, found := s.operations.get(opID); found {
+		var removed bool
+		if op.Engineers, removed = removeRef(op.Engineers, engineerID, engineerRefID); removed {
+			s.byEngineer.remove(engineerID, opID)
+			s.versions[opID]++
+			return nil
 		}
 	}
 	return errors.New("engineer not found in operation")
@@ -357,69 +589,28 @@ func (s *DevStore) RemoveEngineerFromDev(devID string, engineerID string) error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, dev := range s.developers {
-		if dev.Id == devID {
-			for i := range dev.Engineers {
-				if dev.Engineers[i].Id == engineerID {
-					last := len(dev.Engineers) - 1
-					dev.Engineers[i] = dev.Engineers[last]
-					dev.Engineers[last] = nil // avoid retaining pointer
-					dev.Engineers = dev.Engineers[:last]
-					return nil
-				}
-			}
+	if dev, found := s.developers.get(devID); found {
+		var removed bool
+		if dev.Engineers, removed = removeRef(dev.Engineers, engineerID, engineerRefID); removed {
+			s.byEngineer.remove(engineerID, devID)
+			s.versions[devID]++
+			return nil
 		}
 	}
 	return errors.New("engineer not found in dev")
 }
 
-// Helper method to remove engineer from all operations and devs in devops
-func (s *DevOpsStore) RemoveEngineerFromAll(engineerID string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	for _, devops := range s.developer_operations {
-		for _, op := range devops.Ops {
-			for i := range op.Engineers {
-				if op.Engineers[i].Id == engineerID {
-					last := len(op.Engineers) - 1
-					op.Engineers[i] = op.Engineers[last]
-					op.Engineers[last] = nil // avoid retaining pointer
-					op.Engineers = op.Engineers[:last]
-					break
-				}
-			}
-		}
-		for _, dev := range devops.Devs {
-			for i := range dev.Engineers {
-				if dev.Engineers[i].Id == engineerID {
-					last := len(dev.Engineers) - 1
-					dev.Engineers[i] = dev.Engineers[last]
-					dev.Engineers[last] = nil // avoid retaining pointer
-					dev.Engineers = dev.Engineers[:last]
-					break
-				}
-			}
-		}
-	}
-}
-
 // Helper method to remove dev from devops
 func (s *DevOpsStore) RemoveDevFromDevOps(devOpsID string, devID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, devops := range s.developer_operations {
-		if devops.Id == devOpsID {
-			for i := range devops.Devs {
-				if devops.Devs[i].Id == devID {
-					last := len(devops.Devs) - 1
-					devops.Devs[i] = devops.Devs[last]
-					devops.Devs[last] = nil // avoid retaining pointer
-					devops.Devs = devops.Devs[:last]
-					return nil
-				}
-			}
+	if devops, found := s.developer_operations.get(devOpsID); found {
+		var removed bool
+		if devops.Devs, removed = removeRef(devops.Devs, devID, devRefID); removed {
+			s.byDev.remove(devID, devOpsID)
+			s.versions[devOpsID]++
+			return nil
 		}
 	}
 	return errors.New("dev not found in devops")
@@ -430,75 +621,186 @@ func (s *DevOpsStore) RemoveOpsFromDevOps(devOpsID string, opsID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for _, devops := range s.developer_operations {
-		if devops.Id == devOpsID {
-			for i := range devops.Ops {
-				if devops.Ops[i].Id == opsID {
-					last := len(devops.Ops) - 1
-					devops.Ops[i] = devops.Ops[last]
-					devops.Ops[last] = nil // avoid retaining pointer
-					devops.Ops = devops.Ops[:last]
-					return nil
-				}
-			}
+	if devops, found := s.developer_operations.get(devOpsID); found {
+		var removed bool
+		if devops.Ops, removed = removeRef(devops.Ops, opsID, opsRefID); removed {
+			s.byOps.remove(opsID, devOpsID)
+			s.versions[devOpsID]++
+			return nil
 		}
 	}
 	return errors.New("ops not found in devops")
 }
 
+func engineerRefID(engineer *devops_resource.Engineer) string { return engineer.Id }
+func devRefID(dev *devops_resource.Dev) string                { return dev.Id }
+func opsRefID(ops *devops_resource.Ops) string                { return ops.Id }
+
+// verifyEmail reports whether email is a bare RFC 5322 address on a domain with a dot
 func verifyEmail(email string) bool {
-	result, _ := regexp.MatchString("^[\\w._%+\\-\\/\\]+@[\\w.\\-]+\\.[a-z]{2,4}$", email)
-	return result
+	return validation.Email(email) == nil
 }
 
-func getRandId(length int) string {
-	randomBytes := make([]byte, 32)
-	_, err := rand.Read(randomBytes)
+func main() {
+	cfg, err := loadConfig(os.Args[1:], os.Getenv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
 	if err != nil {
-		panic(err)
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	stores, err := openStores(cfg.Storage, cfg.DB)
+	if err != nil {
+		log.Fatalf("failed to configure %s storage: %v", cfg.Storage, err)
+	}
+	ids, err := newIDGenerator(cfg.IDs, time.Now)
+	if err != nil {
+		log.Fatalf("failed to configure ids: %v", err)
+	}
+	server := NewServer(stores, ids, time.Now)
+	server.configureLogging(cfg.LogLevel)
+	if err := server.configureProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("failed to configure trusted proxies: %v", err)
+	}
+
+	if err := server.configureAudit(cfg.Audit, cfg.AuditPath); err != nil {
+		log.Fatalf("failed to configure %s audit sink: %v", cfg.Audit, err)
+	}
+	if err := server.configureAuth(cfg.APIKeys, cfg.JWKS, cfg.JWTIssuer, cfg.JWTAudience, cfg.InsecureNoAuth); err != nil {
+		log.Fatalf("failed to configure authentication: %v", err)
+	}
+	if err := server.configureWebhooks(cfg.Webhooks); err != nil {
+		log.Fatalf("failed to configure webhooks: %v", err)
+	}
+	if err := server.configureRetention(cfg.Retention); err != nil {
+		log.Fatalf("failed to configure retention: %v", err)
+	}
+	if err := server.configureRateLimits(cfg.readLimit(), cfg.writeLimit()); err != nil {
+		log.Fatalf("failed to configure rate limits: %v", err)
+	}
+	if err := server.configureIdempotency(cfg.IdempotencyTTL); err != nil {
+		log.Fatalf("failed to configure idempotency keys: %v", err)
+	}
+
+	router, err := NewRouter(server)
+	if err != nil {
+		log.Fatalf("failed to configure routes: %v", err)
+	}
+
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		log.Fatalf("failed to listen on %s: %v", cfg.Addr, err)
+	}
+	log.Printf("listening on %s", listener.Addr())
+
+	//runs server until SIGINT or SIGTERM, then drains the requests in flight
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	served := server.serve(ctx, cfg, listener, router)
+	if err := server.Close(); err != nil {
+		log.Printf("failed to close the stores: %v", err)
+	}
+	if served != nil {
+		log.Fatalf("server stopped: %v", served)
 	}
-	return base32.StdEncoding.EncodeToString(randomBytes)[:length]
 }
 
-func main() {
-	router := gin.Default()
+// NewRouter registers every route of server on a new gin engine.
+// The OpenAPI document, metrics and health checks need no token.
+// Reads need the viewer role, changes the editor role, and replacing everything and reading
+// the audit log the admin role.
+// Reads and changes are rate limited separately per client, a POST retried with the same
+// Idempotency-Key gets the response of the first one.
+// Reads never see a unit of work half applied, except the event stream which stays open.
+// A database error fails the request with a 500 rather than a 404 or an empty list.
+// It fails when the trusted proxies of s are not IPs or CIDRs.
+func NewRouter(s *Server) (*gin.Engine, error) {
+	router := gin.New()
+	if s.accessLog {
+		router.Use(gin.Logger())
+	}
+	router.Use(gin.Recovery(), recordMetrics())
+	if err := router.SetTrustedProxies(s.trustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+
+	router.GET("/openapi.json", getOpenAPI)
+	router.GET("/metrics", s.getMetrics)
+	router.GET("/healthz", getHealthz)
+	router.GET("/readyz", s.getReadyz)
+
+	viewer := router.Group("/", s.authorize(roleViewer), s.rateLimited(limitReads), s.consistentReads(), s.failOnStorageFaults())
+	subscriber := router.Group("/", s.authorize(roleViewer), s.rateLimited(limitReads))
+	auditor := router.Group("/", s.authorize(roleAdmin), s.rateLimited(limitReads), s.consistentReads())
+	editor := router.Group("/", s.authorize(roleEditor), s.rateLimited(limitWrites), s.idempotent(), s.validateRequestBody(), s.recordAudit(), s.failOnStorageFaults())
+	admin := router.Group("/", s.authorize(roleAdmin), s.rateLimited(limitWrites), s.idempotent(), s.validateRequestBody(), s.recordAudit(), s.failOnStorageFaults())
 
 	//GET routes
-	router.GET("/engineers", getEngineer)
-	router.GET("/engineers/id/:id", getSpecificEngineerById)
-	router.GET("/engineers/name/:name", getSpecificEngineerByName)
-	router.GET("/engineers/email/:email", getSpecificEngineerByEmail)
-	router.GET("/dev", getDev)
-	router.GET("/dev/id/:id", getSpecificDevById)
-	router.GET("/dev/name/:name", getSpecificDevByName)
-	router.GET("/op", getOp)
-	router.GET("/op/id/:id", getSpecificOpsById)
-	router.GET("/op/name/:name", getSpecificOpsByName)
-	router.GET("/devops", getDevOps)
-	router.GET("/devops/:id", getSpecificDevOpsById)
+	viewer.GET("/engineers", s.getEngineer)
+	viewer.GET("/engineers/id/:id", s.getSpecificEngineerById)
+	viewer.GET("/engineers/name/:name", s.getSpecificEngineerByName)
+	viewer.GET("/engineers/email/:email", s.getSpecificEngineerByEmail)
+	viewer.GET("/dev", s.getDev)
+	viewer.GET("/dev/id/:id", s.getSpecificDevById)
+	viewer.GET("/dev/name/:name", s.getSpecificDevByName)
+	viewer.GET("/op", s.getOp)
+	viewer.GET("/op/id/:id", s.getSpecificOpsById)
+	viewer.GET("/op/name/:name", s.getSpecificOpsByName)
+	viewer.GET("/devops", s.getDevOps)
+	viewer.GET("/devops/:id", s.getSpecificDevOpsById)
 
 	//POST routes
-	router.POST("/engineers", postEngineer)
-	router.POST("/dev", postDev)
-	router.POST("/op", postOp)
-	router.POST("/devops", postDevOps)
-	router.POST("/dev/:id", postDevEngineer)
-	router.POST("/op/:id", postOpEngineer)
-	router.POST("/devops/dev/:id", postDevOpsDev)
-	router.POST("/devops/op/:id", postDevOpsOp)
+	editor.POST("/engineers", s.postEngineer)
+	editor.POST("/dev", s.postDev)
+	editor.POST("/op", s.postOp)
+	editor.POST("/devops", s.postDevOps)
+	editor.POST("/dev/:id", s.postDevEngineer)
+	editor.POST("/op/:id", s.postOpEngineer)
+	editor.POST("/devops/dev/:id", s.postDevOpsDev)
+	editor.POST("/devops/op/:id", s.postDevOpsOp)
 
 	//PUT routes
-	router.PUT("/engineers/:id", putEngineer)
-	router.PUT("/dev/:id", putDev)
-	router.PUT("/op/:id", putOp)
-	router.PUT("/devops/:id", putDevOps)
+	editor.PUT("/engineers/:id", s.putEngineer)
+	editor.PUT("/dev/:id", s.putDev)
+	editor.PUT("/op/:id", s.putOp)
+	editor.PUT("/devops/:id", s.putDevOps)
+
+	//PATCH routes
+	editor.PATCH("/engineers/:id", s.patchEngineer)
+	editor.PATCH("/dev/:id", s.patchDev)
+	editor.PATCH("/op/:id", s.patchOp)
+	editor.PATCH("/devops/:id", s.patchDevOps)
 
 	//DELETE routes
-	router.DELETE("/engineers/:id", deleteRequestEngineer)
-	router.DELETE("/dev/:id", deleteRequestDev)
-	router.DELETE("/op/:id", deleteRequestOp)
-	router.DELETE("/devops/:id", deleteRequestDevOps)
+	editor.DELETE("/engineers/:id", s.deleteRequestEngineer)
+	editor.DELETE("/dev/:id", s.deleteRequestDev)
+	editor.DELETE("/op/:id", s.deleteRequestOp)
+	editor.DELETE("/devops/:id", s.deleteRequestDevOps)
+
+	//Archive routes
+	viewer.GET("/archive", s.getArchive)
+	editor.POST("/engineers/:id/restore", s.postEngineerRestore)
+	editor.POST("/dev/:id/restore", s.postDevRestore)
+	editor.POST("/op/:id/restore", s.postOpRestore)
+	editor.POST("/devops/:id/restore", s.postDevOpsRestore)
+
+	//Graph routes
+	viewer.GET("/engineers/:id/memberships", s.getEngineerMemberships)
+	viewer.GET("/devops/:id/engineers", s.getDevOpsEngineers)
+	viewer.GET("/stats", s.getStats)
+
+	//Search routes
+	viewer.GET("/search", s.getSearch)
+
+	//Bulk routes
+	viewer.GET("/export", s.getExport)
+	admin.POST("/import", s.postImport)
+
+	//Audit routes
+	auditor.GET("/audit", s.getAudit)
+
+	//Event routes
+	subscriber.GET("/events", s.getEvents)
 
-	//runs server
-	router.Run(":8080")
+	return router, nil
 }
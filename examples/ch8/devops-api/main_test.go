@@ -48,32 +48,35 @@ type requestDevOpsTest struct {
 var verifyEmailTests = []emailTest{
 	emailTest{"simple valid email format", "bob@bob.com", true},
 	emailTest{"valid email with extended domain name", "b0b123@clever.ask.who", true},
-	emailTest{"special characters in username", "bob#@bob.com", false},
+	emailTest{"special characters allowed by RFC 5322 in username", "bob#@bob.com", true},
+	emailTest{"top level domain longer than four characters", "bob@liatrio.engineering", true},
 	emailTest{"no specificied domain name", "bob@bob", false},
 	emailTest{"empty string for email", "", false},
+	emailTest{"display name", "Bob <bob@bob.com>", false},
+	emailTest{"two @ signs", "bob@@bob.com", false},
 }
 
 /******* Slices of Test Cases for Engineer Request *******/
 var verifyPostEngineer = []requestEngineerTest{
-	requestEngineerTest{"contains client side Id field", devops_resource.Engineer{Name: "Bobs Burgers", Id: getRandId(5), Email: "bob@gmail.com"}, http.StatusCreated},
-	requestEngineerTest{"no client side Name field", devops_resource.Engineer{Email: "bob@gmail.com"}, http.StatusBadRequest},
-	requestEngineerTest{"no client side Email field", devops_resource.Engineer{Name: "Bobs Burgers"}, http.StatusBadRequest},
+	requestEngineerTest{"contains client side Id field", devops_resource.Engineer{Name: "Bobs Burgers", Id: "CLIENT", Email: "bob@gmail.com"}, http.StatusCreated},
+	requestEngineerTest{"no client side Name field", devops_resource.Engineer{Email: "bob@gmail.com"}, http.StatusUnprocessableEntity},
+	requestEngineerTest{"no client side Email field", devops_resource.Engineer{Name: "Bobs Burgers"}, http.StatusUnprocessableEntity},
 	requestEngineerTest{"different engineer", devops_resource.Engineer{Name: "Steven Mendez", Email: "Min3craftSt3v3@gmail.com"}, http.StatusCreated},
-	requestEngineerTest{"duplicate engineer", devops_resource.Engineer{Name: "Bobs Burgers", Email: "bob@gmail.com"}, http.StatusBadRequest},
-	requestEngineerTest{"client side JSON object with empty fields", devops_resource.Engineer{Name: "", Id: "", Email: ""}, http.StatusBadRequest},
+	requestEngineerTest{"duplicate engineer", devops_resource.Engineer{Name: "Bobs Burgers", Email: "bob@gmail.com"}, http.StatusConflict},
+	requestEngineerTest{"client side JSON object with empty fields", devops_resource.Engineer{Name: "", Id: "", Email: ""}, http.StatusUnprocessableEntity},
 }
 
 var verifyPutEngineer = []requestEngineerTest{
-	//Created with client side id TODO: fix where id cannot be created via client side
+	// the engineer is created with sequential ids, so it is the first one
 	requestEngineerTest{"Should update name and email of id 1 engineer", devops_resource.Engineer{Name: "Not Bob", Id: "1", Email: "notbob@gmail.com"}, http.StatusOK},
-	requestEngineerTest{"No id", devops_resource.Engineer{Name: "Not Bob", Email: "notbob@gmail.com"}, http.StatusBadRequest},
+	requestEngineerTest{"No id", devops_resource.Engineer{Name: "Not Bob", Email: "notbob@gmail.com"}, http.StatusNotFound},
 }
 
 var verifyDeleteEngineer = []requestEngineerTest{
-	requestEngineerTest{"should delete nothing and fail", devops_resource.Engineer{Name: "failed", Id: "40"}, http.StatusBadRequest},
-	requestEngineerTest{"should delete nothing and fail since no id", devops_resource.Engineer{Name: "NoId"}, http.StatusBadRequest},
+	requestEngineerTest{"should delete nothing and fail", devops_resource.Engineer{Name: "failed", Id: "40"}, http.StatusNotFound},
+	requestEngineerTest{"should delete nothing and fail since no id", devops_resource.Engineer{Name: "NoId"}, http.StatusNotFound},
 	requestEngineerTest{"should delete test engineer and pass", devops_resource.Engineer{Id: "5"}, http.StatusOK},
-	requestEngineerTest{"duplicate this should fail", devops_resource.Engineer{Id: "5"}, http.StatusBadRequest},
+	requestEngineerTest{"duplicate this should fail", devops_resource.Engineer{Id: "5"}, http.StatusNotFound},
 }
 
 /********************************************/
@@ -81,23 +84,23 @@ var verifyDeleteEngineer = []requestEngineerTest{
 /******* Slices of Test Cases for Developer Resource Request *******/
 var verifyPostDev = []requestDevTest{
 	requestDevTest{"simple developer resource creation", devops_resource.Dev{Name: "dev_ferrets"}, http.StatusCreated},
-	requestDevTest{"no client side Name field", devops_resource.Dev{}, http.StatusBadRequest},
+	requestDevTest{"no client side Name field", devops_resource.Dev{}, http.StatusUnprocessableEntity},
 	requestDevTest{"different developer resource", devops_resource.Dev{Name: "dev_bengal"}, http.StatusCreated},
-	requestDevTest{"duplicate developer resource", devops_resource.Dev{Name: "dev_ferrets"}, http.StatusBadRequest},
-	requestDevTest{"client side JSON object with empty fields", devops_resource.Dev{Name: ""}, http.StatusBadRequest},
+	requestDevTest{"duplicate developer resource", devops_resource.Dev{Name: "dev_ferrets"}, http.StatusConflict},
+	requestDevTest{"client side JSON object with empty fields", devops_resource.Dev{Name: ""}, http.StatusUnprocessableEntity},
 }
 
 var verifyPutDev = []requestDevTest{
-	//Created with client side id TODO: fix where id cannot be created via client side
-	requestDevTest{"should update name id 2 developer resource", devops_resource.Dev{Name: "notferrets", Id: "2"}, http.StatusOK},
-	requestDevTest{"No id", devops_resource.Dev{Name: "dev_notferrets"}, http.StatusBadRequest},
+	// the group is created with sequential ids, so it is the first one
+	requestDevTest{"should update name id 1 developer resource", devops_resource.Dev{Name: "notferrets", Id: "1"}, http.StatusOK},
+	requestDevTest{"No id", devops_resource.Dev{Name: "dev_notferrets"}, http.StatusNotFound},
 }
 
 var verifyDeleteDev = []requestDevTest{
-	requestDevTest{"should delete nothing and fail", devops_resource.Dev{Name: "failed", Id: "40"}, http.StatusBadRequest},
-	requestDevTest{"should delete nothing and fail since no id", devops_resource.Dev{Name: "NoId"}, http.StatusBadRequest},
+	requestDevTest{"should delete nothing and fail", devops_resource.Dev{Name: "failed", Id: "40"}, http.StatusNotFound},
+	requestDevTest{"should delete nothing and fail since no id", devops_resource.Dev{Name: "NoId"}, http.StatusNotFound},
 	requestDevTest{"should delete test developer resource and pass", devops_resource.Dev{Id: "4"}, http.StatusOK},
-	requestDevTest{"duplicate this should fail", devops_resource.Dev{Id: "4"}, http.StatusBadRequest},
+	requestDevTest{"duplicate this should fail", devops_resource.Dev{Id: "4"}, http.StatusNotFound},
 }
 
 /********************************************/
@@ -105,23 +108,23 @@ var verifyDeleteDev = []requestDevTest{
 /******* Slices of Test Cases for Operations Resource Request *******/
 var verifyPostOp = []requestOpTest{
 	requestOpTest{"simple operation resource creation", devops_resource.Ops{Name: "op_ferrets"}, http.StatusCreated},
-	requestOpTest{"no client side Name field", devops_resource.Ops{}, http.StatusBadRequest},
+	requestOpTest{"no client side Name field", devops_resource.Ops{}, http.StatusUnprocessableEntity},
 	requestOpTest{"different operation resource", devops_resource.Ops{Name: "op_bengal"}, http.StatusCreated},
-	requestOpTest{"duplicate operation resource", devops_resource.Ops{Name: "op_ferrets"}, http.StatusBadRequest},
-	requestOpTest{"client side JSON object with empty fields", devops_resource.Ops{Name: ""}, http.StatusBadRequest},
+	requestOpTest{"duplicate operation resource", devops_resource.Ops{Name: "op_ferrets"}, http.StatusConflict},
+	requestOpTest{"client side JSON object with empty fields", devops_resource.Ops{Name: ""}, http.StatusUnprocessableEntity},
 }
 
 var verifyPutOp = []requestOpTest{
-	//Created with client side id TODO: fix where id cannot be created via client side
-	requestOpTest{"should update name id 2 operation resource", devops_resource.Ops{Name: "op_notferrets", Id: "2"}, http.StatusOK},
-	requestOpTest{"No id", devops_resource.Ops{Name: "op_notferrets"}, http.StatusBadRequest},
+	// the group is created with sequential ids, so it is the first one
+	requestOpTest{"should update name id 1 operation resource", devops_resource.Ops{Name: "op_notferrets", Id: "1"}, http.StatusOK},
+	requestOpTest{"No id", devops_resource.Ops{Name: "op_notferrets"}, http.StatusNotFound},
 }
 
 var verifyDeleteOp = []requestOpTest{
-	requestOpTest{"should delete nothing and fail", devops_resource.Ops{Name: "failed", Id: "40"}, http.StatusBadRequest},
-	requestOpTest{"should delete nothing and fail since no id", devops_resource.Ops{Name: "NoId"}, http.StatusBadRequest},
+	requestOpTest{"should delete nothing and fail", devops_resource.Ops{Name: "failed", Id: "40"}, http.StatusNotFound},
+	requestOpTest{"should delete nothing and fail since no id", devops_resource.Ops{Name: "NoId"}, http.StatusNotFound},
 	requestOpTest{"should delete test operation resource and pass", devops_resource.Ops{Id: "2"}, http.StatusOK},
-	requestOpTest{"duplicate this should fail", devops_resource.Ops{Id: "2"}, http.StatusBadRequest},
+	requestOpTest{"duplicate this should fail", devops_resource.Ops{Id: "2"}, http.StatusNotFound},
 }
 
 /********************************************/
@@ -257,6 +260,8 @@ func mockJsonDeleteOp(c *gin.Context, content devops_resource.Ops) {
 
 /******* Test Runs for Engineer Requests *******/
 func TestPostEngineer(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
 
@@ -267,18 +272,20 @@ func TestPostEngineer(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPostEngineer(c, test.testEngineer)
-		postEngineer(c)
+		s.postEngineer(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestPutEngineer(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	engineerStore.Add(&devops_resource.Engineer{Name: "Bob", Id: "1", Email: "bob@gmail.com"})
+	s.Engineers.Create(devops_resource.Engineer{Name: "Bob", Email: "bob@gmail.com"})
 
 	for _, test := range verifyPutEngineer {
 		w = httptest.NewRecorder()
@@ -287,18 +294,20 @@ func TestPutEngineer(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPutEngineer(c, test.testEngineer)
-		putEngineer(c)
+		s.putEngineer(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestDeleteRequestEngineer(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	engineerStore.Add(&devops_resource.Engineer{Name: "Bob", Id: "5", Email: "bob@gmail.com"})
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "Bob", Id: "5", Email: "bob@gmail.com"})
 
 	for _, test := range verifyDeleteEngineer {
 		w = httptest.NewRecorder()
@@ -307,18 +316,20 @@ func TestDeleteRequestEngineer(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonDeleteEngineer(c, test.testEngineer)
-		deleteRequestEngineer(c)
+		s.deleteRequestEngineer(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 /********************************************/
 
 /******* Test Runs for Developer Resource Requests *******/
 func TestPostDev(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
 
@@ -329,18 +340,20 @@ func TestPostDev(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPostDev(c, test.testDev)
-		postDev(c)
+		s.postDev(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	devStore.Clear()
+	s.devStore.Clear()
 }
 
 func TestPutDev(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	devStore.Add(&devops_resource.Dev{Name: "ferrets", Id: "2"})
+	s.Devs.Create(devops_resource.Dev{Name: "ferrets"})
 
 	for _, test := range verifyPutDev {
 		w = httptest.NewRecorder()
@@ -349,18 +362,20 @@ func TestPutDev(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPutDev(c, test.testDev)
-		putDev(c)
+		s.putDev(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	devStore.Clear()
+	s.devStore.Clear()
 }
 
 func TestDeleteRequestDev(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	devStore.Add(&devops_resource.Dev{Name: "ferrets", Id: "4"})
+	s.devStore.Add(&devops_resource.Dev{Name: "ferrets", Id: "4"})
 
 	for _, test := range verifyDeleteDev {
 		w = httptest.NewRecorder()
@@ -369,18 +384,20 @@ func TestDeleteRequestDev(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonDeleteDev(c, test.testDev)
-		deleteRequestDev(c)
+		s.deleteRequestDev(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	devStore.Clear()
+	s.devStore.Clear()
 }
 
 /********************************************/
 
 /******* Test Runs for Operation Resource Requests *******/
 func TestPostOp(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
 
@@ -391,18 +408,20 @@ func TestPostOp(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPostOp(c, test.testOp)
-		postOp(c)
+		s.postOp(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	opsStore.Clear()
+	s.opsStore.Clear()
 }
 
 func TestPutOp(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	opsStore.Add(&devops_resource.Ops{Name: "ferrets", Id: "2"})
+	s.Ops.Create(devops_resource.Ops{Name: "ferrets"})
 
 	for _, test := range verifyPutOp {
 		w = httptest.NewRecorder()
@@ -411,18 +430,20 @@ func TestPutOp(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonPutOp(c, test.testOp)
-		putOp(c)
+		s.putOp(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	opsStore.Clear()
+	s.opsStore.Clear()
 }
 
 func TestDeleteRequestOp(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 	var w *httptest.ResponseRecorder
 	var c *gin.Context
-	opsStore.Add(&devops_resource.Ops{Name: "ferrets", Id: "2"})
+	s.opsStore.Add(&devops_resource.Ops{Name: "ferrets", Id: "2"})
 
 	for _, test := range verifyDeleteOp {
 		w = httptest.NewRecorder()
@@ -431,18 +452,20 @@ func TestDeleteRequestOp(t *testing.T) {
 			Header: make(http.Header),
 		}
 		mockJsonDeleteOp(c, test.testOp)
-		deleteRequestOp(c)
+		s.deleteRequestOp(c)
 		if test.expected != w.Code {
 			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", test.description, test.expected, w.Code)
 		}
 	}
-	opsStore.Clear()
+	s.opsStore.Clear()
 }
 
 /********************************************/
 
 func TestNewEngineer(t *testing.T) {
-	result, err := newEngineer("test_engineer", "test@gmail.com")
+	t.Parallel()
+	s := newTestServer(t)
+	result, err := s.Engineers.Create(devops_resource.Engineer{Name: "test_engineer", Email: "test@gmail.com"})
 	if result.Email != "test@gmail.com" || result.Name != "test_engineer" {
 		t.Errorf("Expected name %s and email %s were not returned", result.Name, result.Email)
 	}
@@ -452,7 +475,9 @@ func TestNewEngineer(t *testing.T) {
 }
 
 func TestNewEngineerBadName(t *testing.T) {
-	result, err := newEngineer("", "test@gmail.com")
+	t.Parallel()
+	s := newTestServer(t)
+	result, err := s.Engineers.Create(devops_resource.Engineer{Name: "", Email: "test@gmail.com"})
 	if err == nil {
 		t.Errorf("Expected name %s was not returned", result.Name)
 	}
@@ -462,8 +487,10 @@ func TestNewEngineerBadName(t *testing.T) {
 }
 
 func TestNewDev(t *testing.T) {
+	t.Parallel()
+	s := newTestServer(t)
 
-	result, err := newDev(devops_resource.Dev{Name: "test_devs"})
+	result, err := s.Devs.Create(devops_resource.Dev{Name: "test_devs"})
 	if result.Name != "test_devs" {
 		t.Errorf("Expected name %s was not returned", result.Name)
 	}
@@ -473,14 +500,18 @@ func TestNewDev(t *testing.T) {
 }
 
 func TestNewDevBadName(t *testing.T) {
-	_, err := newDev(devops_resource.Dev{Name: ""})
+	t.Parallel()
+	s := newTestServer(t)
+	_, err := s.Devs.Create(devops_resource.Dev{Name: ""})
 	if err == nil {
 		t.Errorf("Expected name error but one was not returned")
 	}
 }
 
 func TestNewOp(t *testing.T) {
-	result, err := newOp(devops_resource.Ops{Name: "test_ops"})
+	t.Parallel()
+	s := newTestServer(t)
+	result, err := s.Ops.Create(devops_resource.Ops{Name: "test_ops"})
 	if result.Name != "test_ops" {
 		t.Errorf("Expected name %s was not returned", result.Name)
 	}
@@ -490,7 +521,9 @@ func TestNewOp(t *testing.T) {
 }
 
 func TestNewDevOps(t *testing.T) {
-	_, err := newDevOps(devops_resource.DevOps{})
+	t.Parallel()
+	s := newTestServer(t)
+	_, err := s.DevOps.Create(devops_resource.DevOps{})
 	if err != nil {
 		t.Errorf("Expected no errors but one was returned")
 	}
@@ -498,12 +531,14 @@ func TestNewDevOps(t *testing.T) {
 }
 
 func TestDeleteEngineer(t *testing.T) {
-	result, _ := newEngineer("test_engineer2", "test@gmail.com")
-	delete_result, _ := deleteEngineer(result.Id)
-	if delete_result == false {
-		t.Errorf("Expected true to be returned but %t was instead'", delete_result)
-	}
-	_, delete2_error := deleteEngineer(result.Id)
+	t.Parallel()
+	s := newTestServer(t)
+	result, _ := s.Engineers.Create(devops_resource.Engineer{Name: "test_engineer2", Email: "test@gmail.com"})
+	delete_error := s.Engineers.Delete(result.Id, anyVersion, "")
+	if delete_error != nil {
+		t.Errorf("Expected no error to be returned but %v was instead'", delete_error)
+	}
+	delete2_error := s.Engineers.Delete(result.Id, anyVersion, "")
 	if delete2_error == nil {
 		t.Errorf("Expected error to occur on second delete of same object but nil was returned")
 	}
@@ -562,73 +597,89 @@ func TestDeleteEngineerFrom_Ops(t *testing.T) {
 */
 
 func TestFindEngineerByName(t *testing.T) {
-	newEngineer("bob", "bob@bob.com")
-	_, err := findEngineer_by_Name("bob")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	_, err := s.Engineers.GetByName("bob")
 	if err != nil {
 		t.Errorf("Error: %v", err)
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestFindBadEngineerByName(t *testing.T) {
-	newEngineer("bob", "bob@gmail")
-	_, err := findEngineer_by_Name("bobby")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@gmail"})
+	_, err := s.Engineers.GetByName("bobby")
 	if err == nil {
 		t.Errorf("Error: Expected Errors, recieved none.")
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestFindEngineerByEmail(t *testing.T) {
-	newEngineer("bob", "bob@gmail.com")
-	_, err := findEngineer_by_Email("bob@gmail.com")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@gmail.com"})
+	_, err := s.Engineers.GetByEmail("bob@gmail.com")
 	if err != nil {
 		t.Errorf("Error: %v", err)
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestFindBadEngineerByEmail(t *testing.T) {
-	newEngineer("bob", "bob@gmail")
-	_, err := findEngineer_by_Email("bob@bob.com")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@gmail"})
+	_, err := s.Engineers.GetByEmail("bob@bob.com")
 	if err == nil {
 		t.Errorf("Error: Expected Errors, recieved none.")
 	}
-	engineerStore.Clear()
+	s.engineerStore.Clear()
 }
 
 func TestFindDevByName(t *testing.T) {
-	newDev(devops_resource.Dev{Name: "dev_ferrets"})
-	_, err := findDev_by_Name("dev_ferrets")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets"})
+	_, err := s.Devs.GetByName("dev_ferrets")
 	if err != nil {
 		t.Errorf("Error: %v", err)
 	}
-	devStore.Clear()
+	s.devStore.Clear()
 }
 
 func TestFindBadDevByName(t *testing.T) {
-	newDev(devops_resource.Dev{Name: "dev_ferrets"})
-	_, err := findDev_by_Name("dev_bengals")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets"})
+	_, err := s.Devs.GetByName("dev_bengals")
 	if err == nil {
 		t.Errorf("Error: Expected Errors, recieved none.")
 	}
-	devStore.Clear()
+	s.devStore.Clear()
 }
 
 func TestFindOpsByName(t *testing.T) {
-	newOp(devops_resource.Ops{Name: "ops_ferrets"})
-	_, err := findOps_by_Name("ops_ferrets")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Ops.Create(devops_resource.Ops{Name: "ops_ferrets"})
+	_, err := s.Ops.GetByName("ops_ferrets")
 	if err != nil {
 		t.Errorf("Error: %v", err)
 	}
-	opsStore.Clear()
+	s.opsStore.Clear()
 }
 
 func TestFindBadOpsByName(t *testing.T) {
-	newOp(devops_resource.Ops{Name: "ops_ferrets"})
-	_, err := findOps_by_Name("ops_bengals")
+	t.Parallel()
+	s := newTestServer(t)
+	s.Ops.Create(devops_resource.Ops{Name: "ops_ferrets"})
+	_, err := s.Ops.GetByName("ops_bengals")
 	if err == nil {
 		t.Errorf("Error: Expected Errors, recieved none.")
 	}
-	opsStore.Clear()
+	s.opsStore.Clear()
 }
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prometheus metrics served by GET /metrics in the text exposition format.
+// Requests are labelled with their route pattern rather than their path, so ids
+// don't add a series each.
+var (
+	httpRequests = newCounterVec("devops_http_requests_total",
+		"Requests served, by method, route and status.", "method", "route", "status")
+	httpDuration = newHistogramVec("devops_http_request_duration_seconds",
+		"Time taken to serve a request, by method, route and status.", requestBuckets, "method", "route", "status")
+	lockWait = newHistogramVec("devops_store_lock_wait_seconds",
+		"Time spent waiting for a store lock, by store and read or write mode.", lockBuckets, "store", "mode")
+	rateLimitedRequests = newCounterVec("devops_rate_limited_requests_total",
+		"Requests rejected by the rate limit, by route group.", "group")
+)
+
+// metricsRegistry holds the metrics of the process, each server adds the sizes of its stores
+var metricsRegistry = []collector{httpRequests, httpDuration, lockWait, rateLimitedRequests}
+
+var requestBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+var lockBuckets = []float64{.000001, .00001, .0001, .001, .01, .1, 1}
+
+// collector is one metric family of the registry
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// counterVec is a counter family, one series per combination of label values
+type counterVec struct {
+	name   string
+	help   string
+	labels []string
+	series sync.Map // label values joined by labelSeparator -> *counter
+}
+
+type counter struct {
+	values []string
+	value  atomic.Uint64
+}
+
+// labelSeparator can't appear in a route, method or status
+const labelSeparator = "\xff"
+
+func newCounterVec(name string, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels}
+}
+
+func (v *counterVec) with(values ...string) *counter {
+	key := strings.Join(values, labelSeparator)
+	if series, found := v.series.Load(key); found {
+		return series.(*counter)
+	}
+	series, _ := v.series.LoadOrStore(key, &counter{values: values})
+	return series.(*counter)
+}
+
+func (c *counter) inc() {
+	c.value.Add(1)
+}
+
+func (v *counterVec) write(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, "counter")
+	for _, key := range sortedKeys(&v.series) {
+		series, _ := v.series.Load(key)
+		c := series.(*counter)
+		writeSample(w, v.name, v.labels, c.values, "", "", float64(c.value.Load()))
+	}
+}
+
+// histogramVec is a histogram family, one series per combination of label values
+type histogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+	series  sync.Map // label values joined by labelSeparator -> *histogram
+}
+
+// histogram counts observations cumulatively: counts[i] holds every observation up to
+// buckets[i]. sum holds the bits of a float64 so it can be updated without a lock.
+type histogram struct {
+	values  []string
+	buckets []float64
+	counts  []atomic.Uint64
+	count   atomic.Uint64
+	sum     atomic.Uint64
+}
+
+func newHistogramVec(name string, help string, buckets []float64, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, buckets: buckets}
+}
+
+func (v *histogramVec) with(values ...string) *histogram {
+	key := strings.Join(values, labelSeparator)
+	if series, found := v.series.Load(key); found {
+		return series.(*histogram)
+	}
+	fresh := &histogram{values: values, buckets: v.buckets, counts: make([]atomic.Uint64, len(v.buckets))}
+	series, _ := v.series.LoadOrStore(key, fresh)
+	return series.(*histogram)
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range h.buckets {
+		if value <= bound {
+			h.counts[i].Add(1)
+		}
+	}
+	h.count.Add(1)
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+value)) {
+			return
+		}
+	}
+}
+
+func (v *histogramVec) write(w *bufio.Writer) {
+	writeHeader(w, v.name, v.help, "histogram")
+	for _, key := range sortedKeys(&v.series) {
+		series, _ := v.series.Load(key)
+		h := series.(*histogram)
+		for i, bound := range h.buckets {
+			writeSample(w, v.name+"_bucket", v.labels, h.values, "le", formatFloat(bound), float64(h.counts[i].Load()))
+		}
+		count := float64(h.count.Load())
+		writeSample(w, v.name+"_bucket", v.labels, h.values, "le", "+Inf", count)
+		writeSample(w, v.name+"_sum", v.labels, h.values, "", "", math.Float64frombits(h.sum.Load()))
+		writeSample(w, v.name+"_count", v.labels, h.values, "", "", count)
+	}
+}
+
+// gaugeFunc is a gauge family read when scraped, collect returns the value for each
+// value of label
+type gaugeFunc struct {
+	name    string
+	help    string
+	label   string
+	collect func() map[string]float64
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	values := g.collect()
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		writeSample(w, g.name, []string{g.label}, []string{key}, "", "", values[key])
+	}
+}
+
+// storeSizes counts the resources of the stores of the server
+func (s *Server) storeSizes() map[string]float64 {
+	return map[string]float64{
+		"engineers": float64(len(s.engineerStore.List())),
+		"dev":       float64(len(s.devStore.List())),
+		"ops":       float64(len(s.opsStore.List())),
+		"devops":    float64(len(s.devOpsStore.List())),
+		"archive":   float64(len(s.archiveStore.List(""))),
+	}
+}
+
+func sortedKeys(series *sync.Map) []string {
+	var keys []string
+	series.Range(func(key any, _ any) bool {
+		keys = append(keys, key.(string))
+		return true
+	})
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, kind string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + kind + "\n")
+}
+
+// writeSample writes one line of a series, extraLabel is the le label of histogram buckets
+func writeSample(w *bufio.Writer, name string, labels []string, values []string, extraLabel string, extraValue string, value float64) {
+	w.WriteString(name)
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+`="`+escapeLabel(values[i])+`"`)
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, extraLabel+`="`+extraValue+`"`)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	if math.IsInf(value, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// writeMetrics writes every metric of the registry followed by extra
+func writeMetrics(out io.Writer, extra ...collector) error {
+	w := bufio.NewWriter(out)
+	for _, metric := range append(metricsRegistry[:len(metricsRegistry):len(metricsRegistry)], extra...) {
+		metric.write(w)
+	}
+	return w.Flush()
+}
+
+// recordMetrics counts every request and how long it took. Requests that match no
+// route are labelled with the route "unmatched".
+func recordMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.with(c.Request.Method, route, status).inc()
+		httpDuration.with(c.Request.Method, route, status).observe(time.Since(start).Seconds())
+	}
+}
+
+// getMetrics serves the metrics to Prometheus
+func (s *Server) getMetrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	c.Status(http.StatusOK)
+	writeMetrics(c.Writer, s.storeSize)
+}
+
+// timedRWMutex is a sync.RWMutex recording how long Lock and RLock wait for the lock
+// in devops_store_lock_wait_seconds, labelled with its name
+type timedRWMutex struct {
+	sync.RWMutex
+	name string
+}
+
+func (m *timedRWMutex) Lock() {
+	start := time.Now()
+	m.RWMutex.Lock()
+	m.observe("write", start)
+}
+
+func (m *timedRWMutex) RLock() {
+	start := time.Now()
+	m.RWMutex.RLock()
+	m.observe("read", start)
+}
+
+func (m *timedRWMutex) observe(mode string, start time.Time) {
+	if m.name != "" {
+		lockWait.with(m.name, mode).observe(time.Since(start).Seconds())
+	}
+}
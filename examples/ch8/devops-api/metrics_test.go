@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrape reads the value of series from GET /metrics, 0 when it isn't there yet
+func scrape(t *testing.T, router *gin.Engine, series string) float64 {
+	t.Helper()
+	w := mockConditionalRequest(router, "GET", "/metrics", "", "")
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("Expected: Status Code 200 with the Prometheus text format, Received: %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if value, found := strings.CutPrefix(line, series+" "); found {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("Series %s has an invalid value: %s", series, line)
+			}
+			return number
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedGraph(t, s)
+	router := newTestRouter(t, s)
+
+	tests := []struct {
+		description string
+		request     func()
+		series      string
+		increase    float64
+	}{
+		{
+			"requests are counted by route pattern",
+			func() {
+				mockConditionalRequest(router, "GET", "/engineers/id/E1", "", "")
+				mockConditionalRequest(router, "GET", "/engineers/id/E2", "", "")
+			},
+			`devops_http_requests_total{method="GET",route="/engineers/id/:id",status="200"}`,
+			2,
+		},
+		{
+			"failed requests are counted by status",
+			func() { mockConditionalRequest(router, "GET", "/engineers/id/nobody", "", "") },
+			`devops_http_requests_total{method="GET",route="/engineers/id/:id",status="404"}`,
+			1,
+		},
+		{
+			"unknown paths share one route",
+			func() { mockConditionalRequest(router, "GET", "/ferrets/1", "", "") },
+			`devops_http_requests_total{method="GET",route="unmatched",status="404"}`,
+			1,
+		},
+		{
+			"latency is observed",
+			func() {
+				mockConditionalRequest(router, "POST", "/engineers", "", `{"name": "dave", "email": "dave@bob.com"}`)
+			},
+			`devops_http_request_duration_seconds_count{method="POST",route="/engineers",status="201"}`,
+			1,
+		},
+		{
+			"every latency is below the last bucket",
+			func() {
+				mockConditionalRequest(router, "POST", "/engineers", "", `{"name": "erin", "email": "erin@bob.com"}`)
+			},
+			`devops_http_request_duration_seconds_bucket{method="POST",route="/engineers",status="201",le="+Inf"}`,
+			1,
+		},
+		{
+			"store sizes follow the stores",
+			func() { mockConditionalRequest(router, "POST", "/op", "", `{"name": "op_stoats"}`) },
+			`devops_store_resources{resource="ops"}`,
+			1,
+		},
+		{
+			"lock waits are observed per store and mode",
+			func() { mockConditionalRequest(router, "GET", "/dev/id/D1", "", "") },
+			`devops_store_lock_wait_seconds_count{store="dev",mode="read"}`,
+			1,
+		},
+		{
+			"units of work wait for the stores lock",
+			func() { mockConditionalRequest(router, "DELETE", "/devops/DO2", "", "") },
+			`devops_store_lock_wait_seconds_count{store="units_of_work",mode="write"}`,
+			1,
+		},
+	}
+
+	for _, test := range tests {
+		before := scrape(t, router, test.series)
+		test.request()
+		after := scrape(t, router, test.series)
+		// reads take the locks more than once, only the write counts are exact
+		if after-before < test.increase || (!strings.Contains(test.series, "lock_wait") && after-before != test.increase) {
+			t.Errorf("\nTest: %s\nExpected: %s to increase by %v, Received: %v -> %v", test.description, test.series, test.increase, before, after)
+		}
+	}
+}
+
+func TestMetricsConcurrentRequests(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	series := `devops_http_requests_total{method="GET",route="/engineers",status="200"}`
+	before := scrape(t, router, series)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/engineers", nil))
+		}()
+	}
+	wg.Wait()
+
+	if after := scrape(t, router, series); after-before != 50 {
+		t.Errorf("Expected: 50 more requests, Received: %v", after-before)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+
+	w := mockConditionalRequest(router, "GET", "/healthz", "", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("\nTest: liveness\nExpected: Status Code 200, Received: %d", w.Code)
+	}
+	w = mockConditionalRequest(router, "GET", "/readyz", "", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("\nTest: memory stores are ready\nExpected: Status Code 200, Received: %d", w.Code)
+	}
+
+	s = newSQLiteServer(t, "")
+	router = newTestRouter(t, s)
+	w = mockConditionalRequest(router, "GET", "/readyz", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"storage":"ok"`) {
+		t.Errorf("\nTest: sqlite store is ready\nExpected: Status Code 200, Received: %d %s", w.Code, w.Body.String())
+	}
+
+	s.stores.Close()
+	w = mockConditionalRequest(router, "GET", "/readyz", "", "")
+	var body problem
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if w.Code != http.StatusServiceUnavailable || body.Code != "not_ready" || len(body.Errors) != 1 || !strings.HasPrefix(body.Errors[0], "storage: ") {
+		t.Errorf("\nTest: closed database is not ready\nExpected: Status Code 503 not_ready naming storage, Received: %d %s", w.Code, w.Body.String())
+	}
+	w = mockConditionalRequest(router, "GET", "/healthz", "", "")
+	if w.Code != http.StatusOK {
+		t.Errorf("\nTest: liveness doesn't depend on the store\nExpected: Status Code 200, Received: %d", w.Code)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// The OpenAPI document is maintained by hand in openapi.json and embedded in the binary
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// schema is the subset of OpenAPI schema objects used by openapi.json
+type schema struct {
+	Ref        string             `json:"$ref"`
+	Type       string             `json:"type"`
+	Format     string             `json:"format"`
+	Required   []string           `json:"required"`
+	Properties map[string]*schema `json:"properties"`
+	Items      *schema            `json:"items"`
+	MinLength  *int               `json:"minLength"`
+	Enum       []any              `json:"enum"`
+}
+
+type openAPIOperation struct {
+	RequestBody *struct {
+		Required bool `json:"required"`
+		Content  map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+}
+
+type openAPIDocument struct {
+	Paths      map[string]map[string]openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// embeddedOpenAPI is openapi.json, the document of every server
+var embeddedOpenAPI = mustLoadOpenAPI(openAPISpec)
+
+func mustLoadOpenAPI(spec []byte) *openAPIDocument {
+	var doc openAPIDocument
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		panic("openapi.json is invalid: " + err.Error())
+	}
+	return &doc
+}
+
+var ginParam = regexp.MustCompile(`:([^/]+)`)
+
+// requestSchema finds the request body schema of mediaType for a gin route such as
+// /engineers/:id. accepted is false when the route takes a body but not of mediaType.
+func (doc *openAPIDocument) requestSchema(method string, route string, mediaType string) (bodySchema *schema, required bool, accepted bool) {
+	path := ginParam.ReplaceAllString(route, "{$1}")
+	operation, found := doc.Paths[path][strings.ToLower(method)]
+	if !found || operation.RequestBody == nil {
+		return nil, false, true
+	}
+	content, found := operation.RequestBody.Content[mediaType]
+	if !found {
+		return nil, operation.RequestBody.Required, false
+	}
+	return content.Schema, operation.RequestBody.Required, true
+}
+
+func (doc *openAPIDocument) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		s = doc.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validate checks value against s, collecting a message for every violation
+func (doc *openAPIDocument) validate(value any, s *schema, path string, problems *[]string) {
+	s = doc.resolve(s)
+	if s == nil {
+		return
+	}
+	fail := func(format string, args ...any) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, field := range s.Required {
+			if _, present := object[field]; !present {
+				*problems = append(*problems, path+"."+field+": is required")
+			}
+		}
+		fields := make([]string, 0, len(s.Properties))
+		for field := range s.Properties {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			if fieldValue, present := object[field]; present {
+				doc.validate(fieldValue, s.Properties[field], path+"."+field, problems)
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		for i, item := range items {
+			doc.validate(item, s.Items, fmt.Sprintf("%s[%d]", path, i), problems)
+		}
+	case "string":
+		text, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if s.MinLength != nil && len(text) < *s.MinLength {
+			fail("must be at least %d characters", *s.MinLength)
+		}
+		if s.Format == "email" && !verifyEmail(text) {
+			fail("must be a valid email address")
+		}
+	case "integer", "number":
+		number, ok := value.(float64)
+		if !ok || (s.Type == "integer" && number != float64(int64(number))) {
+			fail("must be an %s", s.Type)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	}
+	if len(s.Enum) > 0 {
+		for _, allowed := range s.Enum {
+			if allowed == value {
+				return
+			}
+		}
+		fail("must be one of %v", s.Enum)
+	}
+}
+
+// validateRequestBody rejects request bodies that do not match the OpenAPI document with a 422.
+// YAML bodies are checked like JSON on the routes documented to take them, such as
+// POST /import, and rejected with a 415 everywhere else. The body is buffered and restored
+// so handlers can still bind it.
+func (s *Server) validateRequestBody() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		yamlBody := isYAML(c.ContentType())
+		mediaType := "application/json"
+		if yamlBody {
+			mediaType = "application/yaml"
+		}
+		bodySchema, required, accepted := s.openAPI.requestSchema(c.Request.Method, c.FullPath(), mediaType)
+		if !accepted && yamlBody {
+			writeError(c, unsupportedMediaType("unsupported_media_type", c.Request.Method+" "+c.FullPath()+" takes JSON bodies only"))
+			return
+		}
+		if bodySchema == nil {
+			c.Next()
+			return
+		}
+		raw, err := readBody(c)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+
+		if len(bytes.TrimSpace(raw)) == 0 {
+			if required {
+				writeError(c, schemaViolation([]string{"body: is required"}))
+				return
+			}
+			c.Next()
+			return
+		}
+		var body any
+		if yamlBody {
+			body, err = decodeYAMLBody(raw)
+		} else {
+			err = json.Unmarshal(raw, &body)
+		}
+		if err != nil {
+			writeError(c, malformedBody(err))
+			return
+		}
+		var problems []string
+		s.openAPI.validate(body, bodySchema, "body", &problems)
+		if len(problems) > 0 {
+			writeError(c, schemaViolation(problems))
+			return
+		}
+		c.Next()
+	}
+}
+
+// maxRequestBody is the largest request body read, larger ones are rejected with a 413
+const maxRequestBody = 32 << 20
+
+// readBody reads the body of the request, at most maxRequestBody bytes of it, and puts it
+// back for the handlers that read it again
+func readBody(c *gin.Context) ([]byte, error) {
+	raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBody))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, contentTooLarge("body_too_large", "request body must be at most "+strconv.Itoa(maxRequestBody>>20)+" MiB")
+	}
+	if err != nil {
+		return nil, badRequest("unreadable_body", "failed to read request body")
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	return raw, nil
+}
+
+// decodeYAMLBody decodes a YAML document into the values json.Unmarshal would give for
+// the same document in JSON, which is what validate expects
+func decodeYAMLBody(raw []byte) (any, error) {
+	var document any
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+	asJSON, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	var body any
+	err = json.Unmarshal(asJSON, &body)
+	return body, err
+}
+
+// schemaViolation lists every way the request body differs from the OpenAPI document
+func schemaViolation(problems []string) error {
+	return &apiError{kind: ErrValidation, code: "schema_violation", message: "request body does not match the schema", details: problems}
+}
+
+func getOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", openAPISpec)
+}
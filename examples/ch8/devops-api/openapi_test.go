@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenAPICoversEveryRoute(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	for _, route := range newTestRouter(t, s).Routes() {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		if _, found := embeddedOpenAPI.Paths[path][strings.ToLower(route.Method)]; !found {
+			t.Errorf("Route %s %s is missing from openapi.json", route.Method, route.Path)
+		}
+	}
+}
+
+var validateBodyTests = []struct {
+	description string
+	method      string
+	url         string
+	body        string
+	expected    int
+}{
+	{"valid engineer", "POST", "/engineers", `{"name": "bob", "email": "bob@bob.com"}`, http.StatusCreated},
+	{"missing email", "POST", "/engineers", `{"name": "alice"}`, http.StatusUnprocessableEntity},
+	{"name is not a string", "POST", "/engineers", `{"name": 7, "email": "seven@bob.com"}`, http.StatusUnprocessableEntity},
+	{"invalid email", "POST", "/engineers", `{"name": "carol", "email": "carol@bob"}`, http.StatusUnprocessableEntity},
+	{"empty body", "POST", "/engineers", ``, http.StatusUnprocessableEntity},
+	{"malformed json", "POST", "/engineers", `{"name": `, http.StatusBadRequest},
+	{"engineers is not an array", "POST", "/dev", `{"name": "dev_ferrets", "engineers": "bob"}`, http.StatusUnprocessableEntity},
+	{"member reference without id", "POST", "/dev", `{"name": "dev_ferrets", "engineers": [{"name": "bob"}]}`, http.StatusUnprocessableEntity},
+	{"valid dev group", "POST", "/dev", `{"name": "dev_ferrets"}`, http.StatusCreated},
+	{"membership body without id", "POST", "/devops/dev/XYZ", `{}`, http.StatusUnprocessableEntity},
+}
+
+func TestValidateRequestBody(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+
+	for _, test := range validateBodyTests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+	}
+}
+
+var validateYAMLBodyTests = []struct {
+	description string
+	url         string
+	body        string
+	expected    int
+	code        string
+}{
+	{"json body sent as yaml", "/engineers", `{"name": "alice"}`, http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{"yaml engineer", "/engineers", "name: bob\nemail: bob@bob.com\n", http.StatusUnsupportedMediaType, "unsupported_media_type"},
+	{"import missing email", "/import", "engineers:\n  - {id: E1, name: bob}\n", http.StatusUnprocessableEntity, "schema_violation"},
+	{"import with engineers not a list", "/import", "engineers: bob\n", http.StatusUnprocessableEntity, "schema_violation"},
+	{"valid import", "/import", "engineers:\n  - {id: E1, name: bob, email: bob@bob.com}\n", http.StatusOK, ""},
+}
+
+func TestValidateYAMLBody(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+
+	for _, test := range validateYAMLBodyTests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, test.url, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", "application/yaml")
+		router.ServeHTTP(w, req)
+		if test.expected != w.Code || !strings.Contains(w.Body.String(), test.code) {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d %s, Received: Status Code %d\nBody: %s", test.description, test.expected, test.code, w.Code, w.Body.String())
+		}
+	}
+	if engineers := s.Engineers.List(); len(engineers) != 1 || engineers[0].Name != "bob" {
+		t.Errorf("\nTest: only the valid import is applied\nExpected: bob, Received: %v", engineers)
+	}
+}
+
+func TestGetOpenAPI(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	newTestRouter(t, s).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"openapi": "3.0.3"`) {
+		t.Errorf("Expected the OpenAPI document, Received: Status Code %d", w.Code)
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// Patch document media types, plain application/json is treated as a merge patch
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// maxPatchAttempts bounds how often a patch is re-applied when the resource changes
+// underneath it, like SafeUpdate in the optimistic locking example
+const maxPatchAttempts = 5
+
+// patcher applies a patch document to the JSON form of a resource
+type patcher func(doc any) (any, error)
+
+// readPatch decodes the request body according to its Content-Type
+func readPatch(c *gin.Context) (patcher, error) {
+	raw, err := readBody(c)
+	if err != nil {
+		return nil, err
+	}
+	switch c.ContentType() {
+	case mergePatchType, "application/json":
+		var patch any
+		if err := json.Unmarshal(raw, &patch); err != nil {
+			return nil, badRequest("malformed_patch", "merge patch is not valid JSON: "+err.Error())
+		}
+		return func(doc any) (any, error) { return mergePatch(deepCopyJSON(doc), patch), nil }, nil
+	case jsonPatchType:
+		operations, err := parseJSONPatch(raw)
+		if err != nil {
+			return nil, badRequest("malformed_patch", err.Error())
+		}
+		return func(doc any) (any, error) {
+			patched, err := applyJSONPatch(doc, operations)
+			if errors.Is(err, errPatchTest) {
+				return nil, conflict("patch_test_failed", err.Error())
+			}
+			if err != nil {
+				return nil, invalid("patch_failed", err.Error())
+			}
+			return patched, nil
+		}, nil
+	}
+	return nil, unsupportedMediaType("unsupported_patch_type", "PATCH bodies must be "+mergePatchType+" or "+jsonPatchType)
+}
+
+// patchResource applies patch to the unexpanded view of resource id and saves the result.
+// With If-Match the patch applies to that version only, otherwise it is re-applied to fresh
+// data when a concurrent write bumps the version between reading and saving.
+func patchResource[T any](c *gin.Context, id string, version func(string) int, view func() (any, error), save func(patched T, version int) error) error {
+	patch, err := readPatch(c)
+	if err != nil {
+		return err
+	}
+	expected, err := ifMatch(c, id, version(id))
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		current := expected
+		if current == anyVersion {
+			current = version(id)
+		}
+		original, err := view()
+		if err != nil {
+			return err
+		}
+		doc, err := toJSONValue(original)
+		if err != nil {
+			return err
+		}
+		patchedDoc, err := patch(doc)
+		if err != nil {
+			return err
+		}
+		patched, err := decodePatched[T](patchedDoc)
+		if err != nil {
+			return err
+		}
+		err = save(patched, current)
+		if errors.Is(err, ErrPrecondition) && expected == anyVersion && attempt < maxPatchAttempts {
+			continue
+		}
+		return err
+	}
+}
+
+func toJSONValue(value any) (any, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	return doc, json.Unmarshal(raw, &doc)
+}
+
+// decodePatched turns the patched document back into a view, rejecting unknown fields
+func decodePatched[T any](doc any) (T, error) {
+	var out T
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return out, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&out); err != nil {
+		return out, invalid("patch_invalid", "patched resource is invalid: "+err.Error())
+	}
+	return out, nil
+}
+
+func immutableID(id string, patchedID string) error {
+	if patchedID != id {
+		return invalid("id_immutable", "id cannot be changed by a patch")
+	}
+	return nil
+}
+
+func engineerRefs(ids []string) []*devops_resource.Engineer {
+	refs := make([]*devops_resource.Engineer, 0, len(ids))
+	for _, id := range ids {
+		refs = append(refs, &devops_resource.Engineer{Id: id})
+	}
+	return refs
+}
+
+// server PATCH handlers, patches apply to the ?expand= view with members as IDs
+func (s *Server) patchEngineer(c *gin.Context) {
+	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithEngineer(c, http.StatusOK, id))
+	err := patchResource(c, id, v.Engineers.Version,
+		func() (any, error) { return v.Engineers.Get(id) },
+		func(patched devops_resource.Engineer, version int) error {
+			if err := immutableID(id, patched.Id); err != nil {
+				return err
+			}
+			return v.Engineers.Update(id, patched, version)
+		})
+	if err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) patchDev(c *gin.Context) {
+	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithDev(c, http.StatusOK, id, parseExpand(c)))
+	err := patchResource(c, id, v.Devs.Version,
+		func() (any, error) {
+			dev, err := v.Devs.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			return renderDev(dev, expansion{}), nil
+		},
+		func(patched groupReference, version int) error {
+			if err := immutableID(id, patched.Id); err != nil {
+				return err
+			}
+			return v.Devs.Update(id, devops_resource.Dev{Name: patched.Name, Engineers: engineerRefs(patched.Engineers)}, version)
+		})
+	if err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) patchOp(c *gin.Context) {
+	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithOps(c, http.StatusOK, id, parseExpand(c)))
+	err := patchResource(c, id, v.Ops.Version,
+		func() (any, error) {
+			op, err := v.Ops.Get(id)
+			if err != nil {
+				return nil, err
+			}
+			return renderOps(op, expansion{}), nil
+		},
+		func(patched groupReference, version int) error {
+			if err := immutableID(id, patched.Id); err != nil {
+				return err
+			}
+			return v.Ops.Update(id, devops_resource.Ops{Name: patched.Name, Engineers: engineerRefs(patched.Engineers)}, version)
+		})
+	if err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) patchDevOps(c *gin.Context) {
+	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithDevOps(c, http.StatusOK, id, parseExpand(c)))
+	err := patchResource(c, id, v.DevOps.Version,
+		func() (any, error) {
+			devops, found := v.devOpsStore.FindByID(id)
+			if !found {
+				return nil, notFound("devops_not_found", "no devops group with id "+id)
+			}
+			return devOpsChart(devops), nil
+		},
+		func(patched chartDevOps, version int) error {
+			if err := immutableID(id, patched.Id); err != nil {
+				return err
+			}
+			next := devops_resource.DevOps{Devs: make([]*devops_resource.Dev, 0), Ops: make([]*devops_resource.Ops, 0)}
+			for _, devID := range patched.Devs {
+				next.Devs = append(next.Devs, &devops_resource.Dev{Id: devID})
+			}
+			for _, opsID := range patched.Ops {
+				next.Ops = append(next.Ops, &devops_resource.Ops{Id: opsID})
+			}
+			return v.DevOps.Update(id, next, version)
+		})
+	if err != nil {
+		writeError(c, err)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+func decodeJSON(t *testing.T, raw string) any {
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		t.Fatalf("bad test JSON %s: %v", raw, err)
+	}
+	return value
+}
+
+var mergePatchTests = []struct {
+	description string
+	target      string
+	patch       string
+	expected    string
+}{
+	{"replace member", `{"a": "b"}`, `{"a": "c"}`, `{"a": "c"}`},
+	{"add member", `{"a": "b"}`, `{"b": "c"}`, `{"a": "b", "b": "c"}`},
+	{"null removes member", `{"a": "b", "b": "c"}`, `{"a": null}`, `{"b": "c"}`},
+	{"arrays are replaced", `{"a": ["b"]}`, `{"a": ["c", "d"]}`, `{"a": ["c", "d"]}`},
+	{"nested objects merge", `{"a": {"b": "c", "d": "e"}}`, `{"a": {"d": null, "f": "g"}}`, `{"a": {"b": "c", "f": "g"}}`},
+	{"non object patch replaces", `{"a": "b"}`, `["c"]`, `["c"]`},
+}
+
+func TestMergePatch(t *testing.T) {
+	for _, test := range mergePatchTests {
+		result := mergePatch(decodeJSON(t, test.target), decodeJSON(t, test.patch))
+		if expected := decodeJSON(t, test.expected); !reflect.DeepEqual(result, expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, expected, result)
+		}
+	}
+}
+
+var jsonPatchTests = []struct {
+	description string
+	target      string
+	patch       string
+	expected    string // empty when the patch must fail
+}{
+	{"add to object", `{"a": 1}`, `[{"op": "add", "path": "/b", "value": 2}]`, `{"a": 1, "b": 2}`},
+	{"add into array", `{"a": [1, 3]}`, `[{"op": "add", "path": "/a/1", "value": 2}]`, `{"a": [1, 2, 3]}`},
+	{"append to array", `{"a": [1]}`, `[{"op": "add", "path": "/a/-", "value": 2}]`, `{"a": [1, 2]}`},
+	{"remove from array", `{"a": [1, 2, 3]}`, `[{"op": "remove", "path": "/a/1"}]`, `{"a": [1, 3]}`},
+	{"replace", `{"a": {"b": 1}}`, `[{"op": "replace", "path": "/a/b", "value": 2}]`, `{"a": {"b": 2}}`},
+	{"move", `{"a": [1], "b": []}`, `[{"op": "move", "from": "/a/0", "path": "/b/-"}]`, `{"a": [], "b": [1]}`},
+	{"copy", `{"a": {"b": 1}}`, `[{"op": "copy", "from": "/a", "path": "/c"}]`, `{"a": {"b": 1}, "c": {"b": 1}}`},
+	{"escaped pointer", `{"a/b": 1, "m~n": 2}`, `[{"op": "remove", "path": "/a~1b"}, {"op": "replace", "path": "/m~0n", "value": 3}]`, `{"m~n": 3}`},
+	{"passing test", `{"a": [1]}`, `[{"op": "test", "path": "/a", "value": [1]}, {"op": "add", "path": "/b", "value": 2}]`, `{"a": [1], "b": 2}`},
+	{"failing test", `{"a": 1}`, `[{"op": "test", "path": "/a", "value": 2}]`, ``},
+	{"remove missing member", `{"a": 1}`, `[{"op": "remove", "path": "/b"}]`, ``},
+	{"index out of range", `{"a": [1]}`, `[{"op": "add", "path": "/a/5", "value": 2}]`, ``},
+	{"replace missing member", `{"a": 1}`, `[{"op": "replace", "path": "/b", "value": 2}]`, ``},
+	{"move into own child", `{"a": {"b": {}}}`, `[{"op": "move", "from": "/a", "path": "/a/b/c"}]`, ``},
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	for _, test := range jsonPatchTests {
+		operations, err := parseJSONPatch([]byte(test.patch))
+		if err != nil {
+			t.Errorf("\nTest: %s\nfailed to parse patch: %v", test.description, err)
+			continue
+		}
+		target := decodeJSON(t, test.target)
+		result, err := applyJSONPatch(target, operations)
+		if test.expected == "" {
+			if err == nil {
+				t.Errorf("\nTest: %s\nError: Expected Errors, recieved none.", test.description)
+			}
+			continue
+		}
+		if expected := decodeJSON(t, test.expected); err != nil || !reflect.DeepEqual(result, expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v %v", test.description, expected, result, err)
+		}
+		if original := decodeJSON(t, test.target); !reflect.DeepEqual(target, original) {
+			t.Errorf("\nTest: %s\nError: the target document was modified in place", test.description)
+		}
+	}
+}
+
+var patchRouteTests = []struct {
+	description string
+	url         string
+	contentType string
+	body        string
+	expected    int
+}{
+	{"rename dev keeps members", "/dev/D1", mergePatchType, `{"name": "dev_bengal"}`, http.StatusOK},
+	{"add member with json patch", "/dev/D1", jsonPatchType, `[{"op": "add", "path": "/engineers/-", "value": "E2"}]`, http.StatusOK},
+	{"remove member with json patch", "/dev/D1", jsonPatchType, `[{"op": "test", "path": "/engineers/0", "value": "E1"}, {"op": "remove", "path": "/engineers/0"}]`, http.StatusOK},
+	{"failed test changes nothing", "/dev/D1", jsonPatchType, `[{"op": "replace", "path": "/name", "value": "x"}, {"op": "test", "path": "/engineers", "value": []}]`, http.StatusConflict},
+	{"unknown engineer", "/dev/D1", jsonPatchType, `[{"op": "add", "path": "/engineers/-", "value": "NOPE"}]`, http.StatusUnprocessableEntity},
+	{"duplicate member", "/dev/D1", jsonPatchType, `[{"op": "add", "path": "/engineers/-", "value": "E2"}]`, http.StatusUnprocessableEntity},
+	{"id is immutable", "/dev/D1", mergePatchType, `{"id": "D2"}`, http.StatusUnprocessableEntity},
+	{"unknown field", "/engineers/E1", mergePatchType, `{"nickname": "bobby"}`, http.StatusUnprocessableEntity},
+	{"invalid email", "/engineers/E1", mergePatchType, `{"email": "bob"}`, http.StatusUnprocessableEntity},
+	{"change email", "/engineers/E1", "application/json", `{"email": "robert@bob.com"}`, http.StatusOK},
+	{"add op group to devops", "/devops/DO1", jsonPatchType, `[{"op": "add", "path": "/ops/-", "value": "O1"}]`, http.StatusOK},
+	{"malformed patch", "/devops/DO1", jsonPatchType, `{"op": "add"}`, http.StatusBadRequest},
+	{"unsupported media type", "/op/O1", "text/plain", `name=x`, http.StatusUnsupportedMediaType},
+	{"missing group", "/op/NOPE", mergePatchType, `{"name": "x"}`, http.StatusNotFound},
+}
+
+func TestPatchRoutes(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "alice", Id: "E2", Email: "alice@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1", Engineers: []*devops_resource.Engineer{{Id: "E1"}}})
+	s.opsStore.Add(&devops_resource.Ops{Name: "op_ferrets", Id: "O1"})
+	s.devOpsStore.Add(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{{Id: "D1"}}})
+	router := newTestRouter(t, s)
+
+	for _, test := range patchRouteTests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("PATCH", test.url, strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		router.ServeHTTP(w, req)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d\nBody: %s", test.description, test.expected, w.Code, w.Body.String())
+		}
+	}
+
+	dev, _ := s.devStore.FindByID("D1")
+	if dev.Name != "dev_bengal" || !reflect.DeepEqual(engineerIDs(dev.Engineers), []string{"E2"}) || s.devStore.Version("D1") != 4 {
+		t.Errorf("Expected: dev_bengal with only E2 at version 4, Received: %s %v at version %d", dev.Name, engineerIDs(dev.Engineers), s.devStore.Version("D1"))
+	}
+	devops, _ := s.devOpsStore.FindByID("DO1")
+	if !reflect.DeepEqual(opsIDs(devops.Ops), []string{"O1"}) || !reflect.DeepEqual(devIDs(devops.Devs), []string{"D1"}) {
+		t.Errorf("Expected: DO1 with D1 and O1, Received: %v %v", devIDs(devops.Devs), opsIDs(devops.Ops))
+	}
+}
+
+func TestPatchHonorsIfMatch(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	router := newTestRouter(t, s)
+
+	for _, test := range []struct {
+		ifMatch  string
+		expected int
+	}{{`"1"`, http.StatusOK}, {`"1"`, http.StatusPreconditionFailed}, {`"2"`, http.StatusOK}} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("PATCH", "/engineers/E1", strings.NewReader(`{"name": "rob"}`))
+		req.Header.Set("Content-Type", mergePatchType)
+		req.Header.Set("If-Match", test.ifMatch)
+		router.ServeHTTP(w, req)
+		if test.expected != w.Code {
+			t.Errorf("\nTest: If-Match %s\nExpected: Status Code %d, Received: Status Code %d", test.ifMatch, test.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+func testEngineerProfile(t *testing.T, s *Server) {
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	manager, err := s.Engineers.Create(devops_resource.Engineer{Name: "alice", Email: "alice@liatrio.com"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	body := `{"name": "bob", "email": "bob@liatrio.com", "role": "sre", "skills": ["go", "terraform"],
+		"timezone": "America/Denver", "manager_id": "` + manager.Id + `", "on_call": true}`
+	w := mockConditionalRequest(router, "POST", "/engineers", "", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected: Status Code %d, Received: Status Code %d\nBody: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created devops_resource.Engineer
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	expected := devops_resource.Engineer{
+		Name: "bob", Id: created.Id, Email: "bob@liatrio.com", Role: "sre", Skills: []string{"go", "terraform"},
+		Timezone: "America/Denver", ManagerId: manager.Id, OnCall: true,
+	}
+	var read devops_resource.Engineer
+	w = mockConditionalRequest(router, "GET", "/engineers/id/"+created.Id, "", "")
+	json.Unmarshal(w.Body.Bytes(), &read)
+	if !reflect.DeepEqual(read, expected) {
+		t.Errorf("\nTest: profile round trip\nExpected: %+v, Received: %+v", expected, read)
+	}
+
+	moved, err := s.Engineers.Create(devops_resource.Engineer{Name: "carol", Email: "carol@liatrio.com", ManagerId: manager.Id})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := s.Engineers.Delete(manager.Id, anyVersion, ""); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if report, _ := s.engineerStore.FindByID(created.Id); report == nil || report.ManagerId != "" {
+		t.Errorf("\nTest: deleting a manager\nExpected: manager_id to be cleared, Received: %+v", report)
+	}
+
+	moved.ManagerId = created.Id
+	if err := s.Engineers.Update(moved.Id, *moved, anyVersion); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := s.Engineers.Restore(manager.Id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if report, _ := s.engineerStore.FindByID(created.Id); report == nil || report.ManagerId != manager.Id {
+		t.Errorf("\nTest: restoring a manager\nExpected: manager_id %s to be restored, Received: %+v", manager.Id, report)
+	}
+	if report, _ := s.engineerStore.FindByID(moved.Id); report == nil || report.ManagerId != created.Id {
+		t.Errorf("\nTest: restoring a manager\nExpected: a report with a new manager to keep it, Received: %+v", report)
+	}
+}
+
+func TestEngineerProfile(t *testing.T) {
+	s := newTestServer(t)
+	testEngineerProfile(t, s)
+}
+
+func TestSQLiteEngineerProfile(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testEngineerProfile(t, s)
+}
+
+var profileProblemTests = []struct {
+	description string
+	method      string
+	url         string
+	body        string
+	code        string
+	errors      []string
+}{
+	{"invalid fields", "POST", "/engineers", `{"name": "carol", "email": "carol@liatrio.com", "skills": ["go", "Go"], "timezone": "Mars/Olympus"}`,
+		"engineer_invalid", []string{"skills[1]: is listed twice", "timezone: must be an IANA time zone such as America/Denver"}},
+	{"own manager", "PUT", "/engineers/E1", `{"name": "bob", "email": "bob@bob.com", "manager_id": "E1"}`,
+		"engineer_invalid", []string{"manager_id: cannot be the engineer itself"}},
+	{"unknown manager", "POST", "/engineers", `{"name": "carol", "email": "carol@liatrio.com", "manager_id": "NOPE"}`,
+		"manager_not_found", nil},
+	{"reporting loop", "PUT", "/engineers/E1", `{"name": "bob", "email": "bob@bob.com", "manager_id": "E2"}`,
+		"manager_cycle", nil},
+	{"wrong type", "POST", "/engineers", `{"name": "carol", "email": "carol@liatrio.com", "on_call": "yes"}`,
+		"schema_violation", []string{"body.on_call: must be a boolean"}},
+}
+
+func TestEngineerProfileProblems(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "alice", Id: "E2", Email: "alice@bob.com", ManagerId: "E1"})
+	router := newTestRouter(t, s)
+
+	for _, test := range profileProblemTests {
+		w := mockConditionalRequest(router, test.method, test.url, "", test.body)
+		var body problem
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusUnprocessableEntity || body.Code != test.code {
+			t.Errorf("\nTest: %s\nExpected: %d %s, Received: %d %s", test.description, http.StatusUnprocessableEntity, test.code, w.Code, body.Code)
+		}
+		if test.errors != nil && !reflect.DeepEqual(body.Errors, test.errors) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.errors, body.Errors)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Route groups with their own rate limit: reads share one bucket per client, changes
+// another, so a client importing a lot can still read
+const (
+	limitReads  = "read"
+	limitWrites = "write"
+)
+
+// rateLimit is a token bucket policy, a client can send burst requests at once and
+// rate requests per second after that. A zero rate disables the limit.
+type rateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// configureRateLimits swaps the limiters of the reads and writes route groups of s,
+// forgetting the buckets of every client
+func (s *Server) configureRateLimits(reads rateLimit, writes rateLimit) error {
+	limiters := map[string]*rateLimiter{}
+	for group, limit := range map[string]rateLimit{limitReads: reads, limitWrites: writes} {
+		if limit.Rate < 0 || (limit.Rate > 0 && limit.Burst < 1) {
+			return fmt.Errorf("%s rate limit must have a rate of 0 or more and a burst of 1 or more", group)
+		}
+		if limit.Rate > 0 {
+			limiters[group] = newRateLimiter(limit, time.Now)
+		}
+	}
+	s.rateLimiters = limiters
+	return nil
+}
+
+// rateLimiter keeps a token bucket per client
+type rateLimiter struct {
+	limit rateLimit
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateDecision is the state of a bucket after taking a request from it
+type rateDecision struct {
+	allowed    bool
+	remaining  int
+	retryAfter time.Duration // until the next request is allowed, when it isn't
+	reset      time.Duration // until the bucket is full again
+}
+
+// bucketSweep is how often buckets that filled up again are dropped, a full bucket
+// is the same as no bucket
+const bucketSweep = time.Minute
+
+func newRateLimiter(limit rateLimit, now func() time.Time) *rateLimiter {
+	return &rateLimiter{limit: limit, now: now, buckets: map[string]*tokenBucket{}, swept: now()}
+}
+
+// take takes a token from the bucket of client
+func (l *rateLimiter) take(client string) rateDecision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.swept) >= bucketSweep {
+		l.sweep(now)
+	}
+
+	bucket, found := l.buckets[client]
+	if !found {
+		bucket = &tokenBucket{tokens: float64(l.limit.Burst), updated: now}
+		l.buckets[client] = bucket
+	}
+	bucket.refill(l.limit, now)
+
+	decision := rateDecision{allowed: bucket.tokens >= 1}
+	if decision.allowed {
+		bucket.tokens--
+	} else {
+		decision.retryAfter = seconds((1 - bucket.tokens) / l.limit.Rate)
+	}
+	decision.remaining = int(bucket.tokens)
+	decision.reset = seconds((float64(l.limit.Burst) - bucket.tokens) / l.limit.Rate)
+	return decision
+}
+
+func (b *tokenBucket) refill(limit rateLimit, now time.Time) {
+	if elapsed := now.Sub(b.updated).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(limit.Burst), b.tokens+elapsed*limit.Rate)
+		b.updated = now
+	}
+}
+
+func (l *rateLimiter) sweep(now time.Time) {
+	for client, bucket := range l.buckets {
+		bucket.refill(l.limit, now)
+		if bucket.tokens >= float64(l.limit.Burst) {
+			delete(l.buckets, client)
+		}
+	}
+	l.swept = now
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// clientOf identifies the caller a request is counted against: its token when
+// authenticated, otherwise its IP address
+func clientOf(c *gin.Context) string {
+	if value, found := c.Get(principalKey); found {
+		return "principal:" + value.(principal).Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// rateLimited rejects requests with a 429 once their client has used up the bucket of
+// group. Every response reports the bucket in the X-RateLimit-* headers, rejections also
+// say when to retry in Retry-After. It runs after authorize so callers are told apart
+// by their token.
+func (s *Server) rateLimited(group string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiter, limited := s.rateLimiters[group]
+		if !limited {
+			c.Next()
+			return
+		}
+		decision := limiter.take(clientOf(c))
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limiter.limit.Burst))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.reset)))
+		if !decision.allowed {
+			rateLimitedRequests.with(group).inc()
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(decision.retryAfter)))
+			writeError(c, tooManyRequests("rate_limited",
+				fmt.Sprintf("too many %s requests, retry in %d seconds", group, ceilSeconds(decision.retryAfter))))
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
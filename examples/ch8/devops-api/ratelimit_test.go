@@ -0,0 +1,126 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(rateLimit{Rate: 2, Burst: 3}, func() time.Time { return now })
+
+	tests := []struct {
+		description string
+		wait        time.Duration
+		client      string
+		expected    rateDecision
+	}{
+		{"first request", 0, "a", rateDecision{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+		{"second request", 0, "a", rateDecision{allowed: true, remaining: 1, reset: time.Second}},
+		{"burst used up", 0, "a", rateDecision{allowed: true, remaining: 0, reset: 1500 * time.Millisecond}},
+		{"rejected until a token is added", 0, "a", rateDecision{allowed: false, remaining: 0, retryAfter: 500 * time.Millisecond, reset: 1500 * time.Millisecond}},
+		{"another client has its own bucket", 0, "b", rateDecision{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+		{"half a token is not enough", 250 * time.Millisecond, "a", rateDecision{allowed: false, remaining: 0, retryAfter: 250 * time.Millisecond, reset: 1250 * time.Millisecond}},
+		{"a token is added at rate", 250 * time.Millisecond, "a", rateDecision{allowed: true, remaining: 0, reset: 1500 * time.Millisecond}},
+		{"the bucket holds at most burst", time.Hour, "a", rateDecision{allowed: true, remaining: 2, reset: 500 * time.Millisecond}},
+	}
+
+	for _, test := range tests {
+		now = now.Add(test.wait)
+		if decision := limiter.take(test.client); decision != test.expected {
+			t.Errorf("\nTest: %s\nExpected: %+v, Received: %+v", test.description, test.expected, decision)
+		}
+	}
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected: the full bucket of b to be swept, Received: %d buckets", len(limiter.buckets))
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	// rates this low add no token while the test runs
+	if err := s.configureRateLimits(rateLimit{Rate: 0.001, Burst: 3}, rateLimit{Rate: 0.001, Burst: 2}); err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(t, s)
+	send := func(method string, url string, body string, remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		description string
+		method      string
+		url         string
+		body        string
+		remoteAddr  string
+		status      int
+		remaining   string
+	}{
+		{"first write", "POST", "/dev", `{"name": "dev_ferrets"}`, "10.0.0.1:1234", http.StatusCreated, "1"},
+		{"failed writes count too", "POST", "/dev", `{"name": ""}`, "10.0.0.1:1234", http.StatusUnprocessableEntity, "0"},
+		{"writes used up", "POST", "/dev", `{"name": "dev_stoats"}`, "10.0.0.1:1234", http.StatusTooManyRequests, "0"},
+		{"reads have their own bucket", "GET", "/dev", "", "10.0.0.1:1234", http.StatusOK, "2"},
+		{"other clients have their own bucket", "POST", "/dev", `{"name": "dev_stoats"}`, "10.0.0.2:1234", http.StatusCreated, "1"},
+		{"clients are told apart by address, not port", "POST", "/op", `{"name": "op_ferrets"}`, "10.0.0.1:4321", http.StatusTooManyRequests, "0"},
+		{"health checks are not limited", "GET", "/healthz", "", "10.0.0.1:1234", http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		w := send(test.method, test.url, test.body, test.remoteAddr)
+		if w.Code != test.status || w.Header().Get("X-RateLimit-Remaining") != test.remaining {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d with %q remaining, Received: %d with %q remaining",
+				test.description, test.status, test.remaining, w.Code, w.Header().Get("X-RateLimit-Remaining"))
+		}
+		if w.Code == http.StatusTooManyRequests && (w.Header().Get("Retry-After") == "" || !strings.Contains(w.Body.String(), `"code":"rate_limited"`)) {
+			t.Errorf("\nTest: %s\nExpected: Retry-After and a rate_limited problem, Received: %q %s", test.description, w.Header().Get("Retry-After"), w.Body.String())
+		}
+	}
+	if _, found := s.devStore.FindByName("dev_stoats"); !found {
+		t.Errorf("Expected: the write of the other client to be stored, Received: not found")
+	}
+}
+
+func TestRateLimitedByToken(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	useAuth(t, s)
+	s.configureRateLimits(rateLimit{Rate: 0.001, Burst: 1}, rateLimit{})
+	router := newTestRouter(t, s)
+
+	get := func(token string) int {
+		req := httptest.NewRequest("GET", "/engineers", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	if get("viewer-key") != http.StatusOK || get("viewer-key") != http.StatusTooManyRequests {
+		t.Errorf("Expected: the second read with the same key to be limited")
+	}
+	if status := get("editor-key"); status != http.StatusOK {
+		t.Errorf("Expected: a key sharing the address to have its own bucket, Received: %d", status)
+	}
+}
+
+func TestConfigureRateLimits(t *testing.T) {
+	s := newTestServer(t)
+	for _, limit := range []rateLimit{{Rate: -1, Burst: 1}, {Rate: 1, Burst: 0}} {
+		if err := s.configureRateLimits(limit, rateLimit{}); err == nil {
+			t.Errorf("Expected: %+v to be rejected, Received: no error", limit)
+		}
+	}
+	if err := s.configureRateLimits(rateLimit{Rate: 1, Burst: 1}, rateLimit{}); err != nil || s.rateLimiters[limitWrites] != nil {
+		t.Errorf("Expected: a zero rate to leave writes unlimited, Received: %v %v", err, s.rateLimiters)
+	}
+}
@@ -1,166 +1,201 @@
 package main
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
 )
 
-func findEngineer_by_Name(engineer_name string) (*devops_resource.Engineer, error) {
-	if engineer, found := engineerStore.FindByName(engineer_name); found {
+func (s engineerService) GetByName(engineer_name string) (*devops_resource.Engineer, error) {
+	if engineer, found := s.engineerStore.FindByName(engineer_name); found {
 		return engineer, nil
 	}
-	return nil, errors.New(" no engineer with that name ")
+	return nil, s.missing(notFound("engineer_not_found", "no engineer named "+engineer_name))
 }
 
-func findEngineer_by_Email(engineer_email string) (*devops_resource.Engineer, error) {
-	if engineer, found := engineerStore.FindByEmail(engineer_email); found {
+func (s engineerService) GetByEmail(engineer_email string) (*devops_resource.Engineer, error) {
+	if engineer, found := s.engineerStore.FindByEmail(engineer_email); found {
 		return engineer, nil
 	}
-	return nil, errors.New(" no engineer with that email ")
+	return nil, s.missing(notFound("engineer_not_found", "no engineer with email "+engineer_email))
 }
 
-func findDev_by_Name(dev_name string) (*devops_resource.Dev, error) {
-	if dev, found := devStore.FindByName(dev_name); found {
-		return dev, nil
+func (s devService) GetByName(dev_name string) (*devops_resource.Dev, error) {
+	if dev, found := s.devStore.FindByName(dev_name); found {
+		return s.resolveDev(dev), nil
 	}
-	return nil, errors.New(" no dev group with that name ")
+	return nil, s.missing(notFound("dev_not_found", "no dev group named "+dev_name))
 }
 
-func findOps_by_Name(ops_name string) (*devops_resource.Ops, error) {
-	if ops, found := opsStore.FindByName(ops_name); found {
-		return ops, nil
+func (s opsService) GetByName(ops_name string) (*devops_resource.Ops, error) {
+	if ops, found := s.opsStore.FindByName(ops_name); found {
+		return s.resolveOps(ops), nil
 	}
-	return nil, errors.New(" no ops group with that name ")
+	return nil, s.missing(notFound("ops_not_found", "no ops group named "+ops_name))
 }
 
-func getSpecificEngineerById(c *gin.Context) {
+func (s *Server) getSpecificEngineerById(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
 
-	engineer, err := findEngineer_by_Id(id)
+	engineer, err := v.Engineers.Get(id)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	setETag(c, v.Engineers.Version(engineer.Id))
 	c.IndentedJSON(http.StatusOK, engineer)
 }
 
-func getSpecificEngineerByName(c *gin.Context) {
+func (s *Server) getSpecificEngineerByName(c *gin.Context) {
+	v := s.forRequest(c)
 	name := c.Param("name")
 
-	engineer, err := findEngineer_by_Name(name)
+	engineer, err := v.Engineers.GetByName(name)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	setETag(c, v.Engineers.Version(engineer.Id))
 	c.IndentedJSON(http.StatusOK, engineer)
 }
 
-func getSpecificEngineerByEmail(c *gin.Context) {
+func (s *Server) getSpecificEngineerByEmail(c *gin.Context) {
+	v := s.forRequest(c)
 	email := c.Param("email")
 
-	engineer, err := findEngineer_by_Email(email)
+	engineer, err := v.Engineers.GetByEmail(email)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
+	setETag(c, v.Engineers.Version(engineer.Id))
 	c.IndentedJSON(http.StatusOK, engineer)
 }
 
-func getSpecificDevById(c *gin.Context) {
+func (s *Server) getSpecificDevById(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
 
-	dev, err := findDev_by_Id(id)
+	dev, err := v.Devs.Get(id)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	//dev_slice := devToDevEngineer(dev)
-	c.IndentedJSON(http.StatusOK, dev)
+	setETag(c, v.Devs.Version(dev.Id))
+	c.IndentedJSON(http.StatusOK, renderDev(dev, parseExpand(c)))
 }
 
-func getSpecificDevByName(c *gin.Context) {
+func (s *Server) getSpecificDevByName(c *gin.Context) {
+	v := s.forRequest(c)
 	name := c.Param("name")
 
-	dev, err := findDev_by_Name(name)
+	dev, err := v.Devs.GetByName(name)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, dev)
+	setETag(c, v.Devs.Version(dev.Id))
+	c.IndentedJSON(http.StatusOK, renderDev(dev, parseExpand(c)))
 }
 
-func getSpecificOpsById(c *gin.Context) {
+func (s *Server) getSpecificOpsById(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
 
-	ops, err := findOp_by_Id(id)
+	ops, err := v.Ops.Get(id)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	//ops_slice := opsToOpsEngineer(ops)
-	c.IndentedJSON(http.StatusOK, ops)
+	setETag(c, v.Ops.Version(ops.Id))
+	c.IndentedJSON(http.StatusOK, renderOps(ops, parseExpand(c)))
 
 }
 
-func getSpecificOpsByName(c *gin.Context) {
+func (s *Server) getSpecificOpsByName(c *gin.Context) {
+	v := s.forRequest(c)
 	name := c.Param("name")
 
-	ops, err := findOps_by_Name(name)
+	ops, err := v.Ops.GetByName(name)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	c.IndentedJSON(http.StatusOK, ops)
+	setETag(c, v.Ops.Version(ops.Id))
+	c.IndentedJSON(http.StatusOK, renderOps(ops, parseExpand(c)))
 }
 
-func getSpecificDevOpsById(c *gin.Context) {
+func (s *Server) getSpecificDevOpsById(c *gin.Context) {
+	v := s.forRequest(c)
 	id := c.Param("id")
 
-	devops, err := findDevOps_by_Id(id)
+	devops, err := v.DevOps.Get(id)
 
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 
-	//ops_slice := opsToOpsEngineer(ops)
-	c.IndentedJSON(http.StatusOK, devops)
+	setETag(c, v.DevOps.Version(devops.Id))
+	c.IndentedJSON(http.StatusOK, renderDevOps(devops, parseExpand(c)))
 
 }
 
-func getEngineer(c *gin.Context) {
-	//engineer_slice := mapToSlice(engineers)
-	c.IndentedJSON(http.StatusOK, engineerStore.List())
+func (s *Server) getEngineer(c *gin.Context) {
+	v := s.forRequest(c)
+	engineers, archived, err := v.listEngineers(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, renderList(engineers, archived, func(engineer *devops_resource.Engineer) any { return engineer }))
 }
 
-func getDev(c *gin.Context) {
-	//dev_slice := devListConversion(developers)
-	c.IndentedJSON(http.StatusOK, devStore.List())
+func (s *Server) getDev(c *gin.Context) {
+	v := s.forRequest(c)
+	devs, archived, err := v.listDevs(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	exp := parseExpand(c)
+	c.IndentedJSON(http.StatusOK, renderList(devs, archived, func(group *devops_resource.Dev) any { return renderDev(v.resolveDev(group), exp) }))
 }
 
-func getOp(c *gin.Context) {
-	//ops_slice := opsListConversion(operations)
-	c.IndentedJSON(http.StatusOK, opsStore.List())
+func (s *Server) getOp(c *gin.Context) {
+	v := s.forRequest(c)
+	ops, archived, err := v.listOps(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	exp := parseExpand(c)
+	c.IndentedJSON(http.StatusOK, renderList(ops, archived, func(group *devops_resource.Ops) any { return renderOps(v.resolveOps(group), exp) }))
 }
 
-func getDevOps(c *gin.Context) {
-	//devops_slice := devopsListConversion(developer_operations)
-	c.IndentedJSON(http.StatusOK, devOpsStore.List())
+func (s *Server) getDevOps(c *gin.Context) {
+	v := s.forRequest(c)
+	devops, archived, err := v.listDevOps(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	exp := parseExpand(c)
+	c.IndentedJSON(http.StatusOK, renderList(devops, archived, func(group *devops_resource.DevOps) any { return renderDevOps(v.resolveDevOps(group), exp) }))
 }
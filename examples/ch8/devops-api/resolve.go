@@ -0,0 +1,190 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// Stores keep group membership as ID references so that an engineer, dev or ops
+// group only exists in one place. The helpers below copy resources in and out of
+// the stores and expand references back into full resources when they are read.
+
+// cloneEngineer copies an engineer, an empty skill list is always nil
+func cloneEngineer(engineer *devops_resource.Engineer) *devops_resource.Engineer {
+	out := *engineer
+	out.Skills = nil
+	if len(engineer.Skills) > 0 {
+		out.Skills = append(out.Skills, engineer.Skills...)
+	}
+	return &out
+}
+
+// normalizeDev copies a dev group, replacing its engineers with ID references
+func normalizeDev(dev *devops_resource.Dev) *devops_resource.Dev {
+	out := &devops_resource.Dev{Name: dev.Name, Id: dev.Id, Engineers: make([]*devops_resource.Engineer, 0, len(dev.Engineers))}
+	for _, engineer := range dev.Engineers {
+		out.Engineers = append(out.Engineers, &devops_resource.Engineer{Id: engineer.Id})
+	}
+	return out
+}
+
+// normalizeOps copies an ops group, replacing its engineers with ID references
+func normalizeOps(ops *devops_resource.Ops) *devops_resource.Ops {
+	out := &devops_resource.Ops{Name: ops.Name, Id: ops.Id, Engineers: make([]*devops_resource.Engineer, 0, len(ops.Engineers))}
+	for _, engineer := range ops.Engineers {
+		out.Engineers = append(out.Engineers, &devops_resource.Engineer{Id: engineer.Id})
+	}
+	return out
+}
+
+// normalizeDevOps copies a devops group, replacing its dev and ops groups with ID references
+func normalizeDevOps(devops *devops_resource.DevOps) *devops_resource.DevOps {
+	out := &devops_resource.DevOps{
+		Id:   devops.Id,
+		Devs: make([]*devops_resource.Dev, 0, len(devops.Devs)),
+		Ops:  make([]*devops_resource.Ops, 0, len(devops.Ops)),
+	}
+	for _, dev := range devops.Devs {
+		out.Devs = append(out.Devs, &devops_resource.Dev{Id: dev.Id})
+	}
+	for _, op := range devops.Ops {
+		out.Ops = append(out.Ops, &devops_resource.Ops{Id: op.Id})
+	}
+	return out
+}
+
+// resolveEngineers looks up engineer references, dropping any that no longer exist
+func (s serviceScope) resolveEngineers(refs []*devops_resource.Engineer) []*devops_resource.Engineer {
+	out := make([]*devops_resource.Engineer, 0, len(refs))
+	for _, ref := range refs {
+		if engineer, found := s.engineerStore.FindByID(ref.Id); found {
+			out = append(out, engineer)
+		}
+	}
+	return out
+}
+
+func (s serviceScope) resolveDev(dev *devops_resource.Dev) *devops_resource.Dev {
+	return &devops_resource.Dev{Name: dev.Name, Id: dev.Id, Engineers: s.resolveEngineers(dev.Engineers)}
+}
+
+func (s serviceScope) resolveOps(ops *devops_resource.Ops) *devops_resource.Ops {
+	return &devops_resource.Ops{Name: ops.Name, Id: ops.Id, Engineers: s.resolveEngineers(ops.Engineers)}
+}
+
+func (s serviceScope) resolveDevOps(devops *devops_resource.DevOps) *devops_resource.DevOps {
+	out := &devops_resource.DevOps{
+		Id:   devops.Id,
+		Devs: make([]*devops_resource.Dev, 0, len(devops.Devs)),
+		Ops:  make([]*devops_resource.Ops, 0, len(devops.Ops)),
+	}
+	for _, ref := range devops.Devs {
+		if dev, found := s.devStore.FindByID(ref.Id); found {
+			out.Devs = append(out.Devs, s.resolveDev(dev))
+		}
+	}
+	for _, ref := range devops.Ops {
+		if op, found := s.opsStore.FindByID(ref.Id); found {
+			out.Ops = append(out.Ops, s.resolveOps(op))
+		}
+	}
+	return out
+}
+
+func engineerIDs(engineers []*devops_resource.Engineer) []string {
+	ids := make([]string, 0, len(engineers))
+	for _, engineer := range engineers {
+		ids = append(ids, engineer.Id)
+	}
+	return ids
+}
+
+func devIDs(devs []*devops_resource.Dev) []string {
+	ids := make([]string, 0, len(devs))
+	for _, dev := range devs {
+		ids = append(ids, dev.Id)
+	}
+	return ids
+}
+
+func opsIDs(ops []*devops_resource.Ops) []string {
+	ids := make([]string, 0, len(ops))
+	for _, op := range ops {
+		ids = append(ids, op.Id)
+	}
+	return ids
+}
+
+// expansion is the set of member types requested with ?expand=engineers,devs,ops
+type expansion map[string]bool
+
+// expandAll expands every member
+var expandAll = expansion{"engineers": true, "devs": true, "ops": true}
+
+// parseExpand reads the expand query parameter, expanding everything when it is absent
+func parseExpand(c *gin.Context) expansion {
+	value, ok := c.GetQuery("expand")
+	if !ok {
+		return expandAll
+	}
+	exp := expansion{}
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			exp[field] = true
+		}
+	}
+	return exp
+}
+
+// Views returned when members are left as references
+type groupReference struct {
+	Name      string   `json:"name"`
+	Id        string   `json:"id"`
+	Engineers []string `json:"engineers"`
+}
+
+type devOpsView struct {
+	Id   string `json:"id"`
+	Devs []any  `json:"dev"`
+	Ops  []any  `json:"ops"`
+}
+
+// renderDev, renderOps and renderDevOps render resolved groups, as returned by Get or
+// resolveDev, resolveOps and resolveDevOps, leaving members that aren't expanded as IDs
+func renderDev(dev *devops_resource.Dev, exp expansion) any {
+	if exp["engineers"] {
+		return dev
+	}
+	return groupReference{Name: dev.Name, Id: dev.Id, Engineers: engineerIDs(dev.Engineers)}
+}
+
+func renderOps(ops *devops_resource.Ops, exp expansion) any {
+	if exp["engineers"] {
+		return ops
+	}
+	return groupReference{Name: ops.Name, Id: ops.Id, Engineers: engineerIDs(ops.Engineers)}
+}
+
+func renderDevOps(devops *devops_resource.DevOps, exp expansion) any {
+	if exp["devs"] && exp["ops"] && exp["engineers"] {
+		return devops
+	}
+	view := devOpsView{Id: devops.Id, Devs: make([]any, 0, len(devops.Devs)), Ops: make([]any, 0, len(devops.Ops))}
+	for _, dev := range devops.Devs {
+		if exp["devs"] {
+			view.Devs = append(view.Devs, renderDev(dev, exp))
+		} else {
+			view.Devs = append(view.Devs, dev.Id)
+		}
+	}
+	for _, op := range devops.Ops {
+		if exp["ops"] {
+			view.Ops = append(view.Ops, renderOps(op, exp))
+		} else {
+			view.Ops = append(view.Ops, op.Id)
+		}
+	}
+	return view
+}
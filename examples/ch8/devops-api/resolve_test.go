@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+func TestEngineerUpdatePropagatesToGroups(t *testing.T) {
+	s := newTestServer(t)
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	dev, _ := s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	op, _ := s.Ops.Create(devops_resource.Ops{Name: "op_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	devops, _ := s.DevOps.Create(devops_resource.DevOps{Devs: []*devops_resource.Dev{dev}, Ops: []*devops_resource.Ops{op}})
+
+	s.Engineers.Update(engineer.Id, devops_resource.Engineer{Name: "not bob", Email: "notbob@bob.com"}, anyVersion)
+
+	found, _ := s.DevOps.Get(devops.Id)
+	if found.Devs[0].Engineers[0].Name != "not bob" || found.Ops[0].Engineers[0].Email != "notbob@bob.com" {
+		t.Errorf("Expected updated engineer in every group, Received: %v and %v", found.Devs[0].Engineers[0], found.Ops[0].Engineers[0])
+	}
+
+	s.Engineers.Delete(engineer.Id, anyVersion, "")
+
+	found, _ = s.DevOps.Get(devops.Id)
+	if len(found.Devs[0].Engineers) != 0 || len(found.Ops[0].Engineers) != 0 {
+		t.Errorf("Expected deleted engineer to be gone from every group, Received: %v and %v", found.Devs[0].Engineers, found.Ops[0].Engineers)
+	}
+}
+
+func TestRemoveEngineerFromOpLeavesDevUntouched(t *testing.T) {
+	s := newTestServer(t)
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	dev, _ := s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	op, _ := s.Ops.Create(devops_resource.Ops{Name: "op_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	s.DevOps.Create(devops_resource.DevOps{Devs: []*devops_resource.Dev{dev}, Ops: []*devops_resource.Ops{op}})
+
+	if err := s.Ops.RemoveEngineer(op.Id, engineer.Id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	found, _ := s.Devs.Get(dev.Id)
+	if len(found.Engineers) != 1 {
+		t.Errorf("Expected engineer to remain in dev group, Received: %v", found.Engineers)
+	}
+}
+
+var expandTests = []struct {
+	description string
+	query       string
+	expected    string
+}{
+	{"expands everything by default", "", `{"id":"DO1","dev":[{"name":"dev_ferrets","id":"D1","engineers":[{"name":"bob","id":"E1","email":"bob@bob.com"}]}],"ops":[]}`},
+	{"empty expand returns references", "?expand=", `{"id":"DO1","dev":["D1"],"ops":[]}`},
+	{"expands groups but not engineers", "?expand=devs,ops", `{"id":"DO1","dev":[{"name":"dev_ferrets","id":"D1","engineers":["E1"]}],"ops":[]}`},
+}
+
+func TestGetDevOpsExpand(t *testing.T) {
+	s := newTestServer(t)
+	s.engineerStore.Add(&devops_resource.Engineer{Name: "bob", Id: "E1", Email: "bob@bob.com"})
+	s.devStore.Add(&devops_resource.Dev{Name: "dev_ferrets", Id: "D1", Engineers: []*devops_resource.Engineer{{Id: "E1"}}})
+	s.devOpsStore.Add(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{{Id: "D1"}}})
+
+	for _, test := range expandTests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/devops/DO1"+test.query, nil)
+		c.Params = []gin.Param{{Key: "id", Value: "DO1"}}
+		s.getSpecificDevOpsById(c)
+
+		var received, expected any
+		json.Unmarshal(w.Body.Bytes(), &received)
+		json.Unmarshal([]byte(test.expected), &expected)
+		receivedJSON, _ := json.Marshal(received)
+		expectedJSON, _ := json.Marshal(expected)
+		if string(receivedJSON) != string(expectedJSON) {
+			t.Errorf("\nTest: %s\nExpected: %s, Received: %s", test.description, expectedJSON, receivedJSON)
+		}
+	}
+}
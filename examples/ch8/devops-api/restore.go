@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// findArchived loads the archived record of kind and id, decoding its resource into resource
+func findArchived(uow *unitOfWork, kind string, id string, resource any) (*archivedRecord, error) {
+	record, found := uow.archive.Find(kind, id)
+	if !found {
+		return nil, notFound(kind+"_not_archived", "no archived "+archivedKindNames[kind]+" with id "+id)
+	}
+	if err := json.Unmarshal(record.Resource, resource); err != nil {
+		return nil, fmt.Errorf("failed to decode archived %s %s: %w", kind, id, err)
+	}
+	return record, nil
+}
+
+// functions to restore archived resources. A restored resource gets its fields and
+// memberships back, and an engineer its direct reports, leaving out groups, members and
+// managers that no longer exist. It fails if another resource took its name or ID in the
+// meantime.
+func (s engineerService) Restore(engineer_id string) (*devops_resource.Engineer, error) {
+	var engineer devops_resource.Engineer
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		record, err := findArchived(uow, archivedEngineer, engineer_id, &engineer)
+		if err != nil {
+			return err
+		}
+		if _, found := uow.engineers.FindByID(engineer_id); found {
+			return conflict("engineer_exists", "engineer "+engineer_id+" already exists")
+		}
+		if _, found := uow.engineers.FindByName(engineer.Name); found {
+			return conflict("engineer_exists", "engineer "+engineer.Name+" already exists")
+		}
+		if _, found := uow.engineers.FindByID(engineer.ManagerId); !found {
+			engineer.ManagerId = ""
+		}
+		if err := uow.engineers.Add(&engineer); err != nil {
+			return err
+		}
+		uow.publish(EngineerRestored, cloneEngineer(&engineer))
+		for _, dev_id := range record.Memberships[archivedDev] {
+			if dev, found := uow.devs.FindByID(dev_id); found && !hasEngineer(dev.Engineers, engineer_id) {
+				uow.devs.AddEngineerToDev(dev_id, &engineer)
+				uow.publish(EngineerAddedToDev, membershipChange{GroupID: dev_id, MemberID: engineer_id})
+			}
+		}
+		for _, op_id := range record.Memberships[archivedOps] {
+			if op, found := uow.ops.FindByID(op_id); found && !hasEngineer(op.Engineers, engineer_id) {
+				uow.ops.AddEngineerToOp(op_id, &engineer)
+				uow.publish(EngineerAddedToOps, membershipChange{GroupID: op_id, MemberID: engineer_id})
+			}
+		}
+		// Reports that found another manager in the meantime keep it
+		for _, report_id := range record.Memberships[archivedReports] {
+			report, found := uow.engineers.FindByID(report_id)
+			if !found || report.ManagerId != "" || reportsTo(&engineer, report_id, uow.engineers.FindByID) {
+				continue
+			}
+			report.ManagerId = engineer_id
+			if err := uow.engineers.Update(report, anyVersion); err != nil {
+				return err
+			}
+			uow.publish(EngineerUpdated, report)
+		}
+		uow.archive.Delete(archivedEngineer, engineer_id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &engineer, nil
+}
+
+func (s devService) Restore(dev_id string) (*devops_resource.Dev, error) {
+	var group chartGroup
+	dev := devops_resource.Dev{Id: dev_id, Engineers: make([]*devops_resource.Engineer, 0)}
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		record, err := findArchived(uow, archivedDev, dev_id, &group)
+		if err != nil {
+			return err
+		}
+		if _, found := uow.devs.FindByID(dev_id); found {
+			return conflict("dev_exists", "dev group "+dev_id+" already exists")
+		}
+		if _, found := uow.devs.FindByName(group.Name); found {
+			return conflict("dev_exists", "dev group "+group.Name+" already exists")
+		}
+		dev.Name = group.Name
+		for _, engineer_id := range group.Engineers {
+			if _, found := uow.engineers.FindByID(engineer_id); found {
+				dev.Engineers = append(dev.Engineers, &devops_resource.Engineer{Id: engineer_id})
+			}
+		}
+		if err := uow.devs.Add(&dev); err != nil {
+			return err
+		}
+		uow.publish(DevRestored, devChart(&dev))
+		for _, devops_id := range record.Memberships[archivedDevOps] {
+			if devops, found := uow.devops.FindByID(devops_id); found && !containsID(devIDs(devops.Devs), dev_id) {
+				uow.devops.AddDevToDevOps(devops_id, &dev)
+				uow.publish(DevAddedToDevOps, membershipChange{GroupID: devops_id, MemberID: dev_id})
+			}
+		}
+		uow.archive.Delete(archivedDev, dev_id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveDev(&dev), nil
+}
+
+func (s opsService) Restore(op_id string) (*devops_resource.Ops, error) {
+	var group chartGroup
+	op := devops_resource.Ops{Id: op_id, Engineers: make([]*devops_resource.Engineer, 0)}
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		record, err := findArchived(uow, archivedOps, op_id, &group)
+		if err != nil {
+			return err
+		}
+		if _, found := uow.ops.FindByID(op_id); found {
+			return conflict("ops_exists", "ops group "+op_id+" already exists")
+		}
+		if _, found := uow.ops.FindByName(group.Name); found {
+			return conflict("ops_exists", "ops group "+group.Name+" already exists")
+		}
+		op.Name = group.Name
+		for _, engineer_id := range group.Engineers {
+			if _, found := uow.engineers.FindByID(engineer_id); found {
+				op.Engineers = append(op.Engineers, &devops_resource.Engineer{Id: engineer_id})
+			}
+		}
+		if err := uow.ops.Add(&op); err != nil {
+			return err
+		}
+		uow.publish(OpsRestored, opsChart(&op))
+		for _, devops_id := range record.Memberships[archivedDevOps] {
+			if devops, found := uow.devops.FindByID(devops_id); found && !containsID(opsIDs(devops.Ops), op_id) {
+				uow.devops.AddOpsToDevOps(devops_id, &op)
+				uow.publish(OpsAddedToDevOps, membershipChange{GroupID: devops_id, MemberID: op_id})
+			}
+		}
+		uow.archive.Delete(archivedOps, op_id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveOps(&op), nil
+}
+
+func (s devOpsService) Restore(devops_id string) (*devops_resource.DevOps, error) {
+	var group chartDevOps
+	devops := devops_resource.DevOps{Id: devops_id, Devs: make([]*devops_resource.Dev, 0), Ops: make([]*devops_resource.Ops, 0)}
+	err := s.inTransaction(func(uow *unitOfWork) error {
+		if _, err := findArchived(uow, archivedDevOps, devops_id, &group); err != nil {
+			return err
+		}
+		if _, found := uow.devops.FindByID(devops_id); found {
+			return conflict("devops_exists", "devops group "+devops_id+" already exists")
+		}
+		for _, dev_id := range group.Devs {
+			if _, found := uow.devs.FindByID(dev_id); found {
+				devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: dev_id})
+			}
+		}
+		for _, op_id := range group.Ops {
+			if _, found := uow.ops.FindByID(op_id); found {
+				devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: op_id})
+			}
+		}
+		if err := uow.devops.Add(&devops); err != nil {
+			return err
+		}
+		uow.publish(DevOpsRestored, devOpsChart(&devops))
+		uow.archive.Delete(archivedDevOps, devops_id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveDevOps(&devops), nil
+}
+
+// server POST handlers for /<resource>/:id/restore
+func (s *Server) postEngineerRestore(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.forRequest(c, s.respondWithEngineer(c, http.StatusOK, id)).Engineers.Restore(id); err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) postDevRestore(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.forRequest(c, s.respondWithDev(c, http.StatusOK, id, expandAll)).Devs.Restore(id); err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) postOpRestore(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.forRequest(c, s.respondWithOps(c, http.StatusOK, id, expandAll)).Ops.Restore(id); err != nil {
+		writeError(c, err)
+	}
+}
+
+func (s *Server) postDevOpsRestore(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := s.forRequest(c, s.respondWithDevOps(c, http.StatusOK, id, expandAll)).DevOps.Restore(id); err != nil {
+		writeError(c, err)
+	}
+}
+
+var archiveSortFields = map[string]func(*archivedRecord) string{
+	"archived_at": func(record *archivedRecord) string { return fmt.Sprintf("%020d", record.ArchivedAt.UnixNano()) },
+	"archived_by": func(record *archivedRecord) string { return record.ArchivedBy },
+}
+
+func archivedRecordID(record *archivedRecord) string { return record.Kind + "/" + record.Id }
+
+// server handler for GET /archive, deleted resources oldest first. ?kind= limits the
+// list to engineer, dev, ops or devops records.
+func (s *Server) getArchive(c *gin.Context) {
+	v := s.forRequest(c)
+	kind := c.Query("kind")
+	if _, known := archivedKindNames[kind]; kind != "" && !known {
+		writeError(c, badRequest("invalid_kind", "kind must be engineer, dev, ops or devops"))
+		return
+	}
+	params, err := parseListParams(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	records := v.archiveStore.List(kind)
+	page, err := paginate(c, records, params, archiveSortFields, archivedRecordID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// searchDoc is an engineer, dev group or ops group as the search index sees it.
+// Devops groups have no name and aren't searchable.
+type searchDoc struct {
+	Kind  string
+	Id    string
+	Name  string
+	Email string
+}
+
+// searchResult is one ranked hit of GET /search
+type searchResult struct {
+	Kind  string `json:"kind"`
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Score int    `json:"score"`
+}
+
+// Terms found in a name count for more than terms found in an email
+const (
+	emailWeight = 1
+	nameWeight  = 2
+)
+
+// How well a query term matches an indexed term
+const (
+	fuzzyMatch  = 1
+	prefixMatch = 2
+	exactMatch  = 3
+)
+
+// invertedIndex maps the lowercase terms of names and emails to the documents they
+// appear in. A field is indexed as a whole and as its words, so "dev_ferrets" is
+// found by "dev_fer" as well as by "ferrets".
+// Exact terms are looked up in postings, while prefixes and typos are found by walking
+// the sorted terms like a trie, so a query never scans the whole vocabulary.
+type invertedIndex struct {
+	mu       sync.RWMutex
+	docs     map[string]*searchDoc
+	postings map[string]map[string]int // term -> doc key -> best field weight
+	terms    []string                  // the terms of postings, sorted
+}
+
+func newInvertedIndex() *invertedIndex {
+	return &invertedIndex{docs: map[string]*searchDoc{}, postings: map[string]map[string]int{}}
+}
+
+// searchTerms splits a field into the lowercase terms it is indexed under
+func searchTerms(field string) []string {
+	field = strings.ToLower(field)
+	if field == "" {
+		return nil
+	}
+	terms := []string{field}
+	for _, word := range strings.FieldsFunc(field, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) {
+		if !containsID(terms, word) {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// emailTerms adds the domain to the terms of an email, so bob@liatrio.com is found by liatrio.com
+func emailTerms(email string) []string {
+	terms := searchTerms(email)
+	if at := strings.LastIndex(email, "@"); at >= 0 && at < len(email)-1 {
+		terms = append(terms, strings.ToLower(email[at+1:]))
+	}
+	return terms
+}
+
+func (x *invertedIndex) put(doc *searchDoc) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.unindex(archiveKey(doc.Kind, doc.Id))
+	for _, term := range x.index(doc) {
+		x.addTerm(term)
+	}
+}
+
+// index posts the terms of doc, returning the terms that are new to the index
+func (x *invertedIndex) index(doc *searchDoc) []string {
+	key := archiveKey(doc.Kind, doc.Id)
+	x.docs[key] = doc
+	var added []string
+	for _, term := range emailTerms(doc.Email) {
+		if x.post(term, key, emailWeight) {
+			added = append(added, term)
+		}
+	}
+	for _, term := range searchTerms(doc.Name) {
+		if x.post(term, key, nameWeight) {
+			added = append(added, term)
+		}
+	}
+	return added
+}
+
+func (x *invertedIndex) remove(kind string, id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.unindex(archiveKey(kind, id))
+}
+
+// post adds key to the documents of term, reporting whether term is new to the index
+func (x *invertedIndex) post(term string, key string, weight int) bool {
+	docs, found := x.postings[term]
+	if !found {
+		docs = map[string]int{}
+		x.postings[term] = docs
+	}
+	docs[key] = max(docs[key], weight)
+	return !found
+}
+
+func (x *invertedIndex) addTerm(term string) {
+	i := sort.SearchStrings(x.terms, term)
+	x.terms = append(x.terms, "")
+	copy(x.terms[i+1:], x.terms[i:])
+	x.terms[i] = term
+}
+
+func (x *invertedIndex) dropTerm(term string) {
+	if i := sort.SearchStrings(x.terms, term); i < len(x.terms) && x.terms[i] == term {
+		x.terms = append(x.terms[:i], x.terms[i+1:]...)
+	}
+}
+
+func (x *invertedIndex) unindex(key string) {
+	doc, found := x.docs[key]
+	if !found {
+		return
+	}
+	for _, term := range append(searchTerms(doc.Name), emailTerms(doc.Email)...) {
+		if docs, found := x.postings[term]; found {
+			delete(docs, key)
+			if len(docs) == 0 {
+				delete(x.postings, term)
+				x.dropTerm(term)
+			}
+		}
+	}
+	delete(x.docs, key)
+}
+
+// rebuild indexes the given resources from scratch, sorting the terms once at the end
+func (x *invertedIndex) rebuild(engineers []*devops_resource.Engineer, devs []*devops_resource.Dev, ops []*devops_resource.Ops) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.docs = map[string]*searchDoc{}
+	x.postings = map[string]map[string]int{}
+	var terms []string
+	for _, engineer := range engineers {
+		terms = append(terms, x.index(&searchDoc{Kind: archivedEngineer, Id: engineer.Id, Name: engineer.Name, Email: engineer.Email})...)
+	}
+	for _, dev := range devs {
+		terms = append(terms, x.index(&searchDoc{Kind: archivedDev, Id: dev.Id, Name: dev.Name})...)
+	}
+	for _, op := range ops {
+		terms = append(terms, x.index(&searchDoc{Kind: archivedOps, Id: op.Id, Name: op.Name})...)
+	}
+	sort.Strings(terms)
+	x.terms = terms
+}
+
+// reindex indexes the current contents of the stores of the server from scratch
+func (s *Server) reindex() {
+	s.search.rebuild(s.engineerStore.List(), s.devStore.List(), s.opsStore.List())
+}
+
+// indexEvents updates the search index with the events of a committed unit of work
+func (s *Server) indexEvents(events []pendingEvent) {
+	x := s.search
+	for _, event := range events {
+		switch data := event.data.(type) {
+		case *devops_resource.Engineer:
+			x.put(&searchDoc{Kind: archivedEngineer, Id: data.Id, Name: data.Name, Email: data.Email})
+		case chartGroup:
+			switch event.kind {
+			case DevCreated, DevUpdated, DevRestored:
+				x.put(&searchDoc{Kind: archivedDev, Id: data.Id, Name: data.Name})
+			case OpsCreated, OpsUpdated, OpsRestored:
+				x.put(&searchDoc{Kind: archivedOps, Id: data.Id, Name: data.Name})
+			}
+		case deletedResource:
+			switch event.kind {
+			case EngineerDeleted:
+				x.remove(archivedEngineer, data.Id)
+			case DevDeleted:
+				x.remove(archivedDev, data.Id)
+			case OpsDeleted:
+				x.remove(archivedOps, data.Id)
+			}
+		case orgChartImported:
+			s.reindex()
+		}
+	}
+}
+
+// maxEdits is how many typos a query term may contain, none for short terms
+func maxEdits(term string) int {
+	switch length := len([]rune(term)); {
+	case length < 3:
+		return 0
+	case length < 6:
+		return 1
+	}
+	return 2
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of adjacent
+// characters turning a into b
+func editDistance(a string, b string) int {
+	s, t := []rune(a), []rune(b)
+	rows := make([][]int, len(s)+1)
+	for i := range rows {
+		rows[i] = make([]int, len(t)+1)
+		rows[i][0] = i
+	}
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			rows[i][j] = nextDistance(t, j, s[i-1], rows[i][j-1], rows[i-1], i > 1, s[max(i-2, 0)], rows[max(i-2, 0)])
+		}
+	}
+	return rows[len(s)][len(t)]
+}
+
+// nextDistance is the distance between a prefix ending in c and the first j characters
+// of t, given the row of the prefix without c (above), the entry left of this one and,
+// when the prefix has a character before c, that character and its row
+func nextDistance(t []rune, j int, c rune, left int, above []int, hasBefore bool, before rune, beforeRow []int) int {
+	cost := 1
+	if c == t[j-1] {
+		cost = 0
+	}
+	distance := min(above[j]+1, left+1, above[j-1]+cost)
+	if hasBefore && j > 1 && c == t[j-2] && before == t[j-1] {
+		distance = min(distance, beforeRow[j-2]+1)
+	}
+	return distance
+}
+
+// fuzzyTerms calls visit with every term at most edits away from query. It walks the
+// sorted terms like a trie, computing one row of editDistance per prefix they share,
+// and leaves a prefix as soon as every entry of its row is over edits, since no term
+// starting with it can come closer.
+func (x *invertedIndex) fuzzyTerms(query string, edits int, visit func(term string)) {
+	q := []rune(query)
+	row := make([]int, len(q)+1)
+	for j := range row {
+		row[j] = j
+	}
+	x.walkTerms(q, edits, 0, len(x.terms), 0, row, false, 0, nil, visit)
+}
+
+// walkTerms visits the terms in terms[lo:hi], which all start with the same prefix of
+// length offset whose distances to the prefixes of q are row
+func (x *invertedIndex) walkTerms(q []rune, edits int, lo int, hi int, offset int, row []int, hasLast bool, last rune, lastRow []int, visit func(term string)) {
+	// the prefix itself sorts before every longer term starting with it
+	if lo < hi && len(x.terms[lo]) == offset {
+		if row[len(q)] <= edits {
+			visit(x.terms[lo])
+		}
+		lo++
+	}
+	next := make([]int, len(q)+1)
+	for lo < hi {
+		c, size := utf8.DecodeRuneInString(x.terms[lo][offset:])
+		end := x.prefixEnd(lo, hi, x.terms[lo][:offset+size])
+		next[0] = row[0] + 1
+		closest := next[0]
+		for j := 1; j <= len(q); j++ {
+			next[j] = nextDistance(q, j, c, next[j-1], row, hasLast, last, lastRow)
+			closest = min(closest, next[j])
+		}
+		if closest <= edits {
+			x.walkTerms(q, edits, lo, end, offset+size, next, true, c, row, visit)
+		}
+		lo = end
+	}
+}
+
+// prefixEnd returns the end of the terms in terms[lo:hi] starting with prefix, which
+// terms[lo] does. It gallops from lo, as most prefixes are shared by a few terms only.
+func (x *invertedIndex) prefixEnd(lo int, hi int, prefix string) int {
+	step := 1
+	for lo+step < hi && strings.HasPrefix(x.terms[lo+step], prefix) {
+		lo += step
+		step *= 2
+	}
+	hi = min(lo+step, hi)
+	return lo + sort.Search(hi-lo, func(i int) bool { return !strings.HasPrefix(x.terms[lo+i], prefix) })
+}
+
+// matchTerms scores the indexed terms matching a query word exactly, by prefix or
+// within edits typos, keeping the best match of each
+func (x *invertedIndex) matchTerms(word string, edits int) map[string]int {
+	matches := map[string]int{}
+	for i := sort.SearchStrings(x.terms, word); i < len(x.terms) && strings.HasPrefix(x.terms[i], word); i++ {
+		matches[x.terms[i]] = prefixMatch
+	}
+	if _, found := x.postings[word]; found {
+		matches[word] = exactMatch
+	}
+	if edits > 0 {
+		x.fuzzyTerms(word, edits, func(term string) {
+			if matches[term] == 0 {
+				matches[term] = fuzzyMatch
+			}
+		})
+	}
+	return matches
+}
+
+// search returns the documents of kind (every kind when empty) matching every word of
+// query, best match first
+func (x *invertedIndex) search(query string, kind string) []*searchResult {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	words := strings.Fields(strings.ToLower(query))
+	scores := map[string]int{}
+	for i, word := range words {
+		best := map[string]int{}
+		for term, quality := range x.matchTerms(word, maxEdits(word)) {
+			for key, weight := range x.postings[term] {
+				best[key] = max(best[key], quality*weight)
+			}
+		}
+		for key := range scores {
+			if _, found := best[key]; !found {
+				delete(scores, key)
+			}
+		}
+		for key, score := range best {
+			if _, found := scores[key]; found || i == 0 {
+				scores[key] += score
+			}
+		}
+	}
+
+	results := make([]*searchResult, 0, len(scores))
+	for key, score := range scores {
+		doc := x.docs[key]
+		if kind == "" || doc.Kind == kind {
+			results = append(results, &searchResult{Kind: doc.Kind, Id: doc.Id, Name: doc.Name, Email: doc.Email, Score: score})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Kind != b.Kind {
+			return searchKindOrder[a.Kind] < searchKindOrder[b.Kind]
+		}
+		return a.Id < b.Id
+	})
+	return results
+}
+
+var searchKindOrder = map[string]int{archivedEngineer: 0, archivedDev: 1, archivedOps: 2}
+
+var searchSortFields = map[string]func(*searchResult) string{
+	"name": func(r *searchResult) string { return r.Name },
+	"id":   func(r *searchResult) string { return r.Id },
+}
+
+func searchResultID(r *searchResult) string { return r.Kind + "/" + r.Id }
+
+// server handler for GET /search?q=, engineers and groups whose name or email matches
+// every word of q exactly, by prefix or with a typo. ?kind= limits the results to
+// engineer, dev or ops.
+func (s *Server) getSearch(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		writeError(c, badRequest("query_required", "q cannot be empty"))
+		return
+	}
+	kind := c.Query("kind")
+	if _, searchable := searchKindOrder[kind]; kind != "" && !searchable {
+		writeError(c, badRequest("invalid_kind", "kind must be engineer, dev or ops"))
+		return
+	}
+	params, err := parseListParams(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	results := s.search.search(query, kind)
+	page, err := paginate(c, results, params, searchSortFields, searchResultID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.IndentedJSON(http.StatusOK, page)
+}
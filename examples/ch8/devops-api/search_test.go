@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+var searchTests = []struct {
+	description string
+	url         string
+	expected    []string
+}{
+	{"names rank above emails", "/search?q=bob", []string{"engineer/E1", "engineer/E2", "engineer/E3"}},
+	{"case-insensitive", "/search?q=ALICE", []string{"engineer/E2"}},
+	{"prefix", "/search?q=car", []string{"engineer/E3"}},
+	{"typo", "/search?q=alcie", []string{"engineer/E2"}},
+	{"email", "/search?q=bob@bob.com", []string{"engineer/E1"}},
+	{"email domain", "/search?q=bob.com", []string{"engineer/E2", "engineer/E1", "engineer/E3"}},
+	{"words of a group name", "/search?q=stoats", []string{"dev/D2"}},
+	{"every word must match", "/search?q=dev+ferrets", []string{"dev/D1"}},
+	{"groups sharing a word", "/search?q=ferrets", []string{"dev/D1", "ops/O1"}},
+	{"typo in a group name", "/search?q=ferets", []string{"dev/D1", "ops/O1"}},
+	{"kind filter", "/search?q=ferrets&kind=ops", []string{"ops/O1"}},
+	{"sort", "/search?q=bob.com&sort=-name", []string{"engineer/E3", "engineer/E1", "engineer/E2"}},
+	{"no match", "/search?q=zebra", []string{}},
+	{"short words need to match exactly", "/search?q=bx", []string{}},
+}
+
+func testSearch(t *testing.T, s *Server) {
+	gin.SetMode(gin.TestMode)
+	seedGraph(t, s)
+	router := newTestRouter(t, s)
+
+	for _, test := range searchTests {
+		w := mockConditionalRequest(router, "GET", test.url, "", "")
+		if hits := searchHits(t, w.Body.Bytes()); !reflect.DeepEqual(hits, test.expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.expected, hits)
+		}
+	}
+
+	for _, url := range []string{"/search", "/search?q=+", "/search?q=bob&kind=devops", "/search?q=bob&sort=email"} {
+		if w := mockConditionalRequest(router, "GET", url, "", ""); w.Code != http.StatusBadRequest {
+			t.Errorf("\nTest: %s\nExpected: Status Code %d, Received: Status Code %d", url, http.StatusBadRequest, w.Code)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	s := newTestServer(t)
+	testSearch(t, s)
+}
+
+func TestSQLiteSearch(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testSearch(t, s)
+}
+
+var searchUpdateTests = []struct {
+	description string
+	change      func(s *Server) error
+	url         string
+	expected    []string
+}{
+	{"renamed engineer", func(s *Server) error {
+		return s.Engineers.Update("E1", devops_resource.Engineer{Name: "robert", Email: "robert@liatrio.com"}, anyVersion)
+	}, "/search?q=robert", []string{"engineer/E1"}},
+	{"old name is gone", func(s *Server) error { return nil }, "/search?q=bob", []string{"engineer/E2", "engineer/E3"}},
+	{"deleted group", func(s *Server) error {
+		return s.Devs.Delete("D2", anyVersion, "")
+	}, "/search?q=stoats", []string{}},
+	{"restored group", func(s *Server) error {
+		_, err := s.Devs.Restore("D2")
+		return err
+	}, "/search?q=stoats", []string{"dev/D2"}},
+	{"rolled back change", func(s *Server) error {
+		s.inTransaction(func(uow *unitOfWork) error {
+			uow.engineers.DeleteByID("E2")
+			return errAbandoned
+		})
+		return nil
+	}, "/search?q=alice", []string{"engineer/E2"}},
+	{"import", func(s *Server) error {
+		return s.importOrgChart(&orgChart{Devs: []chartGroup{{Id: "D3", Name: "dev_minks", Engineers: []string{}}}})
+	}, "/search?q=dev", []string{"dev/D3"}},
+}
+
+func TestSearchFollowsChanges(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	seedGraph(t, s)
+	router := newTestRouter(t, s)
+
+	for _, test := range searchUpdateTests {
+		if err := test.change(s); err != nil {
+			t.Fatalf("\nTest: %s\nError: %v", test.description, err)
+		}
+		w := mockConditionalRequest(router, "GET", test.url, "", "")
+		if hits := searchHits(t, w.Body.Bytes()); !reflect.DeepEqual(hits, test.expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.expected, hits)
+		}
+	}
+}
+
+func TestSQLiteSearchIndexIsRebuilt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devops.db")
+	s := newSQLiteServer(t, path)
+	seedGraph(t, s)
+	if results := newTestServer(t).search.search("carol", ""); len(results) != 0 {
+		t.Errorf("Expected: an empty index, Received: %+v", results)
+	}
+	s.Close()
+	s = newSQLiteServer(t, path)
+	if results := s.search.search("carol", ""); len(results) != 1 || results[0].Id != "E3" {
+		t.Errorf("Expected: carol to be found after reopening the database, Received: %+v", results)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"alice", "alice", 0},
+		{"alice", "alcie", 1},
+		{"ferrets", "ferets", 1},
+		{"bob", "rob", 1},
+		{"kitten", "sitting", 3},
+		{"", "bob", 3},
+		{"ca", "abc", 3},
+	}
+	for _, test := range tests {
+		if distance := editDistance(test.a, test.b); distance != test.expected {
+			t.Errorf("\nTest: %s/%s\nExpected: %d, Received: %d", test.a, test.b, test.expected, distance)
+		}
+	}
+}
+
+func TestFuzzyMatchesEveryTermWithinEdits(t *testing.T) {
+	x := newInvertedIndex()
+	names := searchNames(500)
+	for i, name := range names {
+		x.put(&searchDoc{Kind: archivedEngineer, Id: strconv.Itoa(i), Name: name})
+	}
+	x.remove(archivedEngineer, "7")
+	for _, query := range []string{names[3][:4], names[3], names[9][1:], "x" + names[11], names[7]} {
+		edits := maxEdits(query)
+		expected := map[string]int{}
+		for term := range x.postings {
+			switch distance := editDistance(query, term); {
+			case term == query:
+				expected[term] = exactMatch
+			case strings.HasPrefix(term, query):
+				expected[term] = prefixMatch
+			case distance <= edits:
+				expected[term] = fuzzyMatch
+			}
+		}
+		if matches := x.matchTerms(query, edits); !reflect.DeepEqual(matches, expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", query, expected, matches)
+		}
+	}
+}
+
+func searchHits(t *testing.T, body []byte) []string {
+	var results []*searchResult
+	if err := json.Unmarshal(body, &results); err != nil {
+		t.Fatalf("Error: %v\nBody: %s", err, body)
+	}
+	hits := make([]string, 0, len(results))
+	for _, result := range results {
+		hits = append(hits, archiveKey(result.Kind, result.Id))
+	}
+	return hits
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Server is one instance of the API: its stores, the generator of new ids and the clock
+// it timestamps changes with, along with everything kept about the data in them, such as
+// the search index, the event stream and the audit log. Servers share nothing, so tests
+// and programs embedding the API can run as many as they need side by side.
+//
+// Authentication, rate limits, the access log and trusted proxies are settings of each
+// server too, and apply to the routers NewRouter builds for it afterwards.
+type Server struct {
+	stores        Stores // the stores below, as NewServer got them
+	engineerStore EngineerStorage
+	devStore      DevStorage
+	opsStore      OpsStorage
+	devOpsStore   DevOpsStorage
+	archiveStore  ArchiveStorage
+	faults        *storageFaults
+	ids           idGenerator
+	now           func() time.Time
+
+	// mu lets one unit of work run at a time and keeps consistentReads from seeing one half done
+	mu          timedRWMutex
+	search      *invertedIndex
+	events      *eventBus
+	audit       AuditSink
+	idempotency *idempotencyStore
+	storeSize   *gaugeFunc
+	webhooks    *webhookDispatcher
+	retention   *retentionJob
+
+	// retentionInterval is how often the retention job looks for expired records, so a
+	// record can outlive the retention period by up to this long
+	retentionInterval time.Duration
+
+	// Settings of the routers, see configureAuth, configureRateLimits, configureLogging
+	// and configureProxies
+	auth           *authenticator          // nil turns authentication off, every request is then allowed
+	rateLimiters   map[string]*rateLimiter // limiter of each route group, a group without one is unlimited
+	accessLog      bool                    // whether every request is logged
+	trustedProxies []string                // may set X-Forwarded-For and X-Real-IP
+	openAPI        *openAPIDocument        // request bodies are validated against it
+
+	// stopping is closed when a graceful shutdown begins, so requests that would otherwise
+	// never finish, such as event streams, end and let the shutdown complete
+	stopping     chan struct{}
+	stoppingOnce sync.Once
+
+	// Services used by the handlers, one per resource type
+	Engineers EngineerService
+	Devs      DevService
+	Ops       OpsService
+	DevOps    DevOpsService
+}
+
+// NewServer returns a server working on stores, naming new resources with ids and
+// timestamping changes with now. It starts with an audit log in memory, keeps
+// Idempotency-Key responses for a day, sends no webhooks and logs every request, without
+// authentication or rate limits.
+func NewServer(stores Stores, ids idGenerator, now func() time.Time) *Server {
+	s := &Server{
+		stores:        stores,
+		engineerStore: stores.Engineers,
+		devStore:      stores.Devs,
+		opsStore:      stores.Ops,
+		devOpsStore:   stores.DevOps,
+		archiveStore:  stores.Archive,
+		faults:        stores.Faults,
+		ids:           ids,
+		now:           now,
+		mu:            timedRWMutex{name: "units_of_work"},
+		search:        newInvertedIndex(),
+		events:        newEventBus(now),
+		audit:         newMemoryAuditSink(),
+		idempotency:   newIdempotencyStore(24*time.Hour, now),
+
+		retentionInterval: time.Hour,
+		rateLimiters:      map[string]*rateLimiter{},
+		accessLog:         true,
+		openAPI:           embeddedOpenAPI,
+		stopping:          make(chan struct{}),
+	}
+	s.storeSize = &gaugeFunc{name: "devops_store_resources",
+		help: "Resources in each store.", label: "resource", collect: s.storeSizes}
+	services := newRequestServices(s.scope(stores, nil, nil))
+	s.Engineers, s.Devs, s.Ops, s.DevOps = services.Engineers, services.Devs, services.Ops, services.DevOps
+	s.reindex()
+	return s
+}
+
+// Close stops the background jobs and flushes and closes the stores and the audit log,
+// once no request can use them anymore
+func (s *Server) Close() error {
+	if err := s.configureWebhooks(""); err != nil {
+		return err
+	}
+	if err := s.configureRetention(""); err != nil {
+		return err
+	}
+	return errors.Join(s.stores.Close(), s.closeAudit())
+}
+
+// serve serves handler, a router of s, on listener until ctx is done, then stops accepting
+// connections and waits up to cfg.ShutdownTimeout for the requests in flight to finish
+func (s *Server) serve(ctx context.Context, cfg *config, listener net.Listener, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		if cfg.TLSCert != "" {
+			served <- srv.ServeTLS(listener, cfg.TLSCert, cfg.TLSKey)
+		} else {
+			served <- srv.Serve(listener)
+		}
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, waiting up to %s for requests to finish", cfg.ShutdownTimeout)
+	s.stoppingOnce.Do(func() { close(s.stopping) })
+	drain, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	err := srv.Shutdown(drain)
+	if err != nil {
+		srv.Close()
+		err = fmt.Errorf("requests still running after %s were cut off: %w", cfg.ShutdownTimeout, err)
+	}
+	if served := <-served; !errors.Is(served, http.ErrServerClosed) {
+		return served
+	}
+	return err
+}
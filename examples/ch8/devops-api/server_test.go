@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestServer returns a server of its own on empty memory stores, numbering new
+// resources 1, 2, 3... and closed when the test ends
+func newTestServer(t *testing.T) *Server {
+	s := NewServer(newMemoryStores(), newSequentialGenerator(), time.Now)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// newTestRouter returns the router of s, failing the test when it can't be built
+func newTestRouter(t testing.TB, s *Server) *gin.Engine {
+	router, err := NewRouter(s)
+	if err != nil {
+		t.Fatalf("failed to build the router: %v", err)
+	}
+	return router
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	started := make(chan bool)
+	router.GET("/slow", func(c *gin.Context) {
+		started <- true
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := "http://" + listener.Addr().String()
+	cfg := defaultConfig()
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx, cfg, listener, router) }()
+
+	stream, err := http.Get(base + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Body.Close()
+	slow := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(base + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+	<-started
+	shutdown()
+
+	if body := <-slow; body != "done" {
+		t.Errorf("\nTest: request in flight is drained\nExpected: done, Received: %s", body)
+	}
+	if _, err := io.ReadAll(bufio.NewReader(stream.Body)); err != nil {
+		t.Errorf("\nTest: event stream ends\nExpected: the stream to end cleanly, Received: %v", err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("\nTest: shutdown completes\nExpected: no error, Received: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected: serve to return after shutting down, Received: still serving")
+	}
+	if _, err := http.Get(base + "/healthz"); err == nil {
+		t.Errorf("\nTest: new connections are refused\nExpected: an error, Received: none")
+	}
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	s := newTestServer(t)
+	gin.SetMode(gin.TestMode)
+	release := make(chan bool)
+	defer close(release)
+	started := make(chan bool)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- true
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultConfig()
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+	ctx, shutdown := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- s.serve(ctx, cfg, listener, handler) }()
+	go http.Get("http://" + listener.Addr().String() + "/stuck")
+	<-started
+	shutdown()
+
+	if err := <-served; err == nil || !strings.Contains(err.Error(), "cut off") {
+		t.Errorf("Expected: the stuck request to be cut off, Received: %v", err)
+	}
+}
+
+func TestServerClose(t *testing.T) {
+	dir := t.TempDir()
+	s := newSQLiteServer(t, "")
+	if err := s.configureAudit(auditFile, filepath.Join(dir, "audit.jsonl")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.configureRetention("720h"); err != nil {
+		t.Fatal(err)
+	}
+	s.audit.Append(&auditEntry{Resource: "engineers", ResourceID: "E1"})
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Expected: the backends to close, Received: %v", err)
+	}
+	if s.retention != nil || s.webhooks != nil {
+		t.Errorf("Expected: the background jobs to stop, Received: retention %v webhooks %v", s.retention, s.webhooks)
+	}
+	if err := s.engineerStore.(*SQLiteEngineerStore).Ping(context.Background()); err == nil {
+		t.Errorf("Expected: the database to be closed, Received: still open")
+	}
+	if raw, _ := os.ReadFile(filepath.Join(dir, "audit.jsonl")); !strings.Contains(string(raw), `"E1"`) {
+		t.Errorf("Expected: the audit entry on disk, Received: %s", raw)
+	}
+}
+
+func TestServersAreIsolated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, name := range []string{"bob", "alice", "carol", "dave"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			s := newTestServer(t)
+			router := newTestRouter(t, s)
+
+			// every server numbers its resources from 1 and knows only its own engineer
+			w := mockConditionalRequest(router, "POST", "/engineers", "", `{"name": "`+name+`", "email": "`+name+`@liatrio.com"}`)
+			if w.Code != http.StatusCreated || w.Header().Get("ETag") == "" {
+				t.Fatalf("Expected: Status Code 201, Received: %d %s", w.Code, w.Body.String())
+			}
+			if engineer, err := s.Engineers.Get("1"); err != nil || engineer.Name != name {
+				t.Errorf("Expected: %s as engineer 1, Received: %v %v", name, engineer, err)
+			}
+			if engineers := s.Engineers.List(); len(engineers) != 1 {
+				t.Errorf("Expected: only %s, Received: %d engineers", name, len(engineers))
+			}
+			if results := s.search.search("bob", ""); (name == "bob") != (len(results) == 1) {
+				t.Errorf("Expected: the search index to hold only %s, Received: %+v", name, results)
+			}
+			events, _, unsubscribe := s.events.subscribe(0, 1)
+			unsubscribe()
+			if len(events) != 1 {
+				t.Errorf("Expected: the event of %s only, Received: %+v", name, events)
+			}
+		})
+	}
+}
+
+func TestServerSettingsAreIsolated(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secured, open := newTestServer(t), newTestServer(t)
+	useAuth(t, secured)
+	if err := secured.configureRateLimits(rateLimit{Rate: 0.001, Burst: 1}, rateLimit{}); err != nil {
+		t.Fatal(err)
+	}
+	securedRouter, openRouter := newTestRouter(t, secured), newTestRouter(t, open)
+
+	if w := mockConditionalRequest(securedRouter, "GET", "/engineers", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("\nTest: server with authentication\nExpected: Status Code %d, Received: Status Code %d", http.StatusUnauthorized, w.Code)
+	}
+	for i := 0; i < 3; i++ {
+		if w := mockConditionalRequest(openRouter, "GET", "/engineers", "", ""); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+			t.Errorf("\nTest: server without authentication or rate limits\nExpected: Status Code %d, Received: Status Code %d %q",
+				http.StatusOK, w.Code, w.Header().Get("X-RateLimit-Limit"))
+		}
+	}
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- secured.serve(ctx, defaultConfig(), listener, securedRouter) }()
+	shutdown()
+	<-served
+	select {
+	case <-open.stopping:
+		t.Errorf("Expected: shutting down one server to leave the event streams of the other open")
+	default:
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// Services are what the handlers do to each resource type. Every change runs in a unit of
+// work of the server and fails with an apiError the handlers write as a problem.
+//
+// Get returns a group with its members expanded into full resources, List returns groups
+// with their members as ID references. version is the version a resource must still be
+// at for Update and Delete to apply, or anyVersion. actor is who Delete archives the
+// resource for.
+type EngineerService interface {
+	Create(engineer devops_resource.Engineer) (*devops_resource.Engineer, error)
+	Get(id string) (*devops_resource.Engineer, error)
+	GetByName(name string) (*devops_resource.Engineer, error)
+	GetByEmail(email string) (*devops_resource.Engineer, error)
+	List() []*devops_resource.Engineer
+	Version(id string) int
+	Update(id string, engineer devops_resource.Engineer, version int) error
+	Delete(id string, version int, actor string) error
+	Restore(id string) (*devops_resource.Engineer, error)
+}
+
+type DevService interface {
+	Create(dev devops_resource.Dev) (*devops_resource.Dev, error)
+	Get(id string) (*devops_resource.Dev, error)
+	GetByName(name string) (*devops_resource.Dev, error)
+	List() []*devops_resource.Dev
+	Version(id string) int
+	Update(id string, dev devops_resource.Dev, version int) error
+	Delete(id string, version int, actor string) error
+	Restore(id string) (*devops_resource.Dev, error)
+	AddEngineer(devID string, engineerID string) error
+	RemoveEngineer(devID string, engineerID string) error
+}
+
+type OpsService interface {
+	Create(op devops_resource.Ops) (*devops_resource.Ops, error)
+	Get(id string) (*devops_resource.Ops, error)
+	GetByName(name string) (*devops_resource.Ops, error)
+	List() []*devops_resource.Ops
+	Version(id string) int
+	Update(id string, op devops_resource.Ops, version int) error
+	Delete(id string, version int, actor string) error
+	Restore(id string) (*devops_resource.Ops, error)
+	AddEngineer(opID string, engineerID string) error
+	RemoveEngineer(opID string, engineerID string) error
+}
+
+type DevOpsService interface {
+	Create(devops devops_resource.DevOps) (*devops_resource.DevOps, error)
+	Get(id string) (*devops_resource.DevOps, error)
+	List() []*devops_resource.DevOps
+	Version(id string) int
+	Update(id string, devops devops_resource.DevOps, version int) error
+	Delete(id string, version int, actor string) error
+	Restore(id string) (*devops_resource.DevOps, error)
+	AddDev(devOpsID string, devID string) error
+	AddOps(devOpsID string, opID string) error
+	RemoveDev(devOpsID string, devID string) error
+	RemoveOps(devOpsID string, opID string) error
+}
+
+// The services of a server, implemented in create.go, read.go, update.go, delete.go
+// and restore.go. A scope gives them the stores they read, which record the database
+// errors of the request they serve, and the hooks of its units of work.
+type serviceScope struct {
+	*Server
+	engineerStore EngineerStorage
+	devStore      DevStorage
+	opsStore      OpsStorage
+	devOpsStore   DevOpsStorage
+	archiveStore  ArchiveStorage
+	faults        *storageFaults // database errors of the request, nil outside of one
+	hooks         []unitOfWorkHook
+}
+
+func (s *Server) scope(stores Stores, faults *storageFaults, hooks []unitOfWorkHook) serviceScope {
+	return serviceScope{
+		Server:        s,
+		engineerStore: stores.Engineers,
+		devStore:      stores.Devs,
+		opsStore:      stores.Ops,
+		devOpsStore:   stores.DevOps,
+		archiveStore:  stores.Archive,
+		faults:        faults,
+		hooks:         hooks,
+	}
+}
+
+func (s serviceScope) inTransaction(fn func(uow *unitOfWork) error) error {
+	return s.Server.inTransaction(fn, s.hooks...)
+}
+
+// missing returns err for a resource the stores didn't find, or the database error that
+// kept them from finding it
+func (s serviceScope) missing(err error) error {
+	if fault := s.faults.since(0); fault != nil {
+		return fault
+	}
+	return err
+}
+
+type engineerService struct{ serviceScope }
+type devService struct{ serviceScope }
+type opsService struct{ serviceScope }
+type devOpsService struct{ serviceScope }
+
+// requestServices are the services of a server working in one scope
+type requestServices struct {
+	serviceScope
+	Engineers EngineerService
+	Devs      DevService
+	Ops       OpsService
+	DevOps    DevOpsService
+}
+
+func newRequestServices(scope serviceScope) requestServices {
+	return requestServices{scope, engineerService{scope}, devService{scope}, opsService{scope}, devOpsService{scope}}
+}
+
+// forRequest returns the services of the server for the request of c. They read through
+// stores recording the database errors of the request, see failOnStorageFaults, and run
+// the unit of work hooks its middleware added followed by hooks.
+func (s *Server) forRequest(c *gin.Context, hooks ...unitOfWorkHook) requestServices {
+	stores, faults := s.stores, requestFaults(c)
+	if faults != nil {
+		stores = stores.withFaults(faults)
+	}
+	return newRequestServices(s.scope(stores, faults, append(append([]unitOfWorkHook{}, unitOfWorkHooks(c)...), hooks...)))
+}
+
+// inUnitOfWork returns the services of the server reading the stores of uow
+func (s *Server) inUnitOfWork(uow *unitOfWork) requestServices {
+	stores := Stores{Engineers: uow.engineers, Devs: uow.devs, Ops: uow.ops, DevOps: uow.devops, Archive: uow.archive}
+	return newRequestServices(s.scope(stores, uow.faults, nil))
+}
+
+// responder answers a change with the resource it leaves, read in the unit of work of the
+// change right before it commits. A database error then rolls the change back, rather
+// than failing the response to a change that was made and inviting a retry to repeat it.
+type responder struct {
+	server *Server
+	c      *gin.Context
+	status int
+	kind   string // the kind of the resource, the ID created for it is read when id is empty
+	id     string
+	read   func(in requestServices, id string) (body any, version int, err error)
+}
+
+func (s *Server) respondWithEngineer(c *gin.Context, status int, id string) *responder {
+	return &responder{s, c, status, archivedEngineer, id, func(in requestServices, id string) (any, int, error) {
+		engineer, err := in.Engineers.Get(id)
+		return engineer, in.Engineers.Version(id), err
+	}}
+}
+
+func (s *Server) respondWithDev(c *gin.Context, status int, id string, exp expansion) *responder {
+	return &responder{s, c, status, archivedDev, id, func(in requestServices, id string) (any, int, error) {
+		dev, err := in.Devs.Get(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return renderDev(dev, exp), in.Devs.Version(id), nil
+	}}
+}
+
+func (s *Server) respondWithOps(c *gin.Context, status int, id string, exp expansion) *responder {
+	return &responder{s, c, status, archivedOps, id, func(in requestServices, id string) (any, int, error) {
+		op, err := in.Ops.Get(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return renderOps(op, exp), in.Ops.Version(id), nil
+	}}
+}
+
+func (s *Server) respondWithDevOps(c *gin.Context, status int, id string, exp expansion) *responder {
+	return &responder{s, c, status, archivedDevOps, id, func(in requestServices, id string) (any, int, error) {
+		devops, err := in.DevOps.Get(id)
+		if err != nil {
+			return nil, 0, err
+		}
+		return renderDevOps(devops, exp), in.DevOps.Version(id), nil
+	}}
+}
+
+func (r *responder) begin(uow *unitOfWork) {}
+
+func (r *responder) prepare(uow *unitOfWork) (func(), error) {
+	id := r.id
+	if id == "" {
+		id = uow.created[r.kind]
+	}
+	body, version, err := r.read(r.server.inUnitOfWork(uow), id)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		setETag(r.c, version)
+		r.c.IndentedJSON(r.status, body)
+	}, nil
+}
+
+func (s engineerService) List() []*devops_resource.Engineer { return s.engineerStore.List() }
+func (s engineerService) Version(id string) int             { return s.engineerStore.Version(id) }
+func (s devService) List() []*devops_resource.Dev           { return s.devStore.List() }
+func (s devService) Version(id string) int                  { return s.devStore.Version(id) }
+func (s opsService) List() []*devops_resource.Ops           { return s.opsStore.List() }
+func (s opsService) Version(id string) int                  { return s.opsStore.Version(id) }
+func (s devOpsService) List() []*devops_resource.DevOps     { return s.devOpsStore.List() }
+func (s devOpsService) Version(id string) int               { return s.devOpsStore.Version(id) }
@@ -0,0 +1,807 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Schema for the SQLite backend, memberships are kept in join tables
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS engineers (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	role TEXT NOT NULL DEFAULT '',
+	skills TEXT NOT NULL DEFAULT '[]',
+	timezone TEXT NOT NULL DEFAULT '',
+	manager_id TEXT NOT NULL DEFAULT '',
+	on_call INTEGER NOT NULL DEFAULT 0,
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS devs (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS ops (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS devops (
+	id TEXT PRIMARY KEY,
+	version INTEGER NOT NULL DEFAULT 1
+);
+CREATE TABLE IF NOT EXISTS dev_engineers (
+	dev_id TEXT NOT NULL REFERENCES devs(id) ON DELETE CASCADE,
+	engineer_id TEXT NOT NULL REFERENCES engineers(id) ON DELETE CASCADE,
+	PRIMARY KEY (dev_id, engineer_id)
+);
+CREATE TABLE IF NOT EXISTS ops_engineers (
+	ops_id TEXT NOT NULL REFERENCES ops(id) ON DELETE CASCADE,
+	engineer_id TEXT NOT NULL REFERENCES engineers(id) ON DELETE CASCADE,
+	PRIMARY KEY (ops_id, engineer_id)
+);
+CREATE TABLE IF NOT EXISTS devops_devs (
+	devops_id TEXT NOT NULL REFERENCES devops(id) ON DELETE CASCADE,
+	dev_id TEXT NOT NULL REFERENCES devs(id) ON DELETE CASCADE,
+	PRIMARY KEY (devops_id, dev_id)
+);
+CREATE TABLE IF NOT EXISTS devops_ops (
+	devops_id TEXT NOT NULL REFERENCES devops(id) ON DELETE CASCADE,
+	ops_id TEXT NOT NULL REFERENCES ops(id) ON DELETE CASCADE,
+	PRIMARY KEY (devops_id, ops_id)
+);
+CREATE INDEX IF NOT EXISTS dev_engineers_by_engineer ON dev_engineers (engineer_id);
+CREATE INDEX IF NOT EXISTS ops_engineers_by_engineer ON ops_engineers (engineer_id);
+CREATE INDEX IF NOT EXISTS devops_devs_by_dev ON devops_devs (dev_id);
+CREATE INDEX IF NOT EXISTS devops_ops_by_ops ON devops_ops (ops_id);
+CREATE TABLE IF NOT EXISTS archive (
+	kind TEXT NOT NULL,
+	id TEXT NOT NULL,
+	archived_at INTEGER NOT NULL,
+	archived_by TEXT NOT NULL,
+	resource TEXT NOT NULL,
+	memberships TEXT NOT NULL,
+	PRIMARY KEY (kind, id)
+);
+`
+
+// sqliteMigrations bring databases created by older versions up to sqliteSchema,
+// each one may already have been applied
+var sqliteMigrations = []string{
+	"ALTER TABLE engineers ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+	"ALTER TABLE devs ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+	"ALTER TABLE ops ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+	"ALTER TABLE devops ADD COLUMN version INTEGER NOT NULL DEFAULT 1",
+	"ALTER TABLE engineers ADD COLUMN role TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE engineers ADD COLUMN skills TEXT NOT NULL DEFAULT '[]'",
+	"ALTER TABLE engineers ADD COLUMN timezone TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE engineers ADD COLUMN manager_id TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE engineers ADD COLUMN on_call INTEGER NOT NULL DEFAULT 0",
+	"CREATE INDEX IF NOT EXISTS engineers_by_manager ON engineers (manager_id)",
+}
+
+func migrateSQLite(db *sql.DB) error {
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			return fmt.Errorf("failed to migrate schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// openSQLite opens the database at path and creates the schema if needed
+func openSQLite(path string, schema string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// a single connection serialises writers and keeps :memory: databases shared
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create schema: %w", err)
+	}
+	return db, nil
+}
+
+// Thread-safe SQLite stores for each data type, all sharing one database.
+// db is the *sqliteDB, or the *sql.Tx of the unit of work the store belongs to.
+// faults collects the database errors of the methods that can't return one.
+type SQLiteEngineerStore struct {
+	db     queryer
+	faults *storageFaults
+}
+
+type SQLiteDevStore struct {
+	db     queryer
+	faults *storageFaults
+}
+
+type SQLiteOpsStore struct {
+	db     queryer
+	faults *storageFaults
+}
+
+type SQLiteDevOpsStore struct {
+	db     queryer
+	faults *storageFaults
+}
+
+// storageFaults keeps the database errors hit by store methods such as List and FindByID,
+// which can only answer that nothing was found. The unit of work and the request they ran
+// for check it and fail with a 500 rather than answer as if the data was missing.
+type storageFaults struct {
+	mu    sync.Mutex
+	count int
+	last  error
+}
+
+// record logs err and keeps it, a nil *storageFaults only logs
+func (f *storageFaults) record(err error) {
+	log.Printf("sqlite: %v", err)
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count++
+	f.last = err
+}
+
+// mark returns how many errors were recorded so far, to pass to since
+func (f *storageFaults) mark() int {
+	if f == nil {
+		return 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
+
+// since returns the last error recorded after mark, nil when there was none
+func (f *storageFaults) since(mark int) error {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.count == mark {
+		return nil
+	}
+	return fmt.Errorf("storage failed: %w", f.last)
+}
+
+// queryer is satisfied by *sql.DB, *sqliteDB and *sql.Tx
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// unitOfWorkWait is how long a query outside the open unit of work waits for it to end
+const unitOfWorkWait = 30 * time.Second
+
+// sqliteDB is the database of the stores outside a unit of work. The open unit of work
+// holds its only connection, so their queries wait for it to end first, for at most wait:
+// a store used inside the unit of work by mistake would otherwise wait for it forever.
+type sqliteDB struct {
+	*sql.DB
+	wait time.Duration
+
+	mu     sync.Mutex
+	ended  chan struct{}  // closed when the open unit of work ends, nil without one
+	faults *storageFaults // of the open unit of work, which fails when a query gives up
+}
+
+func (db *sqliteDB) Query(query string, args ...any) (*sql.Rows, error) {
+	if err := db.awaitUnitOfWork(); err != nil {
+		return nil, err
+	}
+	return db.DB.Query(query, args...)
+}
+
+func (db *sqliteDB) Exec(query string, args ...any) (sql.Result, error) {
+	if err := db.awaitUnitOfWork(); err != nil {
+		return nil, err
+	}
+	return db.DB.Exec(query, args...)
+}
+
+func (db *sqliteDB) Begin() (*sql.Tx, error) {
+	if err := db.awaitUnitOfWork(); err != nil {
+		return nil, err
+	}
+	return db.DB.Begin()
+}
+
+func (db *sqliteDB) awaitUnitOfWork() error {
+	db.mu.Lock()
+	ended, faults := db.ended, db.faults
+	db.mu.Unlock()
+	if ended == nil {
+		return nil
+	}
+	timer := time.NewTimer(db.wait)
+	defer timer.Stop()
+	select {
+	case <-ended:
+		return nil
+	case <-timer.C:
+		err := fmt.Errorf("sqlite: gave up after waiting %s for the open unit of work, is a store outside it used inside it?", db.wait)
+		faults.record(err)
+		return err
+	}
+}
+
+// beginUnitOfWork starts a unit of work whose stores share one database transaction
+func (db *sqliteDB) beginUnitOfWork() (*unitOfWork, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	faults, ended := &storageFaults{}, make(chan struct{})
+	db.mu.Lock()
+	db.ended, db.faults = ended, faults
+	db.mu.Unlock()
+	end := func() {
+		db.mu.Lock()
+		db.ended, db.faults = nil, nil
+		db.mu.Unlock()
+		close(ended)
+	}
+	return &unitOfWork{
+		engineers: &SQLiteEngineerStore{db: tx, faults: faults},
+		devs:      &SQLiteDevStore{db: tx, faults: faults},
+		ops:       &SQLiteOpsStore{db: tx, faults: faults},
+		devops:    &SQLiteDevOpsStore{db: tx, faults: faults},
+		archive:   &SQLiteArchiveStore{db: tx, faults: faults},
+		faults:    faults,
+		commit: func() error {
+			defer end()
+			return tx.Commit()
+		},
+		rollback: func() {
+			tx.Rollback()
+			end()
+		},
+	}, nil
+}
+
+// withTx runs fn inside a transaction, rolling back if fn fails. Inside a unit of work
+// a savepoint is used instead so the statements of one store call still apply together.
+func withTx(q queryer, fn func(tx queryer) error) error {
+	db, isDB := q.(interface{ Begin() (*sql.Tx, error) })
+	if !isDB {
+		if _, err := q.Exec("SAVEPOINT store_call"); err != nil {
+			return err
+		}
+		if err := fn(q); err != nil {
+			q.Exec("ROLLBACK TO store_call")
+			q.Exec("RELEASE store_call")
+			return err
+		}
+		_, err := q.Exec("RELEASE store_call")
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// sqliteStores returns the stores working on db, collecting their database errors in faults
+func sqliteStores(db *sqliteDB, faults *storageFaults) Stores {
+	return Stores{
+		Engineers:  &SQLiteEngineerStore{db: db, faults: faults},
+		Devs:       &SQLiteDevStore{db: db, faults: faults},
+		Ops:        &SQLiteOpsStore{db: db, faults: faults},
+		DevOps:     &SQLiteDevOpsStore{db: db, faults: faults},
+		Archive:    &SQLiteArchiveStore{db: db, faults: faults},
+		Faults:     faults,
+		begin:      db.beginUnitOfWork,
+		close:      db.Close,
+		withFaults: func(faults *storageFaults) Stores { return sqliteStores(db, faults) },
+	}
+}
+
+// execAffected runs a statement and reports whether it touched any rows
+func execAffected(q queryer, faults *storageFaults, query string, args ...any) bool {
+	result, err := q.Exec(query, args...)
+	if err != nil {
+		faults.record(err)
+		return false
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		faults.record(err)
+		return false
+	}
+	return rows > 0
+}
+
+// sqliteVersion returns the version of the row with id in table, 0 when there is none
+func sqliteVersion(q queryer, faults *storageFaults, table string, id string) int {
+	rows, err := q.Query("SELECT version FROM "+table+" WHERE id = ?", id)
+	if err != nil {
+		faults.record(err)
+		return 0
+	}
+	defer rows.Close()
+	version := 0
+	if rows.Next() {
+		err = rows.Scan(&version)
+	}
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		faults.record(err)
+	}
+	return version
+}
+
+// bumpVersion increments the version of a row, like ProductRepository.Update in the
+// optimistic locking example it only matches the row while it is still at version
+func bumpVersion(q queryer, faults *storageFaults, table string, id string, version int) error {
+	if execAffected(q, faults, "UPDATE "+table+" SET version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)", id, version, version) {
+		return nil
+	}
+	current := sqliteVersion(q, faults, table, id)
+	if current == 0 {
+		return errors.New(table + " not found in store")
+	}
+	return versionMismatch(id, current, version)
+}
+
+// changeMembership runs a join table statement and bumps the owning group's version
+// when it changed a row
+func changeMembership(db queryer, faults *storageFaults, table string, ownerID string, query string, args ...any) bool {
+	changed := false
+	err := withTx(db, func(tx queryer) error {
+		if changed = execAffected(tx, faults, query, args...); changed {
+			return bumpVersion(tx, faults, table, ownerID, anyVersion)
+		}
+		return nil
+	})
+	if err != nil {
+		faults.record(err)
+	}
+	return changed && err == nil
+}
+
+// engineerColumns are the columns queryEngineers scans, skills are stored as a JSON array
+const engineerColumns = "id, name, email, role, skills, timezone, manager_id, on_call"
+
+func encodeSkills(skills []string) string {
+	if len(skills) == 0 {
+		return "[]"
+	}
+	raw, _ := json.Marshal(skills)
+	return string(raw)
+}
+
+func queryEngineers(q queryer, faults *storageFaults, query string, args ...any) []*devops_resource.Engineer {
+	out := make([]*devops_resource.Engineer, 0)
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		faults.record(fmt.Errorf("failed to query engineers: %w", err))
+		return out
+	}
+	defer rows.Close()
+	for rows.Next() {
+		engineer := &devops_resource.Engineer{}
+		var skills string
+		if err := rows.Scan(&engineer.Id, &engineer.Name, &engineer.Email, &engineer.Role, &skills, &engineer.Timezone, &engineer.ManagerId, &engineer.OnCall); err != nil {
+			faults.record(fmt.Errorf("failed to scan engineer: %w", err))
+			continue
+		}
+		if err := json.Unmarshal([]byte(skills), &engineer.Skills); err != nil {
+			faults.record(fmt.Errorf("failed to decode skills of engineer %s: %w", engineer.Id, err))
+		}
+		if len(engineer.Skills) == 0 {
+			engineer.Skills = nil
+		}
+		out = append(out, engineer)
+	}
+	if err := rows.Err(); err != nil {
+		faults.record(fmt.Errorf("failed to read engineers: %w", err))
+	}
+	return out
+}
+
+// queryIDs collects the single id column returned by query
+func queryIDs(q queryer, faults *storageFaults, query string, args ...any) []string {
+	ids := make([]string, 0)
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		faults.record(fmt.Errorf("failed to query ids: %w", err))
+		return ids
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			faults.record(fmt.Errorf("failed to scan id: %w", err))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		faults.record(fmt.Errorf("failed to read ids: %w", err))
+	}
+	return ids
+}
+
+// queryGroups loads id/name rows and the engineer references of each group from the join table
+func queryGroups(q queryer, faults *storageFaults, joinTable string, joinColumn string, query string, args ...any) ([]string, []string, [][]*devops_resource.Engineer) {
+	var ids, names []string
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		faults.record(fmt.Errorf("failed to query %s: %w", joinTable, err))
+		return nil, nil, nil
+	}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			faults.record(fmt.Errorf("failed to scan %s: %w", joinTable, err))
+			continue
+		}
+		ids = append(ids, id)
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		faults.record(fmt.Errorf("failed to read %s: %w", joinTable, err))
+	}
+	rows.Close()
+
+	members := make([][]*devops_resource.Engineer, len(ids))
+	for i, id := range ids {
+		members[i] = make([]*devops_resource.Engineer, 0)
+		for _, engineerID := range queryIDs(q, faults, "SELECT engineer_id FROM "+joinTable+" WHERE "+joinColumn+" = ? ORDER BY rowid", id) {
+			members[i] = append(members[i], &devops_resource.Engineer{Id: engineerID})
+		}
+	}
+	return ids, names, members
+}
+
+func queryDevs(q queryer, faults *storageFaults, query string, args ...any) []*devops_resource.Dev {
+	ids, names, members := queryGroups(q, faults, "dev_engineers", "dev_id", query, args...)
+	out := make([]*devops_resource.Dev, 0, len(ids))
+	for i := range ids {
+		out = append(out, &devops_resource.Dev{Id: ids[i], Name: names[i], Engineers: members[i]})
+	}
+	return out
+}
+
+func queryOps(q queryer, faults *storageFaults, query string, args ...any) []*devops_resource.Ops {
+	ids, names, members := queryGroups(q, faults, "ops_engineers", "ops_id", query, args...)
+	out := make([]*devops_resource.Ops, 0, len(ids))
+	for i := range ids {
+		out = append(out, &devops_resource.Ops{Id: ids[i], Name: names[i], Engineers: members[i]})
+	}
+	return out
+}
+
+func queryDevOps(q queryer, faults *storageFaults, query string, args ...any) []*devops_resource.DevOps {
+	ids := queryIDs(q, faults, query, args...)
+	out := make([]*devops_resource.DevOps, 0, len(ids))
+	for _, id := range ids {
+		devops := &devops_resource.DevOps{
+			Id:   id,
+			Devs: make([]*devops_resource.Dev, 0),
+			Ops:  make([]*devops_resource.Ops, 0),
+		}
+		for _, devID := range queryIDs(q, faults, "SELECT dev_id FROM devops_devs WHERE devops_id = ? ORDER BY rowid", id) {
+			devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: devID})
+		}
+		for _, opsID := range queryIDs(q, faults, "SELECT ops_id FROM devops_ops WHERE devops_id = ? ORDER BY rowid", id) {
+			devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: opsID})
+		}
+		out = append(out, devops)
+	}
+	return out
+}
+
+// insertMembers writes the join rows linking a group to its members
+func insertMembers(tx queryer, joinTable string, ownerColumn string, memberColumn string, ownerID string, memberIDs []string) error {
+	query := "INSERT OR IGNORE INTO " + joinTable + " (" + ownerColumn + ", " + memberColumn + ") VALUES (?, ?)"
+	for _, memberID := range memberIDs {
+		if _, err := tx.Exec(query, ownerID, memberID); err != nil {
+			return fmt.Errorf("failed to add member %s to %s: %w", memberID, ownerID, err)
+		}
+	}
+	return nil
+}
+
+func firstOf[T any](items []*T) (*T, bool) {
+	if len(items) == 0 {
+		return nil, false
+	}
+	return items[0], true
+}
+
+// SQLiteEngineerStore methods
+func (s *SQLiteEngineerStore) Add(engineer *devops_resource.Engineer) error {
+	_, err := s.db.Exec("INSERT INTO engineers ("+engineerColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)", engineer.Id, engineer.Name, engineer.Email,
+		engineer.Role, encodeSkills(engineer.Skills), engineer.Timezone, engineer.ManagerId, engineer.OnCall)
+	if err != nil {
+		return fmt.Errorf("failed to create engineer: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteEngineerStore) Update(engineer *devops_resource.Engineer, version int) error {
+	return withTx(s.db, func(tx queryer) error {
+		if err := bumpVersion(tx, s.faults, "engineers", engineer.Id, version); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE engineers SET name = ?, email = ?, role = ?, skills = ?, timezone = ?, manager_id = ?, on_call = ? WHERE id = ?",
+			engineer.Name, engineer.Email, engineer.Role, encodeSkills(engineer.Skills), engineer.Timezone, engineer.ManagerId, engineer.OnCall, engineer.Id)
+		return err
+	})
+}
+
+func (s *SQLiteEngineerStore) Version(id string) int {
+	return sqliteVersion(s.db, s.faults, "engineers", id)
+}
+
+func (s *SQLiteEngineerStore) List() []*devops_resource.Engineer {
+	return queryEngineers(s.db, s.faults, "SELECT "+engineerColumns+" FROM engineers ORDER BY rowid")
+}
+
+func (s *SQLiteEngineerStore) FindByID(id string) (*devops_resource.Engineer, bool) {
+	return firstOf(queryEngineers(s.db, s.faults, "SELECT "+engineerColumns+" FROM engineers WHERE id = ?", id))
+}
+
+func (s *SQLiteEngineerStore) FindByName(name string) (*devops_resource.Engineer, bool) {
+	return firstOf(queryEngineers(s.db, s.faults, "SELECT "+engineerColumns+" FROM engineers WHERE name = ? ORDER BY rowid LIMIT 1", name))
+}
+
+func (s *SQLiteEngineerStore) FindByEmail(email string) (*devops_resource.Engineer, bool) {
+	return firstOf(queryEngineers(s.db, s.faults, "SELECT "+engineerColumns+" FROM engineers WHERE email = ? ORDER BY rowid LIMIT 1", email))
+}
+
+func (s *SQLiteEngineerStore) FindByManager(managerID string) []*devops_resource.Engineer {
+	if managerID == "" {
+		return make([]*devops_resource.Engineer, 0)
+	}
+	return queryEngineers(s.db, s.faults, "SELECT "+engineerColumns+" FROM engineers WHERE manager_id = ? ORDER BY rowid", managerID)
+}
+
+func (s *SQLiteEngineerStore) DeleteByID(id string) bool {
+	return execAffected(s.db, s.faults, "DELETE FROM engineers WHERE id = ?", id)
+}
+
+func (s *SQLiteEngineerStore) Clear() {
+	execAffected(s.db, s.faults, "DELETE FROM engineers")
+}
+
+// SQLiteDevStore methods
+func (s *SQLiteDevStore) Add(dev *devops_resource.Dev) error {
+	return withTx(s.db, func(tx queryer) error {
+		if _, err := tx.Exec("INSERT INTO devs (id, name) VALUES (?, ?)", dev.Id, dev.Name); err != nil {
+			return fmt.Errorf("failed to create dev: %w", err)
+		}
+		return insertMembers(tx, "dev_engineers", "dev_id", "engineer_id", dev.Id, engineerIDs(dev.Engineers))
+	})
+}
+
+func (s *SQLiteDevStore) Update(dev *devops_resource.Dev, version int) error {
+	return withTx(s.db, func(tx queryer) error {
+		if err := bumpVersion(tx, s.faults, "devs", dev.Id, version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE devs SET name = ? WHERE id = ?", dev.Name, dev.Id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM dev_engineers WHERE dev_id = ?", dev.Id); err != nil {
+			return err
+		}
+		return insertMembers(tx, "dev_engineers", "dev_id", "engineer_id", dev.Id, engineerIDs(dev.Engineers))
+	})
+}
+
+func (s *SQLiteDevStore) Version(id string) int {
+	return sqliteVersion(s.db, s.faults, "devs", id)
+}
+
+func (s *SQLiteDevStore) List() []*devops_resource.Dev {
+	return queryDevs(s.db, s.faults, "SELECT id, name FROM devs ORDER BY rowid")
+}
+
+func (s *SQLiteDevStore) FindByID(id string) (*devops_resource.Dev, bool) {
+	return firstOf(queryDevs(s.db, s.faults, "SELECT id, name FROM devs WHERE id = ?", id))
+}
+
+func (s *SQLiteDevStore) FindByName(name string) (*devops_resource.Dev, bool) {
+	return firstOf(queryDevs(s.db, s.faults, "SELECT id, name FROM devs WHERE name = ? ORDER BY rowid LIMIT 1", name))
+}
+
+func (s *SQLiteDevStore) FindByEngineer(engineerID string) []*devops_resource.Dev {
+	return queryDevs(s.db, s.faults, `SELECT devs.id, devs.name FROM dev_engineers JOIN devs ON devs.id = dev_engineers.dev_id
+		WHERE dev_engineers.engineer_id = ? ORDER BY dev_engineers.rowid`, engineerID)
+}
+
+func (s *SQLiteDevStore) DeleteByID(id string) bool {
+	return execAffected(s.db, s.faults, "DELETE FROM devs WHERE id = ?", id)
+}
+
+func (s *SQLiteDevStore) AddEngineerToDev(devID string, engineer *devops_resource.Engineer) bool {
+	return changeMembership(s.db, s.faults, "devs", devID, `INSERT OR IGNORE INTO dev_engineers (dev_id, engineer_id)
+		SELECT id, ? FROM devs WHERE id = ?`, engineer.Id, devID)
+}
+
+func (s *SQLiteDevStore) RemoveEngineerFromDev(devID string, engineerID string) error {
+	if !changeMembership(s.db, s.faults, "devs", devID, "DELETE FROM dev_engineers WHERE dev_id = ? AND engineer_id = ?", devID, engineerID) {
+		return errors.New("engineer not found in dev")
+	}
+	return nil
+}
+
+func (s *SQLiteDevStore) Clear() {
+	execAffected(s.db, s.faults, "DELETE FROM devs")
+}
+
+// SQLiteOpsStore methods
+func (s *SQLiteOpsStore) Add(ops *devops_resource.Ops) error {
+	return withTx(s.db, func(tx queryer) error {
+		if _, err := tx.Exec("INSERT INTO ops (id, name) VALUES (?, ?)", ops.Id, ops.Name); err != nil {
+			return fmt.Errorf("failed to create ops: %w", err)
+		}
+		return insertMembers(tx, "ops_engineers", "ops_id", "engineer_id", ops.Id, engineerIDs(ops.Engineers))
+	})
+}
+
+func (s *SQLiteOpsStore) Update(ops *devops_resource.Ops, version int) error {
+	return withTx(s.db, func(tx queryer) error {
+		if err := bumpVersion(tx, s.faults, "ops", ops.Id, version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE ops SET name = ? WHERE id = ?", ops.Name, ops.Id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM ops_engineers WHERE ops_id = ?", ops.Id); err != nil {
+			return err
+		}
+		return insertMembers(tx, "ops_engineers", "ops_id", "engineer_id", ops.Id, engineerIDs(ops.Engineers))
+	})
+}
+
+func (s *SQLiteOpsStore) Version(id string) int {
+	return sqliteVersion(s.db, s.faults, "ops", id)
+}
+
+func (s *SQLiteOpsStore) List() []*devops_resource.Ops {
+	return queryOps(s.db, s.faults, "SELECT id, name FROM ops ORDER BY rowid")
+}
+
+func (s *SQLiteOpsStore) FindByID(id string) (*devops_resource.Ops, bool) {
+	return firstOf(queryOps(s.db, s.faults, "SELECT id, name FROM ops WHERE id = ?", id))
+}
+
+func (s *SQLiteOpsStore) FindByName(name string) (*devops_resource.Ops, bool) {
+	return firstOf(queryOps(s.db, s.faults, "SELECT id, name FROM ops WHERE name = ? ORDER BY rowid LIMIT 1", name))
+}
+
+func (s *SQLiteOpsStore) FindByEngineer(engineerID string) []*devops_resource.Ops {
+	return queryOps(s.db, s.faults, `SELECT ops.id, ops.name FROM ops_engineers JOIN ops ON ops.id = ops_engineers.ops_id
+		WHERE ops_engineers.engineer_id = ? ORDER BY ops_engineers.rowid`, engineerID)
+}
+
+func (s *SQLiteOpsStore) DeleteByID(id string) bool {
+	return execAffected(s.db, s.faults, "DELETE FROM ops WHERE id = ?", id)
+}
+
+func (s *SQLiteOpsStore) AddEngineerToOp(opID string, engineer *devops_resource.Engineer) bool {
+	return changeMembership(s.db, s.faults, "ops", opID, `INSERT OR IGNORE INTO ops_engineers (ops_id, engineer_id)
+		SELECT id, ? FROM ops WHERE id = ?`, engineer.Id, opID)
+}
+
+func (s *SQLiteOpsStore) RemoveEngineerFromOp(opID string, engineerID string) error {
+	if !changeMembership(s.db, s.faults, "ops", opID, "DELETE FROM ops_engineers WHERE ops_id = ? AND engineer_id = ?", opID, engineerID) {
+		return errors.New("engineer not found in operation")
+	}
+	return nil
+}
+
+func (s *SQLiteOpsStore) Clear() {
+	execAffected(s.db, s.faults, "DELETE FROM ops")
+}
+
+// SQLiteDevOpsStore methods
+func (s *SQLiteDevOpsStore) Add(devops *devops_resource.DevOps) error {
+	return withTx(s.db, func(tx queryer) error {
+		if _, err := tx.Exec("INSERT INTO devops (id) VALUES (?)", devops.Id); err != nil {
+			return fmt.Errorf("failed to create devops: %w", err)
+		}
+		if err := insertMembers(tx, "devops_devs", "devops_id", "dev_id", devops.Id, devIDs(devops.Devs)); err != nil {
+			return err
+		}
+		return insertMembers(tx, "devops_ops", "devops_id", "ops_id", devops.Id, opsIDs(devops.Ops))
+	})
+}
+
+func (s *SQLiteDevOpsStore) Update(devops *devops_resource.DevOps, version int) error {
+	return withTx(s.db, func(tx queryer) error {
+		if err := bumpVersion(tx, s.faults, "devops", devops.Id, version); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM devops_devs WHERE devops_id = ?", devops.Id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM devops_ops WHERE devops_id = ?", devops.Id); err != nil {
+			return err
+		}
+		if err := insertMembers(tx, "devops_devs", "devops_id", "dev_id", devops.Id, devIDs(devops.Devs)); err != nil {
+			return err
+		}
+		return insertMembers(tx, "devops_ops", "devops_id", "ops_id", devops.Id, opsIDs(devops.Ops))
+	})
+}
+
+func (s *SQLiteDevOpsStore) Version(id string) int {
+	return sqliteVersion(s.db, s.faults, "devops", id)
+}
+
+func (s *SQLiteDevOpsStore) List() []*devops_resource.DevOps {
+	return queryDevOps(s.db, s.faults, "SELECT id FROM devops ORDER BY rowid")
+}
+
+func (s *SQLiteDevOpsStore) FindByID(id string) (*devops_resource.DevOps, bool) {
+	return firstOf(queryDevOps(s.db, s.faults, "SELECT id FROM devops WHERE id = ?", id))
+}
+
+func (s *SQLiteDevOpsStore) FindByDev(devID string) []*devops_resource.DevOps {
+	return queryDevOps(s.db, s.faults, "SELECT devops_id FROM devops_devs WHERE dev_id = ? ORDER BY rowid", devID)
+}
+
+func (s *SQLiteDevOpsStore) FindByOps(opsID string) []*devops_resource.DevOps {
+	return queryDevOps(s.db, s.faults, "SELECT devops_id FROM devops_ops WHERE ops_id = ? ORDER BY rowid", opsID)
+}
+
+func (s *SQLiteDevOpsStore) DeleteByID(id string) bool {
+	return execAffected(s.db, s.faults, "DELETE FROM devops WHERE id = ?", id)
+}
+
+func (s *SQLiteDevOpsStore) AddDevToDevOps(devOpsID string, dev *devops_resource.Dev) bool {
+	return changeMembership(s.db, s.faults, "devops", devOpsID, `INSERT OR IGNORE INTO devops_devs (devops_id, dev_id)
+		SELECT id, ? FROM devops WHERE id = ?`, dev.Id, devOpsID)
+}
+
+func (s *SQLiteDevOpsStore) AddOpsToDevOps(devOpsID string, ops *devops_resource.Ops) bool {
+	return changeMembership(s.db, s.faults, "devops", devOpsID, `INSERT OR IGNORE INTO devops_ops (devops_id, ops_id)
+		SELECT id, ? FROM devops WHERE id = ?`, ops.Id, devOpsID)
+}
+
+func (s *SQLiteDevOpsStore) RemoveDevFromDevOps(devOpsID string, devID string) error {
+	if !changeMembership(s.db, s.faults, "devops", devOpsID, "DELETE FROM devops_devs WHERE devops_id = ? AND dev_id = ?", devOpsID, devID) {
+		return errors.New("dev not found in devops")
+	}
+	return nil
+}
+
+func (s *SQLiteDevOpsStore) RemoveOpsFromDevOps(devOpsID string, opsID string) error {
+	if !changeMembership(s.db, s.faults, "devops", devOpsID, "DELETE FROM devops_ops WHERE devops_id = ? AND ops_id = ?", devOpsID, opsID) {
+		return errors.New("ops not found in devops")
+	}
+	return nil
+}
+
+func (s *SQLiteDevOpsStore) Clear() {
+	execAffected(s.db, s.faults, "DELETE FROM devops")
+}
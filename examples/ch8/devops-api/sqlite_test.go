@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// newSQLiteServer returns a server on the database file at path, a fresh one when path
+// is empty, numbering new resources 1, 2, 3... and closed when the test ends
+func newSQLiteServer(t *testing.T, path string) *Server {
+	if path == "" {
+		path = filepath.Join(t.TempDir(), "devops.db")
+	}
+	stores, err := openStores(storageSQLite, path)
+	if err != nil {
+		t.Fatalf("failed to open sqlite storage: %v", err)
+	}
+	s := NewServer(stores, newSequentialGenerator(), time.Now)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteDataSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devops.db")
+	s := newSQLiteServer(t, path)
+
+	engineer, err := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	dev, err := s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	op, err := s.Ops.Create(devops_resource.Ops{Name: "op_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if err := s.Devs.AddEngineer(dev.Id, engineer.Id); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	devops, err := s.DevOps.Create(devops_resource.DevOps{Devs: []*devops_resource.Dev{dev}, Ops: []*devops_resource.Ops{op}})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	// reopen the same file as a restarted server would
+	s.Close()
+	s = newSQLiteServer(t, path)
+
+	found, err := s.DevOps.Get(devops.Id)
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if len(found.Devs) != 1 || len(found.Ops) != 1 {
+		t.Fatalf("Expected 1 dev and 1 ops group, Received: %d and %d", len(found.Devs), len(found.Ops))
+	}
+	if len(found.Devs[0].Engineers) != 1 || found.Devs[0].Engineers[0].Email != "bob@bob.com" {
+		t.Errorf("Expected engineer membership to persist in dev group, Received: %v", found.Devs[0].Engineers)
+	}
+	if len(found.Ops[0].Engineers) != 1 || found.Ops[0].Engineers[0].Id != engineer.Id {
+		t.Errorf("Expected engineer membership to persist in ops group, Received: %v", found.Ops[0].Engineers)
+	}
+}
+
+func TestSQLiteUpdateAndDeleteEngineer(t *testing.T) {
+	s := newSQLiteServer(t, "")
+
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	dev, _ := s.Devs.Create(devops_resource.Dev{Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{engineer}})
+
+	if err := s.Engineers.Update(engineer.Id, devops_resource.Engineer{Name: "not bob", Email: "notbob@bob.com"}, anyVersion); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	found, _ := s.Devs.Get(dev.Id)
+	if found.Engineers[0].Name != "not bob" {
+		t.Errorf("Expected updated engineer name in dev group, Received: %s", found.Engineers[0].Name)
+	}
+
+	if err := s.Engineers.Delete(engineer.Id, anyVersion, ""); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	found, _ = s.Devs.Get(dev.Id)
+	if len(found.Engineers) != 0 {
+		t.Errorf("Expected engineer to be removed from dev group, Received: %v", found.Engineers)
+	}
+	if _, err := s.Engineers.Get(engineer.Id); err == nil {
+		t.Errorf("Error: Expected Errors, recieved none.")
+	}
+}
+
+func TestSQLiteErrorsFailRequests(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	router := newTestRouter(t, s)
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+
+	w := mockConditionalRequest(router, "GET", "/engineers", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "bob@bob.com") {
+		t.Fatalf("\nTest: healthy database\nExpected: Status Code 200 listing bob, Received: %d %s", w.Code, w.Body.String())
+	}
+
+	// every query on engineers fails from now on, while the database stays open
+	if _, err := s.engineerStore.(*SQLiteEngineerStore).db.Exec("ALTER TABLE engineers RENAME TO lost_engineers"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	for _, tc := range []struct {
+		description string
+		method      string
+		url         string
+		body        string
+	}{
+		{"list", "GET", "/engineers", ""},
+		{"read", "GET", "/engineers/id/" + engineer.Id, ""},
+		{"group members", "POST", "/dev", `{"name":"dev_ferrets","engineers":[{"id":"` + engineer.Id + `"}]}`},
+		{"delete", "DELETE", "/engineers/" + engineer.Id, ""},
+	} {
+		w := mockConditionalRequest(router, tc.method, tc.url, "", tc.body)
+		var body problem
+		json.Unmarshal(w.Body.Bytes(), &body)
+		if w.Code != http.StatusInternalServerError || body.Code != "internal_error" {
+			t.Errorf("\nTest: %s on a broken database\nExpected: Status Code 500 internal_error, Received: %d %s", tc.description, w.Code, w.Body.String())
+		}
+	}
+	if _, err := s.Devs.GetByName("dev_ferrets"); err == nil {
+		t.Errorf("\nTest: failed unit of work is rolled back\nExpected: no dev group dev_ferrets, Received: one")
+	}
+}
+
+func TestSQLiteFaultAfterWriteRollsBack(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	router := newTestRouter(t, s)
+	db := s.devStore.(*SQLiteDevStore).db
+
+	// the dev is inserted, but reading it back for the response fails
+	if _, err := db.Exec("ALTER TABLE dev_engineers RENAME TO lost_dev_engineers"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	w := mockConditionalRequest(router, "POST", "/dev", "", `{"name":"dev_ferrets"}`)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("\nTest: response can't be read\nExpected: Status Code 500, Received: %d %s", w.Code, w.Body.String())
+	}
+	if _, err := db.Exec("ALTER TABLE lost_dev_engineers RENAME TO dev_engineers"); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	if _, err := s.Devs.GetByName("dev_ferrets"); err == nil {
+		t.Fatalf("\nTest: failed response rolls back the write\nExpected: no dev group dev_ferrets, Received: one")
+	}
+
+	// other requests don't see the fault, and a retry creates the dev once
+	if w := mockConditionalRequest(router, "GET", "/dev", "", ""); w.Code != http.StatusOK {
+		t.Errorf("\nTest: fault stays with its request\nExpected: Status Code 200, Received: %d %s", w.Code, w.Body.String())
+	}
+	if w := mockConditionalRequest(router, "POST", "/dev", "", `{"name":"dev_ferrets"}`); w.Code != http.StatusCreated {
+		t.Fatalf("\nTest: retry\nExpected: Status Code 201, Received: %d %s", w.Code, w.Body.String())
+	}
+	if devs := s.devStore.List(); len(devs) != 1 {
+		t.Errorf("\nTest: retry\nExpected: 1 dev group, Received: %d", len(devs))
+	}
+}
+
+func TestSQLiteStoreOutsideUnitOfWorkFails(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	engineer, _ := s.Engineers.Create(devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"})
+	s.engineerStore.(*SQLiteEngineerStore).db.(*sqliteDB).wait = 50 * time.Millisecond
+
+	// the unit of work holds the only connection, the server's own store can't get one
+	done := make(chan error, 1)
+	go func() {
+		done <- s.inTransaction(func(uow *unitOfWork) error {
+			uow.engineers.DeleteByID(engineer.Id)
+			s.engineerStore.FindByID(engineer.Id)
+			return nil
+		})
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Errorf("\nTest: store outside the unit of work\nExpected: the unit of work to fail, Received: it committed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("\nTest: store outside the unit of work\nExpected: the unit of work to fail, Received: it hangs")
+	}
+	if _, err := s.Engineers.Get(engineer.Id); err != nil {
+		t.Errorf("\nTest: store outside the unit of work\nExpected: the delete rolled back, Received: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// Storage interfaces implemented by the in-memory stores and the SQLite backend.
+// Group resources returned by a store list their members as ID references only,
+// the resolver in resolve.go expands them into full resources at read time.
+//
+// Every resource has a version starting at 1 that is bumped by Update and by membership
+// changes. Update only applies when the stored version still equals version (pass
+// anyVersion to skip the check) and fails with a version_mismatch error otherwise.
+type EngineerStorage interface {
+	Add(engineer *devops_resource.Engineer) error
+	Update(engineer *devops_resource.Engineer, version int) error
+	Version(id string) int
+	List() []*devops_resource.Engineer
+	FindByID(id string) (*devops_resource.Engineer, bool)
+	FindByName(name string) (*devops_resource.Engineer, bool)
+	FindByEmail(email string) (*devops_resource.Engineer, bool)
+	// FindByManager returns the engineers reporting directly to managerID
+	FindByManager(managerID string) []*devops_resource.Engineer
+	DeleteByID(id string) bool
+	Clear()
+}
+
+type DevStorage interface {
+	Add(dev *devops_resource.Dev) error
+	Update(dev *devops_resource.Dev, version int) error
+	Version(id string) int
+	List() []*devops_resource.Dev
+	FindByID(id string) (*devops_resource.Dev, bool)
+	FindByName(name string) (*devops_resource.Dev, bool)
+	// FindByEngineer returns the groups engineerID is a member of
+	FindByEngineer(engineerID string) []*devops_resource.Dev
+	DeleteByID(id string) bool
+	AddEngineerToDev(devID string, engineer *devops_resource.Engineer) bool
+	RemoveEngineerFromDev(devID string, engineerID string) error
+	Clear()
+}
+
+type OpsStorage interface {
+	Add(ops *devops_resource.Ops) error
+	Update(ops *devops_resource.Ops, version int) error
+	Version(id string) int
+	List() []*devops_resource.Ops
+	FindByID(id string) (*devops_resource.Ops, bool)
+	FindByName(name string) (*devops_resource.Ops, bool)
+	FindByEngineer(engineerID string) []*devops_resource.Ops
+	DeleteByID(id string) bool
+	AddEngineerToOp(opID string, engineer *devops_resource.Engineer) bool
+	RemoveEngineerFromOp(opID string, engineerID string) error
+	Clear()
+}
+
+type DevOpsStorage interface {
+	Add(devops *devops_resource.DevOps) error
+	Update(devops *devops_resource.DevOps, version int) error
+	Version(id string) int
+	List() []*devops_resource.DevOps
+	FindByID(id string) (*devops_resource.DevOps, bool)
+	// FindByDev and FindByOps return the devops groups a dev or ops group is a member of
+	FindByDev(devID string) []*devops_resource.DevOps
+	FindByOps(opsID string) []*devops_resource.DevOps
+	DeleteByID(id string) bool
+	AddDevToDevOps(devOpsID string, dev *devops_resource.Dev) bool
+	AddOpsToDevOps(devOpsID string, ops *devops_resource.Ops) bool
+	RemoveDevFromDevOps(devOpsID string, devID string) error
+	RemoveOpsFromDevOps(devOpsID string, opsID string) error
+	Clear()
+}
+
+// Storage backends selectable with -storage or DEVOPS_STORAGE
+const (
+	storageMemory = "memory"
+	storageSQLite = "sqlite"
+)
+
+// Stores are the stores of one server, all on the same backend. Faults collects the
+// database errors of the SQLite stores, it is nil for the memory backend.
+type Stores struct {
+	Engineers EngineerStorage
+	Devs      DevStorage
+	Ops       OpsStorage
+	DevOps    DevOpsStorage
+	Archive   ArchiveStorage
+	Faults    *storageFaults
+
+	// begin starts a unit of work on the stores and close releases what they hold, nil
+	// for stores with nothing to release
+	begin func() (*unitOfWork, error)
+	close func() error
+	// withFaults returns the same stores collecting their database errors in faults
+	// instead, nil for a backend without any
+	withFaults func(faults *storageFaults) Stores
+}
+
+// Begin starts a unit of work on the stores, see inTransaction
+func (st Stores) Begin() (*unitOfWork, error) {
+	if st.begin == nil {
+		return nil, errors.New("units of work cannot be nested")
+	}
+	return st.begin()
+}
+
+// Close closes the database of the stores, the memory stores have nothing to flush
+func (st Stores) Close() error {
+	if st.close == nil {
+		return nil
+	}
+	return st.close()
+}
+
+// newMemoryStores returns empty stores kept in memory
+func newMemoryStores() Stores {
+	engineers, devs, ops, devops, archive := newEngineerStore(), newDevStore(), newOpsStore(), newDevOpsStore(), newArchiveStore()
+	return Stores{
+		Engineers: engineers,
+		Devs:      devs,
+		Ops:       ops,
+		DevOps:    devops,
+		Archive:   archive,
+		begin: func() (*unitOfWork, error) {
+			return beginMemoryUnitOfWork(engineers, devs, ops, devops, archive), nil
+		},
+	}
+}
+
+// openStores opens the stores of the requested backend, dbPath is the database file
+// of the sqlite backend
+func openStores(kind string, dbPath string) (Stores, error) {
+	switch kind {
+	case storageMemory:
+		return newMemoryStores(), nil
+	case storageSQLite:
+		db, err := openSQLite(dbPath, sqliteSchema)
+		if err != nil {
+			return Stores{}, err
+		}
+		if err := migrateSQLite(db); err != nil {
+			db.Close()
+			return Stores{}, err
+		}
+		return sqliteStores(&sqliteDB{DB: db, wait: unitOfWorkWait}, &storageFaults{}), nil
+	}
+	return Stores{}, errors.New("unknown storage backend " + kind)
+}
+
+const storageFaultsKey = "storage_faults"
+
+// failOnStorageFaults gives the request its own record of the database errors hit by the
+// stores it reads through forRequest. A unit of work run for it rolls back once there is
+// one, and a request that committed nothing is answered with a 500 instead of the 404
+// or empty list the store methods fell back to. The response is held back until then.
+func (s *Server) failOnStorageFaults() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.stores.withFaults == nil {
+			c.Next()
+			return
+		}
+		guard := &faultGuard{faults: &storageFaults{}}
+		c.Set(storageFaultsKey, guard.faults)
+		addUnitOfWorkHook(c, guard)
+		header := c.Writer.Header().Clone()
+		held := &heldResponse{ResponseWriter: c.Writer}
+		c.Writer = held
+		c.Next()
+		c.Writer = held.ResponseWriter
+		if err := guard.faults.since(0); err != nil && !guard.committed {
+			for name := range c.Writer.Header() {
+				delete(c.Writer.Header(), name)
+			}
+			for name, values := range header {
+				c.Writer.Header()[name] = values
+			}
+			writeError(c, err)
+			return
+		}
+		held.release()
+	}
+}
+
+func requestFaults(c *gin.Context) *storageFaults {
+	value, _ := c.Get(storageFaultsKey)
+	faults, _ := value.(*storageFaults)
+	return faults
+}
+
+// faultGuard fails the units of work of a request whose reads hit a database error, and
+// notes whether one committed, after which the response stands
+type faultGuard struct {
+	faults    *storageFaults
+	committed bool
+}
+
+func (g *faultGuard) begin(uow *unitOfWork) {}
+
+func (g *faultGuard) prepare(uow *unitOfWork) (func(), error) {
+	if err := g.faults.since(0); err != nil {
+		return nil, err
+	}
+	return func() { g.committed = true }, nil
+}
+
+// heldResponse keeps the status and body written by a handler until release
+type heldResponse struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *heldResponse) WriteHeader(status int) {
+	if status > 0 && w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *heldResponse) WriteHeaderNow() {
+	w.WriteHeader(http.StatusOK)
+}
+
+func (w *heldResponse) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.Write(data)
+}
+
+func (w *heldResponse) WriteString(data string) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.WriteString(data)
+}
+
+func (w *heldResponse) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *heldResponse) Size() int {
+	if w.status == 0 {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *heldResponse) Written() bool {
+	return w.status != 0
+}
+
+// release writes the held response
+func (w *heldResponse) release() {
+	if w.status == 0 {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.WriteHeaderNow()
+	w.ResponseWriter.Write(w.body.Bytes())
+}
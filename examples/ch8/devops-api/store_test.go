@@ -0,0 +1,164 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// benchmarkSizes are the numbers of engineers the store benchmarks run with
+var benchmarkSizes = []int{1000, 10000, 100000}
+
+func seedEngineerStore(n int) *EngineerStore {
+	store := newEngineerStore()
+	for i := 0; i < n; i++ {
+		id := strconv.Itoa(i)
+		store.Add(&devops_resource.Engineer{Id: "E" + id, Name: "engineer" + id, Email: "engineer" + id + "@bob.com"})
+	}
+	return store
+}
+
+func TestEngineerStoreKeepsInsertionOrder(t *testing.T) {
+	store := seedEngineerStore(5)
+	store.DeleteByID("E1")
+	store.DeleteByID("E3")
+	store.Add(&devops_resource.Engineer{Id: "E5", Name: "engineer5", Email: "engineer5@bob.com"})
+
+	expected := []string{"E0", "E2", "E4", "E5"}
+	engineers := store.List()
+	if len(engineers) != len(expected) {
+		t.Fatalf("Expected %d engineers, Received: %d", len(expected), len(engineers))
+	}
+	for i, engineer := range engineers {
+		if engineer.Id != expected[i] {
+			t.Errorf("Expected engineer %s at position %d, Received: %s", expected[i], i, engineer.Id)
+		}
+	}
+}
+
+func TestEngineerStoreIndexesFollowUpdates(t *testing.T) {
+	store := seedEngineerStore(3)
+	store.Update(&devops_resource.Engineer{Id: "E1", Name: "bob", Email: "bob@bob.com"}, anyVersion)
+
+	if _, found := store.FindByName("engineer1"); found {
+		t.Errorf("Expected old name to be removed from the index")
+	}
+	if _, found := store.FindByEmail("engineer1@bob.com"); found {
+		t.Errorf("Expected old email to be removed from the index")
+	}
+	if engineer, found := store.FindByName("bob"); !found || engineer.Id != "E1" {
+		t.Errorf("Expected new name to resolve to E1, Received: %v", engineer)
+	}
+	if engineer, found := store.FindByEmail("bob@bob.com"); !found || engineer.Id != "E1" {
+		t.Errorf("Expected new email to resolve to E1, Received: %v", engineer)
+	}
+
+	store.DeleteByID("E1")
+	if _, found := store.FindByName("bob"); found {
+		t.Errorf("Expected deleted engineer to be removed from the name index")
+	}
+	if err := store.Add(&devops_resource.Engineer{Id: "E0", Name: "dup", Email: "dup@bob.com"}); err == nil {
+		t.Errorf("Expected duplicate id to be rejected")
+	}
+}
+
+func TestGroupMembershipRemovalKeepsOrder(t *testing.T) {
+	store := newDevStore()
+	store.Add(&devops_resource.Dev{Id: "D1", Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{{Id: "E1"}, {Id: "E2"}, {Id: "E3"}}})
+	store.RemoveEngineerFromDev("D1", "E1")
+
+	dev, _ := store.FindByID("D1")
+	if len(dev.Engineers) != 2 || dev.Engineers[0].Id != "E2" || dev.Engineers[1].Id != "E3" {
+		t.Errorf("Expected engineers [E2 E3], Received: %v", engineerIDs(dev.Engineers))
+	}
+}
+
+// benchmarkStore runs lookup against stores of every size in benchmarkSizes, so that
+// an ns/op staying flat as the store grows shows the lookup is O(1)
+func benchmarkStore(b *testing.B, lookup func(store *EngineerStore, n int, i int)) {
+	for _, n := range benchmarkSizes {
+		b.Run("engineers="+strconv.Itoa(n), func(b *testing.B) {
+			store := seedEngineerStore(n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				lookup(store, n, i)
+			}
+		})
+	}
+}
+
+func BenchmarkEngineerStoreFindByID(b *testing.B) {
+	benchmarkStore(b, func(store *EngineerStore, n int, i int) {
+		store.FindByID("E" + strconv.Itoa(i%n))
+	})
+}
+
+func BenchmarkEngineerStoreFindByName(b *testing.B) {
+	benchmarkStore(b, func(store *EngineerStore, n int, i int) {
+		store.FindByName("engineer" + strconv.Itoa(i%n))
+	})
+}
+
+func BenchmarkEngineerStoreFindByEmail(b *testing.B) {
+	benchmarkStore(b, func(store *EngineerStore, n int, i int) {
+		store.FindByEmail("engineer" + strconv.Itoa(i%n) + "@bob.com")
+	})
+}
+
+func BenchmarkEngineerStoreDeleteAndAdd(b *testing.B) {
+	benchmarkStore(b, func(store *EngineerStore, n int, i int) {
+		id := "E" + strconv.Itoa(i%n)
+		engineer, _ := store.FindByID(id)
+		store.DeleteByID(id)
+		store.Add(engineer)
+	})
+}
+
+// searchNames returns n made-up names of 5 to 10 letters, the same ones every run
+func searchNames(n int) []string {
+	random := rand.New(rand.NewSource(1))
+	names := make([]string, n)
+	for i := range names {
+		name := make([]byte, 5+random.Intn(6))
+		for j := range name {
+			name[j] = byte('a' + random.Intn(26))
+		}
+		names[i] = string(name)
+	}
+	return names
+}
+
+// benchmarkSearch runs the query made from the names of the engineers against indexes
+// of every size in benchmarkSizes, an ns/op growing much slower than the index shows
+// lookups don't scan the vocabulary
+func benchmarkSearch(b *testing.B, query func(name string) string) {
+	for _, n := range benchmarkSizes {
+		b.Run("engineers="+strconv.Itoa(n), func(b *testing.B) {
+			names := searchNames(n)
+			engineers := make([]*devops_resource.Engineer, n)
+			for i, name := range names {
+				engineers[i] = &devops_resource.Engineer{Id: "E" + strconv.Itoa(i), Name: name, Email: name + "@liatrio.com"}
+			}
+			x := newInvertedIndex()
+			x.rebuild(engineers, nil, nil)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				x.search(query(names[i%n]), "")
+			}
+		})
+	}
+}
+
+func BenchmarkSearchExact(b *testing.B) {
+	benchmarkSearch(b, func(name string) string { return name })
+}
+
+func BenchmarkSearchPrefix(b *testing.B) {
+	benchmarkSearch(b, func(name string) string { return name[:4] })
+}
+
+func BenchmarkSearchTypo(b *testing.B) {
+	benchmarkSearch(b, func(name string) string { return name[1:2] + name[:1] + name[2:] })
+}
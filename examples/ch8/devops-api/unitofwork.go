@@ -0,0 +1,412 @@
+package main
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// unitOfWork groups changes to the four stores so they apply together or not at all.
+// Code running in a unit of work must only use its stores: with SQLite they share the
+// transaction, with the memory backend they journal every change so it can be undone.
+type unitOfWork struct {
+	engineers EngineerStorage
+	devs      DevStorage
+	ops       OpsStorage
+	devops    DevOpsStorage
+	archive   ArchiveStorage
+	faults    *storageFaults // database errors of its stores, nil in memory
+	commit    func() error
+	rollback  func()
+	events    []pendingEvent
+	now       func() time.Time  // the clock of the server, for timestamps stored by the unit of work
+	created   map[string]string // kind -> the last ID newID gave out for it
+}
+
+type pendingEvent struct {
+	kind eventType
+	data any
+}
+
+// unitOfWorkHook watches the units of work run for a request. begin runs before fn and
+// prepare once fn succeeded, both inside the transaction. An error from prepare rolls
+// the unit of work back, otherwise the function it returns runs once it committed.
+type unitOfWorkHook interface {
+	begin(uow *unitOfWork)
+	prepare(uow *unitOfWork) (committed func(), err error)
+}
+
+const unitOfWorkHooksKey = "unit_of_work_hooks"
+
+// addUnitOfWorkHook runs hook in every unit of work of the services serving the request
+// of c, see forRequest
+func addUnitOfWorkHook(c *gin.Context, hook unitOfWorkHook) {
+	c.Set(unitOfWorkHooksKey, append(unitOfWorkHooks(c), hook))
+}
+
+func unitOfWorkHooks(c *gin.Context) []unitOfWorkHook {
+	value, _ := c.Get(unitOfWorkHooksKey)
+	hooks, _ := value.([]unitOfWorkHook)
+	return hooks
+}
+
+// inTransaction runs fn in a unit of work, committing if it returns nil and rolling back
+// everything it changed otherwise. A database error its stores could not return fails it
+// too, whatever fn returned. Events queued by fn update the search index and are
+// published once it commits, after which the hooks learn it committed.
+func (s *Server) inTransaction(fn func(uow *unitOfWork) error, hooks ...unitOfWorkHook) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uow, err := s.stores.Begin()
+	if err != nil {
+		return err
+	}
+	uow.now = s.now
+	uow.created = map[string]string{}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			uow.rollback()
+			panic(recovered)
+		}
+	}()
+	for _, hook := range hooks {
+		hook.begin(uow)
+	}
+	err = fn(uow)
+	var committed []func()
+	for i := 0; err == nil && i < len(hooks); i++ {
+		var done func()
+		done, err = hooks[i].prepare(uow)
+		committed = append(committed, done)
+	}
+	if fault := uow.faults.since(0); fault != nil {
+		err = fault
+	}
+	if err != nil {
+		uow.rollback()
+		return err
+	}
+	if err := uow.commit(); err != nil {
+		return err
+	}
+	s.indexEvents(uow.events)
+	for _, event := range uow.events {
+		s.events.publish(event.kind, event.data)
+	}
+	for _, fn := range committed {
+		fn()
+	}
+	return nil
+}
+
+// publish queues an event until the unit of work commits
+func (uow *unitOfWork) publish(kind eventType, data any) {
+	uow.events = append(uow.events, pendingEvent{kind: kind, data: data})
+}
+
+// publishMembershipChanges queues an added or removed event for each member that
+// differs between the before and after member lists of a group
+func (uow *unitOfWork) publishMembershipChanges(added eventType, removed eventType, groupID string, before []string, after []string) {
+	for _, id := range before {
+		if !containsID(after, id) {
+			uow.publish(removed, membershipChange{GroupID: groupID, MemberID: id})
+		}
+	}
+	for _, id := range after {
+		if !containsID(before, id) {
+			uow.publish(added, membershipChange{GroupID: groupID, MemberID: id})
+		}
+	}
+}
+
+// consistentReads holds off units of work while a read handler runs, so a response
+// spanning several stores never shows a change that is only partly applied
+func (s *Server) consistentReads() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		c.Next()
+	}
+}
+
+// undoJournal collects functions restoring the state before each change, newest last
+type undoJournal []func()
+
+func (j *undoJournal) record(undo func()) {
+	*j = append(*j, undo)
+}
+
+func (j *undoJournal) undo() {
+	for i := len(*j) - 1; i >= 0; i-- {
+		(*j)[i]()
+	}
+	*j = nil
+}
+
+// beginMemoryUnitOfWork starts a unit of work whose stores journal their changes
+func beginMemoryUnitOfWork(engineers *EngineerStore, devs *DevStore, ops *OpsStore, devops *DevOpsStore, archive *ArchiveStore) *unitOfWork {
+	journal := &undoJournal{}
+	return &unitOfWork{
+		engineers: &journaledEngineerStore{EngineerStore: engineers, journal: journal},
+		devs:      &journaledDevStore{DevStore: devs, journal: journal},
+		ops:       &journaledOpsStore{OpsStore: ops, journal: journal},
+		devops:    &journaledDevOpsStore{DevOpsStore: devops, journal: journal},
+		archive:   &journaledArchiveStore{ArchiveStore: archive, journal: journal},
+		commit:    func() error { return nil },
+		rollback:  journal.undo,
+	}
+}
+
+// capture returns a function putting record id back the way it is now: same contents,
+// version and position, or absent if it doesn't exist yet
+func (s *EngineerStore) capture(id string) func() {
+	s.mu.RLock()
+	old, existed := s.engineers.get(id)
+	if existed {
+		old = cloneEngineer(old)
+	}
+	version, next := s.versions[id], s.engineers.next(id)
+	s.mu.RUnlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if current, found := s.engineers.remove(id); found {
+			s.byName.remove(current.Name, id)
+			s.byEmail.remove(current.Email, id)
+			s.byManager.removeIfSet(current.ManagerId, id)
+			delete(s.versions, id)
+		}
+		if existed {
+			s.engineers.insertBefore(next, id, old)
+			s.byName.add(old.Name, id)
+			s.byEmail.add(old.Email, id)
+			s.byManager.addIfSet(old.ManagerId, id)
+			s.versions[id] = version
+		}
+	}
+}
+
+func (s *DevStore) capture(id string) func() {
+	s.mu.RLock()
+	old, existed := s.developers.get(id)
+	if existed {
+		old = normalizeDev(old)
+	}
+	version, next := s.versions[id], s.developers.next(id)
+	s.mu.RUnlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if current, found := s.developers.remove(id); found {
+			s.byName.remove(current.Name, id)
+			s.byEngineer.removeAll(engineerIDs(current.Engineers), id)
+			delete(s.versions, id)
+		}
+		if existed {
+			s.developers.insertBefore(next, id, old)
+			s.byName.add(old.Name, id)
+			s.byEngineer.addAll(engineerIDs(old.Engineers), id)
+			s.versions[id] = version
+		}
+	}
+}
+
+func (s *OpsStore) capture(id string) func() {
+	s.mu.RLock()
+	old, existed := s.operations.get(id)
+	if existed {
+		old = normalizeOps(old)
+	}
+	version, next := s.versions[id], s.operations.next(id)
+	s.mu.RUnlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if current, found := s.operations.remove(id); found {
+			s.byName.remove(current.Name, id)
+			s.byEngineer.removeAll(engineerIDs(current.Engineers), id)
+			delete(s.versions, id)
+		}
+		if existed {
+			s.operations.insertBefore(next, id, old)
+			s.byName.add(old.Name, id)
+			s.byEngineer.addAll(engineerIDs(old.Engineers), id)
+			s.versions[id] = version
+		}
+	}
+}
+
+func (s *DevOpsStore) capture(id string) func() {
+	s.mu.RLock()
+	old, existed := s.developer_operations.get(id)
+	if existed {
+		old = normalizeDevOps(old)
+	}
+	version, next := s.versions[id], s.developer_operations.next(id)
+	s.mu.RUnlock()
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if current, found := s.developer_operations.remove(id); found {
+			s.byDev.removeAll(devIDs(current.Devs), id)
+			s.byOps.removeAll(opsIDs(current.Ops), id)
+			delete(s.versions, id)
+		}
+		if existed {
+			s.developer_operations.insertBefore(next, id, old)
+			s.byDev.addAll(devIDs(old.Devs), id)
+			s.byOps.addAll(opsIDs(old.Ops), id)
+			s.versions[id] = version
+		}
+	}
+}
+
+// Journaled memory stores record how to undo each change before making it
+type journaledEngineerStore struct {
+	*EngineerStore
+	journal *undoJournal
+}
+
+func (s *journaledEngineerStore) Add(engineer *devops_resource.Engineer) error {
+	s.journal.record(s.capture(engineer.Id))
+	return s.EngineerStore.Add(engineer)
+}
+
+func (s *journaledEngineerStore) Update(engineer *devops_resource.Engineer, version int) error {
+	s.journal.record(s.capture(engineer.Id))
+	return s.EngineerStore.Update(engineer, version)
+}
+
+func (s *journaledEngineerStore) DeleteByID(id string) bool {
+	s.journal.record(s.capture(id))
+	return s.EngineerStore.DeleteByID(id)
+}
+
+func (s *journaledEngineerStore) Clear() {
+	for _, engineer := range s.List() {
+		s.journal.record(s.capture(engineer.Id))
+	}
+	s.EngineerStore.Clear()
+}
+
+type journaledDevStore struct {
+	*DevStore
+	journal *undoJournal
+}
+
+func (s *journaledDevStore) Add(dev *devops_resource.Dev) error {
+	s.journal.record(s.capture(dev.Id))
+	return s.DevStore.Add(dev)
+}
+
+func (s *journaledDevStore) Update(dev *devops_resource.Dev, version int) error {
+	s.journal.record(s.capture(dev.Id))
+	return s.DevStore.Update(dev, version)
+}
+
+func (s *journaledDevStore) DeleteByID(id string) bool {
+	s.journal.record(s.capture(id))
+	return s.DevStore.DeleteByID(id)
+}
+
+func (s *journaledDevStore) AddEngineerToDev(devID string, engineer *devops_resource.Engineer) bool {
+	s.journal.record(s.capture(devID))
+	return s.DevStore.AddEngineerToDev(devID, engineer)
+}
+
+func (s *journaledDevStore) RemoveEngineerFromDev(devID string, engineerID string) error {
+	s.journal.record(s.capture(devID))
+	return s.DevStore.RemoveEngineerFromDev(devID, engineerID)
+}
+
+func (s *journaledDevStore) Clear() {
+	for _, dev := range s.List() {
+		s.journal.record(s.capture(dev.Id))
+	}
+	s.DevStore.Clear()
+}
+
+type journaledOpsStore struct {
+	*OpsStore
+	journal *undoJournal
+}
+
+func (s *journaledOpsStore) Add(ops *devops_resource.Ops) error {
+	s.journal.record(s.capture(ops.Id))
+	return s.OpsStore.Add(ops)
+}
+
+func (s *journaledOpsStore) Update(ops *devops_resource.Ops, version int) error {
+	s.journal.record(s.capture(ops.Id))
+	return s.OpsStore.Update(ops, version)
+}
+
+func (s *journaledOpsStore) DeleteByID(id string) bool {
+	s.journal.record(s.capture(id))
+	return s.OpsStore.DeleteByID(id)
+}
+
+func (s *journaledOpsStore) AddEngineerToOp(opID string, engineer *devops_resource.Engineer) bool {
+	s.journal.record(s.capture(opID))
+	return s.OpsStore.AddEngineerToOp(opID, engineer)
+}
+
+func (s *journaledOpsStore) RemoveEngineerFromOp(opID string, engineerID string) error {
+	s.journal.record(s.capture(opID))
+	return s.OpsStore.RemoveEngineerFromOp(opID, engineerID)
+}
+
+func (s *journaledOpsStore) Clear() {
+	for _, op := range s.List() {
+		s.journal.record(s.capture(op.Id))
+	}
+	s.OpsStore.Clear()
+}
+
+type journaledDevOpsStore struct {
+	*DevOpsStore
+	journal *undoJournal
+}
+
+func (s *journaledDevOpsStore) Add(devops *devops_resource.DevOps) error {
+	s.journal.record(s.capture(devops.Id))
+	return s.DevOpsStore.Add(devops)
+}
+
+func (s *journaledDevOpsStore) Update(devops *devops_resource.DevOps, version int) error {
+	s.journal.record(s.capture(devops.Id))
+	return s.DevOpsStore.Update(devops, version)
+}
+
+func (s *journaledDevOpsStore) DeleteByID(id string) bool {
+	s.journal.record(s.capture(id))
+	return s.DevOpsStore.DeleteByID(id)
+}
+
+func (s *journaledDevOpsStore) AddDevToDevOps(devOpsID string, dev *devops_resource.Dev) bool {
+	s.journal.record(s.capture(devOpsID))
+	return s.DevOpsStore.AddDevToDevOps(devOpsID, dev)
+}
+
+func (s *journaledDevOpsStore) AddOpsToDevOps(devOpsID string, ops *devops_resource.Ops) bool {
+	s.journal.record(s.capture(devOpsID))
+	return s.DevOpsStore.AddOpsToDevOps(devOpsID, ops)
+}
+
+func (s *journaledDevOpsStore) RemoveDevFromDevOps(devOpsID string, devID string) error {
+	s.journal.record(s.capture(devOpsID))
+	return s.DevOpsStore.RemoveDevFromDevOps(devOpsID, devID)
+}
+
+func (s *journaledDevOpsStore) RemoveOpsFromDevOps(devOpsID string, opsID string) error {
+	s.journal.record(s.capture(devOpsID))
+	return s.DevOpsStore.RemoveOpsFromDevOps(devOpsID, opsID)
+}
+
+func (s *journaledDevOpsStore) Clear() {
+	for _, devops := range s.List() {
+		s.journal.record(s.capture(devops.Id))
+	}
+	s.DevOpsStore.Clear()
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// seedUnitOfWork stores bob in D1 and O1, which are both in DO1, plus an unattached alice
+func seedUnitOfWork(t *testing.T, s *Server) {
+	chart := &orgChart{
+		Engineers: []*devops_resource.Engineer{{Id: "E1", Name: "bob", Email: "bob@bob.com"}, {Id: "E2", Name: "alice", Email: "alice@bob.com"}},
+		Devs:      []chartGroup{{Id: "D1", Name: "dev_ferrets", Engineers: []string{"E1"}}},
+		Ops:       []chartGroup{{Id: "O1", Name: "op_ferrets", Engineers: []string{"E1"}}},
+		DevOps:    []chartDevOps{{Id: "DO1", Devs: []string{"D1"}, Ops: []string{"O1"}}},
+	}
+	if err := s.importOrgChart(chart); err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+	s.Engineers.Update("E2", devops_resource.Engineer{Name: "alice", Email: "alice@bob.com"}, anyVersion)
+}
+
+func storeVersions(s *Server) []int {
+	return []int{
+		s.engineerStore.Version("E1"), s.engineerStore.Version("E2"),
+		s.devStore.Version("D1"), s.opsStore.Version("O1"), s.devOpsStore.Version("DO1"),
+	}
+}
+
+var errAbandoned = errors.New("abandoned")
+
+var rollbackTests = []struct {
+	description string
+	change      func(uow *unitOfWork) error
+}{
+	{"cascading delete", func(uow *unitOfWork) error {
+		uow.devs.RemoveEngineerFromDev("D1", "E1")
+		uow.ops.RemoveEngineerFromOp("O1", "E1")
+		uow.engineers.DeleteByID("E1")
+		return errAbandoned
+	}},
+	{"update and add", func(uow *unitOfWork) error {
+		uow.engineers.Add(&devops_resource.Engineer{Id: "E3", Name: "carol", Email: "carol@bob.com"})
+		uow.devs.AddEngineerToDev("D1", &devops_resource.Engineer{Id: "E3"})
+		uow.engineers.Update(&devops_resource.Engineer{Id: "E2", Name: "not alice", Email: "notalice@bob.com"}, anyVersion)
+		uow.devops.Update(&devops_resource.DevOps{Id: "DO1", Devs: []*devops_resource.Dev{}, Ops: []*devops_resource.Ops{}}, anyVersion)
+		return errAbandoned
+	}},
+	{"delete then recreate", func(uow *unitOfWork) error {
+		uow.devops.DeleteByID("DO1")
+		uow.devs.DeleteByID("D1")
+		uow.devs.Add(&devops_resource.Dev{Id: "D1", Name: "dev_stoats", Engineers: []*devops_resource.Engineer{}})
+		return errAbandoned
+	}},
+	{"clear everything", func(uow *unitOfWork) error {
+		uow.devops.Clear()
+		uow.devs.Clear()
+		uow.ops.Clear()
+		uow.engineers.Clear()
+		uow.engineers.Add(&devops_resource.Engineer{Id: "E3", Name: "carol", Email: "carol@bob.com"})
+		return errAbandoned
+	}},
+	{"archive and purge", func(uow *unitOfWork) error {
+		archiveResource(uow, archivedEngineer, "E1", "alice", &devops_resource.Engineer{Id: "E1"}, map[string][]string{})
+		uow.archive.Delete(archivedEngineer, "E1")
+		archiveResource(uow, archivedDev, "D1", "alice", devChart(&devops_resource.Dev{Id: "D1"}), map[string][]string{})
+		return errAbandoned
+	}},
+}
+
+func testRollback(t *testing.T, s *Server) {
+	seedUnitOfWork(t, s)
+	before, versions := s.exportOrgChart(), storeVersions(s)
+	_, stream, unsubscribe := s.events.subscribe(fromNow, subscriberBuffer)
+	defer unsubscribe()
+
+	for _, test := range rollbackTests {
+		err := s.inTransaction(func(uow *unitOfWork) error {
+			uow.publish(EngineerDeleted, deletedResource{Id: "E1"})
+			return test.change(uow)
+		})
+		if !errors.Is(err, errAbandoned) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, errAbandoned, err)
+		}
+		if after := s.exportOrgChart(); !reflect.DeepEqual(after, before) {
+			t.Errorf("\nTest: %s\nExpected: %+v, Received: %+v", test.description, before, after)
+		}
+		if after := storeVersions(s); !reflect.DeepEqual(after, versions) {
+			t.Errorf("\nTest: %s\nExpected: versions %v, Received: %v", test.description, versions, after)
+		}
+		if records := s.archiveStore.List(""); len(records) != 0 {
+			t.Errorf("\nTest: %s\nExpected: an empty archive, Received: %+v", test.description, records)
+		}
+		if _, err := s.Engineers.GetByName("bob"); err != nil {
+			t.Errorf("\nTest: %s\nExpected: bob to still be found by name, Received: %v", test.description, err)
+		}
+		if events := drainEvents(stream); len(events) != 0 {
+			t.Errorf("\nTest: %s\nExpected: no events, Received: %v", test.description, events)
+		}
+	}
+}
+
+func TestUnitOfWorkRollsBack(t *testing.T) {
+	s := newTestServer(t)
+	testRollback(t, s)
+}
+
+func TestSQLiteUnitOfWorkRollsBack(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	testRollback(t, s)
+}
+
+func TestUnitOfWorkPanicRollsBack(t *testing.T) {
+	s := newTestServer(t)
+	seedUnitOfWork(t, s)
+	before := s.exportOrgChart()
+	func() {
+		defer func() { recover() }()
+		s.inTransaction(func(uow *unitOfWork) error {
+			uow.engineers.DeleteByID("E2")
+			panic("boom")
+		})
+	}()
+	if after := s.exportOrgChart(); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected: %+v, Received: %+v", before, after)
+	}
+}
+
+// stressStores runs writers creating, linking and deleting resources while readers
+// export the org chart, every export must be a valid document
+func stressStores(t *testing.T, s *Server, writers int, rounds int) {
+	gin.SetMode(gin.TestMode)
+	router := newTestRouter(t, s)
+	op, err := s.Ops.Create(devops_resource.Ops{Name: "op_ferrets"})
+	if err != nil {
+		t.Fatalf("Error: %v", err)
+	}
+
+	var writing sync.WaitGroup
+	done := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		writing.Add(1)
+		go func(w int) {
+			defer writing.Done()
+			for i := 0; i < rounds; i++ {
+				if err := churn(s, fmt.Sprintf("w%dr%d", w, i), op); err != nil {
+					t.Errorf("\nTest: writer %d round %d\nError: %v", w, i, err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	var reading sync.WaitGroup
+	for r := 0; r < 4; r++ {
+		reading.Add(1)
+		go func() {
+			defer reading.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("GET", "/export", nil))
+				var chart orgChart
+				if err := json.Unmarshal(w.Body.Bytes(), &chart); w.Code != http.StatusOK || err != nil {
+					t.Errorf("Expected: Status Code 200 and an org chart, Received: Status Code %d\nBody: %s", w.Code, w.Body.String())
+					return
+				}
+				if problems := chart.validate(); len(problems) > 0 {
+					t.Errorf("Expected: a consistent export, Received: %v", problems)
+					return
+				}
+			}
+		}()
+	}
+
+	writing.Wait()
+	close(done)
+	reading.Wait()
+
+	if chart := s.exportOrgChart(); len(chart.Engineers) != 0 || len(chart.Devs) != 0 || len(chart.DevOps) != 0 {
+		t.Errorf("Expected: every churned resource to be deleted, Received: %+v", chart)
+	}
+}
+
+// churn creates an engineer, a dev group and a devops group named after prefix, moves
+// them around, abandons a change half way and deletes them again
+func churn(s *Server, prefix string, op *devops_resource.Ops) error {
+	engineer, err := s.Engineers.Create(devops_resource.Engineer{Name: prefix, Email: prefix + "@bob.com"})
+	if err != nil {
+		return err
+	}
+	dev, err := s.Devs.Create(devops_resource.Dev{Name: prefix})
+	if err != nil {
+		return err
+	}
+	if err := s.Devs.AddEngineer(dev.Id, engineer.Id); err != nil {
+		return err
+	}
+	devops, err := s.DevOps.Create(devops_resource.DevOps{Devs: []*devops_resource.Dev{{Id: dev.Id}}})
+	if err != nil {
+		return err
+	}
+	if err := s.DevOps.Update(devops.Id, devops_resource.DevOps{Devs: []*devops_resource.Dev{{Id: dev.Id}}, Ops: []*devops_resource.Ops{{Id: op.Id}}}, anyVersion); err != nil {
+		return err
+	}
+	// leaves dev pointing at a missing engineer until it is rolled back
+	if err := s.inTransaction(func(uow *unitOfWork) error {
+		uow.engineers.DeleteByID(engineer.Id)
+		runtime.Gosched()
+		return errAbandoned
+	}); !errors.Is(err, errAbandoned) {
+		return err
+	}
+	if err := s.Engineers.Delete(engineer.Id, anyVersion, ""); err != nil {
+		return err
+	}
+	if err := s.Devs.Delete(dev.Id, anyVersion, ""); err != nil {
+		return err
+	}
+	return s.DevOps.Delete(devops.Id, anyVersion, "")
+}
+
+func TestUnitOfWorkUnderConcurrency(t *testing.T) {
+	s := newTestServer(t)
+	stressStores(t, s, 8, 25)
+}
+
+func TestSQLiteUnitOfWorkUnderConcurrency(t *testing.T) {
+	s := newSQLiteServer(t, "")
+	stressStores(t, s, 4, 10)
+}
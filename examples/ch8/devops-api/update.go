@@ -1,190 +1,226 @@
 package main
 
 import (
-	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
 )
 
-// functions to update resources//
-func updateEngineer(engineer_id string, name string, email string) (bool, error) {
-	if !verifyEmail(email) {
-		return false, errors.New(" Email is invalid ")
-	}
-	if name == "" {
-		return false, errors.New(" Name cannot be empty ")
+// duplicateRef returns the first ID that appears more than once
+func duplicateRef(ids []string) (string, bool) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return id, true
+		}
+		seen[id] = true
 	}
-	//For updating global engineers map
-	engineer, err := findEngineer_by_Id(engineer_id)
-	if err == nil {
-		engineer.Email = email
-		engineer.Name = name
-	} else {
-		return false, errors.New(" Engineer doesn't exist ")
+	return "", false
+}
+
+// functions to update resources, version is the version the resource must still be at//
+func (s engineerService) Update(engineer_id string, engineer devops_resource.Engineer, version int) error {
+	engineer.Id = engineer_id
+	if errs := validation.Engineer(&engineer); len(errs) > 0 {
+		return invalidFields("engineer_invalid", "engineer "+engineer_id+" is invalid", errs)
 	}
-	return true, nil
+	return s.inTransaction(func(uow *unitOfWork) error {
+		if _, found := uow.engineers.FindByID(engineer_id); !found {
+			return notFound("engineer_not_found", "no engineer with id "+engineer_id)
+		}
+		if err := checkManager(uow, &engineer); err != nil {
+			return err
+		}
+		if err := uow.engineers.Update(&engineer, version); err != nil {
+			return err
+		}
+		uow.publish(EngineerUpdated, cloneEngineer(&engineer))
+		return nil
+	})
 }
 
-func updateDev(id string, newDev devops_resource.Dev) (bool, error) {
+func (s devService) Update(id string, newDev devops_resource.Dev, version int) error {
 	if newDev.Name == "" {
-		return false, errors.New(" Name cannot be empty ")
-	}
-	//For global dev map
-	dev, err := findDev_by_Id(id)
-	if err != nil {
-		return false, errors.New(" Doesn't exist in the developers group")
+		return invalid("name_required", "name cannot be empty")
 	}
-	dev.Name = newDev.Name
-	dev.Engineers = []*devops_resource.Engineer{}
-	for _, eng := range newDev.Engineers {
-		newEngineer, err := findEngineer_by_Id(eng.Id)
-		if err != nil {
-			return false, errors.New(" updatedev:engineer, couldnt find engineer in global array")
+	return s.inTransaction(func(uow *unitOfWork) error {
+		dev, found := uow.devs.FindByID(id)
+		if !found {
+			return notFound("dev_not_found", "no dev group with id "+id)
 		}
-		dev.Engineers = append(dev.Engineers, newEngineer)
-	}
-	return true, nil
+		if dup, found := duplicateRef(engineerIDs(newDev.Engineers)); found {
+			return invalid("duplicate_member", "engineer "+dup+" is listed more than once")
+		}
+		before := engineerIDs(dev.Engineers)
+		dev.Name = newDev.Name
+		dev.Engineers = []*devops_resource.Engineer{}
+		for _, eng := range newDev.Engineers {
+			if _, found := uow.engineers.FindByID(eng.Id); !found {
+				return invalid("engineer_not_found", "engineer "+eng.Id+" does not exist")
+			}
+			dev.Engineers = append(dev.Engineers, &devops_resource.Engineer{Id: eng.Id})
+		}
+		if err := uow.devs.Update(dev, version); err != nil {
+			return err
+		}
+		uow.publish(DevUpdated, devChart(dev))
+		uow.publishMembershipChanges(EngineerAddedToDev, EngineerRemovedFromDev, id, before, engineerIDs(dev.Engineers))
+		return nil
+	})
 }
 
-func updateOps(id string, newOp devops_resource.Ops) (bool, error) {
+func (s opsService) Update(id string, newOp devops_resource.Ops, version int) error {
 	if newOp.Name == "" {
-		return false, errors.New(" Name cannot be empty ")
+		return invalid("name_required", "name cannot be empty")
 	}
-	//For global dev map
-	op, err := findOp_by_Id(id)
-	if err != nil {
-		return false, errors.New(" Doesn't exist in the developers group")
-	}
-	op.Name = newOp.Name
-	op.Engineers = []*devops_resource.Engineer{}
-	for _, eng := range newOp.Engineers {
-		newEngineer, err := findEngineer_by_Id(eng.Id)
-		if err != nil {
-			return false, errors.New(" updatedev:engineer, couldnt find engineer in global array")
+	return s.inTransaction(func(uow *unitOfWork) error {
+		op, found := uow.ops.FindByID(id)
+		if !found {
+			return notFound("ops_not_found", "no ops group with id "+id)
 		}
-		op.Engineers = append(op.Engineers, newEngineer)
-	}
-	return true, nil
+		if dup, found := duplicateRef(engineerIDs(newOp.Engineers)); found {
+			return invalid("duplicate_member", "engineer "+dup+" is listed more than once")
+		}
+		before := engineerIDs(op.Engineers)
+		op.Name = newOp.Name
+		op.Engineers = []*devops_resource.Engineer{}
+		for _, eng := range newOp.Engineers {
+			if _, found := uow.engineers.FindByID(eng.Id); !found {
+				return invalid("engineer_not_found", "engineer "+eng.Id+" does not exist")
+			}
+			op.Engineers = append(op.Engineers, &devops_resource.Engineer{Id: eng.Id})
+		}
+		if err := uow.ops.Update(op, version); err != nil {
+			return err
+		}
+		uow.publish(OpsUpdated, opsChart(op))
+		uow.publishMembershipChanges(EngineerAddedToOps, EngineerRemovedFromOps, id, before, engineerIDs(op.Engineers))
+		return nil
+	})
 }
 
-func updateDevOps(id string, newDevOps devops_resource.DevOps) (bool, error) {
-	//For global dev map
-	devops, err := findDevOps_by_Id(id)
-	if err != nil {
-		return false, errors.New(" Doesn't exist in the developer_operations group")
-	}
-	devops.Devs = []*devops_resource.Dev{}
-	devops.Ops = []*devops_resource.Ops{}
-	for _, dev := range newDevOps.Devs {
-		newDev, err := findDev_by_Id(dev.Id)
-		if err != nil {
-			return false, errors.New(" updatedevops:dev, couldnt find dev in global array")
+func (s devOpsService) Update(id string, newDevOps devops_resource.DevOps, version int) error {
+	return s.inTransaction(func(uow *unitOfWork) error {
+		devops, found := uow.devops.FindByID(id)
+		if !found {
+			return notFound("devops_not_found", "no devops group with id "+id)
 		}
-		devops.Devs = append(devops.Devs, newDev)
-	}
-	for _, ops := range newDevOps.Ops {
-		newOp, err := findOp_by_Id(ops.Id)
-		if err != nil {
-			return false, errors.New(" updatedevops:ops, couldnt find op in global array")
+		if dup, found := duplicateRef(devIDs(newDevOps.Devs)); found {
+			return invalid("duplicate_member", "dev group "+dup+" is listed more than once")
 		}
-		devops.Ops = append(devops.Ops, newOp)
-	}
-	return true, nil
+		if dup, found := duplicateRef(opsIDs(newDevOps.Ops)); found {
+			return invalid("duplicate_member", "ops group "+dup+" is listed more than once")
+		}
+		devsBefore, opsBefore := devIDs(devops.Devs), opsIDs(devops.Ops)
+		devops.Devs = []*devops_resource.Dev{}
+		devops.Ops = []*devops_resource.Ops{}
+		for _, dev := range newDevOps.Devs {
+			if _, found := uow.devs.FindByID(dev.Id); !found {
+				return invalid("dev_not_found", "dev group "+dev.Id+" does not exist")
+			}
+			devops.Devs = append(devops.Devs, &devops_resource.Dev{Id: dev.Id})
+		}
+		for _, ops := range newDevOps.Ops {
+			if _, found := uow.ops.FindByID(ops.Id); !found {
+				return invalid("ops_not_found", "ops group "+ops.Id+" does not exist")
+			}
+			devops.Ops = append(devops.Ops, &devops_resource.Ops{Id: ops.Id})
+		}
+		if err := uow.devops.Update(devops, version); err != nil {
+			return err
+		}
+		uow.publish(DevOpsUpdated, devOpsChart(devops))
+		uow.publishMembershipChanges(DevAddedToDevOps, DevRemovedFromDevOps, id, devsBefore, devIDs(devops.Devs))
+		uow.publishMembershipChanges(OpsAddedToDevOps, OpsRemovedFromDevOps, id, opsBefore, opsIDs(devops.Ops))
+		return nil
+	})
 }
 
 //*****************************//
 
 // server PUT handler
-func putEngineer(c *gin.Context) {
+func (s *Server) putEngineer(c *gin.Context) {
 	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithEngineer(c, http.StatusOK, id))
 	var jsonData devops_resource.Engineer
 	err := c.ShouldBindJSON(&jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = updateEngineer(id, jsonData.Name, jsonData.Email)
+	version, err := ifMatch(c, id, v.Engineers.Version(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
-	engineer, err := findEngineer_by_Id(id)
+	err = v.Engineers.Update(id, jsonData, version)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, engineer)
 }
 
-func putDev(c *gin.Context) {
+func (s *Server) putDev(c *gin.Context) {
 	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithDev(c, http.StatusOK, id, expandAll))
 	var jsonData devops_resource.Dev
 	err := c.ShouldBindJSON(&jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = updateDev(id, jsonData)
+	version, err := ifMatch(c, id, v.Devs.Version(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
-	dev, err := findDev_by_Id(id)
+	err = v.Devs.Update(id, jsonData, version)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, dev)
 }
 
-func putOp(c *gin.Context) {
+func (s *Server) putOp(c *gin.Context) {
 	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithOps(c, http.StatusOK, id, expandAll))
 	var jsonData devops_resource.Ops
 	err := c.ShouldBindJSON(&jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = updateOps(id, jsonData)
+	version, err := ifMatch(c, id, v.Ops.Version(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
-	op, err := findOp_by_Id(id)
+	err = v.Ops.Update(id, jsonData, version)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, op)
 }
 
-func putDevOps(c *gin.Context) {
+func (s *Server) putDevOps(c *gin.Context) {
 	id := c.Param("id")
+	v := s.forRequest(c, s.respondWithDevOps(c, http.StatusOK, id, expandAll))
 	var jsonData devops_resource.DevOps
 	err := c.ShouldBindJSON(&jsonData)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, malformedBody(err))
 		return
 	}
 
-	_, err = updateDevOps(id, jsonData)
+	version, err := ifMatch(c, id, v.DevOps.Version(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
-	devops, err := findDevOps_by_Id(id)
+	err = v.DevOps.Update(id, jsonData, version)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		writeError(c, err)
 	}
-
-	c.IndentedJSON(http.StatusOK, devops)
 }
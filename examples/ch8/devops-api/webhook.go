@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Headers sent with every webhook delivery
+const (
+	webhookEventHeader     = "X-DevOps-Event"
+	webhookDeliveryHeader  = "X-DevOps-Delivery"
+	webhookSignatureHeader = "X-DevOps-Signature"
+)
+
+// webhookFile is the YAML (or JSON) file listing outbound webhooks
+type webhookFile struct {
+	MaxAttempts    int             `yaml:"max_attempts"`
+	InitialBackoff time.Duration   `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration   `yaml:"max_backoff"`
+	Timeout        time.Duration   `yaml:"timeout"`
+	Webhooks       []webhookConfig `yaml:"webhooks"`
+}
+
+// webhookConfig is one receiver, Events limits what it is sent and is every type when empty
+type webhookConfig struct {
+	URL    string      `yaml:"url"`
+	Secret string      `yaml:"secret"`
+	Events []eventType `yaml:"events"`
+}
+
+// webhookDispatcher posts domain events to each webhook in order. A failed delivery is
+// retried with exponential backoff, from initialBackoff doubling up to maxBackoff.
+type webhookDispatcher struct {
+	hooks          []webhookConfig
+	client         *http.Client
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	cancel         context.CancelFunc
+	done           sync.WaitGroup
+}
+
+// webhookBuffer is how many events a webhook may fall behind before it resubscribes
+const webhookBuffer = 1024
+
+// configureWebhooks stops the running dispatcher and starts one for the webhooks in path,
+// no webhooks are sent when path is empty
+func (s *Server) configureWebhooks(path string) error {
+	if s.webhooks != nil {
+		s.webhooks.stop()
+		s.webhooks = nil
+	}
+	if path == "" {
+		return nil
+	}
+	dispatcher, err := loadWebhooks(path)
+	if err != nil {
+		return err
+	}
+	dispatcher.start(s.events)
+	s.webhooks = dispatcher
+	return nil
+}
+
+func loadWebhooks(path string) (*webhookDispatcher, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+	file := webhookFile{MaxAttempts: 5, InitialBackoff: time.Second, MaxBackoff: time.Minute, Timeout: 10 * time.Second}
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks %s: %w", path, err)
+	}
+	if file.MaxAttempts < 1 || file.InitialBackoff <= 0 || file.MaxBackoff < file.InitialBackoff {
+		return nil, fmt.Errorf("webhooks %s: max_attempts must be at least 1 and max_backoff at least initial_backoff", path)
+	}
+	for i, hook := range file.Webhooks {
+		target, err := url.Parse(hook.URL)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return nil, fmt.Errorf("webhook %d needs an http or https url", i)
+		}
+		if hook.Secret == "" {
+			return nil, fmt.Errorf("webhook %d (%s) needs a secret to sign deliveries with", i, hook.URL)
+		}
+		if _, err := parseEventFilter(hook.Events); err != nil {
+			return nil, fmt.Errorf("webhook %d (%s): %w", i, hook.URL, err)
+		}
+	}
+	return &webhookDispatcher{
+		hooks:          file.Webhooks,
+		client:         &http.Client{Timeout: file.Timeout},
+		maxAttempts:    file.MaxAttempts,
+		initialBackoff: file.InitialBackoff,
+		maxBackoff:     file.MaxBackoff,
+	}, nil
+}
+
+// start delivers every event published on bus from now on
+func (d *webhookDispatcher) start(bus *eventBus) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d.cancel = cancel
+	for _, hook := range d.hooks {
+		filter, _ := parseEventFilter(hook.Events)
+		d.done.Add(1)
+		go func(after int64) {
+			defer d.done.Done()
+			d.run(ctx, bus, hook, filter, after)
+		}(bus.lastID())
+	}
+}
+
+// stop waits for in-flight deliveries to give up
+func (d *webhookDispatcher) stop() {
+	d.cancel()
+	d.done.Wait()
+}
+
+func (d *webhookDispatcher) run(ctx context.Context, bus *eventBus, hook webhookConfig, filter eventFilter, after int64) {
+	replay, stream, unsubscribe := bus.subscribe(after, webhookBuffer)
+	defer func() { unsubscribe() }()
+	last := after
+	handle := func(event domainEvent) {
+		last = event.ID
+		if !filter.matches(event.Type) {
+			return
+		}
+		if err := d.deliver(ctx, hook, event); err != nil && ctx.Err() == nil {
+			log.Printf("webhooks: giving up on event %d (%s) for %s: %v", event.ID, event.Type, hook.URL, err)
+		}
+	}
+	for {
+		for _, event := range replay {
+			handle(event)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case event, open := <-stream:
+			if !open {
+				// dropped for falling behind, pick up from the last event handled
+				unsubscribe()
+				replay, stream, unsubscribe = bus.subscribe(last, webhookBuffer)
+				continue
+			}
+			replay = []domainEvent{event}
+		}
+	}
+}
+
+// deliver posts event to hook until it is accepted, attempts run out or ctx is cancelled
+func (d *webhookDispatcher) deliver(ctx context.Context, hook webhookConfig, event domainEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	backoff := d.initialBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, hook, event, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == d.maxAttempts {
+			return fmt.Errorf("attempt %d: %w", attempt, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, d.maxBackoff)
+	}
+}
+
+// post sends one delivery, reporting whether a failure is worth retrying
+func (d *webhookDispatcher) post(ctx context.Context, hook webhookConfig, event domainEvent, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "devops-api-webhooks")
+	request.Header.Set(webhookEventHeader, string(event.Type))
+	request.Header.Set(webhookDeliveryHeader, strconv.FormatInt(event.ID, 10))
+	request.Header.Set(webhookSignatureHeader, signWebhook(hook.Secret, body))
+	response, err := d.client.Do(request)
+	if err != nil {
+		return true, err
+	}
+	response.Body.Close()
+	switch {
+	case response.StatusCode < 300:
+		return false, nil
+	case response.StatusCode >= 500, response.StatusCode == http.StatusRequestTimeout, response.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("receiver answered %s", response.Status)
+	}
+	return false, fmt.Errorf("receiver rejected the delivery with %s", response.Status)
+}
+
+// signWebhook is the value of X-DevOps-Signature: sha256= and the hex HMAC-SHA256 of the body
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
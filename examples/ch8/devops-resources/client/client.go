@@ -0,0 +1,302 @@
+// Package client is a typed Go client for the devops-api in examples/ch8/devops-api.
+//
+//	api, err := client.New("http://localhost:8080", client.WithToken(os.Getenv("DEVOPS_TOKEN")))
+//	if err != nil {
+//		return err
+//	}
+//	engineer, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "bob", Email: "bob@liatrio.com"})
+//	if errors.Is(err, client.ErrConflict) {
+//		// an engineer named bob already exists
+//	}
+//
+// Every method takes a context that bounds the whole call, retries included. Failed
+// requests return an *Error carrying the API's problem details.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy decides how often a request that failed with a 5xx or 429 answer or a
+// network error is sent again, waiting at least as long as the answer's Retry-After. Only requests that are safe to repeat are retried: GET, PUT and
+// DELETE, and any request sent with IfMatch, which the API refuses to apply twice.
+type RetryPolicy struct {
+	MaxAttempts    int           // attempts including the first one, 1 disables retries
+	InitialBackoff time.Duration // wait before the first retry, doubled for every further retry
+	MaxBackoff     time.Duration // upper bound of the wait, a Retry-After header can exceed it
+}
+
+// DefaultRetryPolicy is used unless WithRetryPolicy is given
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+// backoff is the wait before retry number n (1 for the first retry), with jitter so
+// clients that failed together don't retry together
+func (p RetryPolicy) backoff(n int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < n && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if wait <= 0 {
+		return 0
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// Client calls one devops-api instance. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+	userAgent  string
+	retry      RetryPolicy
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends requests through httpClient instead of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken sends token as a bearer token, either an API key or a JWT
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+// WithRetryPolicy replaces DefaultRetryPolicy
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// WithUserAgent sets the User-Agent header of every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) { c.userAgent = userAgent }
+}
+
+// New returns a client for the API at baseURL, such as http://localhost:8080
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be an absolute http or https URL", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+	parsed.RawPath, parsed.RawQuery, parsed.Fragment = "", "", ""
+	c := &Client{baseURL: parsed, httpClient: http.DefaultClient, userAgent: "devops-api-go-client", retry: DefaultRetryPolicy}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// RequestOption adjusts a single call
+type RequestOption func(*request)
+
+// IfMatch makes a PUT, PATCH or DELETE apply only while the resource still has etag,
+// as returned through ETag. Otherwise the call fails with ErrPrecondition.
+func IfMatch(etag string) RequestOption {
+	return func(r *request) { r.header.Set("If-Match", etag) }
+}
+
+// IdempotencyKey makes a POST safe to retry: the API answers a retry with the same key
+// and body with the response to the first attempt instead of applying it again, so the
+// call is retried like a read. Use a new key, such as a UUID, for every change.
+func IdempotencyKey(key string) RequestOption {
+	return func(r *request) { r.header.Set("Idempotency-Key", key) }
+}
+
+// ETag stores the ETag header of the response, the version of the returned resource,
+// in dst
+func ETag(dst *string) RequestOption {
+	return func(r *request) { r.etag = dst }
+}
+
+// request is everything needed to send a call again
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+	header      http.Header
+	etag        *string
+	received    http.Header // headers of the successful response
+}
+
+func newRequest(method string, path string, opts []RequestOption) *request {
+	r := &request{method: method, path: path, query: url.Values{}, header: http.Header{}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// withJSON sets body as the JSON request body
+func (r *request) withJSON(body any, contentType string) (*request, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %w", err)
+	}
+	r.body = raw
+	r.contentType = contentType
+	return r, nil
+}
+
+// repeatable reports whether sending r twice has the same effect as sending it once
+func (r *request) repeatable() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.header.Get("If-Match") != "" || r.header.Get("Idempotency-Key") != ""
+}
+
+// do sends r, retrying when the policy allows it, and decodes a successful response
+// body into out unless out is nil
+func (c *Client) do(ctx context.Context, r *request, out any) error {
+	attempts := 1
+	if r.repeatable() && c.retry.MaxAttempts > 1 {
+		attempts = c.retry.MaxAttempts
+	}
+	var err error
+	var requested time.Duration
+	for attempt := 1; ; attempt++ {
+		requested, err = c.attempt(ctx, r, out)
+		if err == nil || attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+		wait := c.retry.backoff(attempt)
+		if requested > wait {
+			wait = requested
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+	if ctx.Err() != nil && !isAPIError(err) {
+		return ctx.Err()
+	}
+	return err
+}
+
+// attempt sends r once, returning the Retry-After of a failed response
+func (c *Client) attempt(ctx context.Context, r *request, out any) (time.Duration, error) {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return retryAfter(resp.Header.Get("Retry-After")), readError(resp)
+	}
+	r.received = resp.Header
+	if r.etag != nil {
+		*r.etag = resp.Header.Get("ETag")
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return 0, nil
+	}
+	if raw, isRaw := out.(*[]byte); isRaw {
+		*raw, err = io.ReadAll(resp.Body)
+		return 0, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("failed to decode %s %s response: %w", r.method, r.path, err)
+	}
+	return 0, nil
+}
+
+// send builds and sends one HTTP request for r
+func (c *Client) send(ctx context.Context, r *request) (*http.Response, error) {
+	target := c.baseURL.String() + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+	var body io.Reader
+	if r.body != nil {
+		body = bytes.NewReader(r.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range r.header {
+		req.Header[key] = values
+	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", r.contentType)
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	return c.httpClient.Do(req)
+}
+
+// join builds a route path, escaping each segment so an id such as a/b stays one segment
+func join(segments ...string) string {
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+func retryable(err error) bool {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func isAPIError(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string) time.Duration {
+	header = strings.TrimSpace(header)
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && time.Until(date) > 0 {
+		return time.Until(date)
+	}
+	return 0
+}
+
+func sleep(ctx context.Context, wait time.Duration) error {
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
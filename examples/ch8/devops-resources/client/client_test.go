@@ -0,0 +1,329 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client/fake"
+)
+
+var fastRetries = client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+
+func newFake(t *testing.T, opts ...client.Option) (*fake.Server, *client.Client) {
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+	api, err := client.New(srv.URL, append([]client.Option{fastRetries}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv, api
+}
+
+func TestNew(t *testing.T) {
+	for _, baseURL := range []string{"localhost:8080", "ftp://localhost", "/engineers", "http://"} {
+		if _, err := client.New(baseURL); err == nil {
+			t.Errorf("\nTest: %s\nExpected: an error, Received: nil", baseURL)
+		}
+	}
+	if _, err := client.New("https://devops.example.com/api/"); err != nil {
+		t.Errorf("\nTest: base URL with a path\nExpected: no error, Received: %v", err)
+	}
+}
+
+func TestResources(t *testing.T) {
+	ctx := context.Background()
+	_, api := newFake(t)
+
+	bob, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "bob", Email: "bob@liatrio.com", Skills: []string{"go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	alice, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "alice", Email: "alice@bob.com", ManagerId: bob.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := api.GetEngineerByEmail(ctx, "bob@liatrio.com"); err != nil || !reflect.DeepEqual(found, bob) {
+		t.Errorf("\nTest: get by email\nExpected: %+v, Received: %+v %v", bob, found, err)
+	}
+
+	dev, err := api.CreateDev(ctx, devops_resource.Dev{Name: "dev_ferrets", Engineers: []*devops_resource.Engineer{{Id: bob.Id}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dev, err = api.AddEngineerToDev(ctx, dev.Id, alice.Id); err != nil || len(dev.Engineers) != 2 || dev.Engineers[1].Name != "alice" {
+		t.Errorf("\nTest: add engineer to dev\nExpected: bob and alice, Received: %+v %v", dev, err)
+	}
+	ops, err := api.CreateOps(ctx, devops_resource.Ops{Name: "op_ferrets"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	devops, err := api.CreateDevOps(ctx, devops_resource.DevOps{Devs: []*devops_resource.Dev{{Id: dev.Id}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devops, err = api.AddOpsToDevOps(ctx, devops.Id, ops.Id); err != nil || len(devops.Ops) != 1 {
+		t.Errorf("\nTest: add ops to devops\nExpected: one ops group, Received: %+v %v", devops, err)
+	}
+
+	renamed, err := api.PatchDev(ctx, dev.Id, map[string]any{"name": "dev_bengal"})
+	if err != nil || renamed.Name != "dev_bengal" || len(renamed.Engineers) != 2 {
+		t.Errorf("\nTest: merge patch\nExpected: dev_bengal with two engineers, Received: %+v %v", renamed, err)
+	}
+	if err := api.DeleteEngineer(ctx, bob.Id); err != nil {
+		t.Fatal(err)
+	}
+	if found, _ := api.GetEngineer(ctx, alice.Id); found.ManagerId != "" {
+		t.Errorf("\nTest: delete manager\nExpected: manager_id to be cleared, Received: %+v", found)
+	}
+	if found, _ := api.GetDevOps(ctx, devops.Id); len(found.Devs) != 1 || len(found.Devs[0].Engineers) != 1 {
+		t.Errorf("\nTest: delete engineer\nExpected: alice alone in the dev group, Received: %+v", found.Devs[0])
+	}
+
+	chart, err := api.Export(ctx)
+	if err != nil || len(chart.Engineers) != 1 || len(chart.Devs) != 1 || len(chart.Ops) != 1 || len(chart.DevOps) != 1 {
+		t.Errorf("\nTest: export\nExpected: one of each resource, Received: %+v %v", chart, err)
+	}
+}
+
+var errorTests = []struct {
+	description string
+	call        func(ctx context.Context, api *client.Client) error
+	sentinel    error
+	code        string
+	errors      []string
+}{
+	{"missing engineer", func(ctx context.Context, api *client.Client) error {
+		_, err := api.GetEngineer(ctx, "NOPE")
+		return err
+	}, client.ErrNotFound, "engineer_not_found", nil},
+	{"duplicate name", func(ctx context.Context, api *client.Client) error {
+		_, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "bob", Email: "robert@liatrio.com"})
+		return err
+	}, client.ErrConflict, "engineer_exists", nil},
+	{"invalid fields", func(ctx context.Context, api *client.Client) error {
+		_, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "carol", Email: "carol@liatrio.com", Timezone: "Mars/Olympus"})
+		return err
+	}, client.ErrValidation, "engineer_invalid", []string{"timezone: must be an IANA time zone such as America/Denver"}},
+	{"unknown member", func(ctx context.Context, api *client.Client) error {
+		_, err := api.AddEngineerToDev(ctx, "D1", "NOPE")
+		return err
+	}, client.ErrValidation, "engineer_not_found", nil},
+	{"stale etag", func(ctx context.Context, api *client.Client) error {
+		_, err := api.UpdateEngineer(ctx, "E1", devops_resource.Engineer{Name: "rob", Email: "bob@liatrio.com"}, client.IfMatch(`"7"`))
+		return err
+	}, client.ErrPrecondition, "version_mismatch", nil},
+	{"route the fake doesn't serve", func(ctx context.Context, api *client.Client) error {
+		_, err := api.Stats(ctx)
+		return err
+	}, client.ErrServer, "not_implemented", nil},
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	srv, api := newFake(t)
+	srv.Seed(&client.OrgChart{
+		Engineers: []*devops_resource.Engineer{{Id: "E1", Name: "bob", Email: "bob@liatrio.com"}},
+		Devs:      []client.ChartGroup{{Id: "D1", Name: "dev_ferrets"}},
+	})
+
+	for _, test := range errorTests {
+		err := test.call(ctx, api)
+		var apiErr *client.Error
+		if !errors.Is(err, test.sentinel) || !errors.As(err, &apiErr) || apiErr.Code != test.code {
+			t.Errorf("\nTest: %s\nExpected: %v %s, Received: %v", test.description, test.sentinel, test.code, err)
+			continue
+		}
+		if test.errors != nil && !reflect.DeepEqual(apiErr.Errors, test.errors) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.errors, apiErr.Errors)
+		}
+	}
+}
+
+func TestConditionalUpdates(t *testing.T) {
+	ctx := context.Background()
+	_, api := newFake(t)
+
+	var etag string
+	bob, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: "bob", Email: "bob@liatrio.com"}, client.ETag(&etag))
+	if err != nil || etag != `"1"` {
+		t.Fatalf("Expected: ETag \"1\", Received: %q %v", etag, err)
+	}
+	if _, err := api.PatchEngineer(ctx, bob.Id, map[string]any{"role": "sre"}, client.IfMatch(etag), client.ETag(&etag)); err != nil || etag != `"2"` {
+		t.Errorf("\nTest: patch with current etag\nExpected: ETag \"2\", Received: %q %v", etag, err)
+	}
+	if err := api.DeleteEngineer(ctx, bob.Id, client.IfMatch(`"1"`)); !errors.Is(err, client.ErrPrecondition) {
+		t.Errorf("\nTest: delete with stale etag\nExpected: %v, Received: %v", client.ErrPrecondition, err)
+	}
+}
+
+func TestPaging(t *testing.T) {
+	ctx := context.Background()
+	_, api := newFake(t)
+	for _, name := range []string{"carol", "alice", "dave", "bob", "erin"} {
+		if _, err := api.CreateEngineer(ctx, devops_resource.Engineer{Name: name, Email: name + "@bob.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var names []string
+	opts := client.ListOptions{Limit: 2, Sort: "name"}
+	for pages := 0; pages < 10; pages++ {
+		page, err := api.ListEngineers(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 {
+			t.Errorf("Expected: a total of 5, Received: %d", page.Total)
+		}
+		for _, engineer := range page.Items {
+			names = append(names, engineer.Name)
+		}
+		if opts.Cursor = page.NextCursor; opts.Cursor == "" {
+			break
+		}
+	}
+	if expected := []string{"alice", "bob", "carol", "dave", "erin"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected: %v, Received: %v", expected, names)
+	}
+}
+
+var retryTests = []struct {
+	description string
+	failures    []int
+	call        func(ctx context.Context, api *client.Client) error
+	requests    int
+	sentinel    error
+}{
+	{"read after two failures", []int{503, 500}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.ListEngineers(ctx, client.ListOptions{})
+		return err
+	}, 3, nil},
+	{"read failing every time", []int{502, 502, 502}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.ListEngineers(ctx, client.ListOptions{})
+		return err
+	}, 3, client.ErrServer},
+	{"create isn't repeated", []int{500}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.CreateDev(ctx, devops_resource.Dev{Name: "dev_ferrets"})
+		return err
+	}, 1, client.ErrServer},
+	{"create with an idempotency key is repeated", []int{500}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.CreateDev(ctx, devops_resource.Dev{Name: "dev_ferrets"}, client.IdempotencyKey("create-dev-ferrets"))
+		return err
+	}, 2, nil},
+	{"conditional patch is repeated", []int{500}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.PatchEngineer(ctx, "E1", map[string]any{"role": "sre"}, client.IfMatch("*"))
+		return err
+	}, 2, nil},
+	{"rate limited read is repeated", []int{429, 429}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.ListEngineers(ctx, client.ListOptions{})
+		return err
+	}, 3, nil},
+	{"rate limited create isn't repeated", []int{429}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.CreateDev(ctx, devops_resource.Dev{Name: "dev_ferrets"})
+		return err
+	}, 1, client.ErrRateLimited},
+	{"client errors aren't retried", []int{404}, func(ctx context.Context, api *client.Client) error {
+		_, err := api.GetEngineer(ctx, "E1")
+		return err
+	}, 1, client.ErrNotFound},
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	for _, test := range retryTests {
+		srv, api := newFake(t)
+		srv.Seed(&client.OrgChart{Engineers: []*devops_resource.Engineer{{Id: "E1", Name: "bob", Email: "bob@liatrio.com"}}})
+		srv.FailNext(test.failures...)
+
+		err := test.call(ctx, api)
+		if test.sentinel == nil && err != nil || test.sentinel != nil && !errors.Is(err, test.sentinel) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.sentinel, err)
+		}
+		if requests := srv.Requests(); requests != test.requests {
+			t.Errorf("\nTest: %s\nExpected: %d requests, Received: %d", test.description, test.requests, requests)
+		}
+	}
+}
+
+func TestContextCancelsRetries(t *testing.T) {
+	srv, api := newFake(t, client.WithRetryPolicy(client.RetryPolicy{MaxAttempts: 5, InitialBackoff: time.Minute, MaxBackoff: time.Minute}))
+	srv.FailNext(503)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.ListDevs(ctx, client.ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected: %v, Received: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	t.Cleanup(srv.Close)
+	api, _ := client.New(srv.URL, fastRetries)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := api.ListDevs(ctx, client.ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected: the retry to wait for Retry-After past %v, Received: %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestToken(t *testing.T) {
+	srv, api := newFake(t)
+	srv.RequireToken("s3cret")
+	if _, err := api.ListOps(context.Background(), client.ListOptions{}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Errorf("\nTest: without a token\nExpected: %v, Received: %v", client.ErrUnauthorized, err)
+	}
+	authorized, _ := client.New(srv.URL, client.WithToken("s3cret"))
+	if _, err := authorized.ListOps(context.Background(), client.ListOptions{}); err != nil {
+		t.Errorf("\nTest: with the token\nExpected: no error, Received: %v", err)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	var lastEventIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		if r.URL.Query().Get("types") != "EngineerCreated,EngineerDeleted" {
+			t.Errorf("Expected: the types filter, Received: %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(lastEventIDs) == 1 {
+			// the first connection drops after two events
+			fmt.Fprint(w, ": keep-alive\n\n")
+			fmt.Fprint(w, "id: 1\nevent: EngineerCreated\ndata: {\"id\": 1, \"type\": \"EngineerCreated\", \"data\": {\"id\": \"E1\"}}\n\n")
+			fmt.Fprint(w, "id: 2\nevent: EngineerDeleted\ndata: {\"id\": 2, \"type\": \"EngineerDeleted\", \"data\": {\"id\": \"E1\"}}\n\n")
+			return
+		}
+		fmt.Fprint(w, "id: 3\nevent: EngineerCreated\ndata: {\"id\": 3, \"type\": \"EngineerCreated\", \"data\": {\"id\": \"E2\"}}\n\n")
+	}))
+	defer srv.Close()
+	api, _ := client.New(srv.URL, fastRetries)
+
+	var received []int64
+	done := errors.New("done")
+	err := api.Events(context.Background(), client.EventOptions{Types: []string{"EngineerCreated", "EngineerDeleted"}}, func(event client.Event) error {
+		received = append(received, event.ID)
+		if event.ID == 3 {
+			return done
+		}
+		return nil
+	})
+	if err != done {
+		t.Errorf("Expected: the handler's error, Received: %v", err)
+	}
+	if !reflect.DeepEqual(received, []int64{1, 2, 3}) || !reflect.DeepEqual(lastEventIDs, []string{"", "2"}) {
+		t.Errorf("Expected: events 1-3 with a resume after 2, Received: %v resumed with %q", received, lastEventIDs)
+	}
+}
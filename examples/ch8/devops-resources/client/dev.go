@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// ListDevs lists dev groups, see ListOptions for paging and filters
+func (c *Client) ListDevs(ctx context.Context, opts ListOptions) (*Page[*devops_resource.Dev], error) {
+	return list[*devops_resource.Dev](ctx, c, join("dev"), opts.values())
+}
+
+// GetDev reads the dev group with id, members expanded
+func (c *Client) GetDev(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Dev, error) {
+	return call[devops_resource.Dev](ctx, c, newRequest(http.MethodGet, join("dev", "id", id), opts))
+}
+
+// GetDevByName reads the dev group named name, members expanded
+func (c *Client) GetDevByName(ctx context.Context, name string, opts ...RequestOption) (*devops_resource.Dev, error) {
+	return call[devops_resource.Dev](ctx, c, newRequest(http.MethodGet, join("dev", "name", name), opts))
+}
+
+// CreateDev creates a dev group, the API picks its id. Members only need their Id.
+func (c *Client) CreateDev(ctx context.Context, group devops_resource.Dev, opts ...RequestOption) (*devops_resource.Dev, error) {
+	if group.Engineers == nil {
+		group.Engineers = []*devops_resource.Engineer{} // the API rejects null member lists
+	}
+	return send[devops_resource.Dev](ctx, c, http.MethodPost, join("dev"), group, opts)
+}
+
+// UpdateDev replaces the name and members of the dev group with id
+func (c *Client) UpdateDev(ctx context.Context, id string, group devops_resource.Dev, opts ...RequestOption) (*devops_resource.Dev, error) {
+	if group.Engineers == nil {
+		group.Engineers = []*devops_resource.Engineer{} // the API rejects null member lists
+	}
+	return send[devops_resource.Dev](ctx, c, http.MethodPut, join("dev", id), group, opts)
+}
+
+// PatchDev changes only what changes lists, see PatchEngineer. Patches see the
+// members as a list of ids.
+func (c *Client) PatchDev(ctx context.Context, id string, changes any, opts ...RequestOption) (*devops_resource.Dev, error) {
+	return patch[devops_resource.Dev](ctx, c, join("dev", id), changes, opts)
+}
+
+// DeleteDev archives the dev group with id and removes it from its devops groups
+func (c *Client) DeleteDev(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, newRequest(http.MethodDelete, join("dev", id), opts), nil)
+}
+
+// RestoreDev brings back an archived dev group with the members that still exist
+func (c *Client) RestoreDev(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Dev, error) {
+	return call[devops_resource.Dev](ctx, c, newRequest(http.MethodPost, join("dev", id, "restore"), opts))
+}
+
+// AddEngineerToDev adds the engineer with engineerID to the dev group with id
+func (c *Client) AddEngineerToDev(ctx context.Context, id string, engineerID string, opts ...RequestOption) (*devops_resource.Dev, error) {
+	return send[devops_resource.Dev](ctx, c, http.MethodPost, join("dev", id), reference{Id: engineerID}, opts)
+}
+
+// reference is the body of the routes adding a member to a group
+type reference struct {
+	Id string `json:"id"`
+}
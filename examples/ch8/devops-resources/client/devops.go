@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// ListDevOps lists devops groups, see ListOptions for paging and filters
+func (c *Client) ListDevOps(ctx context.Context, opts ListOptions) (*Page[*devops_resource.DevOps], error) {
+	return list[*devops_resource.DevOps](ctx, c, join("devops"), opts.values())
+}
+
+// GetDevOps reads the devops group with id, members expanded
+func (c *Client) GetDevOps(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	return call[devops_resource.DevOps](ctx, c, newRequest(http.MethodGet, join("devops", id), opts))
+}
+
+// CreateDevOps creates a devops group, the API picks its id. Members only need their Id.
+func (c *Client) CreateDevOps(ctx context.Context, group devops_resource.DevOps, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	if group.Devs == nil {
+		group.Devs = []*devops_resource.Dev{} // the API rejects null member lists
+	}
+	if group.Ops == nil {
+		group.Ops = []*devops_resource.Ops{}
+	}
+	return send[devops_resource.DevOps](ctx, c, http.MethodPost, join("devops"), group, opts)
+}
+
+// UpdateDevOps replaces the members of the devops group with id
+func (c *Client) UpdateDevOps(ctx context.Context, id string, group devops_resource.DevOps, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	if group.Devs == nil {
+		group.Devs = []*devops_resource.Dev{} // the API rejects null member lists
+	}
+	if group.Ops == nil {
+		group.Ops = []*devops_resource.Ops{}
+	}
+	return send[devops_resource.DevOps](ctx, c, http.MethodPut, join("devops", id), group, opts)
+}
+
+// PatchDevOps changes only what changes lists, see PatchEngineer. Patches see the dev
+// and ops groups as lists of ids.
+func (c *Client) PatchDevOps(ctx context.Context, id string, changes any, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	return patch[devops_resource.DevOps](ctx, c, join("devops", id), changes, opts)
+}
+
+// DeleteDevOps archives the devops group with id
+func (c *Client) DeleteDevOps(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, newRequest(http.MethodDelete, join("devops", id), opts), nil)
+}
+
+// RestoreDevOps brings back an archived devops group with the members that still exist
+func (c *Client) RestoreDevOps(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	return call[devops_resource.DevOps](ctx, c, newRequest(http.MethodPost, join("devops", id, "restore"), opts))
+}
+
+// AddDevToDevOps adds the dev group with devID to the devops group with id
+func (c *Client) AddDevToDevOps(ctx context.Context, id string, devID string, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	return send[devops_resource.DevOps](ctx, c, http.MethodPost, join("devops", "dev", id), reference{Id: devID}, opts)
+}
+
+// AddOpsToDevOps adds the ops group with opsID to the devops group with id
+func (c *Client) AddOpsToDevOps(ctx context.Context, id string, opsID string, opts ...RequestOption) (*devops_resource.DevOps, error) {
+	return send[devops_resource.DevOps](ctx, c, http.MethodPost, join("devops", "op", id), reference{Id: opsID}, opts)
+}
+
+// DevOpsEngineers lists every engineer of the devops group's dev and ops groups once
+func (c *Client) DevOpsEngineers(ctx context.Context, id string, opts ListOptions) (*Page[*devops_resource.Engineer], error) {
+	return list[*devops_resource.Engineer](ctx, c, join("devops", id, "engineers"), opts.values())
+}
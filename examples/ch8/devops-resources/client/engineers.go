@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+const (
+	jsonType       = "application/json"
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// call sends r and decodes the response into a new T
+func call[T any](ctx context.Context, c *Client, r *request) (*T, error) {
+	out := new(T)
+	if err := c.do(ctx, r, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// send sends body as JSON and decodes the response into a new T
+func send[T any](ctx context.Context, c *Client, method string, path string, body any, opts []RequestOption) (*T, error) {
+	r, err := newRequest(method, path, opts).withJSON(body, jsonType)
+	if err != nil {
+		return nil, err
+	}
+	return call[T](ctx, c, r)
+}
+
+// patch sends a []PatchOperation as a JSON Patch and anything else as a merge patch
+func patch[T any](ctx context.Context, c *Client, path string, body any, opts []RequestOption) (*T, error) {
+	contentType := mergePatchType
+	if _, isJSONPatch := body.([]PatchOperation); isJSONPatch {
+		contentType = jsonPatchType
+	}
+	r, err := newRequest(http.MethodPatch, path, opts).withJSON(body, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return call[T](ctx, c, r)
+}
+
+// list reads one page of a list route
+func list[T any](ctx context.Context, c *Client, path string, query url.Values) (*Page[T], error) {
+	r := newRequest(http.MethodGet, path, nil)
+	r.query = query
+	var items []T
+	if err := c.do(ctx, r, &items); err != nil {
+		return nil, err
+	}
+	return newPage(items, r.received), nil
+}
+
+// ListEngineers lists engineers, see ListOptions for paging and filters
+func (c *Client) ListEngineers(ctx context.Context, opts ListOptions) (*Page[*devops_resource.Engineer], error) {
+	return list[*devops_resource.Engineer](ctx, c, join("engineers"), opts.values())
+}
+
+// GetEngineer reads the engineer with id
+func (c *Client) GetEngineer(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return call[devops_resource.Engineer](ctx, c, newRequest(http.MethodGet, join("engineers", "id", id), opts))
+}
+
+// GetEngineerByName reads the engineer named name
+func (c *Client) GetEngineerByName(ctx context.Context, name string, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return call[devops_resource.Engineer](ctx, c, newRequest(http.MethodGet, join("engineers", "name", name), opts))
+}
+
+// GetEngineerByEmail reads the engineer with email
+func (c *Client) GetEngineerByEmail(ctx context.Context, email string, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return call[devops_resource.Engineer](ctx, c, newRequest(http.MethodGet, join("engineers", "email", email), opts))
+}
+
+// CreateEngineer creates an engineer, the API picks its id
+func (c *Client) CreateEngineer(ctx context.Context, engineer devops_resource.Engineer, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return send[devops_resource.Engineer](ctx, c, http.MethodPost, join("engineers"), engineer, opts)
+}
+
+// UpdateEngineer replaces every field of the engineer with id
+func (c *Client) UpdateEngineer(ctx context.Context, id string, engineer devops_resource.Engineer, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return send[devops_resource.Engineer](ctx, c, http.MethodPut, join("engineers", id), engineer, opts)
+}
+
+// PatchEngineer changes only what changes lists: a []PatchOperation is sent as a JSON
+// Patch, anything else, such as map[string]any{"role": "sre"}, as a merge patch
+func (c *Client) PatchEngineer(ctx context.Context, id string, changes any, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return patch[devops_resource.Engineer](ctx, c, join("engineers", id), changes, opts)
+}
+
+// DeleteEngineer archives the engineer with id and removes it from its groups
+func (c *Client) DeleteEngineer(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, newRequest(http.MethodDelete, join("engineers", id), opts), nil)
+}
+
+// RestoreEngineer brings back an archived engineer and puts it back into its groups
+func (c *Client) RestoreEngineer(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Engineer, error) {
+	return call[devops_resource.Engineer](ctx, c, newRequest(http.MethodPost, join("engineers", id, "restore"), opts))
+}
+
+// EngineerMemberships lists the groups the engineer with id belongs to
+func (c *Client) EngineerMemberships(ctx context.Context, id string) (*Memberships, error) {
+	return call[Memberships](ctx, c, newRequest(http.MethodGet, join("engineers", id, "memberships"), nil))
+}
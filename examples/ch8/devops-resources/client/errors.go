@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Sentinel errors matching the status of a failed call, use them with errors.Is
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrPrecondition = errors.New("precondition failed")
+	ErrMediaType    = errors.New("unsupported media type")
+	ErrValidation   = errors.New("validation failed")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+// Error is a failed call, decoded from the API's RFC 7807 problem details.
+// Code is the machine-readable reason such as engineer_not_found, and Errors lists
+// each problem of a body that failed validation.
+type Error struct {
+	StatusCode int      `json:"status"`
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	Detail     string   `json:"detail"`
+	Instance   string   `json:"instance"`
+	Code       string   `json:"code"`
+	Errors     []string `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	message := "devops-api: " + strconv.Itoa(e.StatusCode)
+	if e.Code != "" {
+		message += " " + e.Code
+	}
+	if e.Detail != "" {
+		message += ": " + e.Detail
+	}
+	if len(e.Errors) > 0 {
+		message += " (" + strings.Join(e.Errors, "; ") + ")"
+	}
+	return message
+}
+
+// Is matches the sentinel for the status code, so errors.Is(err, ErrNotFound) holds
+// for every 404
+func (e *Error) Is(target error) bool {
+	return target == sentinelFor(e.StatusCode)
+}
+
+func sentinelFor(status int) error {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusPreconditionFailed:
+		return ErrPrecondition
+	case http.StatusUnsupportedMediaType:
+		return ErrMediaType
+	case http.StatusUnprocessableEntity:
+		return ErrValidation
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	}
+	if status >= 500 {
+		return ErrServer
+	}
+	return nil
+}
+
+// readError decodes the problem details of a failed response. Bodies that aren't
+// problem details, say from a proxy, become the Detail.
+func readError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	apiErr := &Error{}
+	if err := json.Unmarshal(raw, apiErr); err != nil || apiErr.Code == "" {
+		apiErr = &Error{Title: http.StatusText(resp.StatusCode), Detail: strings.TrimSpace(string(raw))}
+	}
+	apiErr.StatusCode = resp.StatusCode
+	return apiErr
+}
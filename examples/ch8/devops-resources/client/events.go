@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a domain event such as EngineerAddedToDev. Data holds its payload, for
+// created and updated events the resource with its members as ids.
+type Event struct {
+	ID   int64           `json:"id"`
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// EventOptions selects the events Events delivers
+type EventOptions struct {
+	Types []string // only these event types, every type when empty
+	After int64    // replay the recent events after this id, only new events when 0
+}
+
+// handlerError carries an error returned by the callback of Events
+type handlerError struct{ err error }
+
+func (e handlerError) Error() string { return e.err.Error() }
+
+// Events streams domain events to handle, in order, until ctx is done or handle
+// returns an error, which Events then returns. A dropped stream is resumed after the
+// last delivered event. Reconnecting gives up after the retry policy's MaxAttempts
+// failures in a row, or at once on a 4xx answer.
+func (c *Client) Events(ctx context.Context, opts EventOptions, handle func(Event) error) error {
+	after := opts.After
+	failures := 0
+	for {
+		delivered := false
+		err := c.streamEvents(ctx, opts.Types, after, func(event Event) error {
+			delivered = true
+			after = event.ID
+			if err := handle(event); err != nil {
+				return handlerError{err}
+			}
+			return nil
+		})
+		var handlerErr handlerError
+		switch {
+		case errors.As(err, &handlerErr):
+			return handlerErr.err
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && !retryable(err):
+			return err
+		}
+		if delivered {
+			failures = 0
+		}
+		if failures++; failures >= c.retry.MaxAttempts {
+			if err == nil {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("event stream closed: %w", err)
+		}
+		if err := sleep(ctx, c.retry.backoff(failures)); err != nil {
+			return err
+		}
+	}
+}
+
+// streamEvents reads one connection of the event stream until it ends
+func (c *Client) streamEvents(ctx context.Context, types []string, after int64, deliver func(Event) error) error {
+	r := newRequest(http.MethodGet, join("events"), nil)
+	r.header.Set("Accept", "text/event-stream")
+	if len(types) > 0 {
+		r.query.Set("types", strings.Join(types, ","))
+	}
+	if after > 0 {
+		r.header.Set("Last-Event-ID", strconv.FormatInt(after, 10))
+	}
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return readError(resp)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(data.String()), &event); err != nil {
+				return fmt.Errorf("failed to decode event: %w", err)
+			}
+			data.Reset()
+			if err := deliver(event); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		// id and event lines repeat what the data holds, lines starting with : are keep-alives
+	}
+	return scanner.Err()
+}
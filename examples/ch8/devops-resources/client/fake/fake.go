@@ -0,0 +1,458 @@
+// Package fake is an in-memory stand-in for the devops-api, for testing code built on
+// package client without running the API.
+//
+//	srv := fake.NewServer()
+//	defer srv.Close()
+//	api, err := client.New(srv.URL)
+//
+// It serves the engineer, dev, ops and devops routes with the API's validation, error
+// codes, ETags and paging, plus /export and /import. Ids are predictable: E1, E2, ...
+// for engineers, D1 for dev groups, O1 for ops groups and DO1 for devops groups.
+// Deleted resources are dropped rather than archived, and every other route, as well
+// as JSON Patch, answers 501 with the code not_implemented.
+package fake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+)
+
+// Kinds of resources, used in ids and version keys
+const (
+	kindEngineer = "engineer"
+	kindDev      = "dev"
+	kindOps      = "ops"
+	kindDevOps   = "devops"
+)
+
+var idPrefixes = map[string]string{kindEngineer: "E", kindDev: "D", kindOps: "O", kindDevOps: "DO"}
+
+// Server is a running fake devops-api, URL is its base URL
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	chart    client.OrgChart
+	versions map[string]int // kind/id -> version
+	lastIDs  map[string]int // kind -> number of the last generated id
+	token    string
+	failures []int
+	requests int
+}
+
+// NewServer starts an empty fake, stop it with Close
+func NewServer() *Server {
+	s := &Server{}
+	s.reset()
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+func (s *Server) reset() {
+	s.chart = client.OrgChart{
+		Engineers: []*devops_resource.Engineer{},
+		Devs:      []client.ChartGroup{},
+		Ops:       []client.ChartGroup{},
+		DevOps:    []client.ChartDevOps{},
+	}
+	s.versions = map[string]int{}
+	s.lastIDs = map[string]int{}
+}
+
+// RequireToken makes every request without token as its bearer token fail with 401
+func (s *Server) RequireToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = token
+}
+
+// FailNext answers the next requests with statuses, one per request, as if the API
+// failed. Use it to test retries and error handling.
+func (s *Server) FailNext(statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, statuses...)
+}
+
+// Requests counts the requests served so far, failed ones included
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Seed replaces all data with chart like POST /import, keeping its ids
+func (s *Server) Seed(chart *client.OrgChart) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(chart)
+}
+
+// Snapshot returns all data like GET /export
+func (s *Server) Snapshot() *client.OrgChart {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.export()
+}
+
+// apiError is a failed request, written as problem details
+type apiError struct {
+	status  int
+	code    string
+	message string
+	details []string
+}
+
+func (e *apiError) Error() string { return e.message }
+
+func problem(status int, code string, format string, args ...any) error {
+	return &apiError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// response is a successful answer, version sets the ETag unless it is 0
+type response struct {
+	status  int
+	body    any
+	version int
+	header  http.Header
+}
+
+func ok(body any, version int) *response {
+	return &response{status: http.StatusOK, body: body, version: version}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	resp, err := s.serve(r)
+	if err != nil {
+		s.writeProblem(w, r, err)
+		return
+	}
+	for key, values := range resp.header {
+		w.Header()[key] = values
+	}
+	if resp.version > 0 {
+		w.Header().Set("ETag", `"`+strconv.Itoa(resp.version)+`"`)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(resp.status)
+	json.NewEncoder(w).Encode(resp.body)
+}
+
+func (s *Server) writeProblem(w http.ResponseWriter, r *http.Request, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "internal_error", message: err.Error()}
+	}
+	if apiErr.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="devops-api"`)
+	}
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(apiErr.status)
+	json.NewEncoder(w).Encode(client.Error{
+		StatusCode: apiErr.status,
+		Type:       "urn:devops-api:problem:" + apiErr.code,
+		Title:      http.StatusText(apiErr.status),
+		Detail:     apiErr.message,
+		Instance:   r.URL.Path,
+		Code:       apiErr.code,
+		Errors:     apiErr.details,
+	})
+}
+
+// serve routes r the way the API's router does
+func (s *Server) serve(r *http.Request) (*response, error) {
+	if s.token != "" && r.Header.Get("Authorization") != "Bearer "+s.token {
+		return nil, problem(http.StatusUnauthorized, "invalid_token", "bearer token is missing or invalid")
+	}
+	if len(s.failures) > 0 {
+		status := s.failures[0]
+		s.failures = s.failures[1:]
+		return nil, problem(status, "injected_failure", "failure requested with FailNext")
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(r.URL.EscapedPath(), "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, problem(http.StatusNotFound, "not_found", "no route %s", r.URL.Path)
+		}
+		segments = append(segments, unescaped)
+	}
+	route := r.Method + " " + routePattern(segments)
+	switch route {
+	case "GET /engineers":
+		return s.listEngineers(r.URL.Query())
+	case "GET /engineers/id/:", "GET /engineers/name/:", "GET /engineers/email/:":
+		return s.getEngineer(segments[1], segments[2])
+	case "POST /engineers":
+		return s.createEngineer(r)
+	case "PUT /engineers/:", "PATCH /engineers/:":
+		return s.updateEngineer(r, segments[1])
+	case "DELETE /engineers/:":
+		return s.deleteEngineer(r, segments[1])
+
+	case "GET /dev", "GET /op":
+		return s.listGroups(kindOf(segments[0]), r.URL.Query())
+	case "GET /dev/id/:", "GET /dev/name/:", "GET /op/id/:", "GET /op/name/:":
+		return s.getGroup(kindOf(segments[0]), segments[1], segments[2])
+	case "POST /dev", "POST /op":
+		return s.createGroup(r, kindOf(segments[0]))
+	case "POST /dev/:", "POST /op/:":
+		return s.addMember(r, kindOf(segments[0]), segments[1], kindEngineer)
+	case "PUT /dev/:", "PATCH /dev/:", "PUT /op/:", "PATCH /op/:":
+		return s.updateGroup(r, kindOf(segments[0]), segments[1])
+	case "DELETE /dev/:", "DELETE /op/:":
+		return s.deleteGroup(r, kindOf(segments[0]), segments[1])
+
+	case "GET /devops":
+		return s.listDevOps(r.URL.Query())
+	case "GET /devops/:":
+		return s.getDevOps(segments[1])
+	case "POST /devops":
+		return s.createDevOps(r)
+	case "POST /devops/dev/:", "POST /devops/op/:":
+		return s.addMember(r, kindDevOps, segments[2], kindOf(segments[1]))
+	case "PUT /devops/:", "PATCH /devops/:":
+		return s.updateDevOps(r, segments[1])
+	case "DELETE /devops/:":
+		return s.deleteDevOps(r, segments[1])
+
+	case "GET /export":
+		return ok(s.export(), 0), nil
+	case "POST /import":
+		return s.importChart(r)
+	}
+	return nil, problem(http.StatusNotImplemented, "not_implemented", "%s %s is not implemented by the fake", r.Method, r.URL.Path)
+}
+
+// routePattern replaces the segments of a path that hold values with ":", so
+// /dev/id/D1 becomes /dev/id/:
+func routePattern(segments []string) string {
+	pattern := make([]string, len(segments))
+	for i, segment := range segments {
+		pattern[i] = ":"
+		if i == 0 || i == 1 && len(segments) == 3 && lookups[segment] {
+			pattern[i] = segment
+		}
+	}
+	return "/" + strings.Join(pattern, "/")
+}
+
+// lookups are the literal middle segments of routes such as /engineers/email/:email
+// and /devops/dev/:id
+var lookups = map[string]bool{"id": true, "name": true, "email": true, "dev": true, "op": true}
+
+func kindOf(route string) string {
+	switch route {
+	case "dev":
+		return kindDev
+	case "op":
+		return kindOps
+	}
+	return kindEngineer
+}
+
+/******* versions and ids *******/
+
+func (s *Server) version(kind string, id string) int {
+	return s.versions[kind+"/"+id]
+}
+
+func (s *Server) bump(kind string, id string) int {
+	s.versions[kind+"/"+id]++
+	return s.versions[kind+"/"+id]
+}
+
+// newID returns the next free id of kind, skipping ids taken by Seed
+func (s *Server) newID(kind string) string {
+	for {
+		s.lastIDs[kind]++
+		id := idPrefixes[kind] + strconv.Itoa(s.lastIDs[kind])
+		if _, taken := s.versions[kind+"/"+id]; !taken {
+			return id
+		}
+	}
+}
+
+// ifMatch fails unless the If-Match header is absent, * or lists the current ETag
+func ifMatch(r *http.Request, id string, version int) error {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == `"`+strconv.Itoa(version)+`"` {
+			return nil
+		}
+	}
+	return problem(http.StatusPreconditionFailed, "version_mismatch", "If-Match %s does not match the current ETag \"%d\" of %s", header, version, id)
+}
+
+/******* request bodies *******/
+
+func decode(r *http.Request, dst any) error {
+	if err := json.NewDecoder(r.Body).Decode(dst); err != nil {
+		return problem(http.StatusBadRequest, "malformed_body", "request body is not valid JSON: %v", err)
+	}
+	return nil
+}
+
+// patched applies the merge patch in r to current, the resource with id as a PATCH
+// sees it, and decodes the result into dst. A PUT body replaces current instead.
+func patched(r *http.Request, id string, current any, dst any) error {
+	if r.Method == http.MethodPut {
+		return decode(r, dst)
+	}
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	switch contentType {
+	case "application/merge-patch+json", "application/json", "":
+	case "application/json-patch+json":
+		return problem(http.StatusNotImplemented, "not_implemented", "JSON Patch is not implemented by the fake")
+	default:
+		return problem(http.StatusUnsupportedMediaType, "unsupported_patch_type", "PATCH bodies must be application/merge-patch+json or application/json-patch+json")
+	}
+	raw, err := io.ReadAll(r.Body)
+	if err != nil {
+		return problem(http.StatusBadRequest, "unreadable_body", "failed to read request body")
+	}
+	var changes any
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		return problem(http.StatusBadRequest, "malformed_patch", "merge patch is not valid JSON: %v", err)
+	}
+	var document any
+	encoded, _ := json.Marshal(current)
+	json.Unmarshal(encoded, &document)
+	document = mergePatch(document, changes)
+	if object, isObject := document.(map[string]any); !isObject || object["id"] != id {
+		return problem(http.StatusUnprocessableEntity, "id_immutable", "id cannot be changed by a patch")
+	}
+	encoded, _ = json.Marshal(document)
+	if err := json.Unmarshal(encoded, dst); err != nil {
+		return problem(http.StatusUnprocessableEntity, "patch_invalid", "patched resource is invalid: %v", err)
+	}
+	return nil
+}
+
+// mergePatch applies an RFC 7396 merge patch
+func mergePatch(target any, patch any) any {
+	changes, isObject := patch.(map[string]any)
+	if !isObject {
+		return patch
+	}
+	object, isObject := target.(map[string]any)
+	if !isObject {
+		object = map[string]any{}
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(object, key)
+		} else {
+			object[key] = mergePatch(object[key], value)
+		}
+	}
+	return object
+}
+
+/******* paging *******/
+
+// page applies ?limit= and ?cursor= to items, 100 of them without a limit like the API.
+// Cursors are offsets here.
+func page[T any](items []T, query url.Values) (*response, error) {
+	limit, offset := 100, 0
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 1000 {
+			return nil, problem(http.StatusBadRequest, "invalid_limit", "limit must be a number between 1 and 1000")
+		}
+		limit = parsed
+	}
+	if value := query.Get("cursor"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, problem(http.StatusBadRequest, "invalid_cursor", "cursor is invalid")
+		}
+		offset = parsed
+	}
+	header := http.Header{}
+	header.Set("X-Total-Count", strconv.Itoa(len(items)))
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := len(items)
+	if offset+limit < len(items) {
+		end = offset + limit
+		header.Set("X-Next-Cursor", strconv.Itoa(end))
+	}
+	return &response{status: http.StatusOK, body: items[offset:end], header: header}, nil
+}
+
+// sortBy orders items by ?sort=, a leading - sorts descending
+func sortBy[T any](items []T, sortParam string, fields map[string]func(T) string) error {
+	if sortParam == "" {
+		return nil
+	}
+	field := strings.TrimPrefix(sortParam, "-")
+	key, found := fields[field]
+	if !found {
+		return problem(http.StatusBadRequest, "invalid_sort", "cannot sort by %s", field)
+	}
+	descending := field != sortParam
+	sort.SliceStable(items, func(i, j int) bool {
+		if descending {
+			return key(items[i]) > key(items[j])
+		}
+		return key(items[i]) < key(items[j])
+	})
+	return nil
+}
+
+func containsFold(value string, part string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(part))
+}
+
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func without(ids []string, id string) []string {
+	kept := make([]string, 0, len(ids))
+	for _, candidate := range ids {
+		if candidate != id {
+			kept = append(kept, candidate)
+		}
+	}
+	return kept
+}
+
+// duplicate returns an id listed more than once
+func duplicate(ids []string) (string, bool) {
+	seen := map[string]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			return id, true
+		}
+		seen[id] = true
+	}
+	return "", false
+}
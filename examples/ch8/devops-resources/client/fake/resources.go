@@ -0,0 +1,689 @@
+package fake
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
+)
+
+/******* engineers *******/
+
+func copyEngineer(engineer *devops_resource.Engineer) *devops_resource.Engineer {
+	copied := *engineer
+	copied.Skills = append([]string(nil), engineer.Skills...)
+	if len(copied.Skills) == 0 {
+		copied.Skills = nil
+	}
+	return &copied
+}
+
+func (s *Server) findEngineer(id string) (*devops_resource.Engineer, bool) {
+	for _, engineer := range s.chart.Engineers {
+		if engineer.Id == id {
+			return engineer, true
+		}
+	}
+	return nil, false
+}
+
+var engineerSortFields = map[string]func(*devops_resource.Engineer) string{
+	"name":  func(e *devops_resource.Engineer) string { return e.Name },
+	"email": func(e *devops_resource.Engineer) string { return e.Email },
+	"id":    func(e *devops_resource.Engineer) string { return e.Id },
+}
+
+func (s *Server) listEngineers(query url.Values) (*response, error) {
+	engineers := []*devops_resource.Engineer{}
+	for _, engineer := range s.chart.Engineers {
+		domain := engineer.Email[strings.LastIndex(engineer.Email, "@")+1:]
+		if query.Get("email_domain") != "" && !strings.EqualFold(domain, query.Get("email_domain")) {
+			continue
+		}
+		if !containsFold(engineer.Name, query.Get("name")) {
+			continue
+		}
+		engineers = append(engineers, copyEngineer(engineer))
+	}
+	if err := sortBy(engineers, query.Get("sort"), engineerSortFields); err != nil {
+		return nil, err
+	}
+	return page(engineers, query)
+}
+
+func (s *Server) getEngineer(by string, value string) (*response, error) {
+	for _, engineer := range s.chart.Engineers {
+		if by == "id" && engineer.Id == value || by == "name" && engineer.Name == value || by == "email" && engineer.Email == value {
+			return ok(copyEngineer(engineer), s.version(kindEngineer, engineer.Id)), nil
+		}
+	}
+	return nil, problem(http.StatusNotFound, "engineer_not_found", "no engineer with %s %s", by, value)
+}
+
+// checkEngineer validates engineer the way the API does before storing it
+func (s *Server) checkEngineer(engineer *devops_resource.Engineer) error {
+	if errs := validation.Engineer(engineer); len(errs) > 0 {
+		details := make([]string, 0, len(errs))
+		for _, err := range errs {
+			details = append(details, err.Error())
+		}
+		return &apiError{status: http.StatusUnprocessableEntity, code: "engineer_invalid", message: "engineer " + engineer.Name + " is invalid", details: details}
+	}
+	for _, other := range s.chart.Engineers {
+		if other.Name == engineer.Name && other.Id != engineer.Id {
+			return problem(http.StatusConflict, "engineer_exists", "engineer %s already exists", engineer.Name)
+		}
+	}
+	if engineer.ManagerId == "" {
+		return nil
+	}
+	manager, found := s.findEngineer(engineer.ManagerId)
+	if !found {
+		return problem(http.StatusUnprocessableEntity, "manager_not_found", "no engineer with id %s", engineer.ManagerId)
+	}
+	for seen := map[string]bool{}; manager.ManagerId != "" && !seen[manager.Id]; {
+		if manager.ManagerId == engineer.Id {
+			return problem(http.StatusUnprocessableEntity, "manager_cycle", "%s already reports to %s", engineer.ManagerId, engineer.Id)
+		}
+		seen[manager.Id] = true
+		if manager, found = s.findEngineer(manager.ManagerId); !found {
+			break
+		}
+	}
+	return nil
+}
+
+func (s *Server) createEngineer(r *http.Request) (*response, error) {
+	var engineer devops_resource.Engineer
+	if err := decode(r, &engineer); err != nil {
+		return nil, err
+	}
+	engineer.Id = ""
+	if err := s.checkEngineer(&engineer); err != nil {
+		return nil, err
+	}
+	engineer.Id = s.newID(kindEngineer)
+	s.chart.Engineers = append(s.chart.Engineers, copyEngineer(&engineer))
+	return &response{status: http.StatusCreated, body: copyEngineer(&engineer), version: s.bump(kindEngineer, engineer.Id)}, nil
+}
+
+func (s *Server) updateEngineer(r *http.Request, id string) (*response, error) {
+	current, found := s.findEngineer(id)
+	if !found {
+		return nil, problem(http.StatusNotFound, "engineer_not_found", "no engineer with id %s", id)
+	}
+	if err := ifMatch(r, id, s.version(kindEngineer, id)); err != nil {
+		return nil, err
+	}
+	var engineer devops_resource.Engineer
+	if err := patched(r, id, current, &engineer); err != nil {
+		return nil, err
+	}
+	engineer.Id = id
+	if err := s.checkEngineer(&engineer); err != nil {
+		return nil, err
+	}
+	*current = *copyEngineer(&engineer)
+	return ok(copyEngineer(current), s.bump(kindEngineer, id)), nil
+}
+
+func (s *Server) deleteEngineer(r *http.Request, id string) (*response, error) {
+	if _, found := s.findEngineer(id); !found {
+		return nil, problem(http.StatusNotFound, "engineer_not_found", "no engineer with id %s", id)
+	}
+	if err := ifMatch(r, id, s.version(kindEngineer, id)); err != nil {
+		return nil, err
+	}
+	kept := s.chart.Engineers[:0]
+	for _, engineer := range s.chart.Engineers {
+		if engineer.Id == id {
+			continue
+		}
+		if engineer.ManagerId == id {
+			engineer.ManagerId = ""
+			s.bump(kindEngineer, engineer.Id)
+		}
+		kept = append(kept, engineer)
+	}
+	s.chart.Engineers = kept
+	for _, kind := range []string{kindDev, kindOps} {
+		groups := s.groups(kind)
+		for i := range *groups {
+			if group := &(*groups)[i]; contains(group.Engineers, id) {
+				group.Engineers = without(group.Engineers, id)
+				s.bump(kind, group.Id)
+			}
+		}
+	}
+	delete(s.versions, kindEngineer+"/"+id)
+	return ok(map[string]string{"success": "engineer resource deleted"}, 0), nil
+}
+
+/******* dev and ops groups *******/
+
+func (s *Server) groups(kind string) *[]client.ChartGroup {
+	if kind == kindDev {
+		return &s.chart.Devs
+	}
+	return &s.chart.Ops
+}
+
+func (s *Server) findGroup(kind string, id string) (*client.ChartGroup, bool) {
+	groups := s.groups(kind)
+	for i := range *groups {
+		if (*groups)[i].Id == id {
+			return &(*groups)[i], true
+		}
+	}
+	return nil, false
+}
+
+// renderGroup expands the members of a group the way GET /dev/id/:id does
+func (s *Server) renderGroup(group *client.ChartGroup) (string, string, []*devops_resource.Engineer) {
+	engineers := []*devops_resource.Engineer{}
+	for _, id := range group.Engineers {
+		if engineer, found := s.findEngineer(id); found {
+			engineers = append(engineers, copyEngineer(engineer))
+		}
+	}
+	return group.Name, group.Id, engineers
+}
+
+func (s *Server) renderDev(group *client.ChartGroup) *devops_resource.Dev {
+	name, id, engineers := s.renderGroup(group)
+	return &devops_resource.Dev{Name: name, Id: id, Engineers: engineers}
+}
+
+func (s *Server) renderOps(group *client.ChartGroup) *devops_resource.Ops {
+	name, id, engineers := s.renderGroup(group)
+	return &devops_resource.Ops{Name: name, Id: id, Engineers: engineers}
+}
+
+func (s *Server) render(kind string, group *client.ChartGroup) any {
+	if kind == kindDev {
+		return s.renderDev(group)
+	}
+	return s.renderOps(group)
+}
+
+var groupSortFields = map[string]func(client.ChartGroup) string{
+	"name": func(g client.ChartGroup) string { return g.Name },
+	"id":   func(g client.ChartGroup) string { return g.Id },
+}
+
+func (s *Server) listGroups(kind string, query url.Values) (*response, error) {
+	groups := []client.ChartGroup{}
+	for _, group := range *s.groups(kind) {
+		if member := query.Get("member"); member != "" && !contains(group.Engineers, member) {
+			continue
+		}
+		if containsFold(group.Name, query.Get("name")) {
+			groups = append(groups, group)
+		}
+	}
+	if err := sortBy(groups, query.Get("sort"), groupSortFields); err != nil {
+		return nil, err
+	}
+	resp, err := page(groups, query)
+	if err != nil {
+		return nil, err
+	}
+	rendered := []any{}
+	for _, group := range resp.body.([]client.ChartGroup) {
+		rendered = append(rendered, s.render(kind, &group))
+	}
+	resp.body = rendered
+	return resp, nil
+}
+
+func (s *Server) getGroup(kind string, by string, value string) (*response, error) {
+	for _, group := range *s.groups(kind) {
+		if by == "id" && group.Id == value || by == "name" && group.Name == value {
+			return ok(s.render(kind, &group), s.version(kind, group.Id)), nil
+		}
+	}
+	return nil, problem(http.StatusNotFound, kind+"_not_found", "no %s group with %s %s", kind, by, value)
+}
+
+// groupInput reads a dev or ops group from a POST or PUT body, or a merge patch
+// applied to current, with its members as ids
+func groupInput(r *http.Request, current *client.ChartGroup) (*client.ChartGroup, error) {
+	if current != nil && r.Method == http.MethodPatch {
+		var group client.ChartGroup
+		if err := patched(r, current.Id, current, &group); err != nil {
+			return nil, err
+		}
+		return &group, nil
+	}
+	var body devops_resource.Dev
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	group := &client.ChartGroup{Name: body.Name, Engineers: []string{}}
+	for _, engineer := range body.Engineers {
+		if engineer != nil {
+			group.Engineers = append(group.Engineers, engineer.Id)
+		}
+	}
+	return group, nil
+}
+
+// checkGroup validates a dev or ops group the way the API does before storing it
+func (s *Server) checkGroup(kind string, group *client.ChartGroup) error {
+	if group.Name == "" {
+		return problem(http.StatusUnprocessableEntity, "name_required", "name cannot be empty")
+	}
+	for _, other := range *s.groups(kind) {
+		if other.Name == group.Name && other.Id != group.Id {
+			return problem(http.StatusConflict, kind+"_exists", "%s group %s already exists", kind, group.Name)
+		}
+	}
+	if id, found := duplicate(group.Engineers); found {
+		return problem(http.StatusUnprocessableEntity, "duplicate_member", "engineer %s is listed more than once", id)
+	}
+	for _, id := range group.Engineers {
+		if _, found := s.findEngineer(id); !found {
+			return problem(http.StatusUnprocessableEntity, "engineer_not_found", "engineer %s does not exist", id)
+		}
+	}
+	return nil
+}
+
+func (s *Server) createGroup(r *http.Request, kind string) (*response, error) {
+	group, err := groupInput(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkGroup(kind, group); err != nil {
+		return nil, err
+	}
+	group.Id = s.newID(kind)
+	*s.groups(kind) = append(*s.groups(kind), *group)
+	return &response{status: http.StatusCreated, body: s.render(kind, group), version: s.bump(kind, group.Id)}, nil
+}
+
+func (s *Server) updateGroup(r *http.Request, kind string, id string) (*response, error) {
+	current, found := s.findGroup(kind, id)
+	if !found {
+		return nil, problem(http.StatusNotFound, kind+"_not_found", "no %s group with id %s", kind, id)
+	}
+	if err := ifMatch(r, id, s.version(kind, id)); err != nil {
+		return nil, err
+	}
+	group, err := groupInput(r, current)
+	if err != nil {
+		return nil, err
+	}
+	group.Id = id
+	if group.Engineers == nil {
+		group.Engineers = []string{}
+	}
+	if err := s.checkGroup(kind, group); err != nil {
+		return nil, err
+	}
+	*current = *group
+	return ok(s.render(kind, current), s.bump(kind, id)), nil
+}
+
+func (s *Server) deleteGroup(r *http.Request, kind string, id string) (*response, error) {
+	if _, found := s.findGroup(kind, id); !found {
+		return nil, problem(http.StatusNotFound, kind+"_not_found", "no %s group with id %s", kind, id)
+	}
+	if err := ifMatch(r, id, s.version(kind, id)); err != nil {
+		return nil, err
+	}
+	groups := s.groups(kind)
+	kept := (*groups)[:0]
+	for _, group := range *groups {
+		if group.Id != id {
+			kept = append(kept, group)
+		}
+	}
+	*groups = kept
+	for i := range s.chart.DevOps {
+		devops := &s.chart.DevOps[i]
+		if kind == kindDev && contains(devops.Devs, id) {
+			devops.Devs = without(devops.Devs, id)
+			s.bump(kindDevOps, devops.Id)
+		}
+		if kind == kindOps && contains(devops.Ops, id) {
+			devops.Ops = without(devops.Ops, id)
+			s.bump(kindDevOps, devops.Id)
+		}
+	}
+	delete(s.versions, kind+"/"+id)
+	message := map[string]string{kindDev: "developer resource deleted", kindOps: "operations resource deleted"}[kind]
+	return ok(map[string]string{"success": message}, 0), nil
+}
+
+// addMember handles POST /dev/:id, /op/:id, /devops/dev/:id and /devops/op/:id
+func (s *Server) addMember(r *http.Request, kind string, id string, memberKind string) (*response, error) {
+	var member struct {
+		Id string `json:"id"`
+	}
+	if err := decode(r, &member); err != nil {
+		return nil, err
+	}
+	if kind == kindDevOps {
+		devops, found := s.findDevOps(id)
+		if !found {
+			return nil, problem(http.StatusNotFound, "devops_not_found", "no devops group with id %s", id)
+		}
+		if _, found := s.findGroup(memberKind, member.Id); !found {
+			return nil, problem(http.StatusUnprocessableEntity, memberKind+"_not_found", "%s group %s does not exist", memberKind, member.Id)
+		}
+		members := &devops.Devs
+		if memberKind == kindOps {
+			members = &devops.Ops
+		}
+		if contains(*members, member.Id) {
+			return nil, problem(http.StatusConflict, memberKind+"_already_member", "%s group %s is already in devops group %s", memberKind, member.Id, id)
+		}
+		*members = append(*members, member.Id)
+		return ok(s.renderDevOps(devops), s.bump(kindDevOps, id)), nil
+	}
+
+	group, found := s.findGroup(kind, id)
+	if !found {
+		return nil, problem(http.StatusNotFound, kind+"_not_found", "no %s group with id %s", kind, id)
+	}
+	if _, found := s.findEngineer(member.Id); !found {
+		return nil, problem(http.StatusUnprocessableEntity, "engineer_not_found", "engineer %s does not exist", member.Id)
+	}
+	if contains(group.Engineers, member.Id) {
+		return nil, problem(http.StatusConflict, "engineer_already_member", "engineer %s is already in %s group %s", member.Id, kind, id)
+	}
+	group.Engineers = append(group.Engineers, member.Id)
+	return ok(s.render(kind, group), s.bump(kind, id)), nil
+}
+
+/******* devops groups *******/
+
+func (s *Server) findDevOps(id string) (*client.ChartDevOps, bool) {
+	for i := range s.chart.DevOps {
+		if s.chart.DevOps[i].Id == id {
+			return &s.chart.DevOps[i], true
+		}
+	}
+	return nil, false
+}
+
+func (s *Server) renderDevOps(devops *client.ChartDevOps) *devops_resource.DevOps {
+	rendered := &devops_resource.DevOps{Id: devops.Id, Devs: []*devops_resource.Dev{}, Ops: []*devops_resource.Ops{}}
+	for _, id := range devops.Devs {
+		if dev, found := s.findGroup(kindDev, id); found {
+			rendered.Devs = append(rendered.Devs, s.renderDev(dev))
+		}
+	}
+	for _, id := range devops.Ops {
+		if op, found := s.findGroup(kindOps, id); found {
+			rendered.Ops = append(rendered.Ops, s.renderOps(op))
+		}
+	}
+	return rendered
+}
+
+// devOpsMembers lists the dev and then the ops group ids of a devops group
+func devOpsMembers(devops *client.ChartDevOps) []struct {
+	kind string
+	ids  []string
+} {
+	return []struct {
+		kind string
+		ids  []string
+	}{{kindDev, devops.Devs}, {kindOps, devops.Ops}}
+}
+
+// hasEngineer reports whether one of the devops group's dev or ops groups contains engineerID
+func (s *Server) hasEngineer(devops *client.ChartDevOps, engineerID string) bool {
+	for _, members := range devOpsMembers(devops) {
+		for _, id := range members.ids {
+			if group, found := s.findGroup(members.kind, id); found && contains(group.Engineers, engineerID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *Server) listDevOps(query url.Values) (*response, error) {
+	groups := []client.ChartDevOps{}
+	for _, devops := range s.chart.DevOps {
+		if member := query.Get("member"); member != "" && !s.hasEngineer(&devops, member) {
+			continue
+		}
+		if dev := query.Get("dev"); dev != "" && !contains(devops.Devs, dev) {
+			continue
+		}
+		if op := query.Get("op"); op != "" && !contains(devops.Ops, op) {
+			continue
+		}
+		groups = append(groups, devops)
+	}
+	sortFields := map[string]func(client.ChartDevOps) string{"id": func(d client.ChartDevOps) string { return d.Id }}
+	if err := sortBy(groups, query.Get("sort"), sortFields); err != nil {
+		return nil, err
+	}
+	resp, err := page(groups, query)
+	if err != nil {
+		return nil, err
+	}
+	rendered := []*devops_resource.DevOps{}
+	for _, devops := range resp.body.([]client.ChartDevOps) {
+		rendered = append(rendered, s.renderDevOps(&devops))
+	}
+	resp.body = rendered
+	return resp, nil
+}
+
+func (s *Server) getDevOps(id string) (*response, error) {
+	devops, found := s.findDevOps(id)
+	if !found {
+		return nil, problem(http.StatusNotFound, "devops_not_found", "no devops group with id %s", id)
+	}
+	return ok(s.renderDevOps(devops), s.version(kindDevOps, id)), nil
+}
+
+// devOpsInput reads a devops group from a POST or PUT body, or a merge patch applied
+// to current, with its members as ids
+func devOpsInput(r *http.Request, current *client.ChartDevOps) (*client.ChartDevOps, error) {
+	if current != nil && r.Method == http.MethodPatch {
+		var devops client.ChartDevOps
+		if err := patched(r, current.Id, current, &devops); err != nil {
+			return nil, err
+		}
+		return &devops, nil
+	}
+	var body devops_resource.DevOps
+	if err := decode(r, &body); err != nil {
+		return nil, err
+	}
+	devops := &client.ChartDevOps{Devs: []string{}, Ops: []string{}}
+	for _, dev := range body.Devs {
+		if dev != nil {
+			devops.Devs = append(devops.Devs, dev.Id)
+		}
+	}
+	for _, op := range body.Ops {
+		if op != nil {
+			devops.Ops = append(devops.Ops, op.Id)
+		}
+	}
+	return devops, nil
+}
+
+// checkDevOps validates the members of a devops group the way the API does
+func (s *Server) checkDevOps(devops *client.ChartDevOps) error {
+	for _, members := range devOpsMembers(devops) {
+		if id, found := duplicate(members.ids); found {
+			return problem(http.StatusUnprocessableEntity, "duplicate_member", "%s group %s is listed more than once", members.kind, id)
+		}
+		for _, id := range members.ids {
+			if _, found := s.findGroup(members.kind, id); !found {
+				return problem(http.StatusUnprocessableEntity, members.kind+"_not_found", "%s group %s does not exist", members.kind, id)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) createDevOps(r *http.Request) (*response, error) {
+	devops, err := devOpsInput(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDevOps(devops); err != nil {
+		return nil, err
+	}
+	devops.Id = s.newID(kindDevOps)
+	s.chart.DevOps = append(s.chart.DevOps, *devops)
+	return &response{status: http.StatusCreated, body: s.renderDevOps(devops), version: s.bump(kindDevOps, devops.Id)}, nil
+}
+
+func (s *Server) updateDevOps(r *http.Request, id string) (*response, error) {
+	current, found := s.findDevOps(id)
+	if !found {
+		return nil, problem(http.StatusNotFound, "devops_not_found", "no devops group with id %s", id)
+	}
+	if err := ifMatch(r, id, s.version(kindDevOps, id)); err != nil {
+		return nil, err
+	}
+	devops, err := devOpsInput(r, current)
+	if err != nil {
+		return nil, err
+	}
+	devops.Id = id
+	if devops.Devs == nil {
+		devops.Devs = []string{}
+	}
+	if devops.Ops == nil {
+		devops.Ops = []string{}
+	}
+	if err := s.checkDevOps(devops); err != nil {
+		return nil, err
+	}
+	*current = *devops
+	return ok(s.renderDevOps(current), s.bump(kindDevOps, id)), nil
+}
+
+func (s *Server) deleteDevOps(r *http.Request, id string) (*response, error) {
+	if _, found := s.findDevOps(id); !found {
+		return nil, problem(http.StatusNotFound, "devops_not_found", "no devops group with id %s", id)
+	}
+	if err := ifMatch(r, id, s.version(kindDevOps, id)); err != nil {
+		return nil, err
+	}
+	kept := s.chart.DevOps[:0]
+	for _, devops := range s.chart.DevOps {
+		if devops.Id != id {
+			kept = append(kept, devops)
+		}
+	}
+	s.chart.DevOps = kept
+	delete(s.versions, kindDevOps+"/"+id)
+	return ok(map[string]string{"success": "developer operations resource deleted"}, 0), nil
+}
+
+/******* export and import *******/
+
+func (s *Server) export() *client.OrgChart {
+	chart := &client.OrgChart{
+		Engineers: []*devops_resource.Engineer{},
+		Devs:      []client.ChartGroup{},
+		Ops:       []client.ChartGroup{},
+		DevOps:    []client.ChartDevOps{},
+	}
+	for _, engineer := range s.chart.Engineers {
+		chart.Engineers = append(chart.Engineers, copyEngineer(engineer))
+	}
+	for _, group := range s.chart.Devs {
+		chart.Devs = append(chart.Devs, client.ChartGroup{Id: group.Id, Name: group.Name, Engineers: append([]string{}, group.Engineers...)})
+	}
+	for _, group := range s.chart.Ops {
+		chart.Ops = append(chart.Ops, client.ChartGroup{Id: group.Id, Name: group.Name, Engineers: append([]string{}, group.Engineers...)})
+	}
+	for _, devops := range s.chart.DevOps {
+		chart.DevOps = append(chart.DevOps, client.ChartDevOps{Id: devops.Id, Devs: append([]string{}, devops.Devs...), Ops: append([]string{}, devops.Ops...)})
+	}
+	return chart
+}
+
+func (s *Server) importChart(r *http.Request) (*response, error) {
+	var chart client.OrgChart
+	if err := decode(r, &chart); err != nil {
+		return nil, err
+	}
+	if err := s.load(&chart); err != nil {
+		return nil, err
+	}
+	return ok(client.ImportSummary{Engineers: len(chart.Engineers), Devs: len(chart.Devs), Ops: len(chart.Ops), DevOps: len(chart.DevOps)}, 0), nil
+}
+
+// load replaces all data with chart after checking it the way resources are checked
+// when created, leaving the data untouched when chart is invalid
+func (s *Server) load(chart *client.OrgChart) error {
+	previous, previousVersions, previousIDs := s.chart, s.versions, s.lastIDs
+	s.reset()
+	err := func() error {
+		for _, engineer := range chart.Engineers {
+			if engineer == nil || engineer.Id == "" {
+				return problem(http.StatusUnprocessableEntity, "chart_invalid", "every engineer needs an id")
+			}
+			if _, found := s.findEngineer(engineer.Id); found {
+				return problem(http.StatusUnprocessableEntity, "chart_invalid", "engineer %s is listed more than once", engineer.Id)
+			}
+			s.chart.Engineers = append(s.chart.Engineers, copyEngineer(engineer))
+		}
+		for _, engineer := range s.chart.Engineers {
+			if err := s.checkEngineer(engineer); err != nil {
+				return err
+			}
+		}
+		for _, kind := range []string{kindDev, kindOps} {
+			groups := chart.Devs
+			if kind == kindOps {
+				groups = chart.Ops
+			}
+			for _, group := range groups {
+				if _, found := s.findGroup(kind, group.Id); found || group.Id == "" {
+					return problem(http.StatusUnprocessableEntity, "chart_invalid", "%s group ids must be unique and not empty", kind)
+				}
+				group.Engineers = append([]string{}, group.Engineers...)
+				if err := s.checkGroup(kind, &group); err != nil {
+					return err
+				}
+				*s.groups(kind) = append(*s.groups(kind), group)
+			}
+		}
+		for _, devops := range chart.DevOps {
+			if _, found := s.findDevOps(devops.Id); found || devops.Id == "" {
+				return problem(http.StatusUnprocessableEntity, "chart_invalid", "devops group ids must be unique and not empty")
+			}
+			devops.Devs, devops.Ops = append([]string{}, devops.Devs...), append([]string{}, devops.Ops...)
+			if err := s.checkDevOps(&devops); err != nil {
+				return err
+			}
+			s.chart.DevOps = append(s.chart.DevOps, devops)
+		}
+		return nil
+	}()
+	if err != nil {
+		s.chart, s.versions, s.lastIDs = previous, previousVersions, previousIDs
+		return err
+	}
+	for _, engineer := range s.chart.Engineers {
+		s.bump(kindEngineer, engineer.Id)
+	}
+	for _, kind := range []string{kindDev, kindOps} {
+		for _, group := range *s.groups(kind) {
+			s.bump(kind, group.Id)
+		}
+	}
+	for _, devops := range s.chart.DevOps {
+		s.bump(kindDevOps, devops.Id)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// ListOps lists ops groups, see ListOptions for paging and filters
+func (c *Client) ListOps(ctx context.Context, opts ListOptions) (*Page[*devops_resource.Ops], error) {
+	return list[*devops_resource.Ops](ctx, c, join("op"), opts.values())
+}
+
+// GetOps reads the ops group with id, members expanded
+func (c *Client) GetOps(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Ops, error) {
+	return call[devops_resource.Ops](ctx, c, newRequest(http.MethodGet, join("op", "id", id), opts))
+}
+
+// GetOpsByName reads the ops group named name, members expanded
+func (c *Client) GetOpsByName(ctx context.Context, name string, opts ...RequestOption) (*devops_resource.Ops, error) {
+	return call[devops_resource.Ops](ctx, c, newRequest(http.MethodGet, join("op", "name", name), opts))
+}
+
+// CreateOps creates a ops group, the API picks its id. Members only need their Id.
+func (c *Client) CreateOps(ctx context.Context, group devops_resource.Ops, opts ...RequestOption) (*devops_resource.Ops, error) {
+	if group.Engineers == nil {
+		group.Engineers = []*devops_resource.Engineer{} // the API rejects null member lists
+	}
+	return send[devops_resource.Ops](ctx, c, http.MethodPost, join("op"), group, opts)
+}
+
+// UpdateOps replaces the name and members of the ops group with id
+func (c *Client) UpdateOps(ctx context.Context, id string, group devops_resource.Ops, opts ...RequestOption) (*devops_resource.Ops, error) {
+	if group.Engineers == nil {
+		group.Engineers = []*devops_resource.Engineer{} // the API rejects null member lists
+	}
+	return send[devops_resource.Ops](ctx, c, http.MethodPut, join("op", id), group, opts)
+}
+
+// PatchOps changes only what changes lists, see PatchEngineer. Patches see the
+// members as a list of ids.
+func (c *Client) PatchOps(ctx context.Context, id string, changes any, opts ...RequestOption) (*devops_resource.Ops, error) {
+	return patch[devops_resource.Ops](ctx, c, join("op", id), changes, opts)
+}
+
+// DeleteOps archives the ops group with id and removes it from its devops groups
+func (c *Client) DeleteOps(ctx context.Context, id string, opts ...RequestOption) error {
+	return c.do(ctx, newRequest(http.MethodDelete, join("op", id), opts), nil)
+}
+
+// RestoreOps brings back an archived ops group with the members that still exist
+func (c *Client) RestoreOps(ctx context.Context, id string, opts ...RequestOption) (*devops_resource.Ops, error) {
+	return call[devops_resource.Ops](ctx, c, newRequest(http.MethodPost, join("op", id, "restore"), opts))
+}
+
+// AddEngineerToOps adds the engineer with engineerID to the ops group with id
+func (c *Client) AddEngineerToOps(ctx context.Context, id string, engineerID string, opts ...RequestOption) (*devops_resource.Ops, error) {
+	return send[devops_resource.Ops](ctx, c, http.MethodPost, join("op", id), reference{Id: engineerID}, opts)
+}
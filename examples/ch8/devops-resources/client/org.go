@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Archive lists archived resources of kind, engineer, dev, ops or devops, oldest first.
+// An empty kind lists every archived resource.
+func (c *Client) Archive(ctx context.Context, kind string, opts ListOptions) (*Page[*ArchivedRecord], error) {
+	query := opts.values()
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+	return list[*ArchivedRecord](ctx, c, join("archive"), query)
+}
+
+// Stats counts engineers and groups, see GET /stats
+func (c *Client) Stats(ctx context.Context) (*Stats, error) {
+	return call[Stats](ctx, c, newRequest(http.MethodGet, join("stats"), nil))
+}
+
+// Search finds engineers by name or email and groups by name, best match first
+func (c *Client) Search(ctx context.Context, query string, opts SearchOptions) (*Page[*SearchResult], error) {
+	values := opts.values()
+	values.Set("q", query)
+	if opts.Kind != "" {
+		values.Set("kind", opts.Kind)
+	}
+	return list[*SearchResult](ctx, c, join("search"), values)
+}
+
+// Export reads every engineer and group in one document
+func (c *Client) Export(ctx context.Context) (*OrgChart, error) {
+	return call[OrgChart](ctx, c, newRequest(http.MethodGet, join("export"), nil))
+}
+
+// Import replaces all data with chart, all or nothing. Needs the admin role.
+func (c *Client) Import(ctx context.Context, chart *OrgChart) (*ImportSummary, error) {
+	return send[ImportSummary](ctx, c, http.MethodPost, join("import"), chart, nil)
+}
+
+// Audit reads the audit log, needs the admin role
+func (c *Client) Audit(ctx context.Context, opts AuditOptions) (*Page[*AuditEntry], error) {
+	query := opts.values()
+	if opts.Resource != "" {
+		query.Set("resource", opts.Resource)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.UTC().Format(time.RFC3339Nano))
+	}
+	return list[*AuditEntry](ctx, c, join("audit"), query)
+}
+
+// OpenAPI reads the OpenAPI document describing the API
+func (c *Client) OpenAPI(ctx context.Context) ([]byte, error) {
+	var document []byte
+	if err := c.do(ctx, newRequest(http.MethodGet, join("openapi.json"), nil), &document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Metrics reads the Prometheus metrics of the API in the text exposition format
+func (c *Client) Metrics(ctx context.Context) ([]byte, error) {
+	var metrics []byte
+	if err := c.do(ctx, newRequest(http.MethodGet, join("metrics"), nil), &metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// Healthy checks the API is serving requests, see GET /healthz
+func (c *Client) Healthy(ctx context.Context) error {
+	return c.do(ctx, newRequest(http.MethodGet, join("healthz"), nil), nil)
+}
+
+// Ready checks every backend of the API is available, see GET /readyz. An unavailable
+// backend fails with ErrServer, the Errors of the *Error name the failing checks.
+func (c *Client) Ready(ctx context.Context) error {
+	return c.do(ctx, newRequest(http.MethodGet, join("readyz"), nil), nil)
+}
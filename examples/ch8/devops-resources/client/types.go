@@ -0,0 +1,193 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// ListOptions pages, sorts and filters a list route. The zero value lists the first page
+// in insertion order. Filters a route doesn't know are rejected by the API.
+type ListOptions struct {
+	Limit       int    // at most this many items, 1-1000, the API's default of 100 when 0
+	Cursor      string // Page.NextCursor of the previous page, listed with the same Sort
+	Sort        string // name, -name (descending) or id, engineers can also sort by email
+	Name        string // engineers, dev and ops: case-insensitive substring of the name
+	EmailDomain string // engineers: only this email domain
+	Member      string // dev, ops and devops: only groups containing this engineer id
+	Dev         string // devops: only groups containing this dev group id
+	Ops         string // devops: only groups containing this ops group id
+}
+
+func (o ListOptions) values() url.Values {
+	query := url.Values{}
+	set := func(key string, value string) {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	set("cursor", o.Cursor)
+	set("sort", o.Sort)
+	set("name", o.Name)
+	set("email_domain", o.EmailDomain)
+	set("member", o.Member)
+	set("dev", o.Dev)
+	set("op", o.Ops)
+	return query
+}
+
+// Page is one page of a list route
+type Page[T any] struct {
+	Items      []T
+	Total      int    // number of items matching the filters across every page
+	NextCursor string // ListOptions.Cursor of the next page, empty on the last page
+}
+
+func newPage[T any](items []T, header http.Header) *Page[T] {
+	page := &Page[T]{Items: items, NextCursor: header.Get("X-Next-Cursor")}
+	page.Total, _ = strconv.Atoi(header.Get("X-Total-Count"))
+	if items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// PatchOperation is one step of an RFC 6902 JSON Patch
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// GroupRef names a dev or ops group
+type GroupRef struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// DevOpsMembership is a devops group reaching an engineer through the engineer's dev
+// and ops groups listed in Devs and Ops
+type DevOpsMembership struct {
+	Id   string   `json:"id"`
+	Devs []string `json:"dev"`
+	Ops  []string `json:"ops"`
+}
+
+// Memberships lists every group an engineer belongs to
+type Memberships struct {
+	Engineer *devops_resource.Engineer `json:"engineer"`
+	Devs     []GroupRef                `json:"dev"`
+	Ops      []GroupRef                `json:"ops"`
+	DevOps   []DevOpsMembership        `json:"devops"`
+}
+
+// GroupSize is the number of distinct engineers in a group
+type GroupSize struct {
+	Kind      string `json:"kind"`
+	Id        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	Engineers int    `json:"engineers"`
+}
+
+// Stats counts the resources of the organisation
+type Stats struct {
+	Engineers            int         `json:"engineers"`
+	Devs                 int         `json:"dev"`
+	Ops                  int         `json:"ops"`
+	DevOps               int         `json:"devops"`
+	GroupSizes           []GroupSize `json:"group_sizes"`
+	OrphanedEngineers    []string    `json:"orphaned_engineers"`
+	EngineersInDevAndOps []string    `json:"engineers_in_dev_and_ops"`
+}
+
+// SearchOptions narrows a search to one kind, engineer, dev or ops, and pages it
+type SearchOptions struct {
+	Kind string
+	ListOptions
+}
+
+// SearchResult is one ranked hit of a search
+type SearchResult struct {
+	Kind  string `json:"kind"`
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+	Score int    `json:"score"`
+}
+
+// ArchivedRecord is a deleted resource that can be restored. Resource holds the
+// resource as it was, decode it into the devops_resource type matching Kind.
+type ArchivedRecord struct {
+	Kind        string              `json:"kind"`
+	Id          string              `json:"id"`
+	ArchivedAt  time.Time           `json:"archived_at"`
+	ArchivedBy  string              `json:"archived_by"`
+	Resource    json.RawMessage     `json:"resource"`
+	Memberships map[string][]string `json:"memberships"`
+}
+
+// OrgChart is the export and import document, memberships are listed as ids
+type OrgChart struct {
+	Engineers []*devops_resource.Engineer `json:"engineers"`
+	Devs      []ChartGroup                `json:"dev"`
+	Ops       []ChartGroup                `json:"ops"`
+	DevOps    []ChartDevOps               `json:"devops"`
+}
+
+type ChartGroup struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Engineers []string `json:"engineers"`
+}
+
+type ChartDevOps struct {
+	Id   string   `json:"id"`
+	Devs []string `json:"dev"`
+	Ops  []string `json:"ops"`
+}
+
+// ImportSummary counts the resources an import created
+type ImportSummary struct {
+	Engineers int `json:"engineers"`
+	Devs      int `json:"dev"`
+	Ops       int `json:"ops"`
+	DevOps    int `json:"devops"`
+}
+
+// AuditOptions selects audit entries. Resource is engineers, dev, ops, devops or
+// import, optionally followed by /<id>. Sort is time or -time.
+type AuditOptions struct {
+	Resource string
+	Since    time.Time
+	ListOptions
+}
+
+// AuditChange is the value of a field before and after a change
+type AuditChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditEntry records one change made through the API
+type AuditEntry struct {
+	Seq        int64                  `json:"seq"`
+	Time       time.Time              `json:"time"`
+	Actor      string                 `json:"actor"`
+	Client     string                 `json:"client"`
+	Method     string                 `json:"method"`
+	Route      string                 `json:"route"`
+	Path       string                 `json:"path"`
+	Resource   string                 `json:"resource"`
+	ResourceID string                 `json:"resource_id,omitempty"`
+	Before     json.RawMessage        `json:"before,omitempty"`
+	After      json.RawMessage        `json:"after,omitempty"`
+	Diff       map[string]AuditChange `json:"diff,omitempty"`
+}
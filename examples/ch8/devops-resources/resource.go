@@ -1,9 +1,16 @@
 package devops_resource
 
+// Engineer is an individual engineer. Only Name and Email are required, ManagerId is
+// the Id of another engineer and Timezone an IANA time zone such as America/Denver.
 type Engineer struct {
-	Name  string `json:"name"`
-	Id    string `json:"id"`
-	Email string `json:"email"`
+	Name      string   `json:"name" yaml:"name"`
+	Id        string   `json:"id" yaml:"id"`
+	Email     string   `json:"email" yaml:"email"`
+	Role      string   `json:"role,omitempty" yaml:"role,omitempty"`
+	Skills    []string `json:"skills,omitempty" yaml:"skills,omitempty"`
+	Timezone  string   `json:"timezone,omitempty" yaml:"timezone,omitempty"`
+	ManagerId string   `json:"manager_id,omitempty" yaml:"manager_id,omitempty"`
+	OnCall    bool     `json:"on_call,omitempty" yaml:"on_call,omitempty"`
 }
 
 type Dev struct {
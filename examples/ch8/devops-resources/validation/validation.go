@@ -0,0 +1,161 @@
+// Package validation checks devops_resource values, reporting every problem with the
+// field it belongs to so a client can show them next to its inputs.
+//
+//	if errs := validation.Engineer(&engineer); len(errs) > 0 {
+//		for _, err := range errs {
+//			fmt.Println(err.Field, err.Message)
+//		}
+//	}
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	_ "time/tzdata" // time zones validate the same on machines without a zoneinfo database
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+// Limits on the fields of an engineer
+const (
+	MaxEmailLength = 254
+	MaxLocalLength = 64
+	MaxRoleLength  = 64
+	MaxSkills      = 50
+	MaxSkillLength = 64
+)
+
+// FieldError is a problem with one field, Field uses the JSON name of the field and
+// indexes list items as in skills[2]
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string { return e.Field + ": " + e.Message }
+
+// Errors lists every problem found in a value, it is empty when the value is valid
+type Errors []FieldError
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Add records a problem with field
+func (errs *Errors) Add(field string, message string) {
+	*errs = append(*errs, FieldError{Field: field, Message: message})
+}
+
+// Err returns errs as an error, or nil when there are none
+func (errs Errors) Err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Email checks that address is a bare RFC 5322 address, such as bob@liatrio.com or
+// "bob smith"@liatrio.com, on a domain that can receive mail. Display names, angle
+// brackets and comments are rejected.
+func Email(address string) error {
+	if address == "" {
+		return errors.New("is required")
+	}
+	if len(address) > MaxEmailLength {
+		return fmt.Errorf("must be at most %d characters", MaxEmailLength)
+	}
+	parsed, err := mail.ParseAddress(address)
+	if err != nil || parsed.Name != "" || strings.ContainsAny(address, "<>") {
+		return errors.New("must be an email address such as bob@liatrio.com")
+	}
+	at := strings.LastIndex(parsed.Address, "@")
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+	if !strings.HasSuffix(address, "@"+domain) {
+		return errors.New("must be an email address such as bob@liatrio.com")
+	}
+	if len(local) > MaxLocalLength {
+		return fmt.Errorf("must have at most %d characters before the @", MaxLocalLength)
+	}
+	if err := hostname(domain); err != nil {
+		return err
+	}
+	return nil
+}
+
+// hostname checks that domain is made of two or more DNS labels
+func hostname(domain string) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return errors.New("must have a domain such as liatrio.com")
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("has an invalid domain %s", domain)
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return fmt.Errorf("has an invalid domain %s", domain)
+			}
+		}
+	}
+	return nil
+}
+
+// Timezone checks that name is an IANA time zone such as America/Denver or UTC
+func Timezone(name string) error {
+	if name == "Local" {
+		return errors.New("must be an IANA time zone such as America/Denver")
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return errors.New("must be an IANA time zone such as America/Denver")
+	}
+	return nil
+}
+
+// Engineer checks every field of engineer. References to other engineers, such as
+// ManagerId, can only be checked by whoever stores them.
+func Engineer(engineer *devops_resource.Engineer) Errors {
+	errs := Errors{}
+	if strings.TrimSpace(engineer.Name) == "" {
+		errs.Add("name", "is required")
+	}
+	if err := Email(engineer.Email); err != nil {
+		errs.Add("email", err.Error())
+	}
+	if len(engineer.Role) > MaxRoleLength {
+		errs.Add("role", fmt.Sprintf("must be at most %d characters", MaxRoleLength))
+	}
+	if len(engineer.Skills) > MaxSkills {
+		errs.Add("skills", fmt.Sprintf("must have at most %d skills", MaxSkills))
+	}
+	seen := map[string]bool{}
+	for i, skill := range engineer.Skills {
+		field := fmt.Sprintf("skills[%d]", i)
+		switch key := strings.ToLower(strings.TrimSpace(skill)); {
+		case key == "":
+			errs.Add(field, "cannot be empty")
+		case len(skill) > MaxSkillLength:
+			errs.Add(field, fmt.Sprintf("must be at most %d characters", MaxSkillLength))
+		case seen[key]:
+			errs.Add(field, "is listed twice")
+		default:
+			seen[key] = true
+		}
+	}
+	if engineer.Timezone != "" {
+		if err := Timezone(engineer.Timezone); err != nil {
+			errs.Add("timezone", err.Error())
+		}
+	}
+	if engineer.ManagerId != "" && engineer.ManagerId == engineer.Id {
+		errs.Add("manager_id", "cannot be the engineer itself")
+	}
+	return errs
+}
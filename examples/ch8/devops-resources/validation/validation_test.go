@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+)
+
+var emailTests = []struct {
+	description string
+	email       string
+	expected    bool
+}{
+	{"simple address", "bob@bob.com", true},
+	{"long top-level domain", "bob@liatrio.engineering", true},
+	{"subdomains", "b0b123@clever.ask.who", true},
+	{"special characters in the local part", "bob#+ops@bob.com", true},
+	{"quoted local part", `"bob smith"@bob.com`, true},
+	{"no domain", "bob@bob", false},
+	{"empty", "", false},
+	{"display name", "Bob <bob@bob.com>", false},
+	{"angle brackets", "<bob@bob.com>", false},
+	{"comment", "bob@bob.com (Bob)", false},
+	{"two @", "bob@@bob.com", false},
+	{"trailing dot", "bob@bob.com.", false},
+	{"dot before @", "bob.@bob.com", false},
+	{"domain literal", "bob@[10.0.0.1]", false},
+	{"label starting with a hyphen", "bob@-bob.com", false},
+	{"local part too long", "bobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbobbob@bob.com", false},
+}
+
+func TestEmail(t *testing.T) {
+	for _, test := range emailTests {
+		if err := Email(test.email); (err == nil) != test.expected {
+			t.Errorf("\nTest: %s\nEmail: %s\nExpected: %t, Received: %v", test.description, test.email, test.expected, err)
+		}
+	}
+}
+
+var engineerTests = []struct {
+	description string
+	engineer    devops_resource.Engineer
+	expected    []string
+}{
+	{"name and email only", devops_resource.Engineer{Name: "bob", Email: "bob@bob.com"}, []string{}},
+	{"full profile", devops_resource.Engineer{
+		Name: "bob", Id: "E1", Email: "bob@bob.com", Role: "sre", Skills: []string{"go", "terraform"},
+		Timezone: "America/Denver", ManagerId: "E2", OnCall: true,
+	}, []string{}},
+	{"every field wrong", devops_resource.Engineer{
+		Name: " ", Id: "E1", Email: "bob", Skills: []string{"go", "", "Go"}, Timezone: "Mars/Olympus", ManagerId: "E1",
+	}, []string{"name", "email", "skills[1]", "skills[2]", "timezone", "manager_id"}},
+	{"local time zone", devops_resource.Engineer{Name: "bob", Email: "bob@bob.com", Timezone: "Local"}, []string{"timezone"}},
+}
+
+func TestEngineer(t *testing.T) {
+	for _, test := range engineerTests {
+		errs := Engineer(&test.engineer)
+		fields := make([]string, 0, len(errs))
+		for _, err := range errs {
+			fields = append(fields, err.Field)
+		}
+		if !reflect.DeepEqual(fields, test.expected) {
+			t.Errorf("\nTest: %s\nExpected: %v, Received: %v", test.description, test.expected, errs)
+		}
+		if (errs.Err() == nil) != (len(test.expected) == 0) {
+			t.Errorf("\nTest: %s\nExpected: Err() to be nil only without errors, Received: %v", test.description, errs.Err())
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+)
+
+// completeCommand is the hidden command the completion scripts call with the words
+// typed so far, the last one being the word to complete
+const completeCommand = "__complete"
+
+const bashCompletion = `# bash completion for devopsctl, load it with
+#   source <(devopsctl completion bash)
+_devopsctl() {
+    local IFS=$'\n'
+    COMPREPLY=($(devopsctl __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _devopsctl devopsctl
+`
+
+const zshCompletion = `#compdef devopsctl
+# zsh completion for devopsctl, load it with
+#   source <(devopsctl completion zsh)
+_devopsctl() {
+    local -a candidates
+    candidates=(${(f)"$(devopsctl __complete "${(@)words[2,CURRENT]}" 2>/dev/null)"})
+    compadd -a candidates
+}
+compdef _devopsctl devopsctl
+`
+
+func completionCommand() *command {
+	return &command{
+		name:    "completion",
+		summary: "print the shell completion script for bash or zsh",
+		commands: []*command{
+			{
+				name:    "bash",
+				summary: "print the bash completion script",
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					_, err := io.WriteString(a.stdout, bashCompletion)
+					return err
+				},
+			},
+			{
+				name:    "zsh",
+				summary: "print the zsh completion script",
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					_, err := io.WriteString(a.stdout, zshCompletion)
+					return err
+				},
+			},
+		},
+	}
+}
+
+// complete prints the candidates for the last of words, one per line: subcommands,
+// flags, flag values, or the ids of the resources a positional argument names. Ids are
+// read from the selected server, completion stays quiet when it can't be reached.
+func (a *app) complete(ctx context.Context, cmd *command, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, typed := words[len(words)-1], words[:len(words)-1]
+
+	fs := a.completionFlags(cmd)
+	positional := 0
+	previous := ""
+	for i := 0; i < len(typed); i++ {
+		word := typed[i]
+		previous = ""
+		if strings.HasPrefix(word, "-") {
+			name, value, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+			f := fs.Lookup(name)
+			if f == nil || hasValue || isBool(f) {
+				if f != nil && hasValue {
+					fs.Set(name, value)
+				}
+				continue
+			}
+			if i+1 < len(typed) {
+				i++
+				fs.Set(name, typed[i])
+				continue
+			}
+			previous = name
+			continue
+		}
+		if sub := cmd.find(word); sub != nil && cmd.commands != nil {
+			cmd = sub
+			fs = a.completionFlags(cmd)
+			continue
+		}
+		positional++
+	}
+
+	var candidates []string
+	switch {
+	case previous == "o" || previous == "output":
+		candidates = []string{"table", "json", "yaml"}
+	case previous == "context":
+		candidates = a.contextNames()
+	case previous != "":
+		return // a flag value we can't guess
+	case strings.HasPrefix(current, "-"):
+		fs.VisitAll(func(f *flag.Flag) {
+			if len(f.Name) == 1 {
+				candidates = append(candidates, "-"+f.Name)
+			} else {
+				candidates = append(candidates, "--"+f.Name)
+			}
+		})
+	case cmd.commands != nil:
+		for _, sub := range cmd.commands {
+			candidates = append(candidates, sub.name)
+		}
+	case len(cmd.complete) > 0:
+		kind := cmd.complete[len(cmd.complete)-1]
+		if positional < len(cmd.complete) {
+			kind = cmd.complete[positional]
+		} else if !strings.Contains(cmd.args, "...") {
+			return
+		}
+		candidates = a.completeKind(ctx, kind)
+	}
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(a.stdout, candidate)
+		}
+	}
+}
+
+// completionFlags are the flags of cmd bound to a, so --config, --context, --server and
+// --token typed before the word to complete select the server ids are read from
+func (a *app) completionFlags(cmd *command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	a.globalFlags(fs)
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	return fs
+}
+
+func isBool(f *flag.Flag) bool {
+	getter, ok := f.Value.(flag.Getter)
+	if !ok {
+		return false
+	}
+	_, ok = getter.Get().(bool)
+	return ok
+}
+
+func (a *app) contextNames() []string {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, c := range cfg.Contexts {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// completeKind lists the ids of every resource of kind, or the context names
+func (a *app) completeKind(ctx context.Context, kind string) []string {
+	if kind == "context" {
+		return a.contextNames()
+	}
+	api, err := a.client()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	quiet := &app{stderr: io.Discard}
+	var ids []string
+	switch kind {
+	case "engineer":
+		engineers, _ := listAll(quiet, client.ListOptions{}, func(opts client.ListOptions) (*client.Page[*devops_resource.Engineer], error) {
+			return api.ListEngineers(ctx, opts)
+		})
+		for _, engineer := range engineers {
+			ids = append(ids, engineer.Id)
+		}
+	case "dev":
+		devs, _ := listAll(quiet, client.ListOptions{}, func(opts client.ListOptions) (*client.Page[*devops_resource.Dev], error) {
+			return api.ListDevs(ctx, opts)
+		})
+		for _, dev := range devs {
+			ids = append(ids, dev.Id)
+		}
+	case "ops":
+		ops, _ := listAll(quiet, client.ListOptions{}, func(opts client.ListOptions) (*client.Page[*devops_resource.Ops], error) {
+			return api.ListOps(ctx, opts)
+		})
+		for _, op := range ops {
+			ids = append(ids, op.Id)
+		}
+	case "devops":
+		groups, _ := listAll(quiet, client.ListOptions{}, func(opts client.ListOptions) (*client.Page[*devops_resource.DevOps], error) {
+			return api.ListDevOps(ctx, opts)
+		})
+		for _, devops := range groups {
+			ids = append(ids, devops.Id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultServer is used when no context is configured, it is where make run serves
+// the devops api
+const defaultServer = "http://localhost:8080"
+
+// config is the devopsctl config file, a list of named servers and the one in use
+//
+//	current-context: local
+//	contexts:
+//	  - name: local
+//	    server: http://localhost:8080
+//	  - name: staging
+//	    server: https://devops-api.staging.example.com
+//	    token: s3cr3t
+type config struct {
+	CurrentContext string          `yaml:"current-context,omitempty"`
+	Contexts       []contextConfig `yaml:"contexts"`
+}
+
+type contextConfig struct {
+	Name   string `yaml:"name"`
+	Server string `yaml:"server"`
+	Token  string `yaml:"token,omitempty"`
+}
+
+// configPath is path, or $XDG_CONFIG_HOME/devopsctl/config.yaml (~/.config on Linux,
+// the platform's config directory elsewhere) when path is empty
+func configPath(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "devopsctl", "config.yaml"), nil
+}
+
+// loadConfig reads the config file at path, a missing file is an empty config
+func loadConfig(path string) (*config, error) {
+	path, err := configPath(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &config{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg := &config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// save writes the config file, readable only by its owner since it holds tokens
+func (cfg *config) save(path string) error {
+	path, err := configPath(path)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func (cfg *config) find(name string) *contextConfig {
+	for i := range cfg.Contexts {
+		if cfg.Contexts[i].Name == name {
+			return &cfg.Contexts[i]
+		}
+	}
+	return nil
+}
+
+// selected is the context named name, or the current context when name is empty. Without
+// any context the devops api on localhost is used.
+func (cfg *config) selected(name string) (*contextConfig, error) {
+	if name == "" {
+		name = cfg.CurrentContext
+	}
+	if name == "" {
+		return &contextConfig{Server: defaultServer}, nil
+	}
+	selected := cfg.find(name)
+	if selected == nil {
+		return nil, fmt.Errorf("context %q is not configured, see devopsctl config get-contexts", name)
+	}
+	return selected, nil
+}
+
+func configCommand() *command {
+	return &command{
+		name:    "config",
+		summary: "manage the contexts of the config file, one per devops api server",
+		commands: []*command{
+			{
+				name:    "get-contexts",
+				summary: "list the configured contexts",
+				run:     getContexts,
+			},
+			{
+				name:    "current-context",
+				summary: "print the name of the current context",
+				run:     currentContext,
+			},
+			{
+				name:     "use-context",
+				args:     "<name>",
+				summary:  "make name the current context",
+				complete: []string{"context"},
+				run:      useContext,
+			},
+			{
+				name:     "set-context",
+				args:     "<name>",
+				summary:  "add or change a context, with --server and --token",
+				complete: []string{"context"},
+				flags: func(fs *flag.FlagSet) {
+					fs.Bool("use", false, "also make it the current context")
+				},
+				run: setContext,
+			},
+			{
+				name:     "delete-context",
+				args:     "<name>",
+				summary:  "remove a context",
+				complete: []string{"context"},
+				run:      deleteContext,
+			},
+		},
+	}
+}
+
+// contextView is a context as listed, without its token
+type contextView struct {
+	Name    string `json:"name"`
+	Server  string `json:"server"`
+	Current bool   `json:"current"`
+}
+
+func getContexts(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return err
+	}
+	views := make([]contextView, 0, len(cfg.Contexts))
+	for _, c := range cfg.Contexts {
+		views = append(views, contextView{Name: c.Name, Server: c.Server, Current: c.Name == cfg.CurrentContext})
+	}
+	return a.print(views, func() *table {
+		t := &table{header: []string{"CURRENT", "NAME", "SERVER"}}
+		for _, view := range views {
+			current := ""
+			if view.Current {
+				current = "*"
+			}
+			t.add(current, view.Name, view.Server)
+		}
+		return t
+	})
+}
+
+func currentContext(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return err
+	}
+	if cfg.CurrentContext == "" {
+		return errors.New("no current context, the devops api at " + defaultServer + " is used")
+	}
+	fmt.Fprintln(a.stdout, cfg.CurrentContext)
+	return nil
+}
+
+func useContext(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return err
+	}
+	if cfg.find(args[0]) == nil {
+		return fmt.Errorf("context %q is not configured", args[0])
+	}
+	cfg.CurrentContext = args[0]
+	if err := cfg.save(a.config); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Switched to context %q.\n", args[0])
+	return nil
+}
+
+func setContext(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	c := cfg.find(name)
+	if c == nil {
+		if a.server == "" {
+			return usagef("--server is required for a new context")
+		}
+		cfg.Contexts = append(cfg.Contexts, contextConfig{Name: name})
+		c = &cfg.Contexts[len(cfg.Contexts)-1]
+	}
+	if a.server != "" {
+		c.Server = a.server
+	}
+	if a.token != "" {
+		c.Token = a.token
+	}
+	if boolFlag(fs, "use") || cfg.CurrentContext == "" {
+		cfg.CurrentContext = name
+	}
+	if err := cfg.save(a.config); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Context %q set.\n", name)
+	return nil
+}
+
+func deleteContext(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return err
+	}
+	kept := cfg.Contexts[:0]
+	for _, c := range cfg.Contexts {
+		if c.Name != args[0] {
+			kept = append(kept, c)
+		}
+	}
+	if len(kept) == len(cfg.Contexts) {
+		return fmt.Errorf("context %q is not configured", args[0])
+	}
+	cfg.Contexts = kept
+	if cfg.CurrentContext == args[0] {
+		cfg.CurrentContext = ""
+	}
+	if err := cfg.save(a.config); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "Context %q deleted.\n", args[0])
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
+)
+
+func engineersCommand() *command {
+	return &command{
+		name:    "engineers",
+		summary: "list, read, create and delete engineers",
+		commands: []*command{
+			{
+				name:    "list",
+				summary: "list engineers, every page unless --limit is given",
+				flags: func(fs *flag.FlagSet) {
+					listFlags(fs)
+					fs.String("email-domain", "", "only engineers with this email domain")
+				},
+				run: listEngineers,
+			},
+			{
+				name:     "get",
+				args:     "[<id>]",
+				summary:  "read an engineer by id, --name or --email",
+				complete: []string{"engineer"},
+				flags: func(fs *flag.FlagSet) {
+					fs.String("name", "", "read the engineer with this name")
+					fs.String("email", "", "read the engineer with this email")
+				},
+				run: getEngineer,
+			},
+			{
+				name:    "create",
+				summary: "create an engineer, --name and --email are required",
+				flags: func(fs *flag.FlagSet) {
+					fs.String("name", "", "name of the engineer")
+					fs.String("email", "", "email of the engineer")
+					fs.String("role", "", "role, e.g. platform engineer")
+					fs.String("skills", "", "comma separated skills")
+					fs.String("timezone", "", "IANA time zone, e.g. America/Denver")
+					fs.String("manager", "", "id of the engineer's manager")
+					fs.Bool("on-call", false, "the engineer is on call")
+				},
+				run: createEngineer,
+			},
+			{
+				name:     "delete",
+				args:     "<id>...",
+				summary:  "delete engineers, they are removed from their groups",
+				complete: []string{"engineer"},
+				run:      deleteEngineers,
+			},
+		},
+	}
+}
+
+// listFlags are the paging, sorting and name filter flags of every list command
+func listFlags(fs *flag.FlagSet) {
+	fs.Int("limit", 0, "list at most this many, every page when 0")
+	fs.String("cursor", "", "continue after the page that printed this cursor")
+	fs.String("sort", "", "name, -name (descending) or id")
+	fs.String("name", "", "only names containing this, case-insensitive")
+}
+
+func listOptions(fs *flag.FlagSet) client.ListOptions {
+	opts := client.ListOptions{
+		Limit:  intFlag(fs, "limit"),
+		Cursor: stringFlag(fs, "cursor"),
+		Sort:   stringFlag(fs, "sort"),
+		Name:   stringFlag(fs, "name"),
+	}
+	if f := fs.Lookup("email-domain"); f != nil {
+		opts.EmailDomain = f.Value.String()
+	}
+	return opts
+}
+
+// listAll pages through a list route. With a limit only the first page is read and
+// the cursor of the next one is reported on stderr.
+func listAll[T any](a *app, opts client.ListOptions, list func(client.ListOptions) (*client.Page[T], error)) ([]T, error) {
+	var items []T
+	for {
+		page, err := list(opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items, nil
+		}
+		if opts.Limit > 0 {
+			fmt.Fprintf(a.stderr, "%d of %d shown, continue with --cursor %s\n", len(items), page.Total, page.NextCursor)
+			return items, nil
+		}
+		opts.Cursor = page.NextCursor
+	}
+}
+
+func listEngineers(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	engineers, err := listAll(a, listOptions(fs), func(opts client.ListOptions) (*client.Page[*devops_resource.Engineer], error) {
+		return api.ListEngineers(ctx, opts)
+	})
+	if err != nil {
+		return err
+	}
+	if engineers == nil {
+		engineers = []*devops_resource.Engineer{}
+	}
+	return a.print(engineers, func() *table { return engineerTable(engineers...) })
+}
+
+func getEngineer(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	name, email := stringFlag(fs, "name"), stringFlag(fs, "email")
+	given := len(args)
+	if name != "" {
+		given++
+	}
+	if email != "" {
+		given++
+	}
+	if given != 1 {
+		return usagef("give exactly one of <id>, --name or --email")
+	}
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	var engineer *devops_resource.Engineer
+	switch {
+	case name != "":
+		engineer, err = api.GetEngineerByName(ctx, name)
+	case email != "":
+		engineer, err = api.GetEngineerByEmail(ctx, email)
+	default:
+		engineer, err = api.GetEngineer(ctx, args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return a.print(engineer, func() *table { return engineerTable(engineer) })
+}
+
+func createEngineer(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	engineer := devops_resource.Engineer{
+		Name:      stringFlag(fs, "name"),
+		Email:     stringFlag(fs, "email"),
+		Role:      stringFlag(fs, "role"),
+		Timezone:  stringFlag(fs, "timezone"),
+		ManagerId: stringFlag(fs, "manager"),
+		OnCall:    boolFlag(fs, "on-call"),
+	}
+	for _, skill := range strings.Split(stringFlag(fs, "skills"), ",") {
+		if skill = strings.TrimSpace(skill); skill != "" {
+			engineer.Skills = append(engineer.Skills, skill)
+		}
+	}
+	// report every problem at once instead of one round trip per mistake
+	if errs := validation.Engineer(&engineer); len(errs) > 0 {
+		return errs
+	}
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	created, err := api.CreateEngineer(ctx, engineer)
+	if err != nil {
+		return err
+	}
+	return a.print(created, func() *table { return engineerTable(created) })
+}
+
+func deleteEngineers(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	for _, id := range args {
+		if err := api.DeleteEngineer(ctx, id); err != nil {
+			return err
+		}
+		fmt.Fprintf(a.stdout, "Engineer %s deleted.\n", id)
+	}
+	return nil
+}
+
+// flagFor is the create flag of the engineer field a validation error names
+func flagFor(field string) string {
+	if i := strings.IndexByte(field, '['); i >= 0 {
+		field = field[:i]
+	}
+	switch field {
+	case "manager_id":
+		return "manager"
+	default:
+		return strings.ReplaceAll(field, "_", "-")
+	}
+}
@@ -0,0 +1,11 @@
+module devopsctl
+
+go 1.25.0
+
+require (
+	github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources v0.0.0-20230921193819-569bb9d9dbdd
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+// devops-resources is developed alongside the API, build against the copy in this repository
+replace github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources => ../devops-resources
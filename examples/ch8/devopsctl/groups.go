@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"flag"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+)
+
+// groupCommand manages the dev or ops groups, kind is dev or ops
+func groupCommand(kind string) *command {
+	return &command{
+		name:    kind,
+		summary: "list and read " + kind + " groups and add engineers to them",
+		commands: []*command{
+			{
+				name:    "list",
+				summary: "list " + kind + " groups, every page unless --limit is given",
+				flags: func(fs *flag.FlagSet) {
+					listFlags(fs)
+					fs.String("member", "", "only groups containing this engineer id")
+				},
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					return listGroups(ctx, a, fs, kind)
+				},
+			},
+			{
+				name:     "get",
+				args:     "<id>",
+				summary:  "read a " + kind + " group with its engineers",
+				complete: []string{kind},
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					return getGroup(ctx, a, kind, args[0])
+				},
+			},
+			{
+				name:     "add-engineer",
+				args:     "<" + kind + "-id> <engineer-id>",
+				summary:  "add an engineer to a " + kind + " group",
+				complete: []string{kind, "engineer"},
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					return addEngineer(ctx, a, kind, args[0], args[1])
+				},
+			},
+		},
+	}
+}
+
+func listGroups(ctx context.Context, a *app, fs *flag.FlagSet, kind string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	opts := listOptions(fs)
+	opts.Member = stringFlag(fs, "member")
+	var groups []group
+	var value any
+	if kind == "dev" {
+		devs, err := listAll(a, opts, func(opts client.ListOptions) (*client.Page[*devops_resource.Dev], error) {
+			return api.ListDevs(ctx, opts)
+		})
+		if err != nil {
+			return err
+		}
+		for _, dev := range devs {
+			groups = append(groups, group{dev.Id, dev.Name, dev.Engineers})
+		}
+		value = devs
+	} else {
+		ops, err := listAll(a, opts, func(opts client.ListOptions) (*client.Page[*devops_resource.Ops], error) {
+			return api.ListOps(ctx, opts)
+		})
+		if err != nil {
+			return err
+		}
+		for _, op := range ops {
+			groups = append(groups, group{op.Id, op.Name, op.Engineers})
+		}
+		value = ops
+	}
+	if groups == nil {
+		value = []any{}
+	}
+	return a.print(value, func() *table { return groupTable(groups...) })
+}
+
+func getGroup(ctx context.Context, a *app, kind string, id string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	if kind == "dev" {
+		dev, err := api.GetDev(ctx, id)
+		if err != nil {
+			return err
+		}
+		return a.print(dev, func() *table { return groupTable(group{dev.Id, dev.Name, dev.Engineers}) })
+	}
+	ops, err := api.GetOps(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.print(ops, func() *table { return groupTable(group{ops.Id, ops.Name, ops.Engineers}) })
+}
+
+func addEngineer(ctx context.Context, a *app, kind string, id string, engineerID string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	if kind == "dev" {
+		dev, err := api.AddEngineerToDev(ctx, id, engineerID)
+		if err != nil {
+			return err
+		}
+		return a.print(dev, func() *table { return groupTable(group{dev.Id, dev.Name, dev.Engineers}) })
+	}
+	ops, err := api.AddEngineerToOps(ctx, id, engineerID)
+	if err != nil {
+		return err
+	}
+	return a.print(ops, func() *table { return groupTable(group{ops.Id, ops.Name, ops.Engineers}) })
+}
+
+func devOpsCommand() *command {
+	return &command{
+		name:    "devops",
+		summary: "show devops groups and add dev and ops groups to them",
+		commands: []*command{
+			{
+				name:    "list",
+				summary: "list devops groups, every page unless --limit is given",
+				flags: func(fs *flag.FlagSet) {
+					fs.Int("limit", 0, "list at most this many, every page when 0")
+					fs.String("cursor", "", "continue after the page that printed this cursor")
+					fs.String("sort", "", "id or -id (descending)")
+					fs.String("dev", "", "only groups containing this dev group id")
+					fs.String("ops", "", "only groups containing this ops group id")
+				},
+				run: listDevOps,
+			},
+			{
+				name:     "show",
+				args:     "[<id>]",
+				summary:  "show a devops group, or every devops group, --tree draws its members",
+				complete: []string{"devops"},
+				flags: func(fs *flag.FlagSet) {
+					fs.Bool("tree", false, "draw the dev and ops groups and their engineers as a tree")
+				},
+				run: showDevOps,
+			},
+			{
+				name:     "add-dev",
+				args:     "<devops-id> <dev-id>",
+				summary:  "add a dev group to a devops group",
+				complete: []string{"devops", "dev"},
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					return addToDevOps(ctx, a, "dev", args[0], args[1])
+				},
+			},
+			{
+				name:     "add-ops",
+				args:     "<devops-id> <ops-id>",
+				summary:  "add an ops group to a devops group",
+				complete: []string{"devops", "ops"},
+				run: func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+					return addToDevOps(ctx, a, "ops", args[0], args[1])
+				},
+			},
+		},
+	}
+}
+
+func allDevOps(ctx context.Context, a *app, api *client.Client, opts client.ListOptions) ([]*devops_resource.DevOps, error) {
+	groups, err := listAll(a, opts, func(opts client.ListOptions) (*client.Page[*devops_resource.DevOps], error) {
+		return api.ListDevOps(ctx, opts)
+	})
+	if groups == nil {
+		groups = []*devops_resource.DevOps{}
+	}
+	return groups, err
+}
+
+func listDevOps(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	groups, err := allDevOps(ctx, a, api, client.ListOptions{
+		Limit:  intFlag(fs, "limit"),
+		Cursor: stringFlag(fs, "cursor"),
+		Sort:   stringFlag(fs, "sort"),
+		Dev:    stringFlag(fs, "dev"),
+		Ops:    stringFlag(fs, "ops"),
+	})
+	if err != nil {
+		return err
+	}
+	return a.print(groups, func() *table { return devOpsTable(groups...) })
+}
+
+// showDevOps prints one devops group, or all of them, with the members expanded. --tree
+// only changes the table output, JSON and YAML already nest the members.
+func showDevOps(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	var value any
+	var groups []*devops_resource.DevOps
+	if len(args) == 1 {
+		devops, err := api.GetDevOps(ctx, args[0])
+		if err != nil {
+			return err
+		}
+		value, groups = devops, []*devops_resource.DevOps{devops}
+	} else {
+		if groups, err = allDevOps(ctx, a, api, client.ListOptions{}); err != nil {
+			return err
+		}
+		value = groups
+	}
+	if boolFlag(fs, "tree") && a.output == "table" {
+		return writeTree(a.stdout, groups...)
+	}
+	return a.print(value, func() *table { return devOpsTable(groups...) })
+}
+
+func addToDevOps(ctx context.Context, a *app, kind string, id string, groupID string) error {
+	api, err := a.client()
+	if err != nil {
+		return err
+	}
+	var devops *devops_resource.DevOps
+	if kind == "dev" {
+		devops, err = api.AddDevToDevOps(ctx, id, groupID)
+	} else {
+		devops, err = api.AddOpsToDevOps(ctx, id, groupID)
+	}
+	if err != nil {
+		return err
+	}
+	return a.print(devops, func() *table { return devOpsTable(devops) })
+}
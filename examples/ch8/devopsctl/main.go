@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/validation"
+)
+
+// command is one node of the devopsctl command tree. Commands with subcommands only
+// dispatch, the others parse their flags and run.
+type command struct {
+	name     string
+	args     string // positional arguments: "<id>" is required, "[<id>]" optional, "<id>..." repeats
+	summary  string
+	complete []string // what each positional argument completes to: engineer, dev, ops, devops or context
+	flags    func(fs *flag.FlagSet)
+	run      func(ctx context.Context, a *app, fs *flag.FlagSet, args []string) error
+	commands []*command
+}
+
+func (c *command) find(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// app is the state shared by every command: where to write, the global flags and the
+// lazily created client for the selected context
+type app struct {
+	stdout  io.Writer
+	stderr  io.Writer
+	getenv  func(string) string
+	config  string // path of the config file
+	context string // name of the context to use instead of the current one
+	server  string // overrides the server of the context
+	token   string // overrides the token of the context
+	output  string // table, json or yaml
+	api     *client.Client
+}
+
+// usageError is a mistake on the command line, it is reported with the usage of the command
+type usageError struct{ message string }
+
+func (e usageError) Error() string { return e.message }
+
+func usagef(format string, args ...any) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// globalFlags are accepted before and after any command
+func (a *app) globalFlags(fs *flag.FlagSet) {
+	fs.StringVar(&a.config, "config", a.config, "path of the config file")
+	fs.StringVar(&a.context, "context", a.context, "context of the config file to use")
+	fs.StringVar(&a.server, "server", a.server, "URL of the devops api, overrides the context")
+	fs.StringVar(&a.token, "token", a.token, "bearer token, overrides the context")
+	fs.StringVar(&a.output, "o", a.output, "output format: table, json or yaml")
+	fs.StringVar(&a.output, "output", a.output, "output format: table, json or yaml")
+}
+
+// client connects to the server of the selected context, flags and the DEVOPSCTL_SERVER
+// and DEVOPSCTL_TOKEN environment variables take precedence
+func (a *app) client() (*client.Client, error) {
+	if a.api != nil {
+		return a.api, nil
+	}
+	cfg, err := loadConfig(a.config)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := cfg.selected(a.context)
+	if err != nil {
+		return nil, err
+	}
+	server, token := selected.Server, selected.Token
+	if value := a.getenv("DEVOPSCTL_SERVER"); value != "" {
+		server = value
+	}
+	if value := a.getenv("DEVOPSCTL_TOKEN"); value != "" {
+		token = value
+	}
+	if a.server != "" {
+		server = a.server
+	}
+	if a.token != "" {
+		token = a.token
+	}
+	opts := []client.Option{client.WithUserAgent("devopsctl")}
+	if token != "" {
+		opts = append(opts, client.WithToken(token))
+	}
+	if a.api, err = client.New(server, opts...); err != nil {
+		return nil, err
+	}
+	return a.api, nil
+}
+
+func root() *command {
+	return &command{
+		name:    "devopsctl",
+		summary: "manage the devops org chart through the devops api",
+		commands: []*command{
+			engineersCommand(),
+			groupCommand("dev"),
+			groupCommand("ops"),
+			devOpsCommand(),
+			configCommand(),
+			completionCommand(),
+		},
+	}
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdout, os.Stderr, os.Getenv)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command line args and returns the exit code: 0 on success, 1 when
+// the command failed and 2 for usage errors
+func run(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer, getenv func(string) string) int {
+	a := &app{stdout: stdout, stderr: stderr, getenv: getenv, config: getenv("DEVOPSCTL_CONFIG"), output: "table"}
+	if len(args) > 0 && args[0] == completeCommand {
+		a.complete(ctx, root(), args[1:])
+		return 0
+	}
+
+	cmd := root()
+	path := []string{cmd.name}
+	for {
+		fs := flag.NewFlagSet(strings.Join(path, " "), flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		a.globalFlags(fs)
+		if cmd.commands == nil && cmd.flags != nil {
+			cmd.flags(fs)
+		}
+		var positional []string
+		var err error
+		if cmd.commands == nil {
+			positional, err = parse(fs, args)
+		} else if err = fs.Parse(args); err == nil {
+			positional = fs.Args()
+		}
+		if errors.Is(err, flag.ErrHelp) {
+			usage(stdout, cmd, path, fs)
+			return 0
+		}
+		if err != nil {
+			return a.fail(cmd, path, fs, usageError{err.Error()})
+		}
+
+		if cmd.commands == nil {
+			if err := a.check(cmd, positional); err != nil {
+				return a.fail(cmd, path, fs, err)
+			}
+			return a.fail(cmd, path, fs, cmd.run(ctx, a, fs, positional))
+		}
+		if len(positional) == 0 {
+			usage(stderr, cmd, path, fs)
+			return 2
+		}
+		sub := cmd.find(positional[0])
+		if sub == nil {
+			return a.fail(cmd, path, fs, usagef("unknown command %q", positional[0]))
+		}
+		// flags given before the subcommand stay set on a, the rest is parsed again
+		cmd, args, path = sub, positional[1:], append(path, sub.name)
+	}
+}
+
+// parse parses the flags of fs wherever they appear in args, so flags may follow
+// positional arguments as in "engineers get E1 -o json", and returns the positional
+// arguments
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional, args = append(positional, fs.Arg(0)), fs.Args()[1:]
+	}
+}
+
+func stringFlag(fs *flag.FlagSet, name string) string {
+	return fs.Lookup(name).Value.String()
+}
+
+func boolFlag(fs *flag.FlagSet, name string) bool {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func intFlag(fs *flag.FlagSet, name string) int {
+	return fs.Lookup(name).Value.(flag.Getter).Get().(int)
+}
+
+// isSet reports whether the flag name was given on the command line
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) { set = set || f.Name == name })
+	return set
+}
+
+// check validates the positional arguments and the output format before running cmd
+func (a *app) check(cmd *command, positional []string) error {
+	switch a.output {
+	case "table", "json", "yaml":
+	default:
+		return usagef("unknown output format %q, use table, json or yaml", a.output)
+	}
+	optional := strings.Count(cmd.args, "[")
+	required := strings.Count(cmd.args, "<") - strings.Count(cmd.args, "[<")
+	variadic := strings.Contains(cmd.args, "...")
+	if len(positional) < required {
+		return usagef("expected %s", cmd.args)
+	}
+	if !variadic && len(positional) > required+optional {
+		return usagef("unexpected argument %q", positional[required+optional])
+	}
+	return nil
+}
+
+// fail reports err and returns the exit code for it
+func (a *app) fail(cmd *command, path []string, fs *flag.FlagSet, err error) int {
+	var usageErr usageError
+	var invalid validation.Errors
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(a.stderr, "%s: %s\n", strings.Join(path, " "), usageErr.message)
+		usage(a.stderr, cmd, path, fs)
+		return 2
+	case errors.As(err, &invalid):
+		fmt.Fprintf(a.stderr, "%s: invalid input\n", strings.Join(path, " "))
+		for _, problem := range invalid {
+			fmt.Fprintf(a.stderr, "  --%s: %s\n", flagFor(problem.Field), problem.Message)
+		}
+		return 1
+	default:
+		fmt.Fprintf(a.stderr, "%s: %s\n", strings.Join(path, " "), err)
+		return 1
+	}
+}
+
+func usage(w io.Writer, cmd *command, path []string, fs *flag.FlagSet) {
+	line := strings.Join(path, " ")
+	if cmd.commands != nil {
+		line += " <command>"
+	}
+	if cmd.args != "" {
+		line += " " + cmd.args
+	}
+	fmt.Fprintf(w, "Usage: %s [flags]\n\n%s\n", line, cmd.summary)
+	if cmd.commands != nil {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range cmd.commands {
+			fmt.Fprintf(w, "  %-14s %s\n", sub.name, sub.summary)
+		}
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	sort.Strings(names)
+	for _, name := range names {
+		f := fs.Lookup(name)
+		dashes := "--"
+		if len(name) == 1 {
+			dashes = "-"
+		}
+		fmt.Fprintf(w, "  %-14s %s\n", dashes+name, f.Usage)
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client"
+	"github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources/client/fake"
+)
+
+// newServer runs a fake devops api seeded with one devops group
+func newServer(t *testing.T) *fake.Server {
+	t.Helper()
+	srv := fake.NewServer()
+	t.Cleanup(srv.Close)
+	err := srv.Seed(&client.OrgChart{
+		Engineers: []*devops_resource.Engineer{
+			{Id: "E1", Name: "alice", Email: "alice@liatrio.com", Role: "lead", OnCall: true},
+			{Id: "E2", Name: "bob", Email: "bob@liatrio.com", ManagerId: "E1"},
+			{Id: "E3", Name: "carol", Email: "carol@liatrio.com"},
+		},
+		Devs:   []client.ChartGroup{{Id: "D1", Name: "dev_ferrets", Engineers: []string{"E1", "E2"}}},
+		Ops:    []client.ChartGroup{{Id: "O1", Name: "op_ferrets", Engineers: []string{"E3"}}},
+		DevOps: []client.ChartDevOps{{Id: "DO1", Devs: []string{"D1"}, Ops: []string{"O1"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+// devopsctl runs the command line args with env as the only environment variables
+func devopsctl(env map[string]string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), args, &stdout, &stderr, func(key string) string { return env[key] })
+	return stdout.String(), stderr.String(), code
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		description string
+		args        []string
+		code        int
+		stdout      string // exact output when set
+		contains    []string
+		stderr      string // part of the error output
+	}{
+		{
+			description: "List engineers as a table",
+			args:        []string{"engineers", "list"},
+			stdout: "ID   NAME    EMAIL               ROLE   MANAGER   ON-CALL\n" +
+				"E1   alice   alice@liatrio.com   lead             yes\n" +
+				"E2   bob     bob@liatrio.com            E1        \n" +
+				"E3   carol   carol@liatrio.com                    \n",
+		},
+		{
+			description: "List one page of engineers",
+			args:        []string{"engineers", "list", "--limit", "2", "--sort", "-name"},
+			contains:    []string{"carol", "bob"},
+			stderr:      "2 of 3 shown, continue with --cursor",
+		},
+		{
+			description: "Get an engineer as JSON, flags after the id",
+			args:        []string{"engineers", "get", "E2", "-o", "json"},
+			contains:    []string{`"name": "bob"`, `"manager_id": "E1"`},
+		},
+		{
+			description: "Get an engineer by email",
+			args:        []string{"engineers", "get", "--email", "carol@liatrio.com"},
+			contains:    []string{"E3   carol"},
+		},
+		{
+			description: "Get without an id",
+			args:        []string{"engineers", "get"},
+			code:        2,
+			stderr:      "give exactly one of <id>, --name or --email",
+		},
+		{
+			description: "Get an unknown engineer",
+			args:        []string{"engineers", "get", "E9"},
+			code:        1,
+			stderr:      "devops-api: 404 engineer_not_found",
+		},
+		{
+			description: "Create an engineer as YAML",
+			args:        []string{"-o", "yaml", "engineers", "create", "--name", "dave", "--email", "dave@liatrio.com", "--skills", "go, terraform", "--manager", "E1"},
+			stdout:      "name: dave\nid: E4\nemail: dave@liatrio.com\nskills:\n  - go\n  - terraform\nmanager_id: E1\n",
+		},
+		{
+			description: "Create an invalid engineer",
+			args:        []string{"engineers", "create", "--name", "dave", "--email", "dave", "--timezone", "Mars/Base"},
+			code:        1,
+			stderr:      "  --email: ",
+		},
+		{
+			description: "Create an engineer reporting to an unknown manager",
+			args:        []string{"engineers", "create", "--name", "dave", "--email", "dave@liatrio.com", "--manager", "E9"},
+			code:        1,
+			stderr:      "422 manager_not_found",
+		},
+		{
+			description: "Delete engineers",
+			args:        []string{"engineers", "delete", "E2", "E3"},
+			stdout:      "Engineer E2 deleted.\nEngineer E3 deleted.\n",
+		},
+		{
+			description: "Add an engineer to a dev group",
+			args:        []string{"dev", "add-engineer", "D1", "E3"},
+			stdout:      "ID   NAME          ENGINEERS\nD1   dev_ferrets   alice, bob, carol\n",
+		},
+		{
+			description: "Add an engineer to an ops group twice",
+			args:        []string{"ops", "add-engineer", "O1", "E3"},
+			code:        1,
+			stderr:      "409",
+		},
+		{
+			description: "List ops groups of a member",
+			args:        []string{"ops", "list", "--member", "E3", "-o", "json"},
+			contains:    []string{`"name": "op_ferrets"`},
+		},
+		{
+			description: "Show a devops group as a tree",
+			args:        []string{"devops", "show", "DO1", "--tree"},
+			stdout: "DO1\n" +
+				"├── dev D1 dev_ferrets\n" +
+				"│   ├── E1 alice <alice@liatrio.com>\n" +
+				"│   └── E2 bob <bob@liatrio.com>\n" +
+				"└── ops O1 op_ferrets\n" +
+				"    └── E3 carol <carol@liatrio.com>\n",
+		},
+		{
+			description: "Show every devops group as YAML keeps the API's field names",
+			args:        []string{"devops", "show", "-o", "yaml", "--tree"},
+			contains:    []string{"- id: DO1\n  dev:\n    - name: dev_ferrets\n", "  ops:\n    - name: op_ferrets\n"},
+		},
+		{
+			description: "Add an unknown dev group to a devops group",
+			args:        []string{"devops", "add-dev", "DO1", "D9"},
+			code:        1,
+			stderr:      "422 dev_not_found",
+		},
+		{
+			description: "Unknown command",
+			args:        []string{"engineers", "fire"},
+			code:        2,
+			stderr:      `unknown command "fire"`,
+		},
+		{
+			description: "Unknown output format",
+			args:        []string{"engineers", "list", "-o", "xml"},
+			code:        2,
+			stderr:      `unknown output format "xml"`,
+		},
+		{
+			description: "Too many arguments",
+			args:        []string{"dev", "get", "D1", "D2"},
+			code:        2,
+			stderr:      `unexpected argument "D2"`,
+		},
+	}
+
+	for _, test := range tests {
+		srv := newServer(t)
+		env := map[string]string{"DEVOPSCTL_SERVER": srv.URL, "DEVOPSCTL_CONFIG": filepath.Join(t.TempDir(), "config.yaml")}
+		stdout, stderr, code := devopsctl(env, test.args...)
+
+		if code != test.code {
+			t.Errorf("\nTest: %s\nExpected exit code: %d, Received: %d\nStderr: %s", test.description, test.code, code, stderr)
+		}
+		if test.stdout != "" && stdout != test.stdout {
+			t.Errorf("\nTest: %s\nExpected output:\n%s\nReceived:\n%s", test.description, test.stdout, stdout)
+		}
+		for _, part := range test.contains {
+			if !strings.Contains(stdout, part) {
+				t.Errorf("\nTest: %s\nExpected output containing: %q, Received:\n%s", test.description, part, stdout)
+			}
+		}
+		if !strings.Contains(stderr, test.stderr) {
+			t.Errorf("\nTest: %s\nExpected error output containing: %q, Received: %q", test.description, test.stderr, stderr)
+		}
+	}
+}
+
+func TestContexts(t *testing.T) {
+	local := newServer(t)
+	staging := newServer(t)
+	staging.RequireToken("s3cr3t")
+	path := filepath.Join(t.TempDir(), "devopsctl", "config.yaml")
+	env := map[string]string{"DEVOPSCTL_CONFIG": path}
+
+	steps := []struct {
+		description string
+		args        []string
+		code        int
+		stdout      string
+	}{
+		{"No context yet", []string{"config", "current-context"}, 1, ""},
+		{"Add the local context", []string{"config", "set-context", "local", "--server", local.URL}, 0, "Context \"local\" set.\n"},
+		{"The first context is current", []string{"config", "current-context"}, 0, "local\n"},
+		{"Add the staging context", []string{"config", "set-context", "staging", "--server", staging.URL, "--token", "s3cr3t"}, 0, "Context \"staging\" set.\n"},
+		{"Use the local server", []string{"engineers", "delete", "E1"}, 0, "Engineer E1 deleted.\n"},
+		{"Select staging for one command", []string{"--context", "staging", "engineers", "get", "E1", "-o", "json"}, 0, ""},
+		{"Switch to staging", []string{"config", "use-context", "staging"}, 0, "Switched to context \"staging\".\n"},
+		{"Override the token", []string{"engineers", "list", "--token", "wrong"}, 1, ""},
+		{"List the contexts", []string{"config", "get-contexts"}, 0, "CURRENT   NAME      SERVER\n          local     " + local.URL + "\n*         staging   " + staging.URL + "\n"},
+		{"Unknown context", []string{"--context", "prod", "engineers", "list"}, 1, ""},
+		{"Delete the current context", []string{"config", "delete-context", "staging"}, 0, "Context \"staging\" deleted.\n"},
+	}
+	for _, step := range steps {
+		stdout, stderr, code := devopsctl(env, step.args...)
+		if code != step.code {
+			t.Errorf("\nTest: %s\nExpected exit code: %d, Received: %d\nStderr: %s", step.description, step.code, code, stderr)
+		}
+		if step.stdout != "" && stdout != step.stdout {
+			t.Errorf("\nTest: %s\nExpected output:\n%s\nReceived:\n%s", step.description, step.stdout, stdout)
+		}
+	}
+
+	if _, _, code := devopsctl(map[string]string{"DEVOPSCTL_SERVER": local.URL}, "engineers", "get", "E1"); code != 1 {
+		t.Errorf("\nTest: The local server was not used\nExpected E1 to be deleted there, Received exit code: %d", code)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("\nTest: The config file holds tokens\nExpected mode: 0600, Received: %o", info.Mode().Perm())
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	srv := newServer(t)
+	config := filepath.Join(t.TempDir(), "config.yaml")
+	env := map[string]string{"DEVOPSCTL_SERVER": srv.URL, "DEVOPSCTL_CONFIG": config}
+	if _, stderr, code := devopsctl(env, "config", "set-context", "local", "--server", srv.URL); code != 0 {
+		t.Fatal(stderr)
+	}
+
+	tests := []struct {
+		description string
+		words       []string
+		expected    string
+	}{
+		{"Commands", []string{""}, "completion\nconfig\ndev\ndevops\nengineers\nops\n"},
+		{"Subcommands with a prefix", []string{"devops", "a"}, "add-dev\nadd-ops\n"},
+		{"Flags", []string{"devops", "show", "--t"}, "--token\n--tree\n"},
+		{"Output formats", []string{"engineers", "list", "-o", "y"}, "yaml\n"},
+		{"Contexts", []string{"--context", ""}, "local\n"},
+		{"Engineer ids", []string{"engineers", "get", ""}, "E1\nE2\nE3\n"},
+		{"Every argument of delete", []string{"engineers", "delete", "E1", "E"}, "E1\nE2\nE3\n"},
+		{"The second argument", []string{"dev", "add-engineer", "D1", "--output", "json", ""}, "E1\nE2\nE3\n"},
+		{"The first argument", []string{"devops", "add-ops", "D"}, "DO1\n"},
+		{"No more arguments", []string{"dev", "get", "D1", ""}, ""},
+	}
+	for _, test := range tests {
+		stdout, _, code := devopsctl(env, append([]string{completeCommand}, test.words...)...)
+		if code != 0 || stdout != test.expected {
+			t.Errorf("\nTest: %s\nExpected: %q, Received: %q", test.description, test.expected, stdout)
+		}
+	}
+
+	for _, shell := range []string{"bash", "zsh"} {
+		stdout, _, code := devopsctl(env, "completion", shell)
+		if code != 0 || !strings.Contains(stdout, "devopsctl __complete") {
+			t.Errorf("\nTest: %s completion script\nExpected a script calling devopsctl __complete, Received: %q", shell, stdout)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	devops_resource "github.com/liatrio/devops-bootcamp/examples/ch7/devops-resources"
+	"gopkg.in/yaml.v3"
+)
+
+// table is the table output of a command, one row per resource
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+// print writes value in the selected output format, tables are built by rows only
+// when they are needed
+func (a *app) print(value any, rows func() *table) error {
+	switch a.output {
+	case "json":
+		return writeJSON(a.stdout, value)
+	case "yaml":
+		return writeYAML(a.stdout, value)
+	default:
+		return writeTable(a.stdout, rows())
+	}
+}
+
+func writeTable(w io.Writer, t *table) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, value any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// writeYAML writes value with the field names and field order of its JSON encoding, the
+// devops_resource groups only carry json tags
+func writeYAML(w io.Writer, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	// JSON is YAML, decoding it into a node keeps the order of the fields
+	var document yaml.Node
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+	blockStyle(&document)
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// blockStyle drops the flow style and quotes the JSON decoding left on node, empty
+// lists and objects stay [] and {}
+func blockStyle(node *yaml.Node) {
+	if len(node.Content) > 0 || node.Kind == yaml.ScalarNode {
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func engineerTable(engineers ...*devops_resource.Engineer) *table {
+	t := &table{header: []string{"ID", "NAME", "EMAIL", "ROLE", "MANAGER", "ON-CALL"}}
+	for _, engineer := range engineers {
+		onCall := ""
+		if engineer.OnCall {
+			onCall = "yes"
+		}
+		t.add(engineer.Id, engineer.Name, engineer.Email, engineer.Role, engineer.ManagerId, onCall)
+	}
+	return t
+}
+
+// group is what dev and ops groups have in common
+type group struct {
+	Id        string
+	Name      string
+	Engineers []*devops_resource.Engineer
+}
+
+func groupTable(groups ...group) *table {
+	t := &table{header: []string{"ID", "NAME", "ENGINEERS"}}
+	for _, g := range groups {
+		names := make([]string, 0, len(g.Engineers))
+		for _, engineer := range g.Engineers {
+			names = append(names, engineer.Name)
+		}
+		t.add(g.Id, g.Name, strings.Join(names, ", "))
+	}
+	return t
+}
+
+func devOpsTable(groups ...*devops_resource.DevOps) *table {
+	t := &table{header: []string{"ID", "DEV", "OPS"}}
+	for _, devops := range groups {
+		var devs, ops []string
+		for _, dev := range devops.Devs {
+			devs = append(devs, dev.Name)
+		}
+		for _, op := range devops.Ops {
+			ops = append(ops, op.Name)
+		}
+		t.add(devops.Id, strings.Join(devs, ", "), strings.Join(ops, ", "))
+	}
+	return t
+}
+
+// writeTree draws the devops groups with their dev and ops groups and engineers
+//
+//	DO1
+//	├── dev D1 dev_ferrets
+//	│   └── E1 alice <alice@liatrio.com>
+//	└── ops O1 op_ferrets
+func writeTree(w io.Writer, groups ...*devops_resource.DevOps) error {
+	var out strings.Builder
+	for _, devops := range groups {
+		out.WriteString(devops.Id + "\n")
+		var children []group
+		var kinds []string
+		for _, dev := range devops.Devs {
+			children, kinds = append(children, group{dev.Id, dev.Name, dev.Engineers}), append(kinds, "dev")
+		}
+		for _, op := range devops.Ops {
+			children, kinds = append(children, group{op.Id, op.Name, op.Engineers}), append(kinds, "ops")
+		}
+		for i, child := range children {
+			branch, indent := "├── ", "│   "
+			if i == len(children)-1 {
+				branch, indent = "└── ", "    "
+			}
+			fmt.Fprintf(&out, "%s%s %s %s\n", branch, kinds[i], child.Id, child.Name)
+			for j, engineer := range child.Engineers {
+				leaf := "├── "
+				if j == len(child.Engineers)-1 {
+					leaf = "└── "
+				}
+				fmt.Fprintf(&out, "%s%s%s %s <%s>\n", indent, leaf, engineer.Id, engineer.Name, engineer.Email)
+			}
+		}
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}